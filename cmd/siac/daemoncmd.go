@@ -2,12 +2,30 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/spf13/cobra"
 )
 
 var (
+	alertsCmd = &cobra.Command{
+		Use:   "alerts",
+		Short: "View the active alerts",
+		Long:  "View the active alerts of the daemon's modules, oldest first.",
+		Run:   wrap(alertscmd),
+	}
+
+	alertsAcknowledgeCmd = &cobra.Command{
+		Use:   "acknowledge [id]",
+		Short: "Acknowledge an alert",
+		Long: `Acknowledge an alert. The acknowledgement lasts until the condition that
+caused the alert is resolved.`,
+		Run: wrap(alertsacknowledgecmd),
+	}
+
 	stopCmd = &cobra.Command{
 		Use:   "stop",
 		Short: "Stop the Sia daemon",
@@ -58,6 +76,41 @@ func versioncmd() {
 	}
 }
 
+// alertscmd is the handler for the command `siac alerts`.
+// Lists the active alerts.
+func alertscmd() {
+	dag, err := httpClient.DaemonAlertsGet()
+	if err != nil {
+		die("Could not get alerts:", err)
+	}
+	if len(dag.Alerts) == 0 {
+		fmt.Println("No active alerts.")
+		return
+	}
+	fmt.Println("Active Alerts:")
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  ID\tModule\tSeverity\tAcknowledged\tMessage")
+	for _, a := range dag.Alerts {
+		fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\n", a.ID, a.Module, a.Severity, yesNo(a.Acknowledged), a.Message)
+	}
+	w.Flush()
+	for _, a := range dag.Alerts {
+		if a.Cause != "" {
+			fmt.Printf("\n%v: %v\n", a.ID, a.Cause)
+		}
+	}
+}
+
+// alertsacknowledgecmd is the handler for the command `siac alerts
+// acknowledge [id]`. Acknowledges an alert.
+func alertsacknowledgecmd(id string) {
+	err := httpClient.DaemonAlertsAcknowledgePost(modules.AlertID(id))
+	if err != nil {
+		die("Could not acknowledge alert:", err)
+	}
+	fmt.Println("Acknowledged alert", id)
+}
+
 // stopcmd is the handler for the command `siac stop`.
 // Stops the daemon.
 func stopcmd() {
@@ -234,6 +234,7 @@ RPC Stats:
 	Revise Calls:       %v
 	Settings Calls:     %v
 	FormContract Calls: %v
+	Session Calls:      %v
 `,
 			connectabilityString,
 
@@ -269,7 +270,7 @@ RPC Stats:
 
 			nm.ErrorCalls, nm.UnrecognizedCalls, nm.DownloadCalls,
 			nm.RenewCalls, nm.ReviseCalls, nm.SettingsCalls,
-			nm.FormContractCalls)
+			nm.FormContractCalls, nm.SessionCalls)
 	} else {
 		fmt.Printf(`Host info:
 	Connectability Status: %v
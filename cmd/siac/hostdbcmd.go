@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -16,8 +18,10 @@ import (
 const scanHistoryLen = 30
 
 var (
-	hostdbNumHosts int
-	hostdbVerbose  bool
+	hostdbImportOrigin   string
+	hostdbNumHosts       int
+	hostdbRescanProgress bool
+	hostdbVerbose        bool
 )
 
 var (
@@ -28,6 +32,102 @@ var (
 		Run:   wrap(hostdbcmd),
 	}
 
+	hostdbExportCmd = &cobra.Command{
+		Use:   "export [destination]",
+		Short: "Export the hostdb to a file.",
+		Long: `Export all hosts of the hostdb, including their scan history and
+interactions, to a file that can be imported by another renter.`,
+		Run: wrap(hostdbexportcmd),
+	}
+
+	hostdbFilterCmd = &cobra.Command{
+		Use:   "filter [mode] [pubkeys...]",
+		Short: "View or set the hostdb's filter mode.",
+		Long: `View or set the filter mode of the hostdb. Without arguments, the current
+filter mode and the hosts it applies to are shown.
+
+mode is one of:
+  disable    use any host
+  blacklist  never use the listed hosts
+  whitelist  only use the listed hosts
+
+Contracts with hosts that are excluded by the filter are no longer renewed or
+used for uploads, and the renter migrates their data to other hosts.`,
+		Run: hostdbfiltercmd,
+	}
+
+	hostdbHistoryCmd = &cobra.Command{
+		Use:   "history [pubkey]",
+		Short: "View the history of a host's settings.",
+		Long: `View the recorded changes of a host's prices, collateral and storage,
+oldest first. Recent price increases lower the score of a host.`,
+		Run: wrap(hostdbhistorycmd),
+	}
+
+	hostdbImportCmd = &cobra.Command{
+		Use:   "import [source]",
+		Short: "Import an exported hostdb.",
+		Long: `Import a hostdb that was exported by another renter. Unknown hosts are
+added, and the scan history of known hosts is merged with the imported
+history. Imported scans are marked with the origin set by the '--origin' flag,
+or with the name of the file if no origin is set.`,
+		Run: wrap(hostdbimportcmd),
+	}
+
+	hostdbRescanCmd = &cobra.Command{
+		Use:   "rescan",
+		Short: "Rescan all hosts.",
+		Long: `Queue a scan of every host in the hostdb and show the progress of the
+rescan. If the '-p' flag is set, only the progress of the most recent rescan is
+shown.`,
+		Run: wrap(hostdbrescancmd),
+	}
+
+	hostdbScanCmd = &cobra.Command{
+		Use:   "scan [pubkey]",
+		Short: "Scan a host immediately.",
+		Long:  "Scan a host immediately and show whether it is online and its current settings.",
+		Run:   wrap(hostdbscancmd),
+	}
+
+	hostdbScanPolicyCmd = &cobra.Command{
+		Use:   "scanpolicy [setting=value...]",
+		Short: "View or set the hostdb's scan policy.",
+		Long: `View or set the policy that the hostdb uses to scan hosts. Without
+arguments, the current policy is shown.
+
+The settings are:
+  threads   maximum number of hosts that are scanned in parallel
+  online    minimum time between scans of hosts that were online, e.g. 30m
+  offline   minimum time between scans of hosts that were offline, e.g. 4h
+  timeout   how long a host has to accept the connection of a scan, e.g. 30s
+
+The actual scan intervals are randomized up to 6 times as long.
+
+Example:
+  siac hostdb scanpolicy threads=20 online=1h`,
+		Run: hostdbscanpolicycmd,
+	}
+
+	hostdbScoringCmd = &cobra.Command{
+		Use:   "scoring [preset] [factor=weight...]",
+		Short: "View or set the hostdb's scoring profile.",
+		Long: `View or set the profile that the hostdb uses to score hosts. Without
+arguments, the current profile and the available presets are shown.
+
+Each factor of a host's score is raised to the power of its weight, so a
+weight of 1 keeps the default behavior, a larger weight makes the factor more
+important and a weight of 0 ignores it. The factors are age, benchmark,
+collateral, interaction, price, storageremaining, uptime, version and
+volatility.
+
+Examples:
+  siac hostdb scoring cheap           select the "cheap" preset
+  siac hostdb scoring uptime=2        double the importance of uptime
+  siac hostdb scoring fast price=1    use the "fast" preset, but weight prices normally`,
+		Run: hostdbscoringcmd,
+	}
+
 	hostdbViewCmd = &cobra.Command{
 		Use:   "view [pubkey]",
 		Short: "View the full information for a host.",
@@ -41,6 +141,7 @@ func printScoreBreakdown(info *api.HostdbHostsGET) {
 	fmt.Println("\n  Score Breakdown:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	fmt.Fprintf(w, "\t\tAge:\t %.3f\n", info.ScoreBreakdown.AgeAdjustment)
+	fmt.Fprintf(w, "\t\tBenchmark:\t %.3f\n", info.ScoreBreakdown.BenchmarkAdjustment)
 	fmt.Fprintf(w, "\t\tBurn:\t %.3f\n", info.ScoreBreakdown.BurnAdjustment)
 	fmt.Fprintf(w, "\t\tCollateral:\t %.3f\n", info.ScoreBreakdown.CollateralAdjustment)
 	fmt.Fprintf(w, "\t\tInteraction:\t %.3f\n", info.ScoreBreakdown.InteractionAdjustment)
@@ -48,6 +149,7 @@ func printScoreBreakdown(info *api.HostdbHostsGET) {
 	fmt.Fprintf(w, "\t\tStorage:\t %.3f\n", info.ScoreBreakdown.StorageRemainingAdjustment)
 	fmt.Fprintf(w, "\t\tUptime:\t %.3f\n", info.ScoreBreakdown.UptimeAdjustment)
 	fmt.Fprintf(w, "\t\tVersion:\t %.3f\n", info.ScoreBreakdown.VersionAdjustment)
+	fmt.Fprintf(w, "\t\tVolatility:\t %.3f\n", info.ScoreBreakdown.VolatilityAdjustment)
 	w.Flush()
 }
 
@@ -326,5 +428,310 @@ func hostdbviewcmd(pubkey string) {
 	fmt.Println("\n  Scan History Length:", len(info.Entry.ScanHistory))
 	fmt.Printf("  Overall Uptime:      %.3f\n", uptimeRatio)
 
+	printBenchmarks(info.Entry.Benchmarks)
+
 	fmt.Println()
 }
+
+// printBenchmarks prints the average settings latency and download
+// throughput of a host, followed by its individual benchmarks.
+func printBenchmarks(benchmarks []modules.HostBenchmark) {
+	if len(benchmarks) == 0 {
+		return
+	}
+	var latency time.Duration
+	var throughput float64
+	var latencies, downloads int
+	for _, b := range benchmarks {
+		if b.Error != "" {
+			continue
+		}
+		latency += b.SettingsLatency
+		latencies++
+		if b.DownloadThroughput > 0 {
+			throughput += b.DownloadThroughput
+			downloads++
+		}
+	}
+	fmt.Println("\n  Benchmarks:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if latencies > 0 {
+		fmt.Fprintln(w, "\t\tAverage Latency:\t", (latency / time.Duration(latencies)).Round(time.Millisecond))
+	}
+	if downloads > 0 {
+		fmt.Fprintln(w, "\t\tAverage Throughput:\t", speedUnits(int64(throughput/float64(downloads))))
+	}
+	w.Flush()
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\n\t\tTime\tLatency\tThroughput\tError")
+	for _, b := range benchmarks {
+		throughput := "-"
+		if b.DownloadThroughput > 0 {
+			throughput = speedUnits(int64(b.DownloadThroughput))
+		}
+		fmt.Fprintf(w, "\t\t%v\t%v\t%v\t%v\n", b.Timestamp.Format(time.RFC822), b.SettingsLatency.Round(time.Millisecond), throughput, b.Error)
+	}
+	w.Flush()
+}
+
+// hostdbhistorycmd is the handler for the command `siac hostdb history`. It
+// shows the recorded changes of a host's settings.
+func hostdbhistorycmd(pubkey string) {
+	var publicKey types.SiaPublicKey
+	publicKey.LoadString(pubkey)
+	hhhg, err := httpClient.HostDbHostsHistoryGet(publicKey)
+	if err != nil {
+		die("Could not fetch settings history:", err)
+	}
+	if len(hhhg.History) == 0 {
+		fmt.Println("No settings have been recorded for this host.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Time\tStorage (TB / Mo)\tDownload (TB)\tUpload (TB)\tContract\tCollateral (TB / Mo)\tRemaining\tAccepting")
+	for _, c := range hhhg.History {
+		fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", c.Timestamp.Format(time.RFC822),
+			currencyUnits(c.StoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)),
+			currencyUnits(c.DownloadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
+			currencyUnits(c.UploadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
+			currencyUnits(c.ContractPrice),
+			currencyUnits(c.Collateral.Mul(modules.BlockBytesPerMonthTerabyte)),
+			filesizeUnits(int64(c.RemainingStorage)),
+			yesNo(c.AcceptingContracts))
+	}
+	w.Flush()
+}
+
+// hostdbexportcmd is the handler for the command `siac hostdb export`. It
+// exports the hostdb to a file.
+func hostdbexportcmd(destination string) {
+	destination = abs(destination)
+	if err := httpClient.HostDbExportPost(destination); err != nil {
+		die("Could not export hostdb:", err)
+	}
+	fmt.Println("Exported hostdb to", destination)
+}
+
+// hostdbimportcmd is the handler for the command `siac hostdb import`. It
+// imports an exported hostdb.
+func hostdbimportcmd(source string) {
+	hip, err := httpClient.HostDbImportPost(abs(source), hostdbImportOrigin)
+	if err != nil {
+		die("Could not import hostdb:", err)
+	}
+	fmt.Printf("Imported hostdb: %v hosts added, %v merged, %v skipped.\n", hip.Added, hip.Merged, hip.Skipped)
+}
+
+// hostdbfiltercmd is the handler for the command `siac hostdb filter`. It
+// shows or sets the hostdb's filter mode.
+func hostdbfiltercmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		hfmg, err := httpClient.HostDbFilterModeGet()
+		if err != nil {
+			die("Could not get filter mode:", err)
+		}
+		fmt.Println("Filter mode:", hfmg.FilterMode)
+		if len(hfmg.Hosts) > 0 {
+			fmt.Println("\nHosts:")
+			for _, host := range hfmg.Hosts {
+				fmt.Println(" ", host)
+			}
+		}
+		return
+	}
+
+	var fm modules.FilterMode
+	if err := fm.FromString(args[0]); err != nil {
+		die("Could not parse filter mode:", err)
+	}
+	var hosts []types.SiaPublicKey
+	for _, pk := range args[1:] {
+		var spk types.SiaPublicKey
+		spk.LoadString(pk)
+		if len(spk.Key) == 0 {
+			die("Could not parse public key:", pk)
+		}
+		hosts = append(hosts, spk)
+	}
+	err := httpClient.HostDbFilterModePost(fm, hosts)
+	if err != nil {
+		die("Could not set filter mode:", err)
+	}
+	fmt.Println("Filter mode set to", fm)
+}
+
+// printScoringProfile prints the weights of a scoring profile.
+func printScoringProfile(w *tabwriter.Writer, p modules.HostScoringProfile) {
+	fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", p.Name, p.AgeWeight, p.BenchmarkWeight,
+		p.CollateralWeight, p.InteractionWeight, p.PriceWeight, p.StorageRemainingWeight, p.UptimeWeight,
+		p.VersionWeight, p.VolatilityWeight)
+}
+
+// hostdbscoringcmd is the handler for the command `siac hostdb scoring`. It
+// shows or sets the hostdb's scoring profile.
+func hostdbscoringcmd(cmd *cobra.Command, args []string) {
+	hsg, err := httpClient.HostDbScoringGet()
+	if err != nil {
+		die("Could not get scoring profile:", err)
+	}
+	if len(args) == 0 {
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Profile\tAge\tBenchmark\tCollateral\tInteraction\tPrice\tStorage\tUptime\tVersion\tVolatility")
+		printScoringProfile(w, hsg.Profile)
+		w.Flush()
+		fmt.Println("\nPresets:")
+		w = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Preset\tAge\tBenchmark\tCollateral\tInteraction\tPrice\tStorage\tUptime\tVersion\tVolatility")
+		for _, p := range hsg.Presets {
+			printScoringProfile(w, p)
+		}
+		w.Flush()
+		return
+	}
+
+	// The profile starts out as the selected preset, or as the current
+	// profile, and is then modified by the provided weights.
+	p := hsg.Profile
+	if !strings.Contains(args[0], "=") {
+		preset, exists := modules.HostScoringPreset(args[0])
+		if !exists {
+			die("Unknown scoring preset:", args[0])
+		}
+		p = preset
+		args = args[1:]
+		if len(args) == 0 {
+			if err := httpClient.HostDbScoringPresetPost(p.Name); err != nil {
+				die("Could not set scoring profile:", err)
+			}
+			fmt.Println("Scoring profile set to", p.Name)
+			return
+		}
+	}
+	weights := map[string]*float64{
+		"age":              &p.AgeWeight,
+		"benchmark":        &p.BenchmarkWeight,
+		"collateral":       &p.CollateralWeight,
+		"interaction":      &p.InteractionWeight,
+		"price":            &p.PriceWeight,
+		"storageremaining": &p.StorageRemainingWeight,
+		"uptime":           &p.UptimeWeight,
+		"version":          &p.VersionWeight,
+		"volatility":       &p.VolatilityWeight,
+	}
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		weight, exists := weights[kv[0]]
+		if len(kv) != 2 || !exists {
+			cmd.UsageFunc()(cmd)
+			os.Exit(exitCodeUsage)
+		}
+		if _, err := fmt.Sscan(kv[1], weight); err != nil {
+			die("Could not parse weight of "+kv[0]+":", err)
+		}
+	}
+	if err := httpClient.HostDbScoringPost(p); err != nil {
+		die("Could not set scoring profile:", err)
+	}
+	fmt.Println("Scoring profile updated.")
+}
+
+// hostdbscancmd is the handler for the command `siac hostdb scan`. It scans a
+// host immediately.
+func hostdbscancmd(pubkey string) {
+	var publicKey types.SiaPublicKey
+	publicKey.LoadString(pubkey)
+	hsp, err := httpClient.HostDbScanPost(publicKey)
+	if err != nil {
+		die("Could not scan host:", err)
+	}
+	if !hsp.Success {
+		fmt.Println("Host is offline:", hsp.Error)
+		return
+	}
+	entry := hsp.Entry
+	fmt.Println("Host is online.")
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Version:\t", entry.Version)
+	fmt.Fprintln(w, "  Accepting Contracts:\t", yesNo(entry.AcceptingContracts))
+	fmt.Fprintln(w, "  Remaining Storage:\t", filesizeUnits(int64(entry.RemainingStorage)))
+	fmt.Fprintln(w, "  Storage Price (TB / Mo):\t", currencyUnits(entry.StoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)))
+	fmt.Fprintln(w, "  Download Price (TB):\t", currencyUnits(entry.DownloadBandwidthPrice.Mul(modules.BytesPerTerabyte)))
+	fmt.Fprintln(w, "  Upload Price (TB):\t", currencyUnits(entry.UploadBandwidthPrice.Mul(modules.BytesPerTerabyte)))
+	fmt.Fprintln(w, "  Contract Price:\t", currencyUnits(entry.ContractPrice))
+	w.Flush()
+}
+
+// hostdbrescancmd is the handler for the command `siac hostdb rescan`. It
+// starts a rescan of all hosts and shows its progress.
+func hostdbrescancmd() {
+	if !hostdbRescanProgress {
+		if err := httpClient.HostDbRescanPost(); err != nil {
+			die("Could not start rescan:", err)
+		}
+	}
+	hrg, err := httpClient.HostDbRescanGet()
+	if err != nil {
+		die("Could not get rescan progress:", err)
+	}
+	if hrg.StartTime.IsZero() {
+		fmt.Println("No rescan has been started.")
+		return
+	}
+	scanned := hrg.Total - hrg.Remaining
+	if hrg.Active {
+		fmt.Printf("Rescan started %v: %v of %v hosts scanned.\n", hrg.StartTime.Format(time.RFC822), scanned, hrg.Total)
+	} else {
+		fmt.Printf("Rescan started %v: completed, %v hosts scanned.\n", hrg.StartTime.Format(time.RFC822), hrg.Total)
+	}
+}
+
+// hostdbscanpolicycmd is the handler for the command `siac hostdb
+// scanpolicy`. It shows or sets the hostdb's scan policy.
+func hostdbscanpolicycmd(cmd *cobra.Command, args []string) {
+	hspg, err := httpClient.HostDbScanPolicyGet()
+	if err != nil {
+		die("Could not get scan policy:", err)
+	}
+	p := hspg.HostDBScanPolicy
+	if len(args) == 0 {
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  Scanning Threads:\t", p.ScanningThreads)
+		fmt.Fprintln(w, "  Online Scan Interval:\t", p.OnlineScanInterval)
+		fmt.Fprintln(w, "  Offline Scan Interval:\t", p.OfflineScanInterval)
+		fmt.Fprintln(w, "  Scan Timeout:\t", p.ScanTimeout)
+		w.Flush()
+		return
+	}
+
+	durations := map[string]*time.Duration{
+		"offline": &p.OfflineScanInterval,
+		"online":  &p.OnlineScanInterval,
+		"timeout": &p.ScanTimeout,
+	}
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			cmd.UsageFunc()(cmd)
+			os.Exit(exitCodeUsage)
+		}
+		if kv[0] == "threads" {
+			if _, err := fmt.Sscan(kv[1], &p.ScanningThreads); err != nil {
+				die("Could not parse threads:", err)
+			}
+			continue
+		}
+		d, exists := durations[kv[0]]
+		if !exists {
+			cmd.UsageFunc()(cmd)
+			os.Exit(exitCodeUsage)
+		}
+		if *d, err = time.ParseDuration(kv[1]); err != nil {
+			die("Could not parse "+kv[0]+":", err)
+		}
+	}
+	if err := httpClient.HostDbScanPolicyPost(p); err != nil {
+		die("Could not set scan policy:", err)
+	}
+	fmt.Println("Scan policy updated.")
+}
@@ -13,12 +13,30 @@ import (
 
 var (
 	// Flags.
-	hostContractOutputType string // output type for host contracts
-	hostVerbose            bool   // display additional host info
-	initForce              bool   // destroy and reencrypt the wallet on init if it already exists
-	initPassword           bool   // supply a custom password when creating a wallet
-	renterListVerbose      bool   // Show additional info about uploaded files.
-	renterShowHistory      bool   // Show download history in addition to download queue.
+	hostContractOutputType   string // output type for host contracts
+	hostVerbose              bool   // display additional host info
+	initForce                bool   // destroy and reencrypt the wallet on init if it already exists
+	initPassword             bool   // supply a custom password when creating a wallet
+	renterAllowanceDryRun    bool   // Show the contracts an allowance would form without setting it.
+	renterContractFunds      string // Funds of a manually formed or renewed contract.
+	renterExpectedDownload   string // Expected download volume per period.
+	renterExpectedRedundancy string // Expected redundancy of uploaded files.
+	renterExpectedStorage    string // Expected storage volume.
+	renterExpectedUpload     string // Expected upload volume per period.
+	renterListVerbose        bool   // Show additional info about uploaded files.
+	renterMaxCollateralRatio string // Maximum ratio of host collateral to storage price.
+	renterMaxContractPrice   string // Maximum host contract price.
+	renterMaxDownloadPrice   string // Maximum host download bandwidth price, per TB.
+	renterMaxStoragePrice    string // Maximum host storage price, per TB per month.
+	renterMaxUploadPrice     string // Maximum host upload bandwidth price, per TB.
+	renterSpendingCSV        bool   // Print the spending as comma-separated values.
+	renterSpendingHistory    bool   // Show the spending of every billing period.
+	renterShowHistory        bool   // Show download history in addition to download queue.
+	renterSnapshotID         string // ID of the snapshot to restore.
+	renterSyncDelete         bool   // Delete remote files that no longer exist locally.
+	renterSyncDryRun         bool   // Only report the actions a sync would take.
+	renterSyncInterval       string // Interval between runs of a recurring sync.
+	renterSyncJSON           bool   // Print the sync report as JSON.
 )
 
 var (
@@ -84,6 +102,9 @@ func main() {
 	root.AddCommand(versionCmd)
 	root.AddCommand(stopCmd)
 
+	root.AddCommand(alertsCmd)
+	alertsCmd.AddCommand(alertsAcknowledgeCmd)
+
 	root.AddCommand(updateCmd)
 	updateCmd.AddCommand(updateCheckCmd)
 
@@ -95,9 +116,12 @@ func main() {
 	hostContractCmd.Flags().StringVarP(&hostContractOutputType, "type", "t", "value", "Select output type")
 
 	root.AddCommand(hostdbCmd)
-	hostdbCmd.AddCommand(hostdbViewCmd)
+	hostdbCmd.AddCommand(hostdbViewCmd, hostdbExportCmd, hostdbFilterCmd, hostdbHistoryCmd, hostdbImportCmd,
+		hostdbRescanCmd, hostdbScanCmd, hostdbScanPolicyCmd, hostdbScoringCmd)
 	hostdbCmd.Flags().IntVarP(&hostdbNumHosts, "numhosts", "n", 0, "Number of hosts to display from the hostdb")
 	hostdbCmd.Flags().BoolVarP(&hostdbVerbose, "verbose", "v", false, "Display full hostdb information")
+	hostdbImportCmd.Flags().StringVarP(&hostdbImportOrigin, "origin", "", "", "Origin that the imported scans are marked with")
+	hostdbRescanCmd.Flags().BoolVarP(&hostdbRescanProgress, "progress", "p", false, "Only show the progress of the most recent rescan")
 
 	root.AddCommand(minerCmd)
 	minerCmd.AddCommand(minerStartCmd, minerStopCmd)
@@ -114,18 +138,41 @@ func main() {
 	walletUnlockCmd.Flags().BoolVarP(&initPassword, "password", "p", false, "Display interactive password prompt even if SIA_WALLET_PASSWORD is set")
 
 	root.AddCommand(renterCmd)
-	renterCmd.AddCommand(renterFilesDeleteCmd, renterFilesDownloadCmd,
+	renterCmd.AddCommand(renterBackupsCmd, renterFilesDeleteCmd, renterFilesDownloadCmd,
 		renterDownloadsCmd, renterAllowanceCmd, renterSetAllowanceCmd,
 		renterContractsCmd, renterFilesListCmd, renterFilesRenameCmd,
 		renterFilesUploadCmd, renterUploadsCmd, renterExportCmd,
-		renterPricesCmd)
+		renterPricesCmd, renterDownloadEstimateCmd, renterRateLimitCmd,
+		renterRecoverCmd, renterSpendingCmd, renterSyncCmd)
 
-	renterContractsCmd.AddCommand(renterContractsViewCmd)
+	renterBackupsCmd.AddCommand(renterBackupsCreateCmd, renterBackupsRestoreCmd)
+	renterContractsCmd.AddCommand(renterContractsCancelCmd, renterContractsFormCmd, renterContractsRenewCmd, renterContractsViewCmd)
 	renterAllowanceCmd.AddCommand(renterAllowanceCancelCmd)
+	renterRateLimitCmd.AddCommand(renterRateLimitAddCmd, renterRateLimitClearCmd)
+	renterSyncCmd.AddCommand(renterSyncCancelCmd, renterSyncListCmd)
 
 	renterCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional file info such as redundancy")
+	renterBackupsRestoreCmd.Flags().StringVarP(&renterSnapshotID, "id", "", "", "ID of the snapshot to restore, defaults to the most recent snapshot")
+	renterContractsFormCmd.Flags().StringVarP(&renterContractFunds, "funds", "", "", "Funds of the contract, e.g. 50SC")
+	renterContractsRenewCmd.Flags().StringVarP(&renterContractFunds, "funds", "", "", "Funds of the renewed contract, e.g. 50SC")
 	renterDownloadsCmd.Flags().BoolVarP(&renterShowHistory, "history", "H", false, "Show download history in addition to the download queue")
 	renterFilesListCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional file info such as redundancy")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterExpectedStorage, "expected-storage", "", "", "Expected amount of data stored, e.g. 1TB")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterExpectedUpload, "expected-upload", "", "", "Expected amount of data uploaded per period")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterExpectedDownload, "expected-download", "", "", "Expected amount of data downloaded per period")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterExpectedRedundancy, "expected-redundancy", "", "", "Redundancy that files are uploaded with")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterMaxContractPrice, "max-contract-price", "", "", "Maximum contract price of a host")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterMaxStoragePrice, "max-storage-price", "", "", "Maximum storage price of a host, per TB per month")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterMaxUploadPrice, "max-upload-price", "", "", "Maximum upload bandwidth price of a host, per TB")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterMaxDownloadPrice, "max-download-price", "", "", "Maximum download bandwidth price of a host, per TB")
+	renterSetAllowanceCmd.Flags().StringVarP(&renterMaxCollateralRatio, "max-collateral-ratio", "", "", "Maximum ratio of a host's collateral to its storage price")
+	renterSetAllowanceCmd.Flags().BoolVarP(&renterAllowanceDryRun, "dry-run", "", false, "Show the contracts the allowance would form without setting it")
+	renterSpendingCmd.Flags().BoolVarP(&renterSpendingCSV, "csv", "", false, "Print the spending as comma-separated values in hastings")
+	renterSpendingCmd.Flags().BoolVarP(&renterSpendingHistory, "history", "", false, "Show the spending of every billing period")
+	renterSyncCmd.Flags().BoolVarP(&renterSyncDelete, "delete", "", false, "Delete remote files that no longer exist locally")
+	renterSyncCmd.Flags().BoolVarP(&renterSyncDryRun, "dry-run", "", false, "Only report the actions that would be taken")
+	renterSyncCmd.Flags().StringVarP(&renterSyncInterval, "interval", "i", "", "Keep syncing on this interval, e.g. 1h")
+	renterSyncCmd.Flags().BoolVarP(&renterSyncJSON, "json", "", false, "Print the report as JSON")
 	renterExportCmd.AddCommand(renterExportContractTxnsCmd)
 
 	root.AddCommand(gatewayCmd)
@@ -6,11 +6,16 @@ import (
 	"math"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/NebulousLabs/Sia/types"
 )
 
-var errUnableToParseSize = errors.New("unable to parse size")
+var (
+	errUnableToParseSize      = errors.New("unable to parse size")
+	errUnableToParseTimeOfDay = errors.New("unable to parse time of day, expected HH:MM")
+	errUnableToParseWeekdays  = errors.New("unable to parse days, expected a comma separated list such as mon,tue or 'all'")
+)
 
 // filesize returns a string that displays a filesize in human-readable units.
 func filesizeUnits(size int64) string {
@@ -101,6 +106,63 @@ func parsePeriod(period string) (string, error) {
 	return "", errUnableToParseSize
 }
 
+// timeOfDayUnits converts a number of minutes after midnight to HH:MM.
+func timeOfDayUnits(minutes uint16) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseTimeOfDay converts a time of day of the form HH:MM to a number of
+// minutes after midnight. 24:00 is accepted as the end of the day.
+func parseTimeOfDay(tod string) (uint16, error) {
+	var hours, minutes uint16
+	if _, err := fmt.Sscanf(tod, "%d:%d", &hours, &minutes); err != nil {
+		return 0, errUnableToParseTimeOfDay
+	}
+	if minutes >= 60 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, errUnableToParseTimeOfDay
+	}
+	return hours*60 + minutes, nil
+}
+
+// weekdayUnits converts a set of days of the week to a comma separated list
+// of abbreviated day names. An empty set means every day.
+func weekdayUnits(days []time.Weekday) string {
+	if len(days) == 0 {
+		return "all"
+	}
+	names := make([]string, len(days))
+	for i, day := range days {
+		names[i] = strings.ToLower(day.String()[:3])
+	}
+	return strings.Join(names, ",")
+}
+
+// parseWeekdays converts a comma separated list of day names, such as
+// "mon,tue,wed", to a set of days of the week. "all" returns an empty set,
+// meaning every day.
+func parseWeekdays(list string) ([]time.Weekday, error) {
+	list = strings.ToLower(list)
+	if list == "all" {
+		return nil, nil
+	}
+	var days []time.Weekday
+	for _, name := range strings.Split(list, ",") {
+		found := false
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			full := strings.ToLower(day.String())
+			if name == full || name == full[:3] {
+				days = append(days, day)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errUnableToParseWeekdays
+		}
+	}
+	return days, nil
+}
+
 // currencyUnits converts a types.Currency to a string with human-readable
 // units. The unit used will be the largest unit that results in a value
 // greater than 1. The value is rounded to 4 significant digits.
@@ -92,6 +92,47 @@ func TestParsePeriod(t *testing.T) {
 	}
 }
 
+func TestParseTimeOfDay(t *testing.T) {
+	tests := []struct {
+		in  string
+		out uint16
+		err error
+	}{
+		{"00:00", 0, nil},
+		{"9:30", 570, nil},
+		{"17:00", 1020, nil},
+		{"24:00", 1440, nil},
+		{"24:01", 0, errUnableToParseTimeOfDay},
+		{"12:60", 0, errUnableToParseTimeOfDay},
+		{"noon", 0, errUnableToParseTimeOfDay},
+	}
+	for _, test := range tests {
+		res, err := parseTimeOfDay(test.in)
+		if res != test.out || err != test.err {
+			t.Errorf("parseTimeOfDay(%v): expected %v %v, got %v %v", test.in, test.out, test.err, res, err)
+		}
+	}
+	if timeOfDayUnits(570) != "09:30" {
+		t.Error("timeOfDayUnits(570): got", timeOfDayUnits(570))
+	}
+}
+
+func TestParseWeekdays(t *testing.T) {
+	days, err := parseWeekdays("Mon,tuesday,sun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weekdayUnits(days) != "mon,tue,sun" {
+		t.Fatal("wrong days:", weekdayUnits(days))
+	}
+	if days, err := parseWeekdays("all"); err != nil || len(days) != 0 {
+		t.Fatal("expected every day:", days, err)
+	}
+	if _, err := parseWeekdays("mon,someday"); err != errUnableToParseWeekdays {
+		t.Fatal("expected errUnableToParseWeekdays, got", err)
+	}
+}
+
 func TestCurrencyUnits(t *testing.T) {
 	tests := []struct {
 		in, out string
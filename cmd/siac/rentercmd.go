@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,8 +15,10 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 var (
@@ -31,6 +36,35 @@ var (
 		Run:   wrap(renterallowancecmd),
 	}
 
+	renterBackupsCmd = &cobra.Command{
+		Use:   "backups",
+		Short: "View the snapshots of the renter's metadata",
+		Long: `View the encrypted snapshots of the renter's files and contracts that are
+stored on hosts. A snapshot is created automatically once a day if the files
+or contracts changed. Only the 7 most recent snapshots are kept.`,
+		Run: wrap(renterbackupscmd),
+	}
+
+	renterBackupsCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Create a snapshot of the renter's metadata",
+		Long: `Upload an encrypted snapshot of the renter's files and contracts to hosts.
+The snapshot is encrypted with a key derived from the wallet seed, and a record
+of the snapshot is added to the blockchain. The wallet must be unlocked.`,
+		Run: wrap(renterbackupscreatecmd),
+	}
+
+	renterBackupsRestoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a snapshot of the renter's metadata",
+		Long: `Restore the files and contracts of a snapshot. By default the most recent
+snapshot is restored. If the snapshot is unknown, the blockchain is scanned
+for the renter's snapshots first. To restore into a new node, load the wallet
+seed and run 'siac renter recover' first, so that the snapshot can be
+downloaded from its hosts.`,
+		Run: wrap(renterbackupsrestorecmd),
+	}
+
 	renterCmd = &cobra.Command{
 		Use:   "renter",
 		Short: "Perform renter actions",
@@ -45,6 +79,35 @@ var (
 		Run:   wrap(rentercontractscmd),
 	}
 
+	renterContractsCancelCmd = &cobra.Command{
+		Use:   "cancel [contract-id]",
+		Short: "Cancel the specified contract",
+		Long: `Cancel the specified contract. A canceled contract is neither used for
+uploads nor renewed, so its data is migrated to other hosts and the contract
+expires at the end of the current period.`,
+		Run: wrap(rentercontractscancelcmd),
+	}
+
+	renterContractsFormCmd = &cobra.Command{
+		Use:   "form [hostpubkey]",
+		Short: "Form a contract with the specified host",
+		Long: `Form a contract with the specified host, ending with the current period.
+The funds are taken from the allowance. If --funds is not given, the contract
+is funded the same way the renter funds the contracts it forms on its own.`,
+		Run: wrap(rentercontractsformcmd),
+	}
+
+	renterContractsRenewCmd = &cobra.Command{
+		Use:   "renew [contract-id]",
+		Short: "Renew the specified contract immediately",
+		Long: `Renew the specified contract immediately instead of waiting for its renew
+window. The renewed contract ends with the current period, or with the next
+period if the contract already does. The funds are taken from the allowance.
+If --funds is not given, the contract is funded the same way the renter funds
+the contracts it renews on its own.`,
+		Run: wrap(rentercontractsrenewcmd),
+	}
+
 	renterContractsViewCmd = &cobra.Command{
 		Use:   "view [contract-id]",
 		Short: "View details of the specified contract",
@@ -52,6 +115,15 @@ var (
 		Run:   wrap(rentercontractsviewcmd),
 	}
 
+	renterDownloadEstimateCmd = &cobra.Command{
+		Use:   "estimate [path]",
+		Short: "Estimate the cost and duration of a download",
+		Long: `Estimate how much it would cost and how long it would take to download
+every file whose path begins with [path]. Nothing is downloaded and no money
+is spent.`,
+		Run: wrap(renterdownloadestimatecmd),
+	}
+
 	renterDownloadsCmd = &cobra.Command{
 		Use:   "downloads",
 		Short: "View the download queue",
@@ -104,6 +176,50 @@ var (
 		Run:   wrap(renterpricescmd),
 	}
 
+	renterRateLimitAddCmd = &cobra.Command{
+		Use:   "add [days] [start] [end] [max download speed] [max upload speed]",
+		Short: "Add a window to the rate limit schedule",
+		Long: `Add a recurring window to the rate limit schedule. While the window is
+active, its limits replace the default bandwidth limits.
+
+days is a comma separated list of days of the week, such as mon,tue,wed, or
+"all" for every day.
+
+start and end are times of day in local time, given as HH:MM. The window ends
+at midnight if end is 24:00. If end is before start, such as 22:00 and 06:00,
+the window wraps around midnight and ends on the following day; days then
+refers to the day on which the window starts.
+
+The speeds are given in bytes per second with units, such as 1MB or 512KiB.
+0 means unlimited.`,
+		Run: wrap(renterratelimitaddcmd),
+	}
+
+	renterRateLimitClearCmd = &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all windows from the rate limit schedule",
+		Long:  "Remove all windows from the rate limit schedule, so that the default bandwidth limits always apply.",
+		Run:   wrap(renterratelimitclearcmd),
+	}
+
+	renterRateLimitCmd = &cobra.Command{
+		Use:   "ratelimit",
+		Short: "View the bandwidth limits and their schedule",
+		Long:  "View the bandwidth limits currently in effect, the schedule window they come from, and the full rate limit schedule.",
+		Run:   wrap(renterratelimitcmd),
+	}
+
+	renterRecoverCmd = &cobra.Command{
+		Use:   "recover",
+		Short: "Recover the renter's contracts from the blockchain",
+		Long: `Scan the blockchain for the contracts that were formed with the wallet seed,
+and recover the contracts that the renter no longer knows about from their
+hosts. The wallet must be unlocked. Recovered contracts that hold data are
+not used for uploads or renewed, since the renter no longer knows the Merkle
+roots of their data. That data can be downloaded until the contracts expire.`,
+		Run: wrap(renterrecovercmd),
+	}
+
 	renterSetAllowanceCmd = &cobra.Command{
 		Use:   "setallowance [amount] [period] [hosts] [renew window]",
 		Short: "Set the allowance",
@@ -123,12 +239,69 @@ Allowance can be automatically renewed periodically. If the current
 blockheight + the renew window >= the end height the contract,
 then the contract is renewed automatically.
 
+The expected usage is optional. If it is set, every contract is funded
+according to the prices of its host instead of splitting the funds evenly.
+Storage is the amount of data stored, upload and download are the amounts of
+data transferred per period. Sizes are given with a unit (MB, GB, TB, etc.).
+
+The price caps are optional. Hosts whose prices exceed a cap are not used for
+new contracts, and their contracts are not renewed. The storage price cap is
+given in currency/TB/month, the bandwidth price caps in currency/TB. A cap of
+0 removes it. Caps that are not specified keep their current value.
+
+With --dry-run, the allowance is not set. Instead, the contracts that it would
+form with the hosts in the hostdb are shown, along with their fees and the
+amount of data that they can store. Hosts are selected randomly, so repeated
+dry runs may show different hosts.
+
 Note that setting the allowance will cause siad to immediately begin forming
 contracts! You should only set the allowance once you are fully synced and you
 have a reasonable number (>30) of hosts in your hostdb.`,
 		Run: rentersetallowancecmd,
 	}
 
+	renterSpendingCmd = &cobra.Command{
+		Use:   "spending",
+		Short: "View the spending of billing periods",
+		Long: `View what was spent on storage, bandwidth and fees during the current billing
+period, and the money refunded by contracts that expired during it. With
+--history, the spending of every finished period is listed as well. With
+--csv, the values are printed in hastings as comma-separated values.`,
+		Run: wrap(renterspendingcmd),
+	}
+
+	renterSyncCmd = &cobra.Command{
+		Use:   "sync [source directory] [siapath]",
+		Short: "Mirror a local directory to Sia",
+		Long: `Upload every file in a local directory tree to the siapath, keeping the
+relative paths. Files that were uploaded by an earlier sync are only uploaded
+again if they have changed, which is detected by comparing the size,
+modification time and hash of the file.
+
+With --delete, remote files under the siapath that no longer exist locally are
+deleted. With --interval, the renter keeps syncing the directory on that
+interval until the job is cancelled. With --dry-run, the actions are only
+reported. --json prints the report in a machine-readable format.
+
+The sync runs in the background of the renter. siac waits for the first run
+to finish and prints its report; interrupting siac does not stop the sync.`,
+		Run: wrap(rentersynccmd),
+	}
+
+	renterSyncCancelCmd = &cobra.Command{
+		Use:   "cancel [siapath]",
+		Short: "Cancel a sync job",
+		Long:  "Stop the sync job of a siapath. Files that were already uploaded are kept.",
+		Run:   wrap(rentersynccancelcmd),
+	}
+
+	renterSyncListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List sync jobs",
+		Long:  "List the sync jobs along with the progress or result of their most recent run.",
+		Run:   wrap(rentersynclistcmd),
+	}
+
 	renterUploadsCmd = &cobra.Command{
 		Use:   "uploads",
 		Short: "View the upload queue",
@@ -178,6 +351,23 @@ func rentercmd() {
 		currencyUnits(fm.Unspent), currencyUnits(unspentAllocated),
 		currencyUnits(unspentUnallocated))
 
+	mem := rg.Memory
+	fmt.Printf("Memory: %v of %v in use, %v requests waiting (average wait %v)\n",
+		filesizeUnits(int64(mem.InUse)), filesizeUnits(int64(mem.Base)),
+		mem.WaitingRequests, mem.AverageWait.Round(time.Millisecond))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tClass\tIn Use\tReserve\tWaiting\tLongest Wait")
+	for _, c := range []struct {
+		name   string
+		status modules.MemoryClassStatus
+	}{{"Upload", mem.Upload}, {"Download", mem.Download}, {"Stream", mem.Stream}} {
+		fmt.Fprintf(w, "\t%v\t%v\t%v\t%v\t%v\n", c.name, filesizeUnits(int64(c.status.InUse)),
+			filesizeUnits(int64(c.status.Reserve)), c.status.WaitingRequests,
+			c.status.LongestWait.Round(time.Millisecond))
+	}
+	w.Flush()
+	fmt.Println()
+
 	// also list files
 	renterfileslistcmd()
 }
@@ -212,6 +402,127 @@ func renteruploadscmd() {
 	}
 }
 
+// speedUnits returns a string that displays a bandwidth limit in
+// human-readable units.
+func speedUnits(bps int64) string {
+	if bps == 0 {
+		return "unlimited"
+	}
+	return filesizeUnits(bps) + "/s"
+}
+
+// renterratelimitcmd is the handler for the command `siac renter ratelimit`.
+// It shows the bandwidth limits currently in effect and the rate limit
+// schedule.
+func renterratelimitcmd() {
+	rrl, err := httpClient.RenterRateLimitGet()
+	if err != nil {
+		die("Could not get rate limits:", err)
+	}
+	if rrl.ActiveWindow < 0 {
+		fmt.Println("Active window: none, the default limits apply")
+	} else {
+		fmt.Println("Active window:", rrl.ActiveWindow)
+	}
+	fmt.Println("Max download speed:", speedUnits(rrl.MaxDownloadSpeed))
+	fmt.Println("Max upload speed:  ", speedUnits(rrl.MaxUploadSpeed))
+	if len(rrl.Schedule) == 0 {
+		fmt.Println("\nNo rate limit schedule has been set.")
+		return
+	}
+	fmt.Println("\nSchedule:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Window\tDays\tStart\tEnd\tDownload\tUpload\tActive")
+	for i, rlw := range rrl.Schedule {
+		fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\t%v\t%v\n", i, weekdayUnits(rlw.Days),
+			timeOfDayUnits(rlw.Start), timeOfDayUnits(rlw.End),
+			speedUnits(rlw.MaxDownloadSpeed), speedUnits(rlw.MaxUploadSpeed),
+			yesNo(i == rrl.ActiveWindow))
+	}
+	w.Flush()
+}
+
+// renterratelimitaddcmd is the handler for the command `siac renter ratelimit
+// add`. It appends a window to the rate limit schedule.
+func renterratelimitaddcmd(days, start, end, download, upload string) {
+	var rlw modules.RateLimitWindow
+	var err error
+	if rlw.Days, err = parseWeekdays(days); err != nil {
+		die("Could not parse days:", err)
+	}
+	if rlw.Start, err = parseTimeOfDay(start); err != nil {
+		die("Could not parse start:", err)
+	}
+	if rlw.End, err = parseTimeOfDay(end); err != nil {
+		die("Could not parse end:", err)
+	}
+	for _, speed := range []struct {
+		arg   string
+		value *int64
+	}{{download, &rlw.MaxDownloadSpeed}, {upload, &rlw.MaxUploadSpeed}} {
+		if speed.arg == "0" {
+			continue
+		}
+		bps, err := parseFilesize(speed.arg)
+		if err != nil {
+			die("Could not parse speed:", err)
+		}
+		if _, err := fmt.Sscan(bps, speed.value); err != nil {
+			die("Could not parse speed:", err)
+		}
+	}
+
+	rg, err := httpClient.RenterGet()
+	if err != nil {
+		die("Could not get renter settings:", err)
+	}
+	schedule := append(rg.Settings.RateLimitSchedule, rlw)
+	if err := httpClient.RenterPostRateLimitSchedule(schedule); err != nil {
+		die("Could not set rate limit schedule:", err)
+	}
+	fmt.Println("Added rate limit window", len(schedule)-1)
+}
+
+// renterratelimitclearcmd is the handler for the command `siac renter
+// ratelimit clear`. It removes every window from the rate limit schedule.
+func renterratelimitclearcmd() {
+	if err := httpClient.RenterPostRateLimitSchedule(nil); err != nil {
+		die("Could not clear rate limit schedule:", err)
+	}
+	fmt.Println("Rate limit schedule cleared")
+}
+
+// renterdownloadestimatecmd is the handler for the command `siac renter
+// estimate [path]`. It prints the expected cost and duration of downloading
+// every file under the provided path.
+func renterdownloadestimatecmd(path string) {
+	est, err := httpClient.RenterDownloadEstimateGet(path)
+	if err != nil {
+		die("Could not estimate download:", err)
+	}
+	fmt.Println("Download Estimate:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\tFiles:\t%v\n", len(est.Files))
+	fmt.Fprintf(w, "\tSize:\t%v\n", filesizeUnits(int64(est.Bytes)))
+	fmt.Fprintf(w, "\tBandwidth Cost:\t%v\n", currencyUnits(est.BandwidthCost))
+	fmt.Fprintf(w, "\tContract Funds Sufficient:\t%v\n", yesNo(est.FundsSufficient))
+	if est.EstimatedDuration > 0 {
+		fmt.Fprintf(w, "\tEstimated Duration:\t%v\n", est.EstimatedDuration.Round(time.Second))
+	} else {
+		fmt.Fprintf(w, "\tEstimated Duration:\tunknown (no downloads observed yet)\n")
+	}
+	w.Flush()
+	if est.UnavailableChunks > 0 {
+		fmt.Printf("\n%v chunks do not have enough pieces on online hosts and cannot be downloaded.\n", est.UnavailableChunks)
+	}
+	if len(est.InsufficientContracts) > 0 {
+		fmt.Println("\nContracts without enough funds for the download:")
+		for _, id := range est.InsufficientContracts {
+			fmt.Println("  ", id)
+		}
+	}
+}
+
 // renterdownloadscmd is the handler for the command `siac renter downloads`.
 // Lists files currently downloading, and optionally previously downloaded
 // files if the -H or --history flag is specified.
@@ -269,6 +580,61 @@ func renterallowancecmd() {
 	Amount: %v
 	Period: %v blocks
 `, currencyUnits(allowance.Funds), allowance.Period)
+
+	if allowance.ExpectedStorage != 0 || allowance.ExpectedUpload != 0 || allowance.ExpectedDownload != 0 {
+		redundancy := "default"
+		if allowance.ExpectedRedundancy > 0 {
+			redundancy = strconv.FormatFloat(allowance.ExpectedRedundancy, 'f', -1, 64)
+		}
+		fmt.Printf(`
+Expected Usage:
+	Storage:    %v
+	Upload:     %v per period
+	Download:   %v per period
+	Redundancy: %v
+`, filesizeUnits(int64(allowance.ExpectedStorage)), filesizeUnits(int64(allowance.ExpectedUpload)),
+			filesizeUnits(int64(allowance.ExpectedDownload)), redundancy)
+	}
+
+	// priceCap formats a price cap, which is disabled if it is zero.
+	priceCap := func(c types.Currency, unit types.Currency, suffix string) string {
+		if c.IsZero() {
+			return "none"
+		}
+		return currencyUnits(c.Mul(unit)) + suffix
+	}
+	collateralRatio := "none"
+	if allowance.MaxCollateralRatio > 0 {
+		collateralRatio = strconv.FormatFloat(allowance.MaxCollateralRatio, 'f', -1, 64)
+	}
+	fmt.Printf(`
+Price Caps:
+	Contract Price:           %v
+	Storage Price:            %v
+	Upload Bandwidth Price:   %v
+	Download Bandwidth Price: %v
+	Collateral Ratio:         %v
+`, priceCap(allowance.MaxContractPrice, types.NewCurrency64(1), ""),
+		priceCap(allowance.MaxStoragePrice, modules.BlockBytesPerMonthTerabyte, "/TB/month"),
+		priceCap(allowance.MaxUploadBandwidthPrice, modules.BytesPerTerabyte, "/TB"),
+		priceCap(allowance.MaxDownloadBandwidthPrice, modules.BytesPerTerabyte, "/TB"),
+		collateralRatio)
+
+	rrh, err := httpClient.RenterRejectedHostsGet()
+	if err != nil {
+		die("Could not get rejected hosts:", err)
+	}
+	if len(rrh.Hosts) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("Hosts rejected by the price caps:")
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tAddress\tHeight\tReason")
+	for _, host := range rrh.Hosts {
+		fmt.Fprintf(w, "\t%v\t%v\t%v\n", host.NetAddress, host.BlockHeight, host.Reason)
+	}
+	w.Flush()
 }
 
 // renterallowancecancelcmd cancels the current allowance.
@@ -295,6 +661,87 @@ again:
 	fmt.Println("Allowance canceled.")
 }
 
+// renterbackupscmd lists the snapshots of the renter's metadata.
+func renterbackupscmd() {
+	rb, err := httpClient.RenterBackupsGet()
+	if err != nil {
+		die("Could not get snapshots:", err)
+	}
+	if len(rb.Snapshots) == 0 {
+		fmt.Println("No snapshots.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCreated\tFiles\tContracts\tSize\tHosts")
+	for _, s := range rb.Snapshots {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", s.ID, s.CreationTime.Format("2006-01-02 15:04"),
+			s.NumFiles, s.NumContracts, filesizeUnits(int64(s.Size)), len(s.Hosts))
+	}
+	w.Flush()
+}
+
+// renterbackupscreatecmd uploads a snapshot of the renter's metadata.
+func renterbackupscreatecmd() {
+	s, err := httpClient.RenterBackupsPost()
+	if err != nil {
+		die("Could not create snapshot:", err)
+	}
+	fmt.Printf("Uploaded snapshot %v to %v hosts.\n", s.ID, len(s.Hosts))
+}
+
+// renterbackupsrestorecmd restores a snapshot of the renter's metadata.
+func renterbackupsrestorecmd() {
+	var id crypto.Hash
+	if renterSnapshotID != "" {
+		if err := id.LoadString(renterSnapshotID); err != nil {
+			die("Could not parse snapshot id:", err)
+		}
+	}
+	fmt.Println("Restoring snapshot, this may take a while...")
+	if err := httpClient.RenterBackupsRestorePost(id); err != nil {
+		die("Could not restore snapshot:", err)
+	}
+	fmt.Println("Snapshot restored.")
+}
+
+// renterspendingcmd displays the spending of the current billing period, or of
+// every billing period.
+func renterspendingcmd() {
+	rsg, err := httpClient.RenterSpendingGet()
+	if err != nil {
+		die("Could not get spending:", err)
+	}
+	periods := rsg.Periods
+	if !renterSpendingHistory && len(periods) > 0 {
+		periods = periods[len(periods)-1:]
+	}
+
+	if renterSpendingCSV {
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"start", "end", "storage", "upload", "download", "fees", "allocated", "refunded", "unspent"})
+		for _, p := range periods {
+			w.Write([]string{fmt.Sprint(p.StartHeight), fmt.Sprint(p.EndHeight),
+				p.StorageSpending.String(), p.UploadSpending.String(), p.DownloadSpending.String(),
+				p.ContractFees.String(), p.TotalAllocated.String(), p.Refunded.String(), p.Unspent.String()})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			die("Could not write CSV:", err)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Start\tEnd\tStorage\tUpload\tDownload\tFees\tAllocated\tRefunded\tUnspent")
+	for _, p := range periods {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", p.StartHeight, p.EndHeight,
+			currencyUnits(p.StorageSpending), currencyUnits(p.UploadSpending), currencyUnits(p.DownloadSpending),
+			currencyUnits(p.ContractFees), currencyUnits(p.TotalAllocated), currencyUnits(p.Refunded),
+			currencyUnits(p.Unspent))
+	}
+	w.Flush()
+}
+
 // rentersetallowancecmd allows the user to set the allowance.
 // the first two parameters, amount and period, are required.
 // the second two parameters are optional:
@@ -340,6 +787,43 @@ func rentersetallowancecmd(cmd *cobra.Command, args []string) {
 			die("Could not parse renew window:", err)
 		}
 	}
+
+	// Keep the current price caps unless they are overridden.
+	rg, err := httpClient.RenterGet()
+	if err != nil {
+		die("Could not get the current allowance:", err)
+	}
+	current := rg.Settings.Allowance
+	allowance.ExpectedStorage = parseExpectedUsage(renterExpectedStorage, current.ExpectedStorage)
+	allowance.ExpectedUpload = parseExpectedUsage(renterExpectedUpload, current.ExpectedUpload)
+	allowance.ExpectedDownload = parseExpectedUsage(renterExpectedDownload, current.ExpectedDownload)
+	allowance.ExpectedRedundancy = current.ExpectedRedundancy
+	if renterExpectedRedundancy != "" {
+		allowance.ExpectedRedundancy, err = strconv.ParseFloat(renterExpectedRedundancy, 64)
+		if err != nil {
+			die("Could not parse expected redundancy:", err)
+		}
+	}
+	allowance.MaxContractPrice = parsePriceCap(renterMaxContractPrice, types.NewCurrency64(1), current.MaxContractPrice)
+	allowance.MaxStoragePrice = parsePriceCap(renterMaxStoragePrice, modules.BlockBytesPerMonthTerabyte, current.MaxStoragePrice)
+	allowance.MaxUploadBandwidthPrice = parsePriceCap(renterMaxUploadPrice, modules.BytesPerTerabyte, current.MaxUploadBandwidthPrice)
+	allowance.MaxDownloadBandwidthPrice = parsePriceCap(renterMaxDownloadPrice, modules.BytesPerTerabyte, current.MaxDownloadBandwidthPrice)
+	allowance.MaxCollateralRatio = current.MaxCollateralRatio
+	if renterMaxCollateralRatio != "" {
+		allowance.MaxCollateralRatio, err = strconv.ParseFloat(renterMaxCollateralRatio, 64)
+		if err != nil {
+			die("Could not parse max collateral ratio:", err)
+		}
+	}
+
+	if renterAllowanceDryRun {
+		rap, err := httpClient.RenterAllowancePlanGet(allowance)
+		if err != nil {
+			die("Could not plan allowance:", err)
+		}
+		printAllowancePlan(rap.AllowancePlan)
+		return
+	}
 	err = httpClient.RenterPostAllowance(allowance)
 	if err != nil {
 		die("Could not set allowance:", err)
@@ -347,6 +831,99 @@ func rentersetallowancecmd(cmd *cobra.Command, args []string) {
 	fmt.Println("Allowance updated.")
 }
 
+// printAllowancePlan prints the contracts that an allowance would form.
+func printAllowancePlan(plan modules.AllowancePlan) {
+	fmt.Printf(`Allowance Plan:
+	Contracts:         %v, ending at height %v
+	Total Funding:     %v
+	Fees:              %v
+	Siafund Fee:       %v
+	Unallocated:       %v
+	Storage Capacity:  %v at %vx redundancy
+`, len(plan.Contracts), plan.EndHeight, currencyUnits(plan.TotalFunding),
+		currencyUnits(plan.TotalFees), currencyUnits(plan.TotalSiafundFee),
+		currencyUnits(plan.Unallocated), filesizeUnits(int64(plan.StorageCapacity)),
+		plan.Redundancy)
+
+	if len(plan.Contracts) > 0 {
+		fmt.Println()
+		fmt.Println("Planned Contracts:")
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tAddress\tFunding\tFees\tSiafund Fee\tStorage Price\tStorage")
+		for _, pc := range plan.Contracts {
+			fmt.Fprintf(w, "\t%v\t%v\t%v\t%v\t%v/TB/month\t%v\n", pc.NetAddress,
+				currencyUnits(pc.Funding), currencyUnits(pc.ContractPrice.Add(pc.TxnFee)),
+				currencyUnits(pc.SiafundFee), currencyUnits(pc.StoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)),
+				filesizeUnits(int64(pc.Storage)))
+		}
+		w.Flush()
+	}
+	if len(plan.RejectedHosts) > 0 {
+		fmt.Println()
+		fmt.Println("Rejected Hosts:")
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tAddress\tReason")
+		for _, host := range plan.RejectedHosts {
+			fmt.Fprintf(w, "\t%v\t%v\n", host.NetAddress, host.Reason)
+		}
+		w.Flush()
+	}
+}
+
+// parseExpectedUsage parses an expected usage given as a file size, returning
+// the usage in bytes. If the usage is empty, current is returned.
+func parseExpectedUsage(size string, current uint64) uint64 {
+	if size == "" {
+		return current
+	}
+	bytes, err := parseFilesize(size)
+	if err != nil {
+		die("Could not parse expected usage:", err)
+	}
+	usage, err := strconv.ParseUint(bytes, 10, 64)
+	if err != nil {
+		die("Could not parse expected usage:", err)
+	}
+	return usage
+}
+
+// parsePriceCap parses a price cap given in currency per unit, returning the
+// cap in hastings. If the cap is empty, current is returned.
+func parsePriceCap(price string, unit types.Currency, current types.Currency) types.Currency {
+	if price == "" {
+		return current
+	}
+	hastings, err := parseCurrency(price)
+	if err != nil {
+		die("Could not parse price cap:", err)
+	}
+	i, _ := new(big.Int).SetString(hastings, 10)
+	return types.NewCurrency(i).Div(unit)
+}
+
+// parseContractFunds parses the --funds flag of the contract commands. Zero
+// funds select the default funding.
+func parseContractFunds() types.Currency {
+	if renterContractFunds == "" {
+		return types.ZeroCurrency
+	}
+	hastings, err := parseCurrency(renterContractFunds)
+	if err != nil {
+		die("Could not parse funds:", err)
+	}
+	i, _ := new(big.Int).SetString(hastings, 10)
+	return types.NewCurrency(i)
+}
+
+// parseContractID parses the ID of a contract.
+func parseContractID(cid string) types.FileContractID {
+	var hash crypto.Hash
+	if err := hash.LoadString(cid); err != nil {
+		die("Could not parse contract id:", err)
+	}
+	return types.FileContractID(hash)
+}
+
 // byValue sorts contracts by their value in siacoins, high to low. If two
 // contracts have the same value, they are sorted by their host's address.
 type byValue []api.RenterContract
@@ -438,6 +1015,37 @@ Contract %v
 	fmt.Println("Contract not found")
 }
 
+// rentercontractscancelcmd cancels a contract.
+func rentercontractscancelcmd(cid string) {
+	err := httpClient.RenterContractsCancelPost(parseContractID(cid))
+	if err != nil {
+		die("Could not cancel contract:", err)
+	}
+	fmt.Println("Canceled contract", cid)
+}
+
+// rentercontractsformcmd forms a contract with a host.
+func rentercontractsformcmd(pubkey string) {
+	var spk types.SiaPublicKey
+	spk.LoadString(pubkey)
+	rc, err := httpClient.RenterContractsFormPost(spk, parseContractFunds())
+	if err != nil {
+		die("Could not form contract:", err)
+	}
+	fmt.Printf("Formed contract %v with %v for %v, ending at height %v\n",
+		rc.ID, rc.NetAddress, currencyUnits(rc.RenterFunds), rc.EndHeight)
+}
+
+// rentercontractsrenewcmd renews a contract immediately.
+func rentercontractsrenewcmd(cid string) {
+	rc, err := httpClient.RenterContractsRenewPost(parseContractID(cid), parseContractFunds())
+	if err != nil {
+		die("Could not renew contract:", err)
+	}
+	fmt.Printf("Renewed contract %v into %v for %v, ending at height %v\n",
+		cid, rc.ID, currencyUnits(rc.RenterFunds), rc.EndHeight)
+}
+
 // renterfilesdeletecmd is the handler for the command `siac renter delete [path]`.
 // Removes the specified path from the Sia network.
 func renterfilesdeletecmd(path string) {
@@ -605,6 +1213,28 @@ func renterfilesuploadcmd(source, path string) {
 }
 
 // renterpricescmd is the handler for the command `siac renter prices`, which
+// renterrecovercmd scans the blockchain for the renter's contracts and
+// reports the progress of the scan.
+func renterrecovercmd() {
+	err := httpClient.RenterRecoveryScanPost()
+	if err != nil {
+		die("Could not start recovery scan:", err)
+	}
+	fmt.Println("Scanning the blockchain for contracts...")
+	for {
+		rrs, err := httpClient.RenterRecoveryScanGet()
+		if err != nil {
+			die("Could not get recovery status:", err)
+		}
+		if !rrs.ScanInProgress {
+			fmt.Printf("\nFound %v active contracts, recovered %v.\n", rrs.ContractsFound, rrs.ContractsRecovered)
+			return
+		}
+		fmt.Printf("\rScanned height %v", rrs.ScannedHeight)
+		time.Sleep(2 * time.Second)
+	}
+}
+
 // displays the prices of various storage operations.
 func renterpricescmd() {
 	rpg, err := httpClient.RenterPricesGet()
@@ -620,3 +1250,118 @@ func renterpricescmd() {
 	fmt.Fprintln(w, "\tUpload 1 TB:\t", currencyUnits(rpg.UploadTerabyte))
 	w.Flush()
 }
+
+// rentersynccmd is the handler for the command `siac renter sync [source]
+// [siapath]`. It mirrors a local directory to a siapath and prints a report of
+// the actions taken.
+func rentersynccmd(source, siaPath string) {
+	params := modules.RenterSyncParams{
+		Source:  abs(source),
+		SiaPath: siaPath,
+		Delete:  renterSyncDelete,
+		DryRun:  renterSyncDryRun,
+	}
+	if renterSyncInterval != "" {
+		interval, err := time.ParseDuration(renterSyncInterval)
+		if err != nil {
+			die("Could not parse interval:", err)
+		}
+		params.Interval = interval
+	}
+	err := httpClient.RenterSyncPost(params)
+	if err != nil {
+		die("Could not start sync:", err)
+	}
+
+	// Wait for the sync to finish.
+	siaPath = strings.TrimPrefix(siaPath, "/")
+	var report modules.RenterSyncReport
+	for {
+		rsg, err := httpClient.RenterSyncGet()
+		if err != nil {
+			die("Could not get sync progress:", err)
+		}
+		var job *modules.RenterSyncJob
+		for i := range rsg.Jobs {
+			if rsg.Jobs[i].SiaPath == siaPath {
+				job = &rsg.Jobs[i]
+			}
+		}
+		if job == nil {
+			die("Sync job was cancelled.")
+		}
+		if !job.Active {
+			report = job.LastReport
+			break
+		}
+		if !renterSyncJSON && len(job.LastReport.Actions) > 0 {
+			fmt.Printf("\rTook %v of %v actions", job.LastReport.Completed, len(job.LastReport.Actions))
+		}
+		time.Sleep(time.Second)
+	}
+	if renterSyncJSON {
+		js, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			die("Could not encode report:", err)
+		}
+		fmt.Println(string(js))
+		return
+	}
+	if report.Completed > 0 {
+		fmt.Println()
+	}
+
+	if len(report.Actions) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Action\tReason\tSia Path\tError")
+		for _, a := range report.Actions {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", a.Action, a.Reason, a.SiaPath, a.Error)
+		}
+		w.Flush()
+	}
+	if report.Error != "" {
+		die("Sync failed:", report.Error)
+	}
+	verb := "Took"
+	if report.DryRun {
+		verb = "Would take"
+	}
+	fmt.Printf("%v %v actions, %v files unchanged, %v failed.\n", verb, len(report.Actions), report.Unchanged, report.Failed)
+	if params.Interval > 0 && !params.DryRun {
+		fmt.Printf("Syncing %v to %v every %v.\n", params.Source, siaPath, params.Interval)
+	}
+}
+
+// rentersynccancelcmd is the handler for the command `siac renter sync cancel
+// [siapath]`. It stops a recurring sync job.
+func rentersynccancelcmd(siaPath string) {
+	err := httpClient.RenterSyncCancelPost(siaPath)
+	if err != nil {
+		die("Could not cancel sync job:", err)
+	}
+	fmt.Println("Cancelled sync job for", siaPath)
+}
+
+// rentersynclistcmd is the handler for the command `siac renter sync list`. It
+// lists the sync jobs.
+func rentersynclistcmd() {
+	rsg, err := httpClient.RenterSyncGet()
+	if err != nil {
+		die("Could not get sync jobs:", err)
+	}
+	if len(rsg.Jobs) == 0 {
+		fmt.Println("No sync jobs.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Sia Path\tSource\tInterval\tDelete\tLast Run\tActions\tFailed")
+	for _, job := range rsg.Jobs {
+		lastRun := job.LastReport.End.Format(time.RFC822)
+		if job.Active {
+			lastRun = fmt.Sprintf("running (%v/%v)", job.LastReport.Completed, len(job.LastReport.Actions))
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", job.SiaPath, job.Source, job.Interval,
+			yesNo(job.Delete), lastRun, len(job.LastReport.Actions), job.LastReport.Failed)
+	}
+	w.Flush()
+}
@@ -49,6 +49,7 @@ type (
 		listener      net.Listener
 		config        Config
 		moduleClosers []moduleCloser
+		alerters      []modules.Alerter
 		api           http.Handler
 		mu            sync.Mutex
 	}
@@ -373,6 +374,47 @@ func (srv *Server) daemonVersionHandler(w http.ResponseWriter, _ *http.Request,
 	api.WriteJSON(w, DaemonVersion{Version: build.Version, GitRevision: build.GitRevision, BuildTime: build.BuildTime})
 }
 
+// daemonAlertsHandlerGET handles the API call that lists the active alerts of
+// the modules, oldest first.
+func (srv *Server) daemonAlertsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	srv.mu.Lock()
+	alerters := srv.alerters
+	srv.mu.Unlock()
+	alerts := []modules.Alert{}
+	for _, a := range alerters {
+		alerts = append(alerts, a.Alerts()...)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Time.Before(alerts[j].Time)
+	})
+	api.WriteJSON(w, api.DaemonAlertsGet{Alerts: alerts})
+}
+
+// daemonAlertsAcknowledgeHandler handles the API call that acknowledges an
+// alert.
+func (srv *Server) daemonAlertsAcknowledgeHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	id := modules.AlertID(req.FormValue("id"))
+	if id == "" {
+		api.WriteError(w, api.Error{Message: "id must be specified"}, http.StatusBadRequest)
+		return
+	}
+	srv.mu.Lock()
+	alerters := srv.alerters
+	srv.mu.Unlock()
+	for _, a := range alerters {
+		err := a.AcknowledgeAlert(id)
+		if err == modules.ErrUnknownAlert {
+			continue
+		} else if err != nil {
+			api.WriteError(w, api.Error{Message: "failed to acknowledge alert: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		api.WriteSuccess(w)
+		return
+	}
+	api.WriteError(w, api.Error{Message: modules.ErrUnknownAlert.Error()}, http.StatusBadRequest)
+}
+
 // daemonStopHandler handles the API call to stop the daemon cleanly.
 func (srv *Server) daemonStopHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	// can't write after we stop the server, so lie a bit.
@@ -393,6 +435,8 @@ func (srv *Server) daemonStopHandler(w http.ResponseWriter, _ *http.Request, _ h
 func (srv *Server) daemonHandler(password string) http.Handler {
 	router := httprouter.New()
 
+	router.GET("/daemon/alerts", srv.daemonAlertsHandlerGET)
+	router.POST("/daemon/alerts/acknowledge", api.RequirePassword(srv.daemonAlertsAcknowledgeHandler, password))
 	router.GET("/daemon/constants", srv.daemonConstantsHandler)
 	router.GET("/daemon/version", srv.daemonVersionHandler)
 	router.GET("/daemon/update", srv.daemonUpdateHandlerGET)
@@ -582,9 +626,12 @@ func (srv *Server) loadModules() error {
 		w,
 	)
 
-	// connect the API to the server
+	// connect the API to the server, and collect the alerts of the modules
 	srv.mu.Lock()
 	srv.api = a
+	if r != nil {
+		srv.alerters = append(srv.alerters, r)
+	}
 	srv.mu.Unlock()
 
 	// Attempt to auto-unlock the wallet using the SIA_WALLET_PASSWORD env variable
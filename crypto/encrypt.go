@@ -5,6 +5,7 @@ package crypto
 
 import (
 	"crypto/cipher"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"io"
@@ -15,6 +16,10 @@ import (
 )
 
 const (
+	// TwofishNonceSize is the size of the nonce that EncryptBytes prepends to
+	// the ciphertext.
+	TwofishNonceSize = 12
+
 	// TwofishOverhead is the number of bytes added by EncryptBytes
 	TwofishOverhead = 28
 )
@@ -77,6 +82,36 @@ func (key TwofishKey) DecryptBytes(ct Ciphertext) ([]byte, error) {
 	return aead.Open(nil, ct[:aead.NonceSize()], ct[aead.NonceSize():], nil)
 }
 
+// DecryptBytesRange decrypts a range of the plaintext of a ciphertext created
+// by EncryptBytes, without having to download all of the ciphertext. ct is
+// expected to be the nonce, followed by the encrypted bytes of the range,
+// which starts at 'offset' within the plaintext. Since the ciphertext is not
+// complete, the authentication tag can't be checked, so the caller must
+// verify the integrity of the range in some other way, for example with a
+// Merkle range proof.
+func (key TwofishKey) DecryptBytesRange(ct Ciphertext, offset uint64) ([]byte, error) {
+	// Check for a nonce.
+	if len(ct) < TwofishNonceSize {
+		return nil, ErrInsufficientLen
+	}
+
+	// GCM encrypts the plaintext in counter mode. The counter block consists
+	// of the nonce followed by a 32-bit counter, which is 2 for the first
+	// block of the plaintext.
+	iv := make([]byte, twofish.BlockSize)
+	copy(iv, ct[:TwofishNonceSize])
+	binary.BigEndian.PutUint32(iv[TwofishNonceSize:], uint32(2+offset/twofish.BlockSize))
+	stream := cipher.NewCTR(key.NewCipher(), iv)
+
+	// Skip the part of the key stream that precedes offset within the first
+	// block, then decrypt the data.
+	skip := make([]byte, offset%twofish.BlockSize)
+	stream.XORKeyStream(skip, skip)
+	plaintext := make([]byte, len(ct)-TwofishNonceSize)
+	stream.XORKeyStream(plaintext, ct[TwofishNonceSize:])
+	return plaintext, nil
+}
+
 // NewWriter returns a writer that encrypts or decrypts its input stream.
 func (key TwofishKey) NewWriter(w io.Writer) io.Writer {
 	// OK to use a zero IV if the key is unique for each ciphertext.
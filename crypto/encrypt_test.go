@@ -62,6 +62,40 @@ func TestTwofishEncryption(t *testing.T) {
 	}
 }
 
+// TestDecryptBytesRange checks that ranges of a ciphertext can be decrypted
+// without the rest of the ciphertext.
+func TestDecryptBytesRange(t *testing.T) {
+	key := GenerateTwofishKey()
+	plaintext := fastrand.Bytes(600)
+	ciphertext := key.EncryptBytes(plaintext)
+
+	ranges := []struct {
+		offset, length uint64
+	}{
+		{0, 600},
+		{0, 10},
+		{16, 32},
+		{17, 100},
+		{599, 1},
+	}
+	for _, r := range ranges {
+		ct := append(Ciphertext(nil), ciphertext[:TwofishNonceSize]...)
+		ct = append(ct, ciphertext[TwofishNonceSize+r.offset:TwofishNonceSize+r.offset+r.length]...)
+		decrypted, err := key.DecryptBytesRange(ct, r.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decrypted, plaintext[r.offset:r.offset+r.length]) {
+			t.Fatal("Decrypted range does not match the plaintext:", r.offset, r.length)
+		}
+	}
+
+	_, err := key.DecryptBytesRange(ciphertext[:10], 0)
+	if err != ErrInsufficientLen {
+		t.Error("Expecting ErrInsufficientLen:", err)
+	}
+}
+
 // TestReaderWriter probes the NewReader and NewWriter methods of the key type.
 func TestReaderWriter(t *testing.T) {
 	// Get a key for encryption.
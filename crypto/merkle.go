@@ -154,3 +154,95 @@ func VerifySegment(base []byte, hashSet []Hash, numSegments, proofIndex uint64,
 	}
 	return merkletree.VerifyProof(NewHash(), root[:], proofSet, proofIndex, numSegments)
 }
+
+// nodeHash returns the hash of a node of a Merkle tree with the provided
+// children, as computed by merkletree.Tree.
+func nodeHash(left, right Hash) (h Hash) {
+	hasher := NewHash()
+	hasher.Write([]byte{1})
+	hasher.Write(left[:])
+	hasher.Write(right[:])
+	copy(h[:], hasher.Sum(nil))
+	return
+}
+
+// splitSubtree returns the number of leaves in the left child of a subtree
+// with numLeaves leaves, which is the largest power of two that is smaller
+// than numLeaves. numLeaves must be at least 2.
+func splitSubtree(numLeaves uint64) uint64 {
+	split := uint64(1)
+	for split*2 < numLeaves {
+		split *= 2
+	}
+	return split
+}
+
+// segmentRange returns the bytes of the segments [start, end) of b, where b
+// starts at segment 'offset'.
+func segmentRange(b []byte, offset, start, end uint64) []byte {
+	startByte := (start - offset) * SegmentSize
+	endByte := (end - offset) * SegmentSize
+	if endByte > uint64(len(b)) {
+		endByte = uint64(len(b))
+	}
+	return b[startByte:endByte]
+}
+
+// MerkleRangeProof builds a Merkle proof that the data of the segments
+// [proofStart, proofEnd) is a part of the Merkle root formed by 'b'. The proof
+// consists of the roots of the subtrees that don't contain any of the proven
+// segments, ordered from left to right.
+func MerkleRangeProof(b []byte, proofStart, proofEnd uint64) []Hash {
+	var buildProof func(start, end uint64) []Hash
+	buildProof = func(start, end uint64) []Hash {
+		if end <= proofStart || start >= proofEnd {
+			// The subtree doesn't contain any of the proven segments, so its
+			// root is part of the proof.
+			return []Hash{MerkleRoot(segmentRange(b, 0, start, end))}
+		} else if proofStart <= start && end <= proofEnd {
+			// The subtree only contains proven segments, so the verifier can
+			// compute its root.
+			return nil
+		}
+		mid := start + splitSubtree(end-start)
+		return append(buildProof(start, mid), buildProof(mid, end)...)
+	}
+	numSegments := CalculateLeaves(uint64(len(b)))
+	if proofStart >= proofEnd || proofEnd > numSegments {
+		return nil
+	}
+	return buildProof(0, numSegments)
+}
+
+// VerifyRangeProof will verify that the segments [proofStart, proofEnd),
+// given the proof created by MerkleRangeProof, are a part of a Merkle root.
+func VerifyRangeProof(segments []byte, proof []Hash, numSegments, proofStart, proofEnd uint64, root Hash) bool {
+	if proofStart >= proofEnd || proofEnd > numSegments || CalculateLeaves(uint64(len(segments))) != proofEnd-proofStart {
+		return false
+	}
+	var buildRoot func(start, end uint64) (Hash, bool)
+	buildRoot = func(start, end uint64) (Hash, bool) {
+		if end <= proofStart || start >= proofEnd {
+			if len(proof) == 0 {
+				return Hash{}, false
+			}
+			h := proof[0]
+			proof = proof[1:]
+			return h, true
+		} else if proofStart <= start && end <= proofEnd {
+			return MerkleRoot(segmentRange(segments, proofStart, start, end)), true
+		}
+		mid := start + splitSubtree(end-start)
+		left, ok := buildRoot(start, mid)
+		if !ok {
+			return Hash{}, false
+		}
+		right, ok := buildRoot(mid, end)
+		if !ok {
+			return Hash{}, false
+		}
+		return nodeHash(left, right), true
+	}
+	proofRoot, ok := buildRoot(0, numSegments)
+	return ok && len(proof) == 0 && proofRoot == root
+}
@@ -153,3 +153,46 @@ func TestOddDataSize(t *testing.T) {
 		}
 	}
 }
+
+// TestRangeProof builds range proofs for every range of segments of some data
+// and checks that they verify correctly.
+func TestRangeProof(t *testing.T) {
+	// Use a number of segments that isn't a power of two, and a last segment
+	// that isn't full.
+	numSegments := uint64(11)
+	data := fastrand.Bytes(int(numSegments*SegmentSize) - 10)
+	root := MerkleRoot(data)
+
+	for start := uint64(0); start < numSegments; start++ {
+		for end := start + 1; end <= numSegments; end++ {
+			segments := data[start*SegmentSize:]
+			if end < numSegments {
+				segments = data[start*SegmentSize : end*SegmentSize]
+			}
+			proof := MerkleRangeProof(data, start, end)
+			if !VerifyRangeProof(segments, proof, numSegments, start, end, root) {
+				t.Fatal("Proof for range", start, end, "did not pass verification")
+			}
+			// A proof that is missing a hash should not verify.
+			if len(proof) > 0 && VerifyRangeProof(segments, proof[1:], numSegments, start, end, root) {
+				t.Fatal("Verified a proof that is missing a hash for range", start, end)
+			}
+		}
+	}
+
+	// Try proofs with bad data and a bad range.
+	proof := MerkleRangeProof(data, 3, 6)
+	segments := append([]byte(nil), data[3*SegmentSize:6*SegmentSize]...)
+	if VerifyRangeProof(segments, proof, numSegments, 4, 7, root) {
+		t.Error("Verified a proof for the wrong range")
+	}
+	segments[0]++
+	if VerifyRangeProof(segments, proof, numSegments, 3, 6, root) {
+		t.Error("Verified a proof for bad data")
+	}
+
+	// A proof for all of the data is empty.
+	if proof := MerkleRangeProof(data, 0, numSegments); len(proof) != 0 {
+		t.Error("Expected an empty proof, got", len(proof), "hashes")
+	}
+}
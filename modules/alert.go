@@ -0,0 +1,210 @@
+package modules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// SeverityInfo alerts report a condition that does not require any
+	// action.
+	SeverityInfo AlertSeverity = iota
+	// SeverityWarning alerts report a condition that will cause problems if
+	// it is not addressed.
+	SeverityWarning
+	// SeverityError alerts report a condition that is already causing
+	// problems, e.g. contracts that cannot be renewed.
+	SeverityError
+	// SeverityCritical alerts report a condition that causes data loss, e.g.
+	// files that cannot be recovered.
+	SeverityCritical
+)
+
+var (
+	// ErrUnknownAlert is returned when acknowledging an alert that is not
+	// active.
+	ErrUnknownAlert = errors.New("no active alert with that id")
+)
+
+type (
+	// AlertID identifies an alert. The ID of an alert is derived from the
+	// condition that caused it, so that an alert keeps its ID, and its
+	// acknowledgement, for as long as the condition persists.
+	AlertID string
+
+	// AlertSeverity describes how urgent an alert is.
+	AlertSeverity uint64
+
+	// An Alert reports a condition that requires the user's attention. Module
+	// is the module that the alert concerns, Message describes the condition
+	// and Cause contains the underlying error, if any. Time is the time at
+	// which the condition was first detected.
+	Alert struct {
+		ID           AlertID       `json:"id"`
+		Module       string        `json:"module"`
+		Severity     AlertSeverity `json:"severity"`
+		Message      string        `json:"message"`
+		Cause        string        `json:"cause"`
+		Time         time.Time     `json:"time"`
+		Acknowledged bool          `json:"acknowledged"`
+	}
+
+	// An Alerter is a module that raises alerts.
+	Alerter interface {
+		// Alerts returns the active alerts of the module, oldest first.
+		Alerts() []Alert
+
+		// AcknowledgeAlert marks the active alert as acknowledged. The
+		// acknowledgement lasts until the condition of the alert is
+		// resolved.
+		AcknowledgeAlert(id AlertID) error
+	}
+
+	// GenericAlerter keeps track of the active alerts of a module and of the
+	// alerts that the user acknowledged. Modules embed it to implement the
+	// Alerter interface, and persist the acknowledged alerts themselves.
+	GenericAlerter struct {
+		module       string
+		alerts       map[AlertID]Alert
+		acknowledged map[AlertID]struct{}
+		mu           sync.Mutex
+	}
+)
+
+// String returns the name of the severity.
+func (s AlertSeverity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// MarshalJSON marshals the severity as its name.
+func (s AlertSeverity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON unmarshals the name of a severity.
+func (s *AlertSeverity) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+	for severity := SeverityInfo; severity <= SeverityCritical; severity++ {
+		if severity.String() == name {
+			*s = severity
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown alert severity %q", name)
+}
+
+// NewAlerter creates an alerter for the alerts of a module.
+func NewAlerter(module string) *GenericAlerter {
+	return &GenericAlerter{
+		module:       module,
+		alerts:       make(map[AlertID]Alert),
+		acknowledged: make(map[AlertID]struct{}),
+	}
+}
+
+// RegisterAlert raises the alert with the given id, or updates it if it is
+// already active. An alert that is updated keeps the time at which it was
+// raised. If the severity of an alert increases, its acknowledgement is
+// revoked.
+func (a *GenericAlerter) RegisterAlert(id AlertID, message, cause string, severity AlertSeverity) {
+	a.RegisterModuleAlert(id, a.module, message, cause, severity)
+}
+
+// RegisterModuleAlert raises an alert that concerns a different module than
+// the module of the alerter, e.g. an alert about the wallet that was raised by
+// the contractor.
+func (a *GenericAlerter) RegisterModuleAlert(id AlertID, module, message, cause string, severity AlertSeverity) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	alert, exists := a.alerts[id]
+	if !exists {
+		alert.Time = time.Now()
+	} else if severity > alert.Severity {
+		delete(a.acknowledged, id)
+	}
+	alert.ID = id
+	alert.Module = module
+	alert.Severity = severity
+	alert.Message = message
+	alert.Cause = cause
+	a.alerts[id] = alert
+}
+
+// UnregisterAlert resolves the alert with the given id. If the condition of
+// the alert occurs again, it has to be acknowledged again.
+func (a *GenericAlerter) UnregisterAlert(id AlertID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.alerts, id)
+	delete(a.acknowledged, id)
+}
+
+// Alerts returns the active alerts, oldest first.
+func (a *GenericAlerter) Alerts() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	alerts := make([]Alert, 0, len(a.alerts))
+	for id, alert := range a.alerts {
+		_, alert.Acknowledged = a.acknowledged[id]
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Time.Equal(alerts[j].Time) {
+			return alerts[i].ID < alerts[j].ID
+		}
+		return alerts[i].Time.Before(alerts[j].Time)
+	})
+	return alerts
+}
+
+// AcknowledgeAlert marks the active alert as acknowledged.
+func (a *GenericAlerter) AcknowledgeAlert(id AlertID) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, exists := a.alerts[id]; !exists {
+		return ErrUnknownAlert
+	}
+	a.acknowledged[id] = struct{}{}
+	return nil
+}
+
+// Acknowledged returns the ids of the acknowledged alerts, so that the module
+// can persist them.
+func (a *GenericAlerter) Acknowledged() []AlertID {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ids := make([]AlertID, 0, len(a.acknowledged))
+	for id := range a.acknowledged {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// SetAcknowledged restores the acknowledged alerts that were persisted by the
+// module. The acknowledgements apply once the alerts are raised again.
+func (a *GenericAlerter) SetAcknowledged(ids []AlertID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acknowledged = make(map[AlertID]struct{})
+	for _, id := range ids {
+		a.acknowledged[id] = struct{}{}
+	}
+}
@@ -0,0 +1,80 @@
+package modules
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestGenericAlerter tests registering, acknowledging and resolving alerts.
+func TestGenericAlerter(t *testing.T) {
+	a := NewAlerter("renter")
+	a.RegisterAlert("a", "first", "", SeverityWarning)
+	a.RegisterModuleAlert("b", "wallet", "second", "locked", SeverityError)
+	alerts := a.Alerts()
+	if len(alerts) != 2 {
+		t.Fatal("expected 2 alerts, got", len(alerts))
+	} else if alerts[0].ID != "a" || alerts[0].Module != "renter" {
+		t.Fatal("wrong first alert:", alerts[0])
+	} else if alerts[1].ID != "b" || alerts[1].Module != "wallet" || alerts[1].Cause != "locked" {
+		t.Fatal("wrong second alert:", alerts[1])
+	}
+
+	// Only active alerts can be acknowledged.
+	if err := a.AcknowledgeAlert("c"); err != ErrUnknownAlert {
+		t.Fatal("expected ErrUnknownAlert, got", err)
+	}
+	if err := a.AcknowledgeAlert("a"); err != nil {
+		t.Fatal(err)
+	}
+	if alerts := a.Alerts(); !alerts[0].Acknowledged || alerts[1].Acknowledged {
+		t.Fatal("wrong alert was acknowledged:", alerts)
+	}
+
+	// Updating an alert keeps its time and acknowledgement, unless the
+	// severity increases.
+	time := a.Alerts()[0].Time
+	a.RegisterAlert("a", "updated", "", SeverityWarning)
+	if alert := a.Alerts()[0]; alert.Message != "updated" || !alert.Time.Equal(time) || !alert.Acknowledged {
+		t.Fatal("alert was not updated correctly:", alert)
+	}
+	a.RegisterAlert("a", "updated", "", SeverityError)
+	if alert := a.Alerts()[0]; alert.Acknowledged {
+		t.Fatal("acknowledgement was kept after the severity increased")
+	}
+
+	// Resolving an alert removes its acknowledgement.
+	a.AcknowledgeAlert("b")
+	a.UnregisterAlert("b")
+	if len(a.Alerts()) != 1 || len(a.Acknowledged()) != 0 {
+		t.Fatal("alert was not resolved:", a.Alerts(), a.Acknowledged())
+	}
+
+	// Persisted acknowledgements apply once the alert is raised again.
+	a.SetAcknowledged([]AlertID{"b"})
+	a.RegisterAlert("b", "second", "", SeverityError)
+	if alerts := a.Alerts(); !alerts[1].Acknowledged {
+		t.Fatal("persisted acknowledgement was not applied")
+	}
+}
+
+// TestAlertSeverityJSON tests that severities are encoded as their names.
+func TestAlertSeverityJSON(t *testing.T) {
+	for s := SeverityInfo; s <= SeverityCritical; s++ {
+		b, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(b) != `"`+s.String()+`"` {
+			t.Fatal("wrong encoding:", string(b))
+		}
+		var decoded AlertSeverity
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatal(err)
+		} else if decoded != s {
+			t.Fatal("wrong decoding:", decoded)
+		}
+	}
+	var s AlertSeverity
+	if err := json.Unmarshal([]byte(`"urgent"`), &s); err == nil {
+		t.Fatal("unknown severity was decoded")
+	}
+}
@@ -53,6 +53,9 @@ type (
 		// Listen gives the host the ability to receive incoming connections.
 		Listen(string, string) (net.Listener, error)
 
+		// LookupIP resolves a hostname into its IP addresses.
+		LookupIP(string) ([]net.IP, error)
+
 		// LoadFile allows the host to load a persistence structure form disk.
 		LoadFile(persist.Metadata, interface{}, string) error
 
@@ -224,6 +227,11 @@ func (*ProductionDependencies) Listen(s1, s2 string) (net.Listener, error) {
 	return net.Listen(s1, s2)
 }
 
+// LookupIP resolves a hostname into its IP addresses.
+func (*ProductionDependencies) LookupIP(host string) ([]net.IP, error) {
+	return net.LookupIP(host)
+}
+
 // LoadFile loads JSON encoded data from a file.
 func (*ProductionDependencies) LoadFile(meta persist.Metadata, data interface{}, filename string) error {
 	return persist.LoadJSON(meta, data, filename)
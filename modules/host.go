@@ -104,6 +104,7 @@ type (
 		FormContractCalls uint64 `json:"formcontractcalls"`
 		RenewCalls        uint64 `json:"renewcalls"`
 		ReviseCalls       uint64 `json:"revisecalls"`
+		SessionCalls      uint64 `json:"sessioncalls"`
 		SettingsCalls     uint64 `json:"settingscalls"`
 		UnrecognizedCalls uint64 `json:"unrecognizedcalls"`
 	}
@@ -121,6 +121,7 @@ type Host struct {
 	atomicFormContractCalls uint64
 	atomicRenewCalls        uint64
 	atomicReviseCalls       uint64
+	atomicSessionCalls      uint64
 	atomicSettingsCalls     uint64
 	atomicUnrecognizedCalls uint64
 
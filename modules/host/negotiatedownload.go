@@ -6,6 +6,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -22,6 +23,45 @@ var (
 	errRequestOutOfBounds = ErrorCommunication("download request has invalid sector bounds")
 )
 
+// validateDownloadRequests checks that the requested sections are within the
+// bounds of a sector, and that the total size being requested is acceptable.
+// The total size is returned.
+func validateDownloadRequests(requests []modules.DownloadAction, settings modules.HostExternalSettings) (uint64, error) {
+	var totalSize uint64
+	for _, request := range requests {
+		if request.Length > modules.SectorSize || request.Offset+request.Length > modules.SectorSize {
+			return 0, extendErr("download iteration request failed: ", errRequestOutOfBounds)
+		}
+		totalSize += request.Length
+	}
+	if totalSize > settings.MaxDownloadBatchSize {
+		return 0, extendErr("download iteration batch failed: ", errLargeDownloadBatch)
+	}
+	return totalSize, nil
+}
+
+// managedReadSections loads the sectors of the requests and returns the
+// requested sections of each. If merkleProof is set, a Merkle range proof is
+// returned for each section as well, in which case the sections must be
+// aligned to segments.
+func (h *Host) managedReadSections(requests []modules.DownloadAction, merkleProof bool) ([][]byte, [][]crypto.Hash, error) {
+	var payload [][]byte
+	var proofs [][]crypto.Hash
+	for _, request := range requests {
+		sectorData, err := h.ReadSector(request.MerkleRoot)
+		if err != nil {
+			return nil, nil, extendErr("failed to load sector: ", ErrorInternal(err.Error()))
+		}
+		payload = append(payload, sectorData[request.Offset:request.Offset+request.Length])
+		if merkleProof {
+			proofStart := request.Offset / crypto.SegmentSize
+			proofEnd := (request.Offset + request.Length) / crypto.SegmentSize
+			proofs = append(proofs, crypto.MerkleRangeProof(sectorData, proofStart, proofEnd))
+		}
+	}
+	return payload, proofs, nil
+}
+
 // managedDownloadIteration is responsible for managing a single iteration of
 // the download loop for RPCDownload.
 func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) error {
@@ -67,17 +107,10 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 	existingRevision := so.RevisionTransactionSet[len(so.RevisionTransactionSet)-1].FileContractRevisions[0]
 	var payload [][]byte
 	err = func() error {
-		// Check that the length of each file is in-bounds, and that the total
-		// size being requested is acceptable.
-		var totalSize uint64
-		for _, request := range requests {
-			if request.Length > modules.SectorSize || request.Offset+request.Length > modules.SectorSize {
-				return extendErr("download iteration request failed: ", errRequestOutOfBounds)
-			}
-			totalSize += request.Length
-		}
-		if totalSize > settings.MaxDownloadBatchSize {
-			return extendErr("download iteration batch failed: ", errLargeDownloadBatch)
+		// Check that the requests are in-bounds and not too large.
+		totalSize, err := validateDownloadRequests(requests, settings)
+		if err != nil {
+			return err
 		}
 
 		// Verify that the correct amount of money has been moved from the
@@ -89,14 +122,8 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 		}
 
 		// Load the sectors and build the data payload.
-		for _, request := range requests {
-			sectorData, err := h.ReadSector(request.MerkleRoot)
-			if err != nil {
-				return extendErr("failed to load sector: ", ErrorInternal(err.Error()))
-			}
-			payload = append(payload, sectorData[request.Offset:request.Offset+request.Length])
-		}
-		return nil
+		payload, _, err = h.managedReadSections(requests, false)
+		return err
 	}()
 	if err != nil {
 		modules.WriteNegotiationRejection(conn, err) // Error not reported to preserve type in extendErr
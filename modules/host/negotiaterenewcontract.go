@@ -76,7 +76,7 @@ func (h *Host) managedAddRenewCollateral(so storageObligation, settings modules.
 	return builder, newParents, newInputs, newOutputs, nil
 }
 
-// managedRenewContract accepts a request to renew a file contract.
+// managedRPCRenewContract accepts a request to renew a file contract.
 func (h *Host) managedRPCRenewContract(conn net.Conn) error {
 	// Perform the recent revision protocol to get the file contract being
 	// revised.
@@ -90,8 +90,16 @@ func (h *Host) managedRPCRenewContract(conn net.Conn) error {
 		h.managedUnlockStorageObligation(so.id())
 	}()
 
+	return h.managedRenewContract(conn, so)
+}
+
+// managedRenewContract negotiates the renewal of the locked storage
+// obligation after the renter has proven that it owns the contract. The
+// storage obligation of the renewed contract is created during the
+// negotiation.
+func (h *Host) managedRenewContract(conn net.Conn, so storageObligation) error {
 	// Perform the host settings exchange with the renter.
-	err = h.managedRPCSettings(conn)
+	err := h.managedRPCSettings(conn)
 	if err != nil {
 		return extendErr("RPCSettings failed: ", err)
 	}
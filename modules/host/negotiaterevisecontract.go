@@ -12,6 +12,96 @@ import (
 	"github.com/NebulousLabs/Sia/types"
 )
 
+// revisionChanges contains the changes that a batch of revision actions makes
+// to a storage obligation.
+type revisionChanges struct {
+	bandwidthRevenue types.Currency // Upload bandwidth.
+	storageRevenue   types.Currency
+	newCollateral    types.Currency
+	sectorsRemoved   []crypto.Hash
+	sectorsGained    []crypto.Hash
+	gainedSectorData [][]byte
+}
+
+// managedApplyModifications applies the modifications to the sector roots of
+// the storage obligation, and returns the revenue and collateral that the
+// modifications add, as well as the sectors that they remove and add. The
+// storage obligation is not saved, so the modifications can be reversed by
+// discarding it.
+func (h *Host) managedApplyModifications(so *storageObligation, modifications []modules.RevisionAction, settings modules.HostExternalSettings, blockHeight types.BlockHeight) (revisionChanges, error) {
+	var rc revisionChanges
+	for _, modification := range modifications {
+		// Check that the index points to an existing sector root. If the type
+		// is ActionInsert, we permit inserting at the end.
+		if modification.Type == modules.ActionInsert {
+			if modification.SectorIndex > uint64(len(so.SectorRoots)) {
+				return revisionChanges{}, errBadModificationIndex
+			}
+		} else if modification.SectorIndex >= uint64(len(so.SectorRoots)) {
+			return revisionChanges{}, errBadModificationIndex
+		}
+		// Check that the data sent for the sector is not too large.
+		if uint64(len(modification.Data)) > modules.SectorSize {
+			return revisionChanges{}, errLargeSector
+		}
+
+		switch modification.Type {
+		case modules.ActionDelete:
+			// There is no financial information to change, it is enough to
+			// remove the sector.
+			rc.sectorsRemoved = append(rc.sectorsRemoved, so.SectorRoots[modification.SectorIndex])
+			so.SectorRoots = append(so.SectorRoots[0:modification.SectorIndex], so.SectorRoots[modification.SectorIndex+1:]...)
+		case modules.ActionInsert:
+			// Check that the sector size is correct.
+			if uint64(len(modification.Data)) != modules.SectorSize {
+				return revisionChanges{}, errBadSectorSize
+			}
+
+			// Update finances.
+			blocksRemaining := so.proofDeadline() - blockHeight
+			blockBytesCurrency := types.NewCurrency64(uint64(blocksRemaining)).Mul64(modules.SectorSize)
+			rc.bandwidthRevenue = rc.bandwidthRevenue.Add(settings.UploadBandwidthPrice.Mul64(modules.SectorSize))
+			rc.storageRevenue = rc.storageRevenue.Add(settings.StoragePrice.Mul(blockBytesCurrency))
+			rc.newCollateral = rc.newCollateral.Add(settings.Collateral.Mul(blockBytesCurrency))
+
+			// Insert the sector into the root list.
+			newRoot := crypto.MerkleRoot(modification.Data)
+			rc.sectorsGained = append(rc.sectorsGained, newRoot)
+			rc.gainedSectorData = append(rc.gainedSectorData, modification.Data)
+			so.SectorRoots = append(so.SectorRoots[:modification.SectorIndex], append([]crypto.Hash{newRoot}, so.SectorRoots[modification.SectorIndex:]...)...)
+		case modules.ActionModify:
+			// Check that the offset and length are okay. Length is already
+			// known to be appropriately small, but the offset needs to be
+			// checked for being appropriately small as well otherwise there is
+			// a risk of overflow.
+			if modification.Offset > modules.SectorSize || modification.Offset+uint64(len(modification.Data)) > modules.SectorSize {
+				return revisionChanges{}, errIllegalOffsetAndLength
+			}
+
+			// Get the data for the new sector.
+			sector, err := h.ReadSector(so.SectorRoots[modification.SectorIndex])
+			if err != nil {
+				return revisionChanges{}, extendErr("could not read sector: ", ErrorInternal(err.Error()))
+			}
+			copy(sector[modification.Offset:], modification.Data)
+
+			// Update finances.
+			rc.bandwidthRevenue = rc.bandwidthRevenue.Add(settings.UploadBandwidthPrice.Mul64(uint64(len(modification.Data))))
+
+			// Update the sectors removed and gained to indicate that the old
+			// sector has been replaced with a new sector.
+			newRoot := crypto.MerkleRoot(sector)
+			rc.sectorsRemoved = append(rc.sectorsRemoved, so.SectorRoots[modification.SectorIndex])
+			rc.sectorsGained = append(rc.sectorsGained, newRoot)
+			rc.gainedSectorData = append(rc.gainedSectorData, sector)
+			so.SectorRoots[modification.SectorIndex] = newRoot
+		default:
+			return revisionChanges{}, errUnknownModification
+		}
+	}
+	return rc, nil
+}
+
 // managedRevisionIteration handles one iteration of the revision loop. As a
 // performance optimization, multiple iterations of revisions are allowed to be
 // made over the same connection.
@@ -57,88 +147,13 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 		return extendErr("unable to read proposed revision: ", ErrorConnection(err.Error()))
 	}
 
-	// First read all of the modifications. Then make the modifications, but
-	// with the ability to reverse them. Then verify the file contract revision
-	// correctly accounts for the changes.
-	var bandwidthRevenue types.Currency // Upload bandwidth.
-	var storageRevenue types.Currency
-	var newCollateral types.Currency
-	var sectorsRemoved []crypto.Hash
-	var sectorsGained []crypto.Hash
-	var gainedSectorData [][]byte
-	err = func() error {
-		for _, modification := range modifications {
-			// Check that the index points to an existing sector root. If the type
-			// is ActionInsert, we permit inserting at the end.
-			if modification.Type == modules.ActionInsert {
-				if modification.SectorIndex > uint64(len(so.SectorRoots)) {
-					return errBadModificationIndex
-				}
-			} else if modification.SectorIndex >= uint64(len(so.SectorRoots)) {
-				return errBadModificationIndex
-			}
-			// Check that the data sent for the sector is not too large.
-			if uint64(len(modification.Data)) > modules.SectorSize {
-				return errLargeSector
-			}
-
-			switch modification.Type {
-			case modules.ActionDelete:
-				// There is no financial information to change, it is enough to
-				// remove the sector.
-				sectorsRemoved = append(sectorsRemoved, so.SectorRoots[modification.SectorIndex])
-				so.SectorRoots = append(so.SectorRoots[0:modification.SectorIndex], so.SectorRoots[modification.SectorIndex+1:]...)
-			case modules.ActionInsert:
-				// Check that the sector size is correct.
-				if uint64(len(modification.Data)) != modules.SectorSize {
-					return errBadSectorSize
-				}
-
-				// Update finances.
-				blocksRemaining := so.proofDeadline() - blockHeight
-				blockBytesCurrency := types.NewCurrency64(uint64(blocksRemaining)).Mul64(modules.SectorSize)
-				bandwidthRevenue = bandwidthRevenue.Add(settings.UploadBandwidthPrice.Mul64(modules.SectorSize))
-				storageRevenue = storageRevenue.Add(settings.StoragePrice.Mul(blockBytesCurrency))
-				newCollateral = newCollateral.Add(settings.Collateral.Mul(blockBytesCurrency))
-
-				// Insert the sector into the root list.
-				newRoot := crypto.MerkleRoot(modification.Data)
-				sectorsGained = append(sectorsGained, newRoot)
-				gainedSectorData = append(gainedSectorData, modification.Data)
-				so.SectorRoots = append(so.SectorRoots[:modification.SectorIndex], append([]crypto.Hash{newRoot}, so.SectorRoots[modification.SectorIndex:]...)...)
-			case modules.ActionModify:
-				// Check that the offset and length are okay. Length is already
-				// known to be appropriately small, but the offset needs to be
-				// checked for being appropriately small as well otherwise there is
-				// a risk of overflow.
-				if modification.Offset > modules.SectorSize || modification.Offset+uint64(len(modification.Data)) > modules.SectorSize {
-					return errIllegalOffsetAndLength
-				}
-
-				// Get the data for the new sector.
-				sector, err := h.ReadSector(so.SectorRoots[modification.SectorIndex])
-				if err != nil {
-					return extendErr("could not read sector: ", ErrorInternal(err.Error()))
-				}
-				copy(sector[modification.Offset:], modification.Data)
-
-				// Update finances.
-				bandwidthRevenue = bandwidthRevenue.Add(settings.UploadBandwidthPrice.Mul64(uint64(len(modification.Data))))
-
-				// Update the sectors removed and gained to indicate that the old
-				// sector has been replaced with a new sector.
-				newRoot := crypto.MerkleRoot(sector)
-				sectorsRemoved = append(sectorsRemoved, so.SectorRoots[modification.SectorIndex])
-				sectorsGained = append(sectorsGained, newRoot)
-				gainedSectorData = append(gainedSectorData, sector)
-				so.SectorRoots[modification.SectorIndex] = newRoot
-			default:
-				return errUnknownModification
-			}
-		}
-		newRevenue := storageRevenue.Add(bandwidthRevenue)
-		return extendErr("unable to verify updated contract: ", verifyRevision(*so, revision, blockHeight, newRevenue, newCollateral))
-	}()
+	// First make the modifications, but with the ability to reverse them. Then
+	// verify the file contract revision correctly accounts for the changes.
+	changes, err := h.managedApplyModifications(so, modifications, settings, blockHeight)
+	if err == nil {
+		newRevenue := changes.storageRevenue.Add(changes.bandwidthRevenue)
+		err = extendErr("unable to verify updated contract: ", verifyRevision(*so, revision, blockHeight, newRevenue, changes.newCollateral))
+	}
 	if err != nil {
 		modules.WriteNegotiationRejection(conn, err) // Error is ignored so that the error type can be preserved in extendErr.
 		return extendErr("rejected proposed modifications: ", err)
@@ -162,12 +177,12 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 		return extendErr("could not create revision signature: ", err)
 	}
 
-	so.PotentialStorageRevenue = so.PotentialStorageRevenue.Add(storageRevenue)
-	so.RiskedCollateral = so.RiskedCollateral.Add(newCollateral)
-	so.PotentialUploadRevenue = so.PotentialUploadRevenue.Add(bandwidthRevenue)
+	so.PotentialStorageRevenue = so.PotentialStorageRevenue.Add(changes.storageRevenue)
+	so.RiskedCollateral = so.RiskedCollateral.Add(changes.newCollateral)
+	so.PotentialUploadRevenue = so.PotentialUploadRevenue.Add(changes.bandwidthRevenue)
 	so.RevisionTransactionSet = []types.Transaction{txn}
 	h.mu.Lock()
-	err = h.modifyStorageObligation(*so, sectorsRemoved, sectorsGained, gainedSectorData)
+	err = h.modifyStorageObligation(*so, changes.sectorsRemoved, changes.sectorsGained, changes.gainedSectorData)
 	h.mu.Unlock()
 	if err != nil {
 		modules.WriteNegotiationRejection(conn, err) // Error is ignored so that the error type can be preserved in extendErr.
@@ -0,0 +1,281 @@
+package host
+
+import (
+	"net"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errSessionExpired is returned if the renter makes a request after the
+	// session has been open for longer than iteratedConnectionTime.
+	errSessionExpired = ErrorCommunication("session has been open for too long")
+
+	// errUnknownSessionRPC is returned if the renter makes a session request
+	// that the host does not recognize.
+	errUnknownSessionRPC = ErrorCommunication("session request has an unknown RPC")
+
+	// errUnalignedSection is returned if the renter requests a Merkle proof
+	// for a section that isn't aligned to segments.
+	errUnalignedSection = ErrorCommunication("section with Merkle proof is not aligned to segments")
+)
+
+// writeSessionResponse writes the response to the session request with the
+// provided ID. If reqErr is not nil, the response tells the renter that the
+// request failed. Only the error of the write is returned.
+func writeSessionResponse(conn net.Conn, id uint64, reqErr error) error {
+	resp := modules.SessionResponse{ID: id}
+	if reqErr != nil {
+		resp.Error = reqErr.Error()
+		if len(resp.Error) > modules.NegotiateMaxErrorSize {
+			resp.Error = resp.Error[:modules.NegotiateMaxErrorSize]
+		}
+	}
+	if err := encoding.WriteObject(conn, resp); err != nil {
+		return ErrorConnection("failed to write session response: " + err.Error())
+	}
+	return nil
+}
+
+// validateSectionAlignment checks that the offset and length of each section
+// are multiples of crypto.SegmentSize, so that the host can prove the
+// sections with Merkle range proofs.
+func validateSectionAlignment(sections []modules.DownloadAction) error {
+	for _, section := range sections {
+		if section.Length == 0 || section.Offset%crypto.SegmentSize != 0 || section.Length%crypto.SegmentSize != 0 {
+			return errUnalignedSection
+		}
+	}
+	return nil
+}
+
+// managedSessionRead handles a SessionRPCRead request. The host sends the
+// requested sections to the renter in exchange for the payment revision, and
+// proves the sections if the renter asked for Merkle proofs.
+func (h *Host) managedSessionRead(conn net.Conn, so *storageObligation, id uint64) error {
+	// Extend the deadline for the download.
+	conn.SetDeadline(time.Now().Add(modules.NegotiateDownloadTime))
+
+	// Read the requested sections, followed by the signed revision that pays
+	// for them.
+	var req modules.SessionReadRequest
+	maxLen := uint64(modules.NegotiateMaxDownloadActionRequestSize + modules.NegotiateMaxFileContractRevisionSize + modules.NegotiateMaxTransactionSignatureSize)
+	err := encoding.ReadObject(conn, &req, maxLen)
+	if err != nil {
+		return extendErr("failed to read request: ", ErrorConnection(err.Error()))
+	}
+
+	// Grab a set of variables that will be useful later in the function.
+	h.mu.Lock()
+	blockHeight := h.blockHeight
+	secretKey := h.secretKey
+	settings := h.externalSettings()
+	h.mu.Unlock()
+
+	// Verify that the request is acceptable and signed, and then fetch all
+	// of the data for the renter.
+	existingRevision := so.RevisionTransactionSet[len(so.RevisionTransactionSet)-1].FileContractRevisions[0]
+	var txn types.Transaction
+	var payload [][]byte
+	var proofs [][]crypto.Hash
+	err = func() error {
+		totalSize, err := validateDownloadRequests(req.Sections, settings)
+		if err != nil {
+			return err
+		}
+		if req.MerkleProof {
+			if err := validateSectionAlignment(req.Sections); err != nil {
+				return err
+			}
+		}
+		expectedTransfer := settings.DownloadBandwidthPrice.Mul64(totalSize)
+		err = verifyPaymentRevision(existingRevision, req.NewRevision, blockHeight, expectedTransfer)
+		if err != nil {
+			return extendErr("payment verification failed: ", err)
+		}
+		txn, err = createRevisionSignature(req.NewRevision, req.Signature, secretKey, blockHeight)
+		if err != nil {
+			return extendErr("could not create revision signature: ", err)
+		}
+		payload, proofs, err = h.managedReadSections(req.Sections, req.MerkleProof)
+		return err
+	}()
+	if err != nil {
+		writeSessionResponse(conn, id, err) // Error is ignored to preserve type for extendErr
+		return extendErr("read request rejected: ", err)
+	}
+
+	// Update the storage obligation.
+	paymentTransfer := existingRevision.NewValidProofOutputs[0].Value.Sub(req.NewRevision.NewValidProofOutputs[0].Value)
+	so.PotentialDownloadRevenue = so.PotentialDownloadRevenue.Add(paymentTransfer)
+	so.RevisionTransactionSet = []types.Transaction{txn}
+	h.mu.Lock()
+	err = h.modifyStorageObligation(*so, nil, nil, nil)
+	h.mu.Unlock()
+	if err != nil {
+		writeSessionResponse(conn, id, err) // Error is ignored to preserve type for extendErr
+		return extendErr("failed to modify storage obligation: ", ErrorInternal(err.Error()))
+	}
+
+	// Send the host signature, all of the data and the proofs.
+	if err := writeSessionResponse(conn, id, nil); err != nil {
+		return err
+	}
+	err = encoding.WriteObject(conn, modules.SessionReadResponse{
+		Signature:    txn.TransactionSignatures[1],
+		Data:         payload,
+		MerkleProofs: proofs,
+	})
+	if err != nil {
+		return extendErr("failed to write read response: ", ErrorConnection(err.Error()))
+	}
+	return nil
+}
+
+// managedSessionWrite handles a SessionRPCWrite request. The host applies the
+// modifications to the storage obligation in exchange for the payment
+// revision.
+func (h *Host) managedSessionWrite(conn net.Conn, so *storageObligation, id uint64) error {
+	// Set the negotiation deadline.
+	conn.SetDeadline(time.Now().Add(modules.NegotiateFileContractRevisionTime))
+
+	// Read some variables from the host for use later in the function.
+	h.mu.Lock()
+	settings := h.externalSettings()
+	secretKey := h.secretKey
+	blockHeight := h.blockHeight
+	h.mu.Unlock()
+
+	// Read the modifications, followed by the signed revision that pays for
+	// them.
+	var req modules.SessionWriteRequest
+	maxLen := settings.MaxReviseBatchSize + modules.NegotiateMaxFileContractRevisionSize + modules.NegotiateMaxTransactionSignatureSize
+	err := encoding.ReadObject(conn, &req, maxLen)
+	if err != nil {
+		return extendErr("failed to read request: ", ErrorConnection(err.Error()))
+	}
+
+	// Make the modifications, verify that the revision correctly accounts for
+	// them, and sign the revision.
+	var changes revisionChanges
+	var txn types.Transaction
+	err = func() error {
+		var err error
+		changes, err = h.managedApplyModifications(so, req.Actions, settings, blockHeight)
+		if err != nil {
+			return err
+		}
+		newRevenue := changes.storageRevenue.Add(changes.bandwidthRevenue)
+		err = verifyRevision(*so, req.NewRevision, blockHeight, newRevenue, changes.newCollateral)
+		if err != nil {
+			return extendErr("unable to verify updated contract: ", err)
+		}
+		txn, err = createRevisionSignature(req.NewRevision, req.Signature, secretKey, blockHeight)
+		return extendErr("could not create revision signature: ", err)
+	}()
+	if err != nil {
+		writeSessionResponse(conn, id, err) // Error is ignored to preserve type for extendErr
+		return extendErr("write request rejected: ", err)
+	}
+
+	// Update the storage obligation.
+	so.PotentialStorageRevenue = so.PotentialStorageRevenue.Add(changes.storageRevenue)
+	so.RiskedCollateral = so.RiskedCollateral.Add(changes.newCollateral)
+	so.PotentialUploadRevenue = so.PotentialUploadRevenue.Add(changes.bandwidthRevenue)
+	so.RevisionTransactionSet = []types.Transaction{txn}
+	h.mu.Lock()
+	err = h.modifyStorageObligation(*so, changes.sectorsRemoved, changes.sectorsGained, changes.gainedSectorData)
+	h.mu.Unlock()
+	if err != nil {
+		writeSessionResponse(conn, id, err) // Error is ignored to preserve type for extendErr
+		return extendErr("could not modify storage obligation: ", ErrorInternal(err.Error()))
+	}
+
+	// Send the host signature.
+	if err := writeSessionResponse(conn, id, nil); err != nil {
+		return err
+	}
+	err = encoding.WriteObject(conn, modules.SessionWriteResponse{
+		Signature: txn.TransactionSignatures[1],
+	})
+	if err != nil {
+		return extendErr("failed to write revision signature: ", ErrorConnection(err.Error()))
+	}
+	return nil
+}
+
+// managedRPCSession handles a session with a renter. The session starts with
+// the recent revision exchange, which locks the storage obligation for the
+// whole session, followed by the host's settings. Afterwards the renter can
+// make any number of requests until it ends the session, a request fails, or
+// the session has been open for longer than iteratedConnectionTime.
+func (h *Host) managedRPCSession(conn net.Conn) error {
+	// Get the start time to limit the length of the whole session.
+	startTime := time.Now()
+	// Perform the file contract revision exchange, giving the renter the most
+	// recent file contract revision and getting the storage obligation that
+	// will be used for the session.
+	_, so, err := h.managedRPCRecentRevision(conn)
+	if err != nil {
+		return extendErr("failed RPCRecentRevision during RPCSession: ", err)
+	}
+	// The storage obligation is returned with a lock on it. Defer a call to
+	// unlock the storage obligation.
+	defer func() {
+		h.managedUnlockStorageObligation(so.id())
+	}()
+
+	// Send the settings once, so that the renter doesn't have to request them
+	// before every request.
+	err = h.managedRPCSettings(conn)
+	if err != nil {
+		return extendErr("RPCSettings failed: ", err)
+	}
+
+	for {
+		// Wait for the next request of the renter.
+		conn.SetDeadline(time.Now().Add(modules.NegotiateSessionIdleTime))
+		var req modules.SessionRequest
+		err := encoding.ReadObject(conn, &req, modules.NegotiateMaxSessionHeaderSize)
+		if err != nil {
+			return extendErr("failed to read session request: ", ErrorConnection(err.Error()))
+		}
+		if req.RPC == modules.SessionRPCEnd {
+			return nil
+		}
+		if time.Since(startTime) > iteratedConnectionTime {
+			writeSessionResponse(conn, req.ID, errSessionExpired) // Error is ignored to preserve type for extendErr
+			return errSessionExpired
+		}
+
+		switch req.RPC {
+		case modules.SessionRPCRead:
+			err = extendErr("read request failed: ", h.managedSessionRead(conn, &so, req.ID))
+		case modules.SessionRPCRenew:
+			// The renewal uses the same negotiation as RPCRenewContract. The
+			// old contract can't be revised after it has been renewed, so
+			// the session ends.
+			if err := writeSessionResponse(conn, req.ID, nil); err != nil {
+				return err
+			}
+			return extendErr("renew request failed: ", h.managedRenewContract(conn, so))
+		case modules.SessionRPCSettings:
+			err = writeSessionResponse(conn, req.ID, nil)
+			if err == nil {
+				err = extendErr("settings request failed: ", h.managedRPCSettings(conn))
+			}
+		case modules.SessionRPCWrite:
+			err = extendErr("write request failed: ", h.managedSessionWrite(conn, &so, req.ID))
+		default:
+			writeSessionResponse(conn, req.ID, errUnknownSessionRPC) // Error is ignored to preserve type for extendErr
+			err = errUnknownSessionRPC
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
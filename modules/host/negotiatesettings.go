@@ -65,6 +65,9 @@ func (h *Host) externalSettings() modules.HostExternalSettings {
 
 		RevisionNumber: h.revisionNumber,
 		Version:        build.Version,
+
+		EncryptedTransport: true,
+		Sessions:           true,
 	}
 }
 
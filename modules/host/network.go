@@ -277,22 +277,45 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 		return
 	}
 
+	// If the renter starts the encrypted transport, perform the handshake and
+	// read the specifier of the actual RPC from the encrypted connection.
+	rpcConn := conn
+	if id == modules.RPCEncryptedTransport {
+		h.mu.RLock()
+		secretKey := h.secretKey
+		h.mu.RUnlock()
+		rpcConn, err = modules.HostTransportHandshake(conn, secretKey)
+		if err != nil {
+			atomic.AddUint64(&h.atomicErroredCalls, 1)
+			h.log.Debugf("WARN: transport handshake with %v failed: %v", conn.RemoteAddr(), err)
+			return
+		}
+		if err := encoding.ReadObject(rpcConn, &id, 16); err != nil {
+			atomic.AddUint64(&h.atomicUnrecognizedCalls, 1)
+			h.log.Debugf("WARN: incoming encrypted conn %v was malformed: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+
 	switch id {
 	case modules.RPCDownload:
 		atomic.AddUint64(&h.atomicDownloadCalls, 1)
-		err = extendErr("incoming RPCDownload failed: ", h.managedRPCDownload(conn))
+		err = extendErr("incoming RPCDownload failed: ", h.managedRPCDownload(rpcConn))
 	case modules.RPCRenewContract:
 		atomic.AddUint64(&h.atomicRenewCalls, 1)
-		err = extendErr("incoming RPCRenewContract failed: ", h.managedRPCRenewContract(conn))
+		err = extendErr("incoming RPCRenewContract failed: ", h.managedRPCRenewContract(rpcConn))
 	case modules.RPCFormContract:
 		atomic.AddUint64(&h.atomicFormContractCalls, 1)
-		err = extendErr("incoming RPCFormContract failed: ", h.managedRPCFormContract(conn))
+		err = extendErr("incoming RPCFormContract failed: ", h.managedRPCFormContract(rpcConn))
 	case modules.RPCReviseContract:
 		atomic.AddUint64(&h.atomicReviseCalls, 1)
-		err = extendErr("incoming RPCReviseContract failed: ", h.managedRPCReviseContract(conn))
+		err = extendErr("incoming RPCReviseContract failed: ", h.managedRPCReviseContract(rpcConn))
+	case modules.RPCSession:
+		atomic.AddUint64(&h.atomicSessionCalls, 1)
+		err = extendErr("incoming RPCSession failed: ", h.managedRPCSession(rpcConn))
 	case modules.RPCSettings:
 		atomic.AddUint64(&h.atomicSettingsCalls, 1)
-		err = extendErr("incoming RPCSettings failed: ", h.managedRPCSettings(conn))
+		err = extendErr("incoming RPCSettings failed: ", h.managedRPCSettings(rpcConn))
 	case rpcSettingsDeprecated:
 		h.log.Debugln("Received deprecated settings call")
 	default:
@@ -351,6 +374,7 @@ func (h *Host) NetworkMetrics() modules.HostNetworkMetrics {
 		FormContractCalls: atomic.LoadUint64(&h.atomicFormContractCalls),
 		RenewCalls:        atomic.LoadUint64(&h.atomicRenewCalls),
 		ReviseCalls:       atomic.LoadUint64(&h.atomicReviseCalls),
+		SessionCalls:      atomic.LoadUint64(&h.atomicSessionCalls),
 		SettingsCalls:     atomic.LoadUint64(&h.atomicSettingsCalls),
 		UnrecognizedCalls: atomic.LoadUint64(&h.atomicUnrecognizedCalls),
 	}
@@ -69,6 +69,10 @@ const (
 	// encoded HostExternalSettings.
 	NegotiateMaxHostExternalSettingsLen = 16000
 
+	// NegotiateMaxSessionHeaderSize is the maximum allowed size of an encoded
+	// SessionRequest or SessionResponse.
+	NegotiateMaxSessionHeaderSize = 1e3
+
 	// NegotiateMaxSiaPubkeySize defines the maximum size that a SiaPubkey is
 	// allowed to be when being sent over the wire during negotiation.
 	NegotiateMaxSiaPubkeySize = 1e3
@@ -83,6 +87,10 @@ const (
 	// wire during negotiation.
 	NegotiateMaxTransactionSignaturesSize = 5e3
 
+	// NegotiateMaxTransportHandshakeSize is the maximum allowed size of an
+	// encoded TransportHandshakeRequest or TransportHandshakeResponse.
+	NegotiateMaxTransportHandshakeSize = 1e3
+
 	// NegotiateRecentRevisionTime establishes the minimum amount of time that
 	// the connection deadline is expected to be set to when a recent file
 	// contract revision is being requested from the host. The deadline is long
@@ -96,6 +104,10 @@ const (
 	// that both the host and the renter can have time to process large Merkle
 	// tree calculations that may be involved with renewing a file contract.
 	NegotiateRenewContractTime = 600 * time.Second
+
+	// NegotiateSessionIdleTime defines the amount of time that the host waits
+	// for the next request of a session before closing the connection.
+	NegotiateSessionIdleTime = 600 * time.Second
 )
 
 var (
@@ -154,6 +166,11 @@ var (
 	// RPCDownload is the specifier for downloading a file from a host.
 	RPCDownload = types.Specifier{'D', 'o', 'w', 'n', 'l', 'o', 'a', 'd', 2}
 
+	// RPCEncryptedTransport is the specifier for starting the encrypted
+	// transport with the host. After the transport handshake, all data is
+	// encrypted, starting with the specifier of the actual RPC.
+	RPCEncryptedTransport = types.Specifier{'E', 'n', 'c', 'r', 'y', 'p', 't', 'e', 'd'}
+
 	// RPCFormContract is the specifier for forming a contract with a host.
 	RPCFormContract = types.Specifier{'F', 'o', 'r', 'm', 'C', 'o', 'n', 't', 'r', 'a', 'c', 't', 2}
 
@@ -164,6 +181,11 @@ var (
 	// contract.
 	RPCReviseContract = types.Specifier{'R', 'e', 'v', 'i', 's', 'e', 'C', 'o', 'n', 't', 'r', 'a', 'c', 't', 2}
 
+	// RPCSession is the specifier for opening a session with the host. A
+	// session locks a file contract once and then serves any number of
+	// SessionRequests over the same connection.
+	RPCSession = types.Specifier{'S', 'e', 's', 's', 'i', 'o', 'n'}
+
 	// RPCSettings is the specifier for requesting settings from the host.
 	RPCSettings = types.Specifier{'S', 'e', 't', 't', 'i', 'n', 'g', 's', 2}
 
@@ -177,6 +199,26 @@ var (
 		Standard: uint64(1 << 22), // 4 MiB
 		Testing:  uint64(1 << 12), // 4 KiB
 	}).(uint64)
+
+	// SessionRPCEnd is the specifier of a SessionRequest that ends the
+	// session.
+	SessionRPCEnd = types.Specifier{'E', 'n', 'd'}
+
+	// SessionRPCRead is the specifier of a SessionRequest that downloads
+	// sector data.
+	SessionRPCRead = types.Specifier{'R', 'e', 'a', 'd'}
+
+	// SessionRPCRenew is the specifier of a SessionRequest that renews the
+	// contract of the session. The session ends after the renewal.
+	SessionRPCRenew = types.Specifier{'R', 'e', 'n', 'e', 'w'}
+
+	// SessionRPCSettings is the specifier of a SessionRequest that requests
+	// the host's settings.
+	SessionRPCSettings = types.Specifier{'S', 'e', 't', 't', 'i', 'n', 'g', 's'}
+
+	// SessionRPCWrite is the specifier of a SessionRequest that revises the
+	// sectors of the contract.
+	SessionRPCWrite = types.Specifier{'W', 'r', 'i', 't', 'e'}
 )
 
 type (
@@ -260,6 +302,16 @@ type (
 		// which is the most recent.
 		RevisionNumber uint64 `json:"revisionnumber"`
 		Version        string `json:"version"`
+
+		// EncryptedTransport indicates that the host supports the encrypted
+		// transport, which renters can start with RPCEncryptedTransport.
+		// Hosts below v1.3.4 don't send this field.
+		EncryptedTransport bool `json:"encryptedtransport"`
+
+		// Sessions indicates that the host supports RPCSession. Renters
+		// use the older revision RPCs with hosts that don't. Hosts below
+		// v1.3.4 don't send this field.
+		Sessions bool `json:"sessions"`
 	}
 
 	// A RevisionAction is a description of an edit to be performed on a file
@@ -280,8 +332,144 @@ type (
 		Offset      uint64
 		Data        []byte
 	}
+
+	// A SessionRequest precedes every request that the renter makes during a
+	// session. The RPC determines the body that follows the request. The host
+	// answers every request with a SessionResponse with the same ID, followed
+	// by the body of the response if the request succeeded.
+	SessionRequest struct {
+		ID  uint64
+		RPC types.Specifier
+	}
+
+	// A SessionResponse is the host's answer to a SessionRequest. If Error is
+	// not empty, the request failed and the host ends the session.
+	SessionResponse struct {
+		ID    uint64
+		Error string
+	}
+
+	// A SessionReadRequest is the body of a SessionRPCRead request. It
+	// contains the sections that the renter wants to download, and the signed
+	// revision that pays for them. If MerkleProof is set, the offset and
+	// length of each section must be multiples of crypto.SegmentSize, and the
+	// host proves that each section is part of its sector.
+	SessionReadRequest struct {
+		Sections    []DownloadAction
+		MerkleProof bool
+		NewRevision types.FileContractRevision
+		Signature   types.TransactionSignature
+	}
+
+	// A SessionReadResponse contains the host's signature of the revision
+	// and the data of the requested sections. If Merkle proofs were
+	// requested, MerkleProofs contains the range proof of each section, as
+	// created by crypto.MerkleRangeProof.
+	SessionReadResponse struct {
+		Signature    types.TransactionSignature
+		Data         [][]byte
+		MerkleProofs [][]crypto.Hash
+	}
+
+	// A SessionWriteRequest is the body of a SessionRPCWrite request. It
+	// contains the modifications to the contract's sectors, and the signed
+	// revision that pays for them.
+	SessionWriteRequest struct {
+		Actions     []RevisionAction
+		NewRevision types.FileContractRevision
+		Signature   types.TransactionSignature
+	}
+
+	// A SessionWriteResponse contains the host's signature of the revision.
+	SessionWriteResponse struct {
+		Signature types.TransactionSignature
+	}
+
+	// A TransportHandshakeRequest starts the encrypted transport. It contains
+	// the renter's ephemeral X25519 public key.
+	TransportHandshakeRequest struct {
+		PublicKey [32]byte
+	}
+
+	// A TransportHandshakeResponse contains the host's ephemeral X25519
+	// public key, and the host's signature of both ephemeral keys, which
+	// proves to the renter that the key belongs to the host.
+	TransportHandshakeResponse struct {
+		PublicKey [32]byte
+		Signature crypto.Signature
+	}
 )
 
+// compatHostExternalSettings is a HostExternalSettings that can also be
+// decoded from the settings of hosts below v1.3.4, which end after the
+// Version field.
+type compatHostExternalSettings struct {
+	HostExternalSettings
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (hes *compatHostExternalSettings) UnmarshalSia(r io.Reader) error {
+	dec := encoding.NewDecoder(r)
+	err := dec.DecodeAll(
+		&hes.AcceptingContracts,
+		&hes.MaxDownloadBatchSize,
+		&hes.MaxDuration,
+		&hes.MaxReviseBatchSize,
+		&hes.NetAddress,
+		&hes.RemainingStorage,
+		&hes.SectorSize,
+		&hes.TotalStorage,
+		&hes.UnlockHash,
+		&hes.WindowSize,
+		&hes.Collateral,
+		&hes.MaxCollateral,
+		&hes.ContractPrice,
+		&hes.DownloadBandwidthPrice,
+		&hes.StoragePrice,
+		&hes.UploadBandwidthPrice,
+		&hes.RevisionNumber,
+		&hes.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	// COMPATv1.3.4 - hosts below v1.3.4 don't send EncryptedTransport and
+	// Sessions.
+	for _, field := range []struct {
+		name  string
+		value *bool
+	}{
+		{"EncryptedTransport", &hes.EncryptedTransport},
+		{"Sessions", &hes.Sessions},
+	} {
+		var b [1]byte
+		if _, err := io.ReadFull(r, b[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch b[0] {
+		case 0:
+			*field.value = false
+		case 1:
+			*field.value = true
+		default:
+			return errors.New("could not decode " + field.name + ": invalid boolean")
+		}
+	}
+	return nil
+}
+
+// ReadSignedHostSettings reads the host's settings from r (usually a
+// net.Conn) and verifies that they were signed by pk. The settings of hosts
+// below v1.3.4 are accepted as well.
+func ReadSignedHostSettings(r io.Reader, maxLen uint64, pk crypto.PublicKey) (HostExternalSettings, error) {
+	var hes compatHostExternalSettings
+	err := crypto.ReadSignedObject(r, &hes, maxLen, pk)
+	return hes.HostExternalSettings, err
+}
+
 // ReadNegotiationAcceptance reads an accept/reject response from r (usually a
 // net.Conn). If the response is not AcceptResponse, ReadNegotiationAcceptance
 // returns the response as an error. If the response is StopResponse,
@@ -2,9 +2,11 @@ package modules
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -125,3 +127,70 @@ func TestNegotiationResponses(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// truncatedSettings encodes settings the way older hosts do, without the
+// last n boolean fields.
+type truncatedSettings struct {
+	HostExternalSettings
+	n int
+}
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (ts truncatedSettings) MarshalSia(w io.Writer) error {
+	b := encoding.Marshal(ts.HostExternalSettings)
+	_, err := w.Write(b[:len(b)-ts.n])
+	return err
+}
+
+// TestReadSignedHostSettings checks that the settings of current hosts and
+// of hosts below v1.3.4 can be read.
+func TestReadSignedHostSettings(t *testing.T) {
+	sk, pk := crypto.GenerateKeyPair()
+	settings := HostExternalSettings{
+		NetAddress:         "foo.com:1234",
+		Collateral:         types.SiacoinPrecision,
+		Version:            "1.3.4",
+		EncryptedTransport: true,
+		Sessions:           true,
+	}
+
+	buf := new(bytes.Buffer)
+	if err := crypto.WriteSignedObject(buf, settings, sk); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSignedHostSettings(buf, NegotiateMaxHostExternalSettingsLen, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !read.EncryptedTransport || !read.Sessions || read.Version != settings.Version || read.NetAddress != settings.NetAddress || read.Collateral.Cmp(settings.Collateral) != 0 {
+		t.Fatal("settings were not read correctly:", read)
+	}
+
+	// Settings without the Sessions field.
+	buf.Reset()
+	if err := crypto.WriteSignedObject(buf, truncatedSettings{settings, 1}, sk); err != nil {
+		t.Fatal(err)
+	}
+	read, err = ReadSignedHostSettings(buf, NegotiateMaxHostExternalSettingsLen, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !read.EncryptedTransport || read.Sessions {
+		t.Fatal("settings without Sessions were not read correctly:", read)
+	}
+
+	// Settings of older hosts don't have the EncryptedTransport and Sessions
+	// fields.
+	settings.Version = "1.3.3"
+	buf.Reset()
+	if err := crypto.WriteSignedObject(buf, truncatedSettings{settings, 2}, sk); err != nil {
+		t.Fatal(err)
+	}
+	read, err = ReadSignedHostSettings(buf, NegotiateMaxHostExternalSettingsLen, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.EncryptedTransport || read.Sessions || read.Version != "1.3.3" || read.NetAddress != settings.NetAddress {
+		t.Fatal("old settings were not read correctly:", read)
+	}
+}
@@ -2,6 +2,7 @@ package modules
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"time"
 
@@ -47,6 +48,104 @@ type Allowance struct {
 	Hosts       uint64            `json:"hosts"`
 	Period      types.BlockHeight `json:"period"`
 	RenewWindow types.BlockHeight `json:"renewwindow"`
+
+	// Optional expected usage. If any of the volumes is set, the contractor
+	// funds each contract according to the prices of its host instead of
+	// splitting the funds evenly across all hosts. ExpectedRedundancy is the
+	// redundancy files are uploaded with, 0 means the default redundancy.
+	ExpectedStorage    uint64  `json:"expectedstorage"`  // bytes
+	ExpectedUpload     uint64  `json:"expectedupload"`   // bytes per period
+	ExpectedDownload   uint64  `json:"expecteddownload"` // bytes per period
+	ExpectedRedundancy float64 `json:"expectedredundancy"`
+
+	// Optional price caps. Hosts whose prices exceed a cap are not used for
+	// new contracts, and their existing contracts are not renewed. A zero
+	// value means that there is no cap.
+	MaxContractPrice          types.Currency `json:"maxcontractprice"`
+	MaxStoragePrice           types.Currency `json:"maxstorageprice"`           // per byte per block
+	MaxUploadBandwidthPrice   types.Currency `json:"maxuploadbandwidthprice"`   // per byte
+	MaxDownloadBandwidthPrice types.Currency `json:"maxdownloadbandwidthprice"` // per byte
+
+	// MaxCollateralRatio caps the ratio of a host's collateral to its storage
+	// price. The renter pays the siafund fee on the collateral, so excessive
+	// collateral makes contracts more expensive.
+	MaxCollateralRatio float64 `json:"maxcollateralratio"`
+}
+
+// PriceCapViolation returns a description of the first price cap of the
+// allowance that the host settings exceed, or the empty string if the host is
+// within all of the caps.
+func (a Allowance) PriceCapViolation(s HostExternalSettings) string {
+	exceeds := func(price, limit types.Currency) bool {
+		return !limit.IsZero() && price.Cmp(limit) > 0
+	}
+	switch {
+	case exceeds(s.ContractPrice, a.MaxContractPrice):
+		return "contract price exceeds the maximum contract price"
+	case exceeds(s.StoragePrice, a.MaxStoragePrice):
+		return "storage price exceeds the maximum storage price"
+	case exceeds(s.UploadBandwidthPrice, a.MaxUploadBandwidthPrice):
+		return "upload bandwidth price exceeds the maximum upload bandwidth price"
+	case exceeds(s.DownloadBandwidthPrice, a.MaxDownloadBandwidthPrice):
+		return "download bandwidth price exceeds the maximum download bandwidth price"
+	case a.MaxCollateralRatio > 0 && s.Collateral.Cmp(s.StoragePrice.MulFloat(a.MaxCollateralRatio)) > 0:
+		return "collateral exceeds the maximum collateral ratio"
+	}
+	return ""
+}
+
+// A PlannedContract is a contract that the contractor would form if the
+// allowance of an AllowancePlan was set. Funding is the total cost of the
+// contract, which includes the contract price, the transaction fee and the
+// siafund fee. Storage is the number of bytes that the remaining funds can
+// store on the host until the contract ends.
+type PlannedContract struct {
+	HostPublicKey types.SiaPublicKey `json:"hostpublickey"`
+	NetAddress    NetAddress         `json:"netaddress"`
+	Funding       types.Currency     `json:"funding"`
+	ContractPrice types.Currency     `json:"contractprice"`
+	TxnFee        types.Currency     `json:"txnfee"`
+	SiafundFee    types.Currency     `json:"siafundfee"`
+	StoragePrice  types.Currency     `json:"storageprice"`
+	Storage       uint64             `json:"storage"`
+}
+
+// An AllowancePlan describes the contracts that the contractor would form for
+// an allowance, based on the hosts currently in the hostdb. StorageCapacity is
+// the amount of file data that the planned contracts can store when files are
+// uploaded with the given redundancy. TotalFees is the sum of the contract
+// prices and transaction fees, the siafund fees are reported separately.
+// Hosts that were considered but not chosen are listed in RejectedHosts.
+type AllowancePlan struct {
+	Contracts       []PlannedContract `json:"contracts"`
+	RejectedHosts   []RejectedHost    `json:"rejectedhosts"`
+	EndHeight       types.BlockHeight `json:"endheight"`
+	Redundancy      float64           `json:"redundancy"`
+	StorageCapacity uint64            `json:"storagecapacity"`
+
+	TotalFunding    types.Currency `json:"totalfunding"`
+	TotalFees       types.Currency `json:"totalfees"`
+	TotalSiafundFee types.Currency `json:"totalsiafundfee"`
+	Unallocated     types.Currency `json:"unallocated"`
+}
+
+// A RejectedHost is a host that the contractor refused to form or renew a
+// contract with because its prices exceed the caps of the allowance.
+type RejectedHost struct {
+	PublicKey   types.SiaPublicKey `json:"publickey"`
+	NetAddress  NetAddress         `json:"netaddress"`
+	Reason      string             `json:"reason"`
+	BlockHeight types.BlockHeight  `json:"blockheight"`
+}
+
+// ContractRecoveryStatus reports the progress of a scan of the blockchain for
+// the contracts of the renter. Only contracts formed with a key derived from
+// the wallet seed can be recovered.
+type ContractRecoveryStatus struct {
+	ScanInProgress     bool              `json:"scaninprogress"`
+	ScannedHeight      types.BlockHeight `json:"scannedheight"`
+	ContractsFound     uint64            `json:"contractsfound"`
+	ContractsRecovered uint64            `json:"contractsrecovered"`
 }
 
 // ContractUtility contains metrics internal to the contractor that reflect the
@@ -116,15 +215,255 @@ type HostDBEntry struct {
 
 	LastHistoricUpdate types.BlockHeight
 
+	// IPNets contains the subnets of the addresses that the host's NetAddress
+	// resolves to. LastIPNetChange is the time at which they last changed.
+	// Hosts that share a subnet are not considered independent, so only one
+	// of them is used.
+	IPNets          []string  `json:"ipnets"`
+	LastIPNetChange time.Time `json:"lastipnetchange"`
+
+	// Benchmarks are the most recent benchmarks of the host, oldest first.
+	// Only hosts that the renter has contracts with are benchmarked, and only
+	// if the renter has opted in.
+	Benchmarks []HostBenchmark `json:"benchmarks"`
+
+	// SettingsHistory records the host's prices and storage every time they
+	// changed, oldest first. Changes older than the retention period of the
+	// hostdb are discarded, except for the most recent one.
+	SettingsHistory []HostSettingsChange `json:"settingshistory"`
+
 	// The public key of the host, stored separately to minimize risk of certain
 	// MitM based vulnerabilities.
 	PublicKey types.SiaPublicKey `json:"publickey"`
 }
 
+// FilterMode is the mode of the hostdb's host filter.
+type FilterMode int
+
+const (
+	// HostDBFilterDisabled means that no hosts are filtered.
+	HostDBFilterDisabled FilterMode = iota
+
+	// HostDBFilterBlacklist means that the listed hosts are never used.
+	HostDBFilterBlacklist
+
+	// HostDBFilterWhitelist means that only the listed hosts are used.
+	HostDBFilterWhitelist
+)
+
+// ErrInvalidFilterMode is returned when parsing an unknown filter mode.
+var ErrInvalidFilterMode = errors.New("filter mode must be 'disable', 'blacklist' or 'whitelist'")
+
+// String returns the name of the filter mode.
+func (fm FilterMode) String() string {
+	switch fm {
+	case HostDBFilterDisabled:
+		return "disable"
+	case HostDBFilterBlacklist:
+		return "blacklist"
+	case HostDBFilterWhitelist:
+		return "whitelist"
+	}
+	return "unknown"
+}
+
+// FromString sets the filter mode from its name.
+func (fm *FilterMode) FromString(s string) error {
+	switch s {
+	case "disable":
+		*fm = HostDBFilterDisabled
+	case "blacklist":
+		*fm = HostDBFilterBlacklist
+	case "whitelist":
+		*fm = HostDBFilterWhitelist
+	default:
+		return ErrInvalidFilterMode
+	}
+	return nil
+}
+
+// HostDBScanPolicy determines how the hostdb scans hosts.
+type HostDBScanPolicy struct {
+	// ScanningThreads is the maximum number of hosts that are scanned in
+	// parallel.
+	ScanningThreads int `json:"scanningthreads"`
+
+	// OnlineScanInterval and OfflineScanInterval are the minimum amounts of
+	// time between the scans of hosts that were online and offline at their
+	// last scan. The actual intervals are randomized up to 6 times as long.
+	OnlineScanInterval  time.Duration `json:"onlinescaninterval"`
+	OfflineScanInterval time.Duration `json:"offlinescaninterval"`
+
+	// ScanTimeout is how long a host has to accept the connection of a scan.
+	ScanTimeout time.Duration `json:"scantimeout"`
+}
+
+// HostDBImportReport summarizes the import of an exported hostdb.
+type HostDBImportReport struct {
+	// Added is the number of hosts that were not known before the import.
+	Added int `json:"added"`
+
+	// Merged is the number of known hosts whose history was merged with the
+	// imported history.
+	Merged int `json:"merged"`
+
+	// Skipped is the number of imported hosts that were invalid.
+	Skipped int `json:"skipped"`
+}
+
+// HostDBRescanProgress reports the progress of a full rescan of the hostdb.
+type HostDBRescanProgress struct {
+	// Active is true while hosts of the rescan remain to be scanned.
+	Active bool `json:"active"`
+
+	// StartTime is the time at which the most recent rescan was started.
+	StartTime time.Time `json:"starttime"`
+
+	// Total is the number of hosts of the rescan, Remaining the number of
+	// hosts that have not been scanned yet.
+	Total     int `json:"total"`
+	Remaining int `json:"remaining"`
+}
+
+// HostScoringProfile determines how the factors of a host's score are traded
+// off against each other. Each adjustment of the score is raised to the power
+// of its weight, so a weight of 1 keeps the default behavior, a weight above 1
+// makes the factor more important and a weight of 0 ignores the factor.
+// Adjustments that disqualify a host, such as exceeding the price caps of the
+// allowance, are never weighted.
+type HostScoringProfile struct {
+	// Name is the name of the preset that the profile is based on, or
+	// HostScoringProfileCustom if the weights were set individually.
+	Name string `json:"name"`
+
+	AgeWeight              float64 `json:"ageweight"`
+	BenchmarkWeight        float64 `json:"benchmarkweight"`
+	CollateralWeight       float64 `json:"collateralweight"`
+	InteractionWeight      float64 `json:"interactionweight"`
+	PriceWeight            float64 `json:"priceweight"`
+	StorageRemainingWeight float64 `json:"storageremainingweight"`
+	UptimeWeight           float64 `json:"uptimeweight"`
+	VersionWeight          float64 `json:"versionweight"`
+	VolatilityWeight       float64 `json:"volatilityweight"`
+}
+
+// HostScoringProfileCustom is the name of a scoring profile whose weights were
+// set individually.
+const HostScoringProfileCustom = "custom"
+
+var (
+	// DefaultHostScoringProfile weights all factors of a host's score
+	// equally.
+	DefaultHostScoringProfile = HostScoringProfile{
+		Name:                   "default",
+		AgeWeight:              1,
+		BenchmarkWeight:        1,
+		CollateralWeight:       1,
+		InteractionWeight:      1,
+		PriceWeight:            1,
+		StorageRemainingWeight: 1,
+		UptimeWeight:           1,
+		VersionWeight:          1,
+		VolatilityWeight:       1,
+	}
+
+	// HostScoringPresets are the scoring profiles that can be selected by
+	// name. "cheap" favors low prices for archival storage, "fast" favors
+	// hosts that respond consistently for streaming, and "reliable" favors
+	// established hosts with good uptime and collateral.
+	HostScoringPresets = []HostScoringProfile{
+		DefaultHostScoringProfile,
+		{
+			Name:                   "cheap",
+			AgeWeight:              0.5,
+			BenchmarkWeight:        0.5,
+			CollateralWeight:       0.5,
+			InteractionWeight:      1,
+			PriceWeight:            2,
+			StorageRemainingWeight: 1,
+			UptimeWeight:           0.75,
+			VersionWeight:          1,
+			VolatilityWeight:       1.5,
+		},
+		{
+			Name:                   "fast",
+			AgeWeight:              1,
+			BenchmarkWeight:        2,
+			CollateralWeight:       0.5,
+			InteractionWeight:      2,
+			PriceWeight:            0.5,
+			StorageRemainingWeight: 1,
+			UptimeWeight:           2,
+			VersionWeight:          1,
+			VolatilityWeight:       0.5,
+		},
+		{
+			Name:                   "reliable",
+			AgeWeight:              2,
+			BenchmarkWeight:        1,
+			CollateralWeight:       1.5,
+			InteractionWeight:      1.5,
+			PriceWeight:            0.75,
+			StorageRemainingWeight: 1.5,
+			UptimeWeight:           3,
+			VersionWeight:          1,
+			VolatilityWeight:       2,
+		},
+	}
+)
+
+// HostScoringPreset returns the preset scoring profile with the given name.
+func HostScoringPreset(name string) (HostScoringProfile, bool) {
+	for _, p := range HostScoringPresets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return HostScoringProfile{}, false
+}
+
+// HostBenchmark is a measurement of how quickly a host responds and how
+// quickly it transfers data.
+type HostBenchmark struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	// SettingsLatency is the time it took to request the host's settings,
+	// including dialing the host.
+	SettingsLatency time.Duration `json:"settingslatency"`
+
+	// DownloadThroughput is the speed in bytes per second at which a sector
+	// was downloaded from the host. It is zero if the renter had no data
+	// stored on the host.
+	DownloadThroughput float64 `json:"downloadthroughput"`
+
+	// Error is set if the benchmark failed.
+	Error string `json:"error,omitempty"`
+}
+
+// HostSettingsChange is a snapshot of the settings of a host that renters
+// care about most, taken when one of them changed.
+type HostSettingsChange struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	AcceptingContracts     bool           `json:"acceptingcontracts"`
+	Collateral             types.Currency `json:"collateral"`
+	ContractPrice          types.Currency `json:"contractprice"`
+	DownloadBandwidthPrice types.Currency `json:"downloadbandwidthprice"`
+	RemainingStorage       uint64         `json:"remainingstorage"`
+	StoragePrice           types.Currency `json:"storageprice"`
+	TotalStorage           uint64         `json:"totalstorage"`
+	UploadBandwidthPrice   types.Currency `json:"uploadbandwidthprice"`
+}
+
 // HostDBScan represents a single scan event.
 type HostDBScan struct {
 	Timestamp time.Time `json:"timestamp"`
 	Success   bool      `json:"success"`
+
+	// Origin is empty for scans performed by this renter. Scans that were
+	// imported from another renter's hostdb are marked with the origin that
+	// was provided when importing them.
+	Origin string `json:"origin,omitempty"`
 }
 
 // HostScoreBreakdown provides a piece-by-piece explanation of why a host has
@@ -139,6 +478,7 @@ type HostScoreBreakdown struct {
 	ConversionRate float64        `json:"conversionrate"`
 
 	AgeAdjustment              float64 `json:"ageadjustment"`
+	BenchmarkAdjustment        float64 `json:"benchmarkadjustment"`
 	BurnAdjustment             float64 `json:"burnadjustment"`
 	CollateralAdjustment       float64 `json:"collateraladjustment"`
 	InteractionAdjustment      float64 `json:"interactionadjustment"`
@@ -146,6 +486,66 @@ type HostScoreBreakdown struct {
 	StorageRemainingAdjustment float64 `json:"storageremainingadjustment"`
 	UptimeAdjustment           float64 `json:"uptimeadjustment"`
 	VersionAdjustment          float64 `json:"versionadjustment"`
+	VolatilityAdjustment       float64 `json:"volatilityadjustment"`
+}
+
+// RenterDownloadEstimate is a dry-run estimate of what it would cost, and how
+// long it would take, to download a set of files.
+type RenterDownloadEstimate struct {
+	// The files that the estimate covers and their combined size.
+	Files []string `json:"files"`
+	Bytes uint64   `json:"bytes"`
+
+	// BandwidthCost is the total download bandwidth cost, using the cheapest
+	// hosts that hold enough pieces of each chunk.
+	BandwidthCost types.Currency `json:"bandwidthcost"`
+
+	// FundsSufficient indicates whether the renter funds remaining in the
+	// contracts used for the download cover the cost of the download.
+	// InsufficientContracts lists the contracts that would run dry.
+	FundsSufficient       bool                   `json:"fundssufficient"`
+	InsufficientContracts []types.FileContractID `json:"insufficientcontracts"`
+
+	// UnavailableChunks is the number of chunks that do not have enough
+	// pieces on active contracts to be recovered.
+	UnavailableChunks uint64 `json:"unavailablechunks"`
+
+	// EstimatedDuration is the expected duration of the download based on the
+	// throughput observed from the workers. It is zero if no throughput has
+	// been observed yet.
+	EstimatedDuration time.Duration `json:"estimatedduration"`
+}
+
+// MemoryStatus reports the state of the renter's memory manager.
+type MemoryStatus struct {
+	// Base is the total amount of memory that the renter may use, InUse is
+	// the amount of memory currently in use.
+	Base  uint64 `json:"base"`
+	InUse uint64 `json:"inuse"`
+
+	// WaitingRequests is the number of requests waiting for memory.
+	// AverageWait is the average time spent waiting by the requests that
+	// could not be granted immediately.
+	WaitingRequests int           `json:"waitingrequests"`
+	AverageWait     time.Duration `json:"averagewait"`
+
+	// The status of the memory used by uploads, downloads and streams.
+	Upload   MemoryClassStatus `json:"upload"`
+	Download MemoryClassStatus `json:"download"`
+	Stream   MemoryClassStatus `json:"stream"`
+}
+
+// MemoryClassStatus reports the memory used by one kind of renter operation.
+type MemoryClassStatus struct {
+	// Reserve is the amount of memory that only this kind of operation may
+	// use. InUse is the amount of memory currently in use.
+	Reserve uint64 `json:"reserve"`
+	InUse   uint64 `json:"inuse"`
+
+	// WaitingRequests is the number of requests waiting for memory and
+	// LongestWait is how long the oldest of them has been waiting.
+	WaitingRequests int           `json:"waitingrequests"`
+	LongestWait     time.Duration `json:"longestwait"`
 }
 
 // RenterPriceEstimation contains a bunch of files estimating the costs of
@@ -171,6 +571,156 @@ type RenterSettings struct {
 	MaxUploadSpeed   int64     `json:"maxuploadspeed"`
 	MaxDownloadSpeed int64     `json:"maxdownloadspeed"`
 	StreamCacheSize  uint64    `json:"streamcachesize"`
+
+	// MaxMemory is the amount of memory in bytes that the renter may use for
+	// buffering uploads and downloads. Setting it to zero resets it to the
+	// default.
+	MaxMemory uint64 `json:"maxmemory"`
+
+	// The amount of memory in bytes out of MaxMemory that is reserved for
+	// uploads, downloads and streaming respectively. Memory reserved for one
+	// kind of operation cannot be used by the others.
+	UploadMemoryReserve   uint64 `json:"uploadmemoryreserve"`
+	DownloadMemoryReserve uint64 `json:"downloadmemoryreserve"`
+	StreamMemoryReserve   uint64 `json:"streammemoryreserve"`
+
+	// RateLimitSchedule is a weekly schedule of bandwidth limits. While a
+	// window is active, its limits replace MaxUploadSpeed and
+	// MaxDownloadSpeed.
+	RateLimitSchedule []RateLimitWindow `json:"ratelimitschedule"`
+
+	// BenchmarkHosts enables the periodic benchmarking of the hosts that the
+	// renter has contracts with. Benchmarks download data from the hosts, so
+	// they are paid for out of the allowance.
+	BenchmarkHosts bool `json:"benchmarkhosts"`
+}
+
+// RateLimitWindow is a recurring window of time during which the renter
+// applies different bandwidth limits. Times are in minutes after midnight,
+// local time. If multiple windows overlap, the first one in the schedule wins.
+type RateLimitWindow struct {
+	// Days are the days of the week on which the window starts. If empty,
+	// the window applies every day.
+	Days []time.Weekday `json:"days"`
+
+	// Start and End are the minutes after midnight at which the window
+	// begins and ends. Start is inclusive, End is exclusive. If End is before
+	// Start, the window wraps around midnight and ends on the following day.
+	Start uint16 `json:"start"`
+	End   uint16 `json:"end"`
+
+	// The bandwidth limits in bytes per second while the window is active.
+	// Zero means unlimited.
+	MaxDownloadSpeed int64 `json:"maxdownloadspeed"`
+	MaxUploadSpeed   int64 `json:"maxuploadspeed"`
+}
+
+// RenterRateLimitStatus reports the bandwidth limits currently applied by the
+// renter.
+type RenterRateLimitStatus struct {
+	// ActiveWindow is the index in the schedule of the window that is
+	// currently active, or -1 if the default limits apply.
+	ActiveWindow int `json:"activewindow"`
+
+	// The bandwidth limits currently in effect.
+	MaxDownloadSpeed int64 `json:"maxdownloadspeed"`
+	MaxUploadSpeed   int64 `json:"maxuploadspeed"`
+
+	// Schedule is the full rate limit schedule.
+	Schedule []RateLimitWindow `json:"schedule"`
+}
+
+// Actions that can be taken by a renter sync job.
+const (
+	// SyncActionUpload uploads a local file that has no remote counterpart.
+	SyncActionUpload = "upload"
+
+	// SyncActionUpdate replaces a remote file whose local counterpart has
+	// changed.
+	SyncActionUpdate = "update"
+
+	// SyncActionDelete deletes a remote file whose local counterpart has been
+	// removed.
+	SyncActionDelete = "delete"
+)
+
+// RenterSyncParams describes a local directory that is mirrored to a siapath
+// prefix.
+type RenterSyncParams struct {
+	// Source is the absolute path of the local directory.
+	Source string `json:"source"`
+
+	// SiaPath is the prefix under which the files of the directory are
+	// uploaded.
+	SiaPath string `json:"siapath"`
+
+	// Delete indicates that remote files which no longer exist locally should
+	// be deleted.
+	Delete bool `json:"delete"`
+
+	// DryRun indicates that the actions should only be reported, not taken.
+	DryRun bool `json:"dryrun"`
+
+	// Interval is the time between runs of the job. Zero means that the
+	// directory is only synced once.
+	Interval time.Duration `json:"interval"`
+}
+
+// RenterSyncAction is a single action taken, or planned, by a sync job.
+type RenterSyncAction struct {
+	Action  string `json:"action"`
+	SiaPath string `json:"siapath"`
+	Source  string `json:"source"`
+
+	// Reason explains why the action was chosen, e.g. "new", "size",
+	// "hash" or "missing".
+	Reason string `json:"reason"`
+
+	// Error is set if the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// RenterSyncReport describes a single run of a sync job. While the sync is
+// running, End is zero and Completed is the number of actions that have been
+// taken so far.
+type RenterSyncReport struct {
+	Source    string             `json:"source"`
+	SiaPath   string             `json:"siapath"`
+	DryRun    bool               `json:"dryrun"`
+	Start     time.Time          `json:"start"`
+	End       time.Time          `json:"end"`
+	Actions   []RenterSyncAction `json:"actions"`
+	Completed uint64             `json:"completed"`
+	Unchanged uint64             `json:"unchanged"`
+	Failed    uint64             `json:"failed"`
+
+	// Error is set if the sync as a whole failed.
+	Error string `json:"error,omitempty"`
+}
+
+// RenterSyncJob is a sync job along with the report of its current or most
+// recent run.
+type RenterSyncJob struct {
+	RenterSyncParams
+	Active     bool             `json:"active"`
+	LastReport RenterSyncReport `json:"lastreport"`
+}
+
+// A RenterSnapshot is an encrypted snapshot of the renter's file metadata and
+// contract headers that is stored on hosts. Snapshots are encrypted with a key
+// derived from the wallet seed, so that they can be restored into a new node.
+type RenterSnapshot struct {
+	// ID is the hash of the encrypted snapshot.
+	ID           crypto.Hash `json:"id"`
+	CreationTime time.Time   `json:"creationtime"`
+
+	// Size is the size of the encrypted snapshot in bytes.
+	Size         uint64 `json:"size"`
+	NumFiles     uint64 `json:"numfiles"`
+	NumContracts uint64 `json:"numcontracts"`
+
+	// Hosts are the hosts that store the snapshot.
+	Hosts []types.SiaPublicKey `json:"hosts"`
 }
 
 // HostDBScans represents a sortable slice of scans.
@@ -286,9 +836,29 @@ type ContractorSpending struct {
 	ContractSpendingDeprecated types.Currency `json:"contractspending"`
 }
 
+// ContractorPeriodSpending contains the spending of the Contractor during a
+// billing period, along with the money that was refunded by contracts that
+// expired during the period.
+type ContractorPeriodSpending struct {
+	ContractorSpending
+
+	// StartHeight and EndHeight are the block heights at which the period
+	// began and ended. The EndHeight of the current period is the height at
+	// which it is expected to end.
+	StartHeight types.BlockHeight `json:"startheight"`
+	EndHeight   types.BlockHeight `json:"endheight"`
+
+	// Refunded is the money returned to the renter by contracts that expired
+	// during the period.
+	Refunded types.Currency `json:"refunded"`
+}
+
 // A Renter uploads, tracks, repairs, and downloads a set of files for the
 // user.
 type Renter interface {
+	// Alerter reports the alerts of the renter and its contractor.
+	Alerter
+
 	// ActiveHosts provides the list of hosts that the renter is selecting,
 	// sorted by preference.
 	ActiveHosts() []HostDBEntry
@@ -299,12 +869,23 @@ type Renter interface {
 	// Close closes the Renter.
 	Close() error
 
+	// CancelContract marks the contract as canceled, so that it is neither
+	// used for uploads nor renewed.
+	CancelContract(id types.FileContractID) error
+
 	// Contracts returns the contracts formed by the renter.
 	Contracts() []RenterContract
 
+	// ContractRecoveryStatus returns the progress of the most recent scan of
+	// the blockchain for the renter's contracts.
+	ContractRecoveryStatus() ContractRecoveryStatus
+
 	// ContractUtility provides the contract utility for a given id
 	ContractUtility(id types.FileContractID) (ContractUtility, bool)
 
+	// CreateSnapshot uploads a snapshot of the renter's metadata to hosts.
+	CreateSnapshot() (RenterSnapshot, error)
+
 	// CurrentPeriod returns the height at which the current allowance period
 	// began.
 	CurrentPeriod() types.BlockHeight
@@ -313,6 +894,10 @@ type Renter interface {
 	// billing period.
 	PeriodSpending() ContractorSpending
 
+	// SpendingHistory returns the spending of every finished billing period,
+	// followed by the spending of the current period.
+	SpendingHistory() []ContractorPeriodSpending
+
 	// DeleteFile deletes a file entry from the renter.
 	DeleteFile(path string) error
 
@@ -327,15 +912,38 @@ type Renter interface {
 	// DownloadHistory lists all the files that have been scheduled for download.
 	DownloadHistory() []DownloadInfo
 
+	// EstimateDownload returns the expected cost and duration of downloading
+	// every file whose siapath begins with the provided prefix.
+	EstimateDownload(siaPathPrefix string) (RenterDownloadEstimate, error)
+
+	// ExportHostDB writes the hostdb's entries, including their scan
+	// history and interactions, to a file.
+	ExportHostDB(dst string) error
+
 	// File returns information on specific file queried by user
 	File(siaPath string) (FileInfo, error)
 
+	// FilterMode returns the hostdb's filter mode and the hosts it applies
+	// to.
+	FilterMode() (FilterMode, []types.SiaPublicKey)
+
 	// FileList returns information on all of the files stored by the renter.
 	FileList() []FileInfo
 
+	// FormContract forms a contract with the host, funded with the specified
+	// amount. If funding is zero, the default funding is used.
+	FormContract(pk types.SiaPublicKey, funding types.Currency) (RenterContract, error)
+
 	// Host provides the DB entry and score breakdown for the requested host.
 	Host(pk types.SiaPublicKey) (HostDBEntry, bool)
 
+	// ImportHostDB merges the entries of an exported hostdb into the hostdb.
+	// Imported scans are marked with the provided origin.
+	ImportHostDB(src, origin string) (HostDBImportReport, error)
+
+	// MemoryStatus returns the current state of the renter's memory manager.
+	MemoryStatus() MemoryStatus
+
 	// LoadSharedFiles loads a '.sia' file into the renter. A .sia file may
 	// contain multiple files. The paths of the added files are returned.
 	LoadSharedFiles(source string) ([]string, error)
@@ -344,38 +952,108 @@ type Renter interface {
 	// renter.
 	LoadSharedFilesASCII(asciiSia string) ([]string, error)
 
+	// PlanAllowance simulates the contract formation for the allowance
+	// without forming any contracts or spending any money.
+	PlanAllowance(a Allowance) (AllowancePlan, error)
+
 	// PriceEstimation estimates the cost in siacoins of performing various
 	// storage and data operations.
 	PriceEstimation() RenterPriceEstimation
 
+	// RateLimitStatus returns the bandwidth limits currently in effect and the
+	// schedule window they come from.
+	RateLimitStatus() RenterRateLimitStatus
+
+	// RejectedHosts returns the hosts that were rejected because their prices
+	// exceed the caps of the allowance.
+	RejectedHosts() []RejectedHost
+
+	// RecoverContracts starts a scan of the blockchain for the renter's
+	// contracts. Contracts that are not known to the renter are recovered
+	// from their hosts.
+	RecoverContracts() error
+
 	// RenameFile changes the path of a file.
 	RenameFile(path, newPath string) error
 
+	// RescanHosts queues a scan of every host in the hostdb.
+	RescanHosts() error
+
+	// RescanProgress returns the progress of the most recent full rescan of
+	// the hostdb.
+	RescanProgress() HostDBRescanProgress
+
+	// RenewContract renews the contract immediately, funded with the
+	// specified amount. If funding is zero, the default funding is used.
+	RenewContract(id types.FileContractID, funding types.Currency) (RenterContract, error)
+
+	// RestoreSnapshot restores the files and contracts of a snapshot. If the
+	// ID is empty, the most recent snapshot is restored.
+	RestoreSnapshot(id crypto.Hash) error
+
 	// EstimateHostScore will return the score for a host with the provided
 	// settings, assuming perfect age and uptime adjustments
 	EstimateHostScore(entry HostDBEntry) HostScoreBreakdown
 
+	// ScanHost scans the host immediately and returns its updated entry. The
+	// error is the reason the scan failed, if it did.
+	ScanHost(pk types.SiaPublicKey) (HostDBEntry, error)
+
+	// ScanPolicy returns the policy that the hostdb uses to scan hosts.
+	ScanPolicy() HostDBScanPolicy
+
 	// ScoreBreakdown will return the score for a host db entry using the
 	// hostdb's weighting algorithm.
 	ScoreBreakdown(entry HostDBEntry) HostScoreBreakdown
 
+	// ScoringProfile returns the profile that the hostdb uses to score
+	// hosts.
+	ScoringProfile() HostScoringProfile
+
 	// Settings returns the Renter's current settings.
 	Settings() RenterSettings
 
 	// SetSettings sets the Renter's settings.
 	SetSettings(RenterSettings) error
 
+	// SetFilterMode sets the hostdb's filter mode. In blacklist mode the
+	// provided hosts are never used, in whitelist mode only the provided
+	// hosts are used.
+	SetFilterMode(fm FilterMode, hosts []types.SiaPublicKey) error
+
+	// SetScanPolicy sets the policy that the hostdb uses to scan hosts.
+	SetScanPolicy(HostDBScanPolicy) error
+
+	// SetScoringProfile sets the profile that the hostdb uses to score
+	// hosts, and recalculates the scores of all hosts.
+	SetScoringProfile(HostScoringProfile) error
+
 	// ShareFiles creates a '.sia' file that can be shared with others.
 	ShareFiles(paths []string, shareDest string) error
 
 	// ShareFilesAscii creates an ASCII-encoded '.sia' file.
 	ShareFilesASCII(paths []string) (asciiSia string, err error)
 
+	// Snapshots returns the snapshots of the renter's metadata, oldest
+	// first.
+	Snapshots() []RenterSnapshot
+
 	// Streamer creates a io.ReadSeeker that can be used to stream downloads
 	// from the Sia network and also returns the fileName of the streamed
 	// resource.
 	Streamer(siaPath string) (string, io.ReadSeeker, error)
 
+	// Sync starts mirroring a local directory to a siapath prefix in the
+	// background. If an interval is provided, the directory is synced again
+	// on that interval until the job is cancelled.
+	Sync(params RenterSyncParams) error
+
+	// SyncJobs returns the sync jobs along with their progress.
+	SyncJobs() []RenterSyncJob
+
+	// CancelSync stops the sync job for a siapath prefix.
+	CancelSync(siaPath string) error
+
 	// Upload uploads a file using the input parameters.
 	Upload(FileUploadParams) error
 }
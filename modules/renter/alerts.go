@@ -0,0 +1,80 @@
+package renter
+
+// alerts.go raises alerts about files whose redundancy has dropped, and
+// combines them with the alerts of the contractor. Alerts are updated every
+// time the repair loop checks the health of the files.
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	// alertIDLowRedundancy is the ID of the alert that is raised when files
+	// have a redundancy below alertRedundancyThreshold.
+	alertIDLowRedundancy = modules.AlertID("renter-lowredundancy")
+
+	// alertIDUnrecoverable is the ID of the alert that is raised when files
+	// have a redundancy below 1.
+	alertIDUnrecoverable = modules.AlertID("renter-unrecoverable")
+)
+
+// Alerts returns the active alerts of the renter and its contractor, oldest
+// first.
+func (r *Renter) Alerts() []modules.Alert {
+	alerts := append(r.staticAlerter.Alerts(), r.hostContractor.Alerts()...)
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Time.Before(alerts[j].Time)
+	})
+	return alerts
+}
+
+// AcknowledgeAlert acknowledges an active alert of the renter or its
+// contractor. The acknowledgement is persisted.
+func (r *Renter) AcknowledgeAlert(id modules.AlertID) error {
+	err := r.staticAlerter.AcknowledgeAlert(id)
+	if err == modules.ErrUnknownAlert {
+		return r.hostContractor.AcknowledgeAlert(id)
+	} else if err != nil {
+		return err
+	}
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	return r.saveSync()
+}
+
+// managedUpdateRedundancyAlerts raises or resolves the alerts about files
+// whose redundancy has dropped. Files that are still being uploaded are
+// ignored.
+func (r *Renter) managedUpdateRedundancyAlerts() {
+	var low, unrecoverable []string
+	for _, fi := range r.FileList() {
+		if fi.UploadProgress < 100 || fi.Redundancy < 0 {
+			continue
+		}
+		if fi.Redundancy < 1 {
+			unrecoverable = append(unrecoverable, fi.SiaPath)
+		} else if fi.Redundancy < alertRedundancyThreshold {
+			low = append(low, fi.SiaPath)
+		}
+	}
+
+	sort.Strings(unrecoverable)
+	sort.Strings(low)
+	if len(unrecoverable) == 0 {
+		r.staticAlerter.UnregisterAlert(alertIDUnrecoverable)
+	} else {
+		r.staticAlerter.RegisterAlert(alertIDUnrecoverable,
+			fmt.Sprintf("%v files have a redundancy below 1 and cannot be recovered, including %v", len(unrecoverable), unrecoverable[0]),
+			"", modules.SeverityCritical)
+	}
+	if len(low) == 0 {
+		r.staticAlerter.UnregisterAlert(alertIDLowRedundancy)
+	} else {
+		r.staticAlerter.RegisterAlert(alertIDLowRedundancy,
+			fmt.Sprintf("%v files have a redundancy below %v, including %v", len(low), alertRedundancyThreshold, low[0]),
+			"", modules.SeverityWarning)
+	}
+}
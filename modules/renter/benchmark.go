@@ -0,0 +1,129 @@
+package renter
+
+// benchmark.go periodically benchmarks the hosts that the renter has
+// contracts with. A benchmark measures how long it takes to request the
+// settings of a host and, if the renter stores data on the host, how quickly
+// a sector can be downloaded. The benchmarks are recorded in the hostdb,
+// which uses them to score the hosts.
+//
+// Downloading a sector costs money, so benchmarks are only run if enabled in
+// the renter's settings.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// threadedBenchmarkLoop benchmarks the contracted hosts every
+// benchmarkInterval while benchmarking is enabled.
+func (r *Renter) threadedBenchmarkLoop() {
+	err := r.tg.Add()
+	if err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	for {
+		select {
+		case <-r.tg.StopChan():
+			return
+		case <-time.After(benchmarkInterval):
+		}
+
+		id := r.mu.RLock()
+		enabled := r.benchmarkHosts
+		r.mu.RUnlock(id)
+		if !enabled {
+			continue
+		}
+		r.managedBenchmarkHosts()
+	}
+}
+
+// managedBenchmarkHosts benchmarks every host that the renter has a contract
+// with that is good for renew.
+func (r *Renter) managedBenchmarkHosts() {
+	roots := r.managedBenchmarkRoots()
+	benchmarked := make(map[string]struct{})
+	for _, c := range r.hostContractor.Contracts() {
+		if !c.Utility.GoodForRenew || r.hostContractor.IsOffline(c.ID) {
+			continue
+		}
+		if _, exists := benchmarked[c.HostPublicKey.String()]; exists {
+			continue
+		}
+		benchmarked[c.HostPublicKey.String()] = struct{}{}
+
+		root, hasRoot := roots[c.ID]
+		b := r.managedBenchmarkHost(c, root, hasRoot)
+		if b.Error != "" {
+			r.log.Debugln("Benchmark of host", c.HostPublicKey, "failed:", b.Error)
+		}
+		if err := r.hostDB.RecordBenchmark(c.HostPublicKey, b); err != nil {
+			r.log.Debugln("Unable to record benchmark of host", c.HostPublicKey, err)
+		}
+
+		select {
+		case <-r.tg.StopChan():
+			return
+		default:
+		}
+	}
+}
+
+// managedBenchmarkRoots returns the Merkle root of a sector stored on every
+// contract that the renter has uploaded file data to.
+func (r *Renter) managedBenchmarkRoots() map[types.FileContractID]crypto.Hash {
+	id := r.mu.RLock()
+	files := make([]*file, 0, len(r.files))
+	for _, f := range r.files {
+		files = append(files, f)
+	}
+	r.mu.RUnlock(id)
+
+	roots := make(map[types.FileContractID]crypto.Hash)
+	for _, f := range files {
+		f.mu.RLock()
+		for _, fc := range f.contracts {
+			if len(fc.Pieces) == 0 {
+				continue
+			}
+			roots[r.hostContractor.ResolveID(fc.ID)] = fc.Pieces[0].MerkleRoot
+		}
+		f.mu.RUnlock()
+	}
+	return roots
+}
+
+// managedBenchmarkHost benchmarks the host of a contract. The sector with the
+// provided root is downloaded to measure the throughput of the host, unless
+// hasRoot is false.
+func (r *Renter) managedBenchmarkHost(c modules.RenterContract, root crypto.Hash, hasRoot bool) modules.HostBenchmark {
+	b := modules.HostBenchmark{Timestamp: time.Now()}
+	latency, err := r.hostDB.BenchmarkSettings(c.HostPublicKey)
+	b.SettingsLatency = latency
+	if err != nil {
+		b.Error = err.Error()
+		return b
+	}
+	if !hasRoot {
+		return b
+	}
+
+	d, err := r.hostContractor.Downloader(c.ID, r.tg.StopChan())
+	if err != nil {
+		b.Error = err.Error()
+		return b
+	}
+	defer d.Close()
+	start := time.Now()
+	if _, err := d.Sector(root); err != nil {
+		b.Error = err.Error()
+		return b
+	}
+	b.DownloadThroughput = float64(modules.SectorSize) / time.Since(start).Seconds()
+	return b
+}
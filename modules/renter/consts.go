@@ -1,6 +1,7 @@
 package renter
 
 import (
+	"runtime"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -50,6 +51,20 @@ var (
 
 	// Erasure-coded piece size
 	pieceSize = modules.SectorSize - crypto.TwofishOverhead
+
+	// uploadEncodeThreads is the number of threads that erasure code and
+	// encrypt upload chunks in parallel. Chunks are read ahead of these threads
+	// for as long as the memory manager has memory available.
+	uploadEncodeThreads = runtime.NumCPU()
+
+	// maxUploadBatchPieces is the maximum number of queued pieces that a
+	// worker uploads to its host at once. The pieces are sent in as few
+	// contract revisions as the host allows.
+	maxUploadBatchPieces = build.Select(build.Var{
+		Dev:      4,
+		Standard: 4,
+		Testing:  2,
+	}).(int)
 )
 
 const (
@@ -61,12 +76,6 @@ const (
 	// worker has experienced a download failure.
 	downloadFailureCooldown = time.Second * 3
 
-	// memoryPriorityLow is used to request low priority memory
-	memoryPriorityLow = false
-
-	// memoryPriorityHigh is used to request high priority memory
-	memoryPriorityHigh = true
-
 	// destinationTypeSeekStream is the destination type used for downloads
 	// from the /renter/stream endpoint.
 	destinationTypeSeekStream = "httpseekstream"
@@ -74,9 +83,22 @@ const (
 	// defaultStreamCacheSize is the default cache size of the /renter/stream cache in
 	// chunks, the user can set a custom cache size through the API
 	defaultStreamCacheSize = 2
+
+	// syncTempDir is the siapath under which sync jobs upload the new versions
+	// of changed files before they replace the old versions.
+	syncTempDir = ".sync"
 )
 
 var (
+	// alertRedundancyThreshold is the redundancy below which the renter
+	// raises an alert about an uploaded file. Files below a redundancy of 1
+	// cannot be recovered.
+	alertRedundancyThreshold = build.Select(build.Var{
+		Dev:      float64(1),
+		Standard: float64(1.5),
+		Testing:  float64(1),
+	}).(float64)
+
 	// chunkDownloadTimeout defines the maximum amount of time to wait for a
 	// chunk download to finish before returning in the download-to-upload repair
 	// loop
@@ -111,6 +133,39 @@ var (
 		Testing:  250 * time.Millisecond,
 	}).(time.Duration)
 
+	// rateLimitScheduleInterval is how often the renter checks whether a new
+	// rate limit window has become active.
+	rateLimitScheduleInterval = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// minSyncInterval is the shortest interval allowed between runs of a
+	// recurring sync job.
+	minSyncInterval = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: time.Minute,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// syncUpdateCheckInterval is how often a sync job checks whether the new
+	// version of a changed file has become available.
+	syncUpdateCheckInterval = build.Select(build.Var{
+		Dev:      5 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  100 * time.Millisecond,
+	}).(time.Duration)
+
+	// syncUpdateTimeout is how long a sync job waits for the new version of a
+	// changed file to become available before giving up on the update and
+	// keeping the old version.
+	syncUpdateTimeout = build.Select(build.Var{
+		Dev:      time.Hour,
+		Standard: 24 * time.Hour,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+
 	// rebuildChunkHeapInterval defines how long the renter sleeps between
 	// checking on the filesystem health.
 	rebuildChunkHeapInterval = build.Select(build.Var{
@@ -127,6 +182,39 @@ var (
 		Testing:  0.25,
 	}).(float64)
 
+	// snapshotHosts is the number of hosts that a snapshot of the renter's
+	// metadata is uploaded to.
+	snapshotHosts = build.Select(build.Var{
+		Dev:      2,
+		Standard: 10,
+		Testing:  2,
+	}).(int)
+
+	// snapshotsKept is the number of snapshots of the renter's metadata that
+	// are kept on hosts. Once there are more, the sectors of the oldest
+	// snapshot are overwritten by the next snapshot.
+	snapshotsKept = build.Select(build.Var{
+		Dev:      3,
+		Standard: 7,
+		Testing:  2,
+	}).(int)
+
+	// benchmarkInterval is the amount of time between benchmarks of the hosts
+	// that the renter has contracts with.
+	benchmarkInterval = build.Select(build.Var{
+		Dev:      5 * time.Minute,
+		Standard: 6 * time.Hour,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// snapshotInterval is the amount of time between the automatic snapshots
+	// of the renter's metadata.
+	snapshotInterval = build.Select(build.Var{
+		Dev:      10 * time.Minute,
+		Standard: 24 * time.Hour,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+
 	// Prime to avoid intersecting with regular events.
 	uploadFailureCooldown = build.Select(build.Var{
 		Dev:      time.Second * 7,
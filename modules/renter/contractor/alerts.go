@@ -0,0 +1,111 @@
+package contractor
+
+// alerts.go raises alerts about the budget and the health of the contracts,
+// which would otherwise only show up in the log. The alerts are updated during
+// contract maintenance, and their acknowledgements are persisted with the
+// contractor.
+
+import (
+	"fmt"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+const (
+	// alertIDGoodForUpload is the ID of the alert that is raised when fewer
+	// contracts are good for upload than the allowance asks for.
+	alertIDGoodForUpload = modules.AlertID("contractor-goodforupload")
+
+	// alertIDSpending is the ID of the alert that is raised when most of the
+	// allowance has been allocated to contracts.
+	alertIDSpending = modules.AlertID("contractor-spending")
+
+	// alertIDWalletLocked is the ID of the alert that is raised when contracts
+	// need to be renewed while the wallet is locked.
+	alertIDWalletLocked = modules.AlertID("wallet-locked-renewal")
+)
+
+// alertIDRenewFailed returns the ID of the alert that is raised when the
+// contract cannot be renewed.
+func alertIDRenewFailed(id types.FileContractID) modules.AlertID {
+	return modules.AlertID("contractor-renew-" + id.String())
+}
+
+// Alerts returns the active alerts of the contractor, oldest first.
+func (c *Contractor) Alerts() []modules.Alert {
+	return c.staticAlerter.Alerts()
+}
+
+// AcknowledgeAlert acknowledges the active alert and persists the
+// acknowledgement.
+func (c *Contractor) AcknowledgeAlert(id modules.AlertID) error {
+	if err := c.staticAlerter.AcknowledgeAlert(id); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveSync()
+}
+
+// managedUpdateAlerts raises or resolves the alerts about the spending of the
+// allowance and the number of contracts that are good for upload.
+func (c *Contractor) managedUpdateAlerts() {
+	c.mu.RLock()
+	allowance := c.allowance
+	spending := c.readlockPeriodSpending()
+	c.mu.RUnlock()
+	if allowance.Hosts == 0 || allowance.Funds.IsZero() {
+		c.staticAlerter.UnregisterAlert(alertIDSpending)
+		c.staticAlerter.UnregisterAlert(alertIDGoodForUpload)
+		return
+	}
+
+	// Check how much of the allowance has been allocated. Once all of it is
+	// allocated, contracts that run out of money can no longer be refreshed.
+	threshold := allowance.Funds.MulFloat(alertSpendingThreshold)
+	if spending.TotalAllocated.Cmp(threshold) < 0 {
+		c.staticAlerter.UnregisterAlert(alertIDSpending)
+	} else {
+		severity := modules.SeverityWarning
+		if spending.TotalAllocated.Cmp(allowance.Funds) >= 0 {
+			severity = modules.SeverityError
+		}
+		c.staticAlerter.RegisterAlert(alertIDSpending,
+			fmt.Sprintf("%v of the allowance of %v has been allocated to contracts", spending.TotalAllocated.HumanString(), allowance.Funds.HumanString()),
+			"", severity)
+	}
+
+	// Check that there are enough contracts to upload to.
+	var uploadContracts uint64
+	for _, id := range c.staticContracts.IDs() {
+		if cu, ok := c.managedContractUtility(id); ok && cu.GoodForUpload {
+			uploadContracts++
+		}
+	}
+	if uploadContracts >= allowance.Hosts {
+		c.staticAlerter.UnregisterAlert(alertIDGoodForUpload)
+	} else {
+		c.staticAlerter.RegisterAlert(alertIDGoodForUpload,
+			fmt.Sprintf("only %v of %v contracts are good for upload", uploadContracts, allowance.Hosts),
+			"", modules.SeverityWarning)
+	}
+}
+
+// managedUpdateWalletAlert raises an alert if contracts need to be renewed
+// while the wallet is locked, since they cannot be renewed until the wallet is
+// unlocked.
+func (c *Contractor) managedUpdateWalletAlert(renewals int) {
+	unlocked, err := c.wallet.Unlocked()
+	if renewals == 0 || (unlocked && err == nil) {
+		c.staticAlerter.UnregisterAlert(alertIDWalletLocked)
+		return
+	}
+	cause := modules.ErrLockedWallet.Error()
+	if err != nil {
+		cause = err.Error()
+	}
+	c.staticAlerter.RegisterModuleAlert(alertIDWalletLocked, "wallet",
+		fmt.Sprintf("%v contracts need to be renewed, but the wallet is locked", renewals),
+		cause, modules.SeverityError)
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 var (
@@ -19,6 +20,20 @@ var (
 	ErrAllowanceZeroWindow = errors.New("renew window must be non-zero")
 )
 
+// validateAllowance checks that the allowance can be used to form contracts.
+func validateAllowance(a modules.Allowance) error {
+	if a.Hosts == 0 {
+		return errAllowanceNoHosts
+	} else if a.Period == 0 {
+		return errAllowanceZeroPeriod
+	} else if a.RenewWindow == 0 {
+		return ErrAllowanceZeroWindow
+	} else if a.RenewWindow >= a.Period {
+		return errAllowanceWindowSize
+	}
+	return nil
+}
+
 // SetAllowance sets the amount of money the Contractor is allowed to spend on
 // contracts over a given time period, divided among the number of hosts
 // specified. Note that Contractor can start forming contracts as soon as
@@ -30,9 +45,9 @@ var (
 // SetAllowance is interrupted, renewed contracts may be lost, though the
 // allocated funds will eventually be returned.
 //
-// If a is the empty allowance, SetAllowance will archive the current contract
-// set. The contracts cannot be used to create Editors or Downloads, and will
-// not be renewed.
+// If a is the empty allowance, ignoring the price caps, SetAllowance will
+// archive the current contract set. The contracts cannot be used to create
+// Editors or Downloads, and will not be renewed.
 //
 // TODO: can an Editor or Downloader be used across renewals?
 // TODO: will hosts allow renewing the same contract twice?
@@ -40,7 +55,7 @@ var (
 // NOTE: At this time, transaction fees are not counted towards the allowance.
 // This means the contractor may spend more than allowance.Funds.
 func (c *Contractor) SetAllowance(a modules.Allowance) error {
-	if reflect.DeepEqual(a, modules.Allowance{}) {
+	if isCancelAllowance(a) {
 		return c.managedCancelAllowance()
 	}
 	if reflect.DeepEqual(a, c.allowance) {
@@ -48,14 +63,8 @@ func (c *Contractor) SetAllowance(a modules.Allowance) error {
 	}
 
 	// sanity checks
-	if a.Hosts == 0 {
-		return errAllowanceNoHosts
-	} else if a.Period == 0 {
-		return errAllowanceZeroPeriod
-	} else if a.RenewWindow == 0 {
-		return ErrAllowanceZeroWindow
-	} else if a.RenewWindow >= a.Period {
-		return errAllowanceWindowSize
+	if err := validateAllowance(a); err != nil {
+		return err
 	} else if !c.cs.Synced() {
 		return errAllowanceNotSynced
 	}
@@ -68,6 +77,7 @@ func (c *Contractor) SetAllowance(a modules.Allowance) error {
 		c.currentPeriod = c.blockHeight
 	}
 	c.allowance = a
+	c.rejectedHosts = make(map[string]modules.RejectedHost)
 	err := c.saveSync()
 	c.mu.Unlock()
 	if err != nil {
@@ -81,6 +91,13 @@ func (c *Contractor) SetAllowance(a modules.Allowance) error {
 	return nil
 }
 
+// isCancelAllowance returns true if the allowance cancels the current
+// allowance. The price caps are ignored, since they do not allow the
+// contractor to spend any money on their own.
+func isCancelAllowance(a modules.Allowance) bool {
+	return a.Funds.IsZero() && a.Hosts == 0 && a.Period == 0 && a.RenewWindow == 0
+}
+
 // managedCancelAllowance handles the special case where the allowance is empty.
 func (c *Contractor) managedCancelAllowance() error {
 	c.log.Println("INFO: canceling allowance")
@@ -110,9 +127,20 @@ func (c *Contractor) managedCancelAllowance() error {
 		}
 	}
 
-	// Clear out the allowance and save.
+	// Record the spending of the canceled period, then clear out the
+	// allowance and save.
 	c.mu.Lock()
+	if !reflect.DeepEqual(c.allowance, modules.Allowance{}) {
+		c.spendingHistory = append(c.spendingHistory, modules.ContractorPeriodSpending{
+			ContractorSpending: c.readlockPeriodSpending(),
+			StartHeight:        c.currentPeriod,
+			EndHeight:          c.blockHeight,
+			Refunded:           c.periodRefunds,
+		})
+		c.periodRefunds = types.ZeroCurrency
+	}
 	c.allowance = modules.Allowance{}
+	c.rejectedHosts = make(map[string]modules.RejectedHost)
 	c.currentPeriod = 0
 	err := c.saveSync()
 	c.mu.Unlock()
@@ -13,6 +13,21 @@ var (
 	// contract.
 	minContractFundRenewalThreshold = float64(0.03) // 3%
 
+	// alertSpendingThreshold is the fraction of the allowance that has to be
+	// allocated to contracts before the contractor raises an alert about its
+	// spending.
+	alertSpendingThreshold = float64(0.9) // 90%
+
+	// defaultExpectedRedundancy is the redundancy assumed when estimating
+	// contract funding from an allowance that does not specify one. It matches
+	// the renter's default 10-of-30 erasure coding.
+	defaultExpectedRedundancy = float64(3)
+
+	// estFormationTxnSize is the estimated size of a contract formation
+	// transaction set. It matches the estimate that the proto package uses to
+	// calculate the transaction fee of a new contract.
+	estFormationTxnSize = uint64(2048)
+
 	// randomHostsBufferForScore defines how many extra hosts are queried when trying
 	// to figure out an appropriate minimum score for the hosts that we have.
 	randomHostsBufferForScore = build.Select(build.Var{
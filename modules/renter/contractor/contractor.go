@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/NebulousLabs/Sia/modules"
@@ -64,6 +65,34 @@ type Contractor struct {
 	staticContracts *proto.ContractSet
 	oldContracts    map[types.FileContractID]modules.RenterContract
 	renewedIDs      map[types.FileContractID]types.FileContractID
+
+	// refreshedIDs maps a contract to the contract it was refreshed from. A
+	// refresh is a renewal in the middle of a period that keeps the end
+	// height of the contract, so the refreshed contracts belong to the same
+	// period as the contract that replaced them.
+	refreshedIDs map[types.FileContractID]types.FileContractID
+
+	// canceledContracts contains the contracts that were canceled by the
+	// user. They are neither used for uploads nor renewed.
+	canceledContracts map[types.FileContractID]struct{}
+
+	// recoveryStatus reports the progress of the most recent scan of the
+	// blockchain for the renter's contracts.
+	recoveryStatus modules.ContractRecoveryStatus
+
+	// spendingHistory contains the spending of every finished period, and
+	// periodRefunds the money refunded by contracts that expired during the
+	// current period.
+	spendingHistory []modules.ContractorPeriodSpending
+	periodRefunds   types.Currency
+
+	// rejectedHosts contains the hosts whose prices exceed the caps of the
+	// allowance, keyed by the string representation of their public key.
+	rejectedHosts map[string]modules.RejectedHost
+
+	// staticAlerter contains the alerts about the budget and the health of
+	// the contracts.
+	staticAlerter *modules.GenericAlerter
 }
 
 // readlockResolveID returns the ID of the most recent renewal of id.
@@ -76,6 +105,22 @@ func (c *Contractor) readlockResolveID(id types.FileContractID) types.FileContra
 	return id
 }
 
+// readlockPreviousContracts returns the contracts that were refreshed into the
+// contract with the given id, most recent first.
+func (c *Contractor) readlockPreviousContracts(id types.FileContractID) []modules.RenterContract {
+	var previous []modules.RenterContract
+	oldID, exists := c.refreshedIDs[id]
+	for exists {
+		contract, ok := c.oldContracts[oldID]
+		if !ok {
+			break
+		}
+		previous = append(previous, contract)
+		oldID, exists = c.refreshedIDs[oldID]
+	}
+	return previous
+}
+
 // Allowance returns the current allowance.
 func (c *Contractor) Allowance() modules.Allowance {
 	c.mu.RLock()
@@ -83,32 +128,64 @@ func (c *Contractor) Allowance() modules.Allowance {
 	return c.allowance
 }
 
+// RejectedHosts returns the hosts that were rejected because their prices
+// exceed the caps of the allowance.
+func (c *Contractor) RejectedHosts() []modules.RejectedHost {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	hosts := make([]modules.RejectedHost, 0, len(c.rejectedHosts))
+	for _, host := range c.rejectedHosts {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].NetAddress < hosts[j].NetAddress
+	})
+	return hosts
+}
+
 // PeriodSpending returns the amount spent on contracts during the current
 // billing period.
 func (c *Contractor) PeriodSpending() modules.ContractorSpending {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.readlockPeriodSpending()
+}
+
+// SpendingHistory returns the spending of every finished billing period,
+// followed by the spending of the current period.
+func (c *Contractor) SpendingHistory() []modules.ContractorPeriodSpending {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	history := append([]modules.ContractorPeriodSpending(nil), c.spendingHistory...)
+	return append(history, modules.ContractorPeriodSpending{
+		ContractorSpending: c.readlockPeriodSpending(),
+		StartHeight:        c.currentPeriod,
+		EndHeight:          c.currentPeriod + c.allowance.Period - c.allowance.RenewWindow,
+		Refunded:           c.periodRefunds,
+	})
+}
 
+// readlockPeriodSpending returns the amount spent on contracts during the
+// current billing period.
+func (c *Contractor) readlockPeriodSpending() modules.ContractorSpending {
 	var spending modules.ContractorSpending
-	for _, contract := range c.staticContracts.ViewAll() {
-		// Calculate ContractFees
-		spending.ContractFees = spending.ContractFees.Add(contract.ContractFee)
-		spending.ContractFees = spending.ContractFees.Add(contract.TxnFee)
-		spending.ContractFees = spending.ContractFees.Add(contract.SiafundFee)
-		// Calculate TotalAllocated
-		spending.TotalAllocated = spending.TotalAllocated.Add(contract.TotalCost)
-		spending.ContractSpendingDeprecated = spending.TotalAllocated
-		// Calculate Spending
-		spending.DownloadSpending = spending.DownloadSpending.Add(contract.DownloadSpending)
-		spending.UploadSpending = spending.UploadSpending.Add(contract.UploadSpending)
-		spending.StorageSpending = spending.StorageSpending.Add(contract.StorageSpending)
-		// TODO: fix PreviousContracts
-		// for _, pre := range contract.PreviousContracts {
-		// 	spending.ContractSpending = spending.ContractSpending.Add(pre.TotalCost)
-		// 	spending.DownloadSpending = spending.DownloadSpending.Add(pre.DownloadSpending)
-		// 	spending.UploadSpending = spending.UploadSpending.Add(pre.UploadSpending)
-		// 	spending.StorageSpending = spending.StorageSpending.Add(pre.StorageSpending)
-		// }
+	for _, active := range c.staticContracts.ViewAll() {
+		// Contracts that were refreshed during the period count towards the
+		// spending of the period as well.
+		contractLine := append([]modules.RenterContract{active}, c.readlockPreviousContracts(active.ID)...)
+		for _, contract := range contractLine {
+			// Calculate ContractFees
+			spending.ContractFees = spending.ContractFees.Add(contract.ContractFee)
+			spending.ContractFees = spending.ContractFees.Add(contract.TxnFee)
+			spending.ContractFees = spending.ContractFees.Add(contract.SiafundFee)
+			// Calculate TotalAllocated
+			spending.TotalAllocated = spending.TotalAllocated.Add(contract.TotalCost)
+			spending.ContractSpendingDeprecated = spending.TotalAllocated
+			// Calculate Spending
+			spending.DownloadSpending = spending.DownloadSpending.Add(contract.DownloadSpending)
+			spending.UploadSpending = spending.UploadSpending.Add(contract.UploadSpending)
+			spending.StorageSpending = spending.StorageSpending.Add(contract.StorageSpending)
+		}
 	}
 	// Calculate amount of spent money to get unspent money.
 	allSpending := spending.ContractFees
@@ -227,14 +304,18 @@ func NewCustomContractor(cs consensusSet, w wallet, tp transactionPool, hdb host
 		wallet:     w,
 
 		interruptMaintenance: make(chan struct{}),
-
-		staticContracts: contractSet,
-		downloaders:     make(map[types.FileContractID]*hostDownloader),
-		editors:         make(map[types.FileContractID]*hostEditor),
-		oldContracts:    make(map[types.FileContractID]modules.RenterContract),
-		renewedIDs:      make(map[types.FileContractID]types.FileContractID),
-		renewing:        make(map[types.FileContractID]bool),
-		revising:        make(map[types.FileContractID]bool),
+		staticAlerter:        modules.NewAlerter("contractor"),
+
+		staticContracts:   contractSet,
+		canceledContracts: make(map[types.FileContractID]struct{}),
+		downloaders:       make(map[types.FileContractID]*hostDownloader),
+		editors:           make(map[types.FileContractID]*hostEditor),
+		oldContracts:      make(map[types.FileContractID]modules.RenterContract),
+		renewedIDs:        make(map[types.FileContractID]types.FileContractID),
+		refreshedIDs:      make(map[types.FileContractID]types.FileContractID),
+		rejectedHosts:     make(map[string]modules.RejectedHost),
+		renewing:          make(map[types.FileContractID]bool),
+		revising:          make(map[types.FileContractID]bool),
 	}
 
 	// Close the contract set and logger upon shutdown.
@@ -26,7 +26,9 @@ func (newStub) Unsubscribe(modules.ConsensusSetSubscriber) { return }
 
 // wallet stubs
 func (newStub) NextAddress() (uc types.UnlockConditions, err error)          { return }
+func (newStub) PrimarySeed() (s modules.Seed, p uint64, err error)           { return }
 func (newStub) StartTransaction() (tb modules.TransactionBuilder, err error) { return }
+func (newStub) Unlocked() (bool, error)                                      { return true, nil }
 
 // transaction pool stubs
 func (newStub) AcceptTransactionSet([]types.Transaction) error      { return nil }
@@ -38,6 +40,7 @@ func (newStub) ActiveHosts() []modules.HostDBEntry
 func (newStub) Host(types.SiaPublicKey) (settings modules.HostDBEntry, ok bool)      { return }
 func (newStub) IncrementSuccessfulInteractions(key types.SiaPublicKey)               { return }
 func (newStub) IncrementFailedInteractions(key types.SiaPublicKey)                   { return }
+func (newStub) IsFiltered(types.SiaPublicKey) bool                                   { return false }
 func (newStub) RandomHosts(int, []types.SiaPublicKey) ([]modules.HostDBEntry, error) { return nil, nil }
 func (newStub) ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown {
 	return modules.HostScoreBreakdown{}
@@ -112,6 +115,33 @@ func TestResolveID(t *testing.T) {
 	}
 }
 
+// TestPreviousContracts tests that the contracts refreshed into a contract are
+// returned as part of its contract line.
+func TestPreviousContracts(t *testing.T) {
+	c := &Contractor{
+		refreshedIDs: map[types.FileContractID]types.FileContractID{
+			{3}: {2},
+			{2}: {1},
+			{5}: {4},
+		},
+		oldContracts: map[types.FileContractID]modules.RenterContract{
+			{1}: {ID: types.FileContractID{1}},
+			{2}: {ID: types.FileContractID{2}},
+		},
+	}
+	previous := c.readlockPreviousContracts(types.FileContractID{3})
+	if len(previous) != 2 || previous[0].ID != (types.FileContractID{2}) || previous[1].ID != (types.FileContractID{1}) {
+		t.Fatal("wrong previous contracts:", previous)
+	}
+	// Contracts that are no longer known are skipped.
+	if previous := c.readlockPreviousContracts(types.FileContractID{5}); len(previous) != 0 {
+		t.Fatal("expected no previous contracts, got", previous)
+	}
+	if previous := c.readlockPreviousContracts(types.FileContractID{1}); len(previous) != 0 {
+		t.Fatal("expected no previous contracts, got", previous)
+	}
+}
+
 // TestAllowance tests the Allowance method.
 func TestAllowance(t *testing.T) {
 	c := &Contractor{
@@ -138,6 +168,7 @@ func (stubHostDB) ActiveHosts() (hs []modules.HostDBEntry)
 func (stubHostDB) Host(types.SiaPublicKey) (h modules.HostDBEntry, ok bool)                  { return }
 func (stubHostDB) IncrementSuccessfulInteractions(key types.SiaPublicKey)                    { return }
 func (stubHostDB) IncrementFailedInteractions(key types.SiaPublicKey)                        { return }
+func (stubHostDB) IsFiltered(types.SiaPublicKey) bool                                        { return false }
 func (stubHostDB) PublicKey() (spk types.SiaPublicKey)                                       { return }
 func (stubHostDB) RandomHosts(int, []types.SiaPublicKey) (hs []modules.HostDBEntry, _ error) { return }
 func (stubHostDB) ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown {
@@ -529,7 +560,9 @@ func TestIntegrationSetAllowance(t *testing.T) {
 // testWalletShim is used to test the walletBridge type.
 type testWalletShim struct {
 	nextAddressCalled bool
+	primarySeedCalled bool
 	startTxnCalled    bool
+	unlockedCalled    bool
 }
 
 // These stub implementations for the walletShim interface set their respective
@@ -538,10 +571,18 @@ func (ws *testWalletShim) NextAddress() (types.UnlockConditions, error) {
 	ws.nextAddressCalled = true
 	return types.UnlockConditions{}, nil
 }
+func (ws *testWalletShim) PrimarySeed() (modules.Seed, uint64, error) {
+	ws.primarySeedCalled = true
+	return modules.Seed{}, 0, nil
+}
 func (ws *testWalletShim) StartTransaction() (modules.TransactionBuilder, error) {
 	ws.startTxnCalled = true
 	return nil, nil
 }
+func (ws *testWalletShim) Unlocked() (bool, error) {
+	ws.unlockedCalled = true
+	return true, nil
+}
 
 // TestWalletBridge tests the walletBridge type.
 func TestWalletBridge(t *testing.T) {
@@ -551,8 +592,63 @@ func TestWalletBridge(t *testing.T) {
 	if !shim.nextAddressCalled {
 		t.Error("NextAddress was not called on the shim")
 	}
+	bridge.PrimarySeed()
+	if !shim.primarySeedCalled {
+		t.Error("PrimarySeed was not called on the shim")
+	}
 	bridge.StartTransaction()
 	if !shim.startTxnCalled {
 		t.Error("StartTransaction was not called on the shim")
 	}
+	bridge.Unlocked()
+	if !shim.unlockedCalled {
+		t.Error("Unlocked was not called on the shim")
+	}
+}
+
+// ipNetHostDB is a stub hostDB that returns hosts from a map.
+type ipNetHostDB struct {
+	stubHostDB
+	hosts map[string]modules.HostDBEntry
+}
+
+func (hdb ipNetHostDB) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
+	h, ok := hdb.hosts[spk.String()]
+	return h, ok
+}
+
+// TestIPNetViolations checks that contracts with hosts that share a subnet
+// are detected, keeping the host that has used the subnet the longest.
+func TestIPNetViolations(t *testing.T) {
+	now := time.Now()
+	hosts := []modules.HostDBEntry{
+		{IPNets: []string{"1.2.3.0/24"}, LastIPNetChange: now},
+		{IPNets: []string{"1.2.3.0/24"}, LastIPNetChange: now.Add(-time.Hour)},
+		{IPNets: []string{"4.5.6.0/24", "1.2.3.0/24"}, LastIPNetChange: now.Add(time.Hour)},
+		{IPNets: []string{"7.8.9.0/24"}, LastIPNetChange: now},
+		{IPNets: []string{"7.8.9.0/24"}, LastIPNetChange: now.Add(-time.Hour)},
+	}
+	hdb := ipNetHostDB{hosts: make(map[string]modules.HostDBEntry)}
+	var contracts []modules.RenterContract
+	var utilities []modules.ContractUtility
+	for i := range hosts {
+		hosts[i].PublicKey = types.SiaPublicKey{Key: []byte{byte(i)}}
+		hdb.hosts[hosts[i].PublicKey.String()] = hosts[i]
+		contracts = append(contracts, modules.RenterContract{HostPublicKey: hosts[i].PublicKey})
+		utilities = append(utilities, modules.ContractUtility{GoodForUpload: true, GoodForRenew: true})
+	}
+	// The oldest host of the second subnet is not good for renew, so it
+	// doesn't take part in the check.
+	utilities[4].GoodForRenew = false
+
+	c := &Contractor{hdb: hdb}
+	violations := c.managedIPNetViolations(contracts, utilities)
+	if len(violations) != 2 {
+		t.Fatal("expected 2 violations, got", violations)
+	}
+	for _, i := range []int{0, 2} {
+		if _, ok := violations[i]; !ok {
+			t.Fatal("expected violation for contract", i, violations)
+		}
+	}
 }
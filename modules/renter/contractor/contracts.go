@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -20,6 +21,7 @@ var (
 	// than the amount necessary to store at least one sector
 	ErrInsufficientAllowance = errors.New("allowance is not large enough to cover fees of contract creation")
 	errTooExpensive          = errors.New("host price was too high")
+	errPriceCapExceeded      = errors.New("host prices exceed the caps of the allowance")
 )
 
 // contractEndHeight returns the height at which the Contractor's contracts
@@ -97,12 +99,24 @@ func (c *Contractor) managedMarkContractsUtility() error {
 	}
 
 	// Update utility fields for each contract.
-	for _, contract := range c.staticContracts.ViewAll() {
-		utility := func() (u modules.ContractUtility) {
+	contracts := c.staticContracts.ViewAll()
+	utilities := make([]modules.ContractUtility, len(contracts))
+	for i, contract := range contracts {
+		utilities[i] = func() (u modules.ContractUtility) {
 			// Start the contract in good standing.
 			u.GoodForUpload = true
 			u.GoodForRenew = true
 
+			// Contract has no utility if it was canceled by the user.
+			c.mu.RLock()
+			_, canceled := c.canceledContracts[contract.ID]
+			c.mu.RUnlock()
+			if canceled {
+				u.GoodForUpload = false
+				u.GoodForRenew = false
+				return
+			}
+
 			host, exists := c.hdb.Host(contract.HostPublicKey)
 			// Contract has no utility if the host is not in the database.
 			if !exists {
@@ -110,6 +124,20 @@ func (c *Contractor) managedMarkContractsUtility() error {
 				u.GoodForRenew = false
 				return
 			}
+			// Contract has no utility if the host is excluded by the hostdb's
+			// filter mode. The renter will migrate the data to other hosts.
+			if c.hdb.IsFiltered(contract.HostPublicKey) {
+				u.GoodForUpload = false
+				u.GoodForRenew = false
+				return
+			}
+			// Contract has no utility if the host's prices exceed the caps of
+			// the allowance.
+			if c.managedCheckPriceCaps(host) != nil {
+				u.GoodForUpload = false
+				u.GoodForRenew = false
+				return
+			}
 			// Contract has no utility if the score is poor.
 			if !minScore.IsZero() && c.hdb.ScoreBreakdown(host).Score.Cmp(minScore) < 0 {
 				u.GoodForUpload = false
@@ -143,9 +171,20 @@ func (c *Contractor) managedMarkContractsUtility() error {
 			}
 			return
 		}()
+	}
+
+	// Contracts with hosts that share a subnet with another host are not
+	// independent of each other. Only the contract with the host that has used
+	// the subnet the longest is kept, the renter will migrate the data of the
+	// other contracts to other hosts.
+	for i := range c.managedIPNetViolations(contracts, utilities) {
+		utilities[i].GoodForUpload = false
+		utilities[i].GoodForRenew = false
+	}
 
-		// Apply changes.
-		err := c.managedUpdateContractUtility(contract.ID, utility)
+	// Apply changes.
+	for i, contract := range contracts {
+		err := c.managedUpdateContractUtility(contract.ID, utilities[i])
 		if err != nil {
 			return err
 		}
@@ -153,6 +192,72 @@ func (c *Contractor) managedMarkContractsUtility() error {
 	return nil
 }
 
+// managedIPNetViolations returns the indices of the contracts whose hosts share
+// a subnet with the host of another contract that is good for renew. Of the
+// hosts that share a subnet, the host whose subnets changed the longest ago is
+// not considered a violation.
+func (c *Contractor) managedIPNetViolations(contracts []modules.RenterContract, utilities []modules.ContractUtility) map[int]struct{} {
+	var candidates []int
+	hosts := make(map[int]modules.HostDBEntry)
+	for i, contract := range contracts {
+		if !utilities[i].GoodForRenew {
+			continue
+		}
+		host, exists := c.hdb.Host(contract.HostPublicKey)
+		if !exists {
+			continue
+		}
+		candidates = append(candidates, i)
+		hosts[i] = host
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return hosts[candidates[a]].LastIPNetChange.Before(hosts[candidates[b]].LastIPNetChange)
+	})
+
+	violations := make(map[int]struct{})
+	usedNets := make(map[string]struct{})
+	for _, i := range candidates {
+		host := hosts[i]
+		for _, ipNet := range host.IPNets {
+			if _, used := usedNets[ipNet]; used {
+				violations[i] = struct{}{}
+				break
+			}
+		}
+		if _, violation := violations[i]; violation {
+			continue
+		}
+		for _, ipNet := range host.IPNets {
+			usedNets[ipNet] = struct{}{}
+		}
+	}
+	return violations
+}
+
+// managedCheckPriceCaps checks the host's prices against the caps of the
+// allowance. Hosts that exceed a cap are recorded as rejected, and hosts that
+// no longer exceed any cap are removed from the rejected hosts.
+func (c *Contractor) managedCheckPriceCaps(host modules.HostDBEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := host.PublicKey.String()
+	reason := c.allowance.PriceCapViolation(host.HostExternalSettings)
+	if reason == "" {
+		delete(c.rejectedHosts, key)
+		return nil
+	}
+	if _, exists := c.rejectedHosts[key]; !exists {
+		c.log.Printf("Rejecting host %v: %v", host.NetAddress, reason)
+	}
+	c.rejectedHosts[key] = modules.RejectedHost{
+		PublicKey:   host.PublicKey,
+		NetAddress:  host.NetAddress,
+		Reason:      reason,
+		BlockHeight: c.blockHeight,
+	}
+	return fmt.Errorf("%v: %v", errPriceCapExceeded, reason)
+}
+
 // managedNewContract negotiates an initial file contract with the specified
 // host, saves it, and returns it.
 func (c *Contractor) managedNewContract(host modules.HostDBEntry, contractFunding types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
@@ -160,6 +265,9 @@ func (c *Contractor) managedNewContract(host modules.HostDBEntry, contractFundin
 	if host.StoragePrice.Cmp(maxStoragePrice) > 0 {
 		return modules.RenterContract{}, errTooExpensive
 	}
+	if err := c.managedCheckPriceCaps(host); err != nil {
+		return modules.RenterContract{}, err
+	}
 	// cap host.MaxCollateral
 	if host.MaxCollateral.Cmp(maxCollateral) > 0 {
 		host.MaxCollateral = maxCollateral
@@ -170,6 +278,12 @@ func (c *Contractor) managedNewContract(host modules.HostDBEntry, contractFundin
 	if err != nil {
 		return modules.RenterContract{}, err
 	}
+	// derive the contract's key from the wallet seed, so that the contract
+	// can be recovered
+	renterSeed, err := c.managedRenterSeed()
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
 
 	// create contract params
 	c.mu.RLock()
@@ -179,6 +293,7 @@ func (c *Contractor) managedNewContract(host modules.HostDBEntry, contractFundin
 		StartHeight:   c.blockHeight,
 		EndHeight:     endHeight,
 		RefundAddress: uc.UnlockHash(),
+		RenterSeed:    renterSeed,
 	}
 	c.mu.RUnlock()
 
@@ -218,6 +333,8 @@ func (c *Contractor) managedRenew(sc *proto.SafeContract, contractFunding types.
 		return modules.RenterContract{}, errors.New("no record of that host")
 	} else if host.StoragePrice.Cmp(maxStoragePrice) > 0 {
 		return modules.RenterContract{}, errTooExpensive
+	} else if err := c.managedCheckPriceCaps(host); err != nil {
+		return modules.RenterContract{}, err
 	}
 	// cap host.MaxCollateral
 	if host.MaxCollateral.Cmp(maxCollateral) > 0 {
@@ -279,6 +396,8 @@ func (c *Contractor) threadedContractMaintenance() {
 	wantedHosts := c.allowance.Hosts
 	c.mu.RUnlock()
 	if wantedHosts <= 0 {
+		c.managedUpdateAlerts()
+		c.managedUpdateWalletAlert(0)
 		return
 	}
 	// Only one instance of this thread should be running at a time. Under
@@ -292,6 +411,7 @@ func (c *Contractor) threadedContractMaintenance() {
 		return
 	}
 	defer c.maintenanceLock.Unlock()
+	defer c.managedUpdateAlerts()
 
 	// Update the utility fields for this contract based on the most recent
 	// hostdb.
@@ -312,8 +432,9 @@ func (c *Contractor) threadedContractMaintenance() {
 	// The actions inside this RLock are complex enough to merit wrapping them
 	// in a function where we can defer the unlock.
 	type renewal struct {
-		id     types.FileContractID
-		amount types.Currency
+		id        types.FileContractID
+		amount    types.Currency
+		endHeight types.BlockHeight
 	}
 	var endHeight types.BlockHeight
 	var fundsAvailable types.Currency
@@ -335,9 +456,14 @@ func (c *Contractor) threadedContractMaintenance() {
 	// get the full picture for how many funds are available.
 	var fundsUsed types.Currency
 	for _, contract := range c.staticContracts.ViewAll() {
-		// Calculate the cost of the contract line.
+		// Calculate the cost of the contract line, including the contracts
+		// that were refreshed into this contract during the period.
 		contractLineCost := contract.TotalCost
-		// TODO: add previous contracts here
+		c.mu.RLock()
+		for _, pre := range c.readlockPreviousContracts(contract.ID) {
+			contractLineCost = contractLineCost.Add(pre.TotalCost)
+		}
+		c.mu.RUnlock()
 
 		// Check if the contract is expiring. The funds in the contract are
 		// handled differently based on this information.
@@ -380,7 +506,26 @@ func (c *Contractor) threadedContractMaintenance() {
 		if !ok || !utility.GoodForRenew {
 			continue
 		}
-		if blockHeight+allowance.RenewWindow >= contract.EndHeight {
+		if blockHeight+allowance.RenewWindow >= contract.EndHeight && hasExpectedUsage(allowance) {
+			// This contract needs to be renewed because it is going to
+			// expire soon. The renewed contract is funded according to the
+			// expected usage and the current prices of the host.
+			host, ok := c.hdb.Host(contract.HostPublicKey)
+			if !ok {
+				continue
+			}
+			renewAmount := contractFunding(allowance, host, blockHeight, endHeight)
+			if renewAmount.Cmp(fundsAvailable) > 0 {
+				c.log.Println("WARN: performing a limited renew due to low allowance")
+				renewAmount = fundsAvailable
+			}
+			fundsAvailable = fundsAvailable.Sub(renewAmount)
+			renewSet = append(renewSet, renewal{
+				id:        contract.ID,
+				amount:    renewAmount,
+				endHeight: endHeight,
+			})
+		} else if blockHeight+allowance.RenewWindow >= contract.EndHeight {
 			// This contract needs to be renewed because it is going to
 			// expire soon. First step is to calculate how much money should
 			// be used in the renewal, based on how much of the contract
@@ -393,7 +538,11 @@ func (c *Contractor) threadedContractMaintenance() {
 			// subtracting out all of the fees, and then all of the unused
 			// money that was allocated (the RenterFunds).
 			renewAmount := contract.TotalCost.Sub(contract.ContractFee).Sub(contract.TxnFee).Sub(contract.SiafundFee).Sub(contract.RenterFunds)
-			// TODO: add previous contracts here
+			c.mu.RLock()
+			for _, pre := range c.readlockPreviousContracts(contract.ID) {
+				renewAmount = renewAmount.Add(pre.TotalCost.Sub(pre.ContractFee).Sub(pre.TxnFee).Sub(pre.SiafundFee).Sub(pre.RenterFunds))
+			}
+			c.mu.RUnlock()
 
 			// Get an estimate for how much the fees will cost.
 			//
@@ -415,8 +564,9 @@ func (c *Contractor) threadedContractMaintenance() {
 			// The contract needs to be renewed because it is going to
 			// expire soon, and we need to refresh the time.
 			renewSet = append(renewSet, renewal{
-				id:     contract.ID,
-				amount: renewAmount,
+				id:        contract.ID,
+				amount:    renewAmount,
+				endHeight: endHeight,
 			})
 		} else {
 			// Check if the contract has exhausted its funding and requires
@@ -435,16 +585,52 @@ func (c *Contractor) threadedContractMaintenance() {
 			sectorBandwidthPrice := host.UploadBandwidthPrice.Mul64(modules.SectorSize)
 			sectorPrice := sectorStoragePrice.Add(sectorBandwidthPrice)
 			percentRemaining, _ := big.NewRat(0, 1).SetFrac(contract.RenterFunds.Big(), contract.TotalCost.Big()).Float64()
-			if contract.RenterFunds.Cmp(sectorPrice.Mul64(3)) < 0 || percentRemaining < minContractFundRenewalThreshold {
+			outOfFunds := contract.RenterFunds.Cmp(sectorPrice.Mul64(3)) < 0 || percentRemaining < minContractFundRenewalThreshold
+			if outOfFunds && hasExpectedUsage(allowance) {
+				// This contract has run out of funds. Topping it up shortly
+				// before it enters the renew window means paying the
+				// contract fees twice in quick succession, so such contracts
+				// are renewed early into the next period instead, if the
+				// allowance can cover it.
+				if blockHeight+2*allowance.RenewWindow >= contract.EndHeight {
+					nextEndHeight := endHeight + allowance.Period - allowance.RenewWindow
+					renewAmount := contractFunding(allowance, host, blockHeight, nextEndHeight)
+					if renewAmount.Cmp(fundsAvailable) < 0 {
+						fundsAvailable = fundsAvailable.Sub(renewAmount)
+						renewSet = append(renewSet, renewal{
+							id:        contract.ID,
+							amount:    renewAmount,
+							endHeight: nextEndHeight,
+						})
+						continue
+					}
+				}
+				refreshAmount := contractFunding(allowance, host, blockHeight, contract.EndHeight)
+				if refreshAmount.Cmp(fundsAvailable) < 0 {
+					fundsAvailable = fundsAvailable.Sub(refreshAmount)
+					refreshSet[contract.ID] = struct{}{}
+					renewSet = append(renewSet, renewal{
+						id:        contract.ID,
+						amount:    refreshAmount,
+						endHeight: contract.EndHeight,
+					})
+				} else {
+					c.log.Println("WARN: cannot refresh empty contract due to low allowance.")
+				}
+			} else if outOfFunds {
 				// This contract does need to be refreshed. Make sure there
 				// are enough funds available to perform the refresh, and
-				// then execute.
+				// then execute. The refresh keeps the end height of the
+				// contract, and the renewal carries the Merkle roots of the
+				// contract forward.
 				refreshAmount := contract.TotalCost.Mul64(2)
 				if refreshAmount.Cmp(fundsAvailable) < 0 {
+					fundsAvailable = fundsAvailable.Sub(refreshAmount)
 					refreshSet[contract.ID] = struct{}{}
 					renewSet = append(renewSet, renewal{
-						id:     contract.ID,
-						amount: refreshAmount,
+						id:        contract.ID,
+						amount:    refreshAmount,
+						endHeight: contract.EndHeight,
 					})
 				} else {
 					c.log.Println("WARN: cannot refresh empty contract due to low allowance.")
@@ -456,7 +642,10 @@ func (c *Contractor) threadedContractMaintenance() {
 		c.log.Printf("renewing %v contracts", len(renewSet))
 	}
 
-	// Remove contracts that are not scheduled for renew from firstFailedRenew.
+	c.managedUpdateWalletAlert(len(renewSet))
+
+	// Remove contracts that are not scheduled for renew from firstFailedRenew,
+	// and resolve the alerts about their failed renewals.
 	c.mu.Lock()
 	newFirstFailedRenew := make(map[types.FileContractID]types.BlockHeight)
 	for _, r := range renewSet {
@@ -464,6 +653,11 @@ func (c *Contractor) threadedContractMaintenance() {
 			newFirstFailedRenew[r.id] = c.numFailedRenews[r.id]
 		}
 	}
+	for id := range c.numFailedRenews {
+		if _, exists := newFirstFailedRenew[id]; !exists {
+			c.staticAlerter.UnregisterAlert(alertIDRenewFailed(id))
+		}
+	}
 	c.numFailedRenews = newFirstFailedRenew
 	c.mu.Unlock()
 
@@ -472,6 +666,7 @@ func (c *Contractor) threadedContractMaintenance() {
 		// Pull the variables out of the renewal.
 		id := renewal.id
 		amount := renewal.amount
+		renewEndHeight := renewal.endHeight
 
 		// Renew one contract.
 		func() {
@@ -517,7 +712,7 @@ func (c *Contractor) threadedContractMaintenance() {
 			// before. Once it has failed for a certain number of blocks in a
 			// row and reached its second half of the renew window, we give up
 			// on renewing it and set goodForRenew to false.
-			newContract, errRenew := c.managedRenew(oldContract, amount, endHeight)
+			newContract, errRenew := c.managedRenew(oldContract, amount, renewEndHeight)
 			if errRenew != nil {
 				// Increment the number of failed renews for the contract.
 				c.mu.Lock()
@@ -529,6 +724,9 @@ func (c *Contractor) threadedContractMaintenance() {
 				c.mu.RLock()
 				numRenews, failedBefore := c.numFailedRenews[md.ID]
 				c.mu.RUnlock()
+				c.staticAlerter.RegisterAlert(alertIDRenewFailed(md.ID),
+					fmt.Sprintf("failed to renew contract %v with host %v %v times", md.ID, md.HostPublicKey, numRenews),
+					errRenew.Error(), modules.SeverityError)
 				secondHalfOfWindow := blockHeight+allowance.RenewWindow/2 >= md.EndHeight
 				replace := numRenews >= consecutiveRenewalsBeforeReplacement
 				if failedBefore && secondHalfOfWindow && replace {
@@ -549,45 +747,15 @@ func (c *Contractor) threadedContractMaintenance() {
 				c.staticContracts.Return(oldContract)
 				return
 			}
-			c.log.Printf("Renewed contract %v\n", id)
-
-			// Update the utility values for the new contract, and for the old
-			// contract.
-			newUtility := modules.ContractUtility{
-				GoodForUpload: true,
-				GoodForRenew:  true,
-			}
-			if err := c.managedUpdateContractUtility(newContract.ID, newUtility); err != nil {
-				c.log.Println("Failed to update the contract utilities", err)
-				return
-			}
-			oldUtility.GoodForRenew = false
-			oldUtility.GoodForUpload = false
-			if err := oldContract.UpdateUtility(oldUtility); err != nil {
-				c.log.Println("Failed to update the contract utilities", err)
-				return
-			}
-			// If the contract is a mid-cycle renew, add the contract line to
-			// the new contract. The contract line is not included/extended if
-			// we are just renewing because the contract is expiring.
-			if _, exists := refreshSet[id]; exists {
-				// TODO: update PreviousContracts
+			c.staticAlerter.UnregisterAlert(alertIDRenewFailed(id))
+			_, refreshed := refreshSet[id]
+			if refreshed {
+				c.log.Printf("Refreshed contract %v\n", id)
+			} else {
+				c.log.Printf("Renewed contract %v\n", id)
 			}
-
-			// Lock the contractor as we update it to use the new contract
-			// instead of the old contract.
-			c.mu.Lock()
-			defer c.mu.Unlock()
-			// Delete the old contract.
-			c.staticContracts.Delete(oldContract)
-			// Store the contract in the record of historic contracts.
-			c.oldContracts[id] = oldContract.Metadata()
-			// Add a mapping from the old contract to the new contract.
-			c.renewedIDs[id] = newContract.ID
-			// Save the contractor.
-			err = c.saveSync()
-			if err != nil {
-				c.log.Println("Failed to save the contractor after creating a new contract.")
+			if err := c.managedReplaceRenewedContract(oldContract, oldUtility, newContract, refreshed); err != nil {
+				c.log.Println("Failed to replace the renewed contract:", err)
 			}
 		}()
 
@@ -645,18 +813,26 @@ func (c *Contractor) threadedContractMaintenance() {
 	// Form contracts with the hosts one at a time, until we have enough
 	// contracts.
 	for _, host := range hosts {
+		// If the expected usage is known, fund the contract according to the
+		// prices of the host.
+		contractFunds := initialContractFunds
+		if hasExpectedUsage(allowance) {
+			contractFunds = contractFunding(allowance, host, blockHeight, endHeight)
+		}
+
 		// Determine if we have enough money to form a new contract.
-		if fundsAvailable.Cmp(initialContractFunds) < 0 {
+		if fundsAvailable.Cmp(contractFunds) < 0 {
 			c.log.Println("WARN: need to form new contracts, but unable to because of a low allowance")
 			break
 		}
 
 		// Attempt forming a contract with this host.
-		newContract, err := c.managedNewContract(host, initialContractFunds, endHeight)
+		newContract, err := c.managedNewContract(host, contractFunds, endHeight)
 		if err != nil {
 			c.log.Printf("Attempted to form a contract with %v, but negotiation failed: %v\n", host.NetAddress, err)
 			continue
 		}
+		fundsAvailable = fundsAvailable.Sub(contractFunds)
 
 		// Add this contract to the contractor and save.
 		err = c.managedUpdateContractUtility(newContract.ID, modules.ContractUtility{
@@ -691,6 +867,50 @@ func (c *Contractor) threadedContractMaintenance() {
 	}
 }
 
+// managedReplaceRenewedContract replaces a contract that was renewed with the
+// contract it was renewed into. If the renewal was a refresh, the old contract
+// is recorded as part of the new contract's line. The old contract must have
+// been acquired by the caller; it is deleted from the contract set, or
+// returned if the utilities cannot be updated.
+func (c *Contractor) managedReplaceRenewedContract(oldContract *proto.SafeContract, oldUtility modules.ContractUtility, newContract modules.RenterContract, refreshed bool) error {
+	// Update the utility values for the new contract, and for the old
+	// contract.
+	newUtility := modules.ContractUtility{
+		GoodForUpload: true,
+		GoodForRenew:  true,
+	}
+	if err := c.managedUpdateContractUtility(newContract.ID, newUtility); err != nil {
+		c.staticContracts.Return(oldContract)
+		return err
+	}
+	oldUtility.GoodForRenew = false
+	oldUtility.GoodForUpload = false
+	if err := oldContract.UpdateUtility(oldUtility); err != nil {
+		c.staticContracts.Return(oldContract)
+		return err
+	}
+
+	// Lock the contractor as we update it to use the new contract instead of
+	// the old contract.
+	id := oldContract.Metadata().ID
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// If the contract is a mid-cycle renew, add the contract line to the new
+	// contract. The contract line is not included/extended if we are just
+	// renewing because the contract is expiring.
+	if refreshed {
+		c.refreshedIDs[newContract.ID] = id
+	}
+	// Delete the old contract.
+	c.staticContracts.Delete(oldContract)
+	// Store the contract in the record of historic contracts.
+	c.oldContracts[id] = oldContract.Metadata()
+	// Add a mapping from the old contract to the new contract.
+	c.renewedIDs[id] = newContract.ID
+	// Save the contractor.
+	return c.saveSync()
+}
+
 // managedUpdateContractUtility is a helper function that acquires a contract, updates
 // its ContractUtility and returns the contract again.
 func (c *Contractor) managedUpdateContractUtility(id types.FileContractID, utility modules.ContractUtility) error {
@@ -21,11 +21,15 @@ type (
 	// transactionBuilder.
 	walletShim interface {
 		NextAddress() (types.UnlockConditions, error)
+		PrimarySeed() (modules.Seed, uint64, error)
 		StartTransaction() (modules.TransactionBuilder, error)
+		Unlocked() (bool, error)
 	}
 	wallet interface {
 		NextAddress() (types.UnlockConditions, error)
+		PrimarySeed() (modules.Seed, uint64, error)
 		StartTransaction() (transactionBuilder, error)
+		Unlocked() (bool, error)
 	}
 	transactionBuilder interface {
 		AddArbitraryData([]byte) uint64
@@ -53,6 +57,7 @@ type (
 		Host(types.SiaPublicKey) (modules.HostDBEntry, bool)
 		IncrementSuccessfulInteractions(key types.SiaPublicKey)
 		IncrementFailedInteractions(key types.SiaPublicKey)
+		IsFiltered(types.SiaPublicKey) bool
 		RandomHosts(n int, exclude []types.SiaPublicKey) ([]modules.HostDBEntry, error)
 		ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown
 	}
@@ -73,10 +78,16 @@ type WalletBridge struct {
 // NextAddress computes and returns the next address of the wallet.
 func (ws *WalletBridge) NextAddress() (types.UnlockConditions, error) { return ws.W.NextAddress() }
 
+// PrimarySeed returns the primary seed of the wallet.
+func (ws *WalletBridge) PrimarySeed() (modules.Seed, uint64, error) { return ws.W.PrimarySeed() }
+
 // StartTransaction creates a new transactionBuilder that can be used to create
 // and sign a transaction.
 func (ws *WalletBridge) StartTransaction() (transactionBuilder, error) { return ws.W.StartTransaction() }
 
+// Unlocked reports whether the wallet is unlocked.
+func (ws *WalletBridge) Unlocked() (bool, error) { return ws.W.Unlocked() }
+
 // stdPersist implements the persister interface. The filename required by
 // these functions is internal to stdPersist.
 type stdPersist struct {
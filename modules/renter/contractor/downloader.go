@@ -21,6 +21,12 @@ type Downloader interface {
 	// retrieve.
 	Sector(root crypto.Hash) ([]byte, error)
 
+	// Download retrieves the requested sections of sectors, and revises the
+	// underlying contract to pay the host proportionally to the data
+	// retrieved. The offset and length of each section must be multiples of
+	// crypto.SegmentSize.
+	Download(sections []modules.DownloadAction) ([][]byte, error)
+
 	// Close terminates the connection to the host.
 	Close() error
 }
@@ -101,6 +107,23 @@ func (hd *hostDownloader) Sector(root crypto.Hash) ([]byte, error) {
 	return sector, nil
 }
 
+// Download retrieves the requested sections of sectors, and revises the
+// underlying contract to pay the host proportionally to the data retrieved.
+func (hd *hostDownloader) Download(sections []modules.DownloadAction) ([][]byte, error) {
+	hd.mu.Lock()
+	defer hd.mu.Unlock()
+	if hd.invalid {
+		return nil, errInvalidDownloader
+	}
+
+	// Download the sections.
+	_, data, err := hd.downloader.Download(sections)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 // Downloader returns a Downloader object that can be used to download sectors
 // from a host.
 func (c *Contractor) Downloader(id types.FileContractID, cancel <-chan struct{}) (_ Downloader, err error) {
@@ -23,6 +23,20 @@ type Editor interface {
 	// returns the Merkle root of the data.
 	Upload(data []byte) (root crypto.Hash, err error)
 
+	// UploadBatch revises the underlying contract to store several sectors.
+	// The sectors are sent in as few revisions as the host allows. It returns
+	// the Merkle roots of the sectors. If a revision fails, the roots of the
+	// sectors that were uploaded before the failure are returned along with
+	// the error.
+	UploadBatch(sectors [][]byte) (roots []crypto.Hash, err error)
+
+	// ReplaceBatch revises the underlying contract to overwrite the sectors
+	// with the roots oldRoots with new sectors, in as few revisions as the
+	// host allows. It returns the Merkle roots of the new sectors. If a
+	// revision fails, the roots of the sectors that were replaced before the
+	// failure are returned along with the error.
+	ReplaceBatch(oldRoots []crypto.Hash, sectors [][]byte) (roots []crypto.Hash, err error)
+
 	// Address returns the address of the host.
 	Address() modules.NetAddress
 
@@ -113,6 +127,66 @@ func (he *hostEditor) Upload(data []byte) (_ crypto.Hash, err error) {
 	return sectorRoot, nil
 }
 
+// UploadBatch negotiates the revisions that add several sectors to a file
+// contract. Each revision adds as many sectors as the host allows. If a
+// revision fails, the roots of the sectors added by the previous revisions
+// are returned along with the error.
+func (he *hostEditor) UploadBatch(sectors [][]byte) ([]crypto.Hash, error) {
+	he.mu.Lock()
+	defer he.mu.Unlock()
+	if he.invalid {
+		return nil, errInvalidEditor
+	}
+
+	// Perform the uploads.
+	var roots []crypto.Hash
+	batchSize := he.editor.MaxUploadBatch()
+	for len(sectors) > 0 {
+		batch := sectors
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		_, batchRoots, err := he.editor.UploadBatch(batch)
+		if err != nil {
+			return roots, err
+		}
+		roots = append(roots, batchRoots...)
+		sectors = sectors[len(batch):]
+	}
+	return roots, nil
+}
+
+// ReplaceBatch negotiates the revisions that overwrite several sectors of a
+// file contract. Each revision overwrites as many sectors as the host allows.
+// If a revision fails, the roots of the sectors written by the previous
+// revisions are returned along with the error.
+func (he *hostEditor) ReplaceBatch(oldRoots []crypto.Hash, sectors [][]byte) ([]crypto.Hash, error) {
+	he.mu.Lock()
+	defer he.mu.Unlock()
+	if he.invalid {
+		return nil, errInvalidEditor
+	} else if len(oldRoots) != len(sectors) {
+		return nil, errors.New("number of sectors doesn't match the number of replaced roots")
+	}
+
+	// Perform the replacements.
+	var roots []crypto.Hash
+	batchSize := he.editor.MaxUploadBatch()
+	for len(sectors) > 0 {
+		n := len(sectors)
+		if n > batchSize {
+			n = batchSize
+		}
+		_, batchRoots, err := he.editor.ReplaceBatch(oldRoots[:n], sectors[:n])
+		if err != nil {
+			return roots, err
+		}
+		roots = append(roots, batchRoots...)
+		oldRoots, sectors = oldRoots[n:], sectors[n:]
+	}
+	return roots, nil
+}
+
 // Editor returns a Editor object that can be used to upload, modify, and
 // delete sectors on a host.
 func (c *Contractor) Editor(id types.FileContractID, cancel <-chan struct{}) (_ Editor, err error) {
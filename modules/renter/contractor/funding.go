@@ -0,0 +1,54 @@
+package contractor
+
+// funding.go estimates how much money a contract needs to cover the renter's
+// expected usage, based on the prices of the contract's host.
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// hasExpectedUsage returns true if the allowance specifies the renter's
+// expected usage.
+func hasExpectedUsage(a modules.Allowance) bool {
+	return a.ExpectedStorage != 0 || a.ExpectedUpload != 0 || a.ExpectedDownload != 0
+}
+
+// contractFunding estimates the funding that a contract with the host needs
+// to cover the host's share of the expected usage between blockHeight and
+// endHeight. Every host stores an equal share of the redundant data, while
+// downloads only fetch the original data. Bandwidth is scaled by the length of
+// the contract relative to the period. The estimate includes the contract
+// price and the siafund fee, and adds a buffer of one third to the cost of
+// storage and bandwidth.
+func contractFunding(a modules.Allowance, host modules.HostDBEntry, blockHeight, endHeight types.BlockHeight) types.Currency {
+	if endHeight <= blockHeight || a.Hosts == 0 || a.Period == 0 {
+		return host.ContractPrice
+	}
+	redundancy := a.ExpectedRedundancy
+	if redundancy <= 0 {
+		redundancy = defaultExpectedRedundancy
+	}
+	duration := uint64(endHeight - blockHeight)
+	storage := uint64(float64(a.ExpectedStorage)*redundancy) / a.Hosts
+	upload := uint64(float64(a.ExpectedUpload)*redundancy) / a.Hosts
+	download := a.ExpectedDownload / a.Hosts
+
+	storageCost := host.StoragePrice.Mul64(storage).Mul64(duration)
+	uploadCost := host.UploadBandwidthPrice.Mul64(upload).Mul64(duration).Div64(uint64(a.Period))
+	downloadCost := host.DownloadBandwidthPrice.Mul64(download).Mul64(duration).Div64(uint64(a.Period))
+	renterFunds := storageCost.Add(uploadCost).Add(downloadCost)
+	renterFunds = renterFunds.Add(renterFunds.Div64(3))
+
+	// The siafund fee is paid on both the renter's funds and the collateral
+	// that the host puts up for the stored data.
+	collateral := host.Collateral.Mul64(storage).Mul64(duration)
+	if collateral.Cmp(host.MaxCollateral) > 0 {
+		collateral = host.MaxCollateral
+	}
+	if collateral.Cmp(maxCollateral) > 0 {
+		collateral = maxCollateral
+	}
+	siafundFee := types.Tax(blockHeight, renterFunds.Add(collateral))
+	return renterFunds.Add(host.ContractPrice).Add(siafundFee)
+}
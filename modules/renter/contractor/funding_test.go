@@ -0,0 +1,61 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestContractFunding checks that contracts are funded according to the
+// expected usage and the prices of their host.
+func TestContractFunding(t *testing.T) {
+	a := modules.Allowance{
+		Hosts:              10,
+		Period:             100,
+		ExpectedStorage:    1e9,
+		ExpectedUpload:     1e9,
+		ExpectedDownload:   1e9,
+		ExpectedRedundancy: 2,
+	}
+	var host modules.HostDBEntry
+	host.ContractPrice = types.SiacoinPrecision
+	host.StoragePrice = types.NewCurrency64(1)
+	host.UploadBandwidthPrice = types.NewCurrency64(1)
+	host.DownloadBandwidthPrice = types.NewCurrency64(1)
+
+	// Without collateral the funding consists of the contract price, the
+	// siafund fee and the cost of storage and bandwidth plus a buffer.
+	funding := contractFunding(a, host, 0, 100)
+	renterFunds := types.NewCurrency64(2e8*100 + 2e8 + 1e8)
+	renterFunds = renterFunds.Add(renterFunds.Div64(3))
+	expected := renterFunds.Add(host.ContractPrice).Add(types.Tax(0, renterFunds))
+	if !funding.Equals(expected) {
+		t.Fatalf("expected funding of %v, got %v", expected, funding)
+	}
+
+	// A more expensive host receives more funding.
+	expensive := host
+	expensive.StoragePrice = types.NewCurrency64(2)
+	if contractFunding(a, expensive, 0, 100).Cmp(funding) <= 0 {
+		t.Fatal("expensive host did not receive more funding")
+	}
+
+	// A longer contract receives more funding.
+	if contractFunding(a, host, 0, 200).Cmp(funding) <= 0 {
+		t.Fatal("longer contract did not receive more funding")
+	}
+
+	// Collateral increases the siafund fee, but is capped by the host.
+	host.Collateral = types.NewCurrency64(1)
+	host.MaxCollateral = types.NewCurrency64(1e9)
+	withCollateral := contractFunding(a, host, 0, 100)
+	if !withCollateral.Equals(expected.Add(types.Tax(0, renterFunds.Add(host.MaxCollateral))).Sub(types.Tax(0, renterFunds))) {
+		t.Fatal("collateral was not capped:", withCollateral)
+	}
+
+	// A contract that has already ended only needs the contract price.
+	if !contractFunding(a, host, 100, 100).Equals(host.ContractPrice) {
+		t.Fatal("expected only the contract price for an empty duration")
+	}
+}
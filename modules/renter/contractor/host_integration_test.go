@@ -3,6 +3,7 @@ package contractor
 import (
 	"bytes"
 	"errors"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -312,6 +313,471 @@ func TestIntegrationUploadDownload(t *testing.T) {
 	}
 }
 
+// TestIntegrationSession tests that the renter can upload, download and
+// request the host's settings within a single session.
+func TestIntegrationSession(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// create testing trio
+	h, c, _, err := newTestingTrio(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer c.Close()
+
+	// get the host's entry from the db
+	hostEntry, ok := c.hdb.Host(h.PublicKey())
+	if !ok {
+		t.Fatal("no entry for host in db")
+	}
+
+	// form a contract with the host
+	contract, err := c.managedNewContract(hostEntry, types.SiacoinPrecision.Mul64(50), c.blockHeight+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// open a session with the host
+	s, err := c.staticContracts.NewSession(hostEntry, contract.ID, c.blockHeight, c.hdb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// upload two sectors
+	data := fastrand.Bytes(int(modules.SectorSize))
+	_, root, err := s.Upload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2 := fastrand.Bytes(int(modules.SectorSize))
+	_, root2, err := s.Upload(data2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// raise the host's download price; the host rejects payments below its
+	// current price, so later downloads only succeed if the session uses
+	// the settings it received
+	settings := h.InternalSettings()
+	settings.MinDownloadBandwidthPrice = hostEntry.DownloadBandwidthPrice.Mul64(2).Add(types.NewCurrency64(1))
+	if err := h.SetInternalSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	newHost, err := s.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newHost.DownloadBandwidthPrice.Equals(settings.MinDownloadBandwidthPrice) {
+		t.Fatalf("expected download price %v, got %v", settings.MinDownloadBandwidthPrice, newHost.DownloadBandwidthPrice)
+	}
+
+	// download part of the first sector
+	action := modules.DownloadAction{
+		MerkleRoot: root,
+		Offset:     2 * crypto.SegmentSize,
+		Length:     3 * crypto.SegmentSize,
+	}
+	_, sections, err := s.Download([]modules.DownloadAction{action})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 1 || !bytes.Equal(sections[0], data[action.Offset:][:action.Length]) {
+		t.Fatal("downloaded section does not match original")
+	}
+
+	// download the second sector
+	_, retrieved, err := s.Sector(root2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(retrieved, data2) {
+		t.Fatal("downloaded data does not match original")
+	}
+}
+
+// TestIntegrationSessionFallback tests that editors and downloaders fall back
+// to the older revision RPCs if a host advertises sessions but rejects them.
+func TestIntegrationSessionFallback(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// create testing trio
+	h, c, _, err := newTestingTrio(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer c.Close()
+
+	// get the host's entry from the db; the host should advertise sessions
+	hostEntry, ok := c.hdb.Host(h.PublicKey())
+	if !ok {
+		t.Fatal("no entry for host in db")
+	}
+	if !hostEntry.Sessions {
+		t.Fatal("host doesn't advertise sessions")
+	}
+
+	// form a contract with the host
+	contract, err := c.managedNewContract(hostEntry, types.SiacoinPrecision.Mul64(50), c.blockHeight+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// put a proxy in front of the host that closes the connection when a
+	// session is requested, like a host that doesn't know the RPC
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var id types.Specifier
+				if err := encoding.ReadObject(conn, &id, 16); err != nil || id == modules.RPCSession {
+					return
+				}
+				hostConn, err := net.Dial("tcp", string(hostEntry.NetAddress))
+				if err != nil {
+					return
+				}
+				defer hostConn.Close()
+				if err := encoding.WriteObject(hostConn, id); err != nil {
+					return
+				}
+				go io.Copy(hostConn, conn)
+				io.Copy(conn, hostConn)
+			}()
+		}
+	}()
+	proxyEntry := hostEntry
+	proxyEntry.NetAddress = modules.NetAddress(l.Addr().String())
+	proxyEntry.EncryptedTransport = false
+
+	// upload and download a sector through the proxy
+	editor, err := c.staticContracts.NewEditor(proxyEntry, contract.ID, c.blockHeight, c.hdb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(int(modules.SectorSize))
+	_, root, err := editor.Upload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor.Close()
+	downloader, err := c.staticContracts.NewDownloader(proxyEntry, contract.ID, c.hdb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, retrieved, err := downloader.Sector(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloader.Close()
+	if !bytes.Equal(retrieved, data) {
+		t.Fatal("downloaded data does not match original")
+	}
+
+	// the host should only have served the older RPCs
+	metrics := h.NetworkMetrics()
+	if metrics.SessionCalls != 0 || metrics.ReviseCalls == 0 || metrics.DownloadCalls == 0 {
+		t.Fatalf("expected only revise and download calls, got %+v", metrics)
+	}
+}
+
+// TestIntegrationEncryptedTransport tests that a renter and a host of the
+// current version negotiate the encrypted transport.
+func TestIntegrationEncryptedTransport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// create testing trio
+	h, c, _, err := newTestingTrio(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer c.Close()
+
+	// get the host's entry from the db; the host should advertise the
+	// encrypted transport
+	hostEntry, ok := c.hdb.Host(h.PublicKey())
+	if !ok {
+		t.Fatal("no entry for host in db")
+	}
+	if !hostEntry.EncryptedTransport {
+		t.Fatal("host did not advertise the encrypted transport")
+	}
+	if hostEntry.Version != build.Version {
+		t.Fatalf("expected host version %v, got %v", build.Version, hostEntry.Version)
+	}
+
+	// request the host's settings through the encrypted transport
+	var pk crypto.PublicKey
+	copy(pk[:], hostEntry.PublicKey.Key)
+	conn, err := net.Dial("tcp", string(hostEntry.NetAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := encoding.WriteObject(conn, modules.RPCEncryptedTransport); err != nil {
+		t.Fatal(err)
+	}
+	encConn, err := modules.RenterTransportHandshake(conn, hostEntry.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := encoding.WriteObject(encConn, modules.RPCSettings); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := modules.ReadSignedHostSettings(encConn, modules.NegotiateMaxHostExternalSettingsLen, pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.NetAddress != hostEntry.NetAddress {
+		t.Fatalf("expected net address %v, got %v", hostEntry.NetAddress, settings.NetAddress)
+	}
+
+	// the handshake should fail if the host doesn't sign it with the
+	// expected key
+	conn2, err := net.Dial("tcp", string(hostEntry.NetAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	if err := encoding.WriteObject(conn2, modules.RPCEncryptedTransport); err != nil {
+		t.Fatal(err)
+	}
+	wrongKey := types.SiaPublicKey{
+		Algorithm: types.SignatureEd25519,
+		Key:       fastrand.Bytes(crypto.PublicKeySize),
+	}
+	if _, err := modules.RenterTransportHandshake(conn2, wrongKey); err == nil {
+		t.Fatal("expected handshake with the wrong host key to fail")
+	}
+
+	// form a contract, upload and download over the encrypted transport
+	contract, err := c.managedNewContract(hostEntry, types.SiacoinPrecision.Mul64(50), c.blockHeight+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor, err := c.Editor(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(int(modules.SectorSize))
+	root, err := editor.Upload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor.Close()
+	downloader, err := c.Downloader(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer downloader.Close()
+	retrieved, err := downloader.Sector(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, retrieved) {
+		t.Fatal("downloaded data does not match original")
+	}
+}
+
+// TestIntegrationUploadBatch tests that the contractor uploads batches of
+// sectors in as many revisions as the host requires, and that the roots of
+// the uploaded sectors are returned if a revision fails.
+func TestIntegrationUploadBatch(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// create testing trio
+	h, c, _, err := newTestingTrio(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer c.Close()
+
+	// get the host's entry from the db
+	hostEntry, ok := c.hdb.Host(h.PublicKey())
+	if !ok {
+		t.Fatal("no entry for host in db")
+	}
+
+	// form a contract with the host
+	contract, err := c.managedNewContract(hostEntry, types.SiacoinPrecision.Mul64(50), c.blockHeight+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only allow two sectors per revision
+	settings := h.InternalSettings()
+	settings.MaxReviseBatchSize = 2 * (modules.SectorSize + 64)
+	if err := h.SetInternalSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	// upload a batch that needs several revisions
+	editor, err := c.Editor(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectors := make([][]byte, 5)
+	for i := range sectors {
+		sectors[i] = fastrand.Bytes(int(modules.SectorSize))
+	}
+	roots, err := editor.UploadBatch(sectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor.Close()
+	if len(roots) != len(sectors) {
+		t.Fatalf("expected %v roots, got %v", len(sectors), len(roots))
+	}
+	downloader, err := c.Downloader(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, root := range roots {
+		retrieved, err := downloader.Sector(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(retrieved, sectors[i]) {
+			t.Fatal("downloaded data does not match original")
+		}
+	}
+	downloader.Close()
+
+	// raise the host's storage price, so that the contract runs out of funds
+	// during the next batch
+	settings.MinStoragePrice = types.SiacoinPrecision.Mul64(5).Div64(modules.SectorSize * 100)
+	if err := h.SetInternalSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	editor, err = c.Editor(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer editor.Close()
+	sectors = make([][]byte, 40)
+	for i := range sectors {
+		sectors[i] = fastrand.Bytes(int(modules.SectorSize))
+	}
+	roots, err = editor.UploadBatch(sectors)
+	if err == nil {
+		t.Fatal("expected upload to fail")
+	}
+	if len(roots) == 0 || len(roots) >= len(sectors) {
+		t.Fatalf("expected the roots of some sectors, got %v", len(roots))
+	}
+	for i, root := range roots {
+		if root != crypto.MerkleRoot(sectors[i]) {
+			t.Fatal("wrong root for sector", i)
+		}
+	}
+}
+
+// TestIntegrationReplaceBatch tests that the contractor can overwrite sectors
+// without growing the contract.
+func TestIntegrationReplaceBatch(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// create testing trio
+	h, c, _, err := newTestingTrio(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer c.Close()
+
+	// get the host's entry from the db
+	hostEntry, ok := c.hdb.Host(h.PublicKey())
+	if !ok {
+		t.Fatal("no entry for host in db")
+	}
+
+	// form a contract with the host
+	contract, err := c.managedNewContract(hostEntry, types.SiacoinPrecision.Mul64(50), c.blockHeight+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// upload three sectors and replace two of them
+	editor, err := c.Editor(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectors := make([][]byte, 3)
+	for i := range sectors {
+		sectors[i] = fastrand.Bytes(int(modules.SectorSize))
+	}
+	roots, err := editor.UploadBatch(sectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, _ := c.staticContracts.View(contract.ID)
+	newSectors := [][]byte{fastrand.Bytes(int(modules.SectorSize)), fastrand.Bytes(int(modules.SectorSize))}
+	newRoots, err := editor.ReplaceBatch([]crypto.Hash{roots[2], roots[0]}, newSectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor.Close()
+	after, _ := c.staticContracts.View(contract.ID)
+	if after.Transaction.FileContractRevisions[0].NewFileSize != before.Transaction.FileContractRevisions[0].NewFileSize {
+		t.Fatal("contract size changed")
+	} else if !after.StorageSpending.Equals(before.StorageSpending) {
+		t.Fatal("storage was paid for")
+	}
+
+	// the replaced sectors are gone, the others can be downloaded
+	downloader, err := c.Downloader(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[crypto.Hash][]byte{roots[1]: sectors[1], newRoots[0]: newSectors[0], newRoots[1]: newSectors[1]}
+	for root, data := range expected {
+		retrieved, err := downloader.Sector(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(retrieved, data) {
+			t.Fatal("downloaded data does not match original")
+		}
+	}
+	if _, err := downloader.Sector(roots[0]); err == nil {
+		t.Fatal("replaced sector was downloaded")
+	}
+	downloader.Close()
+
+	// a sector that isn't stored can't be replaced
+	editor, err = c.Editor(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer editor.Close()
+	if _, err := editor.ReplaceBatch([]crypto.Hash{roots[0]}, newSectors[:1]); err == nil {
+		t.Fatal("expected replacing a missing sector to fail")
+	}
+}
+
 // TestIntegrationRenew tests that the contractor can renew a previously-
 // formed file contract.
 func TestIntegrationRenew(t *testing.T) {
@@ -0,0 +1,247 @@
+package contractor
+
+// manualcontracts.go lets the user form, cancel and renew individual contracts
+// instead of waiting for the contract maintenance to do so. The operations use
+// the same negotiation code as the contract maintenance, and they never spend
+// more than the unallocated funds of the allowance.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errAllowanceNotSet         = errors.New("an allowance must be set to manage contracts")
+	errContractCanceled        = errors.New("contract has been canceled")
+	errContractNotFound        = errors.New("no record of that contract")
+	errContractNotGoodForRenew = errors.New("contract is not good for renew")
+	errContractRenewing        = errors.New("contract is already being renewed")
+	errFundingExceedsAllowance = errors.New("funding exceeds the unallocated funds of the allowance")
+	errHostFiltered            = errors.New("host is excluded by the hostdb's filter mode")
+	errHostHasContract         = errors.New("already have a contract with that host")
+	errHostNotFound            = errors.New("no record of that host")
+)
+
+// managedLockMaintenance stops any running contract maintenance and prevents
+// new maintenance from starting until the returned function is called.
+func (c *Contractor) managedLockMaintenance() func() {
+	c.managedInterruptContractMaintenance()
+	c.maintenanceLock.Lock()
+	return c.maintenanceLock.Unlock
+}
+
+// managedUnallocatedFunds returns the funds of the allowance that have not been
+// allocated to contracts during the current period.
+func (c *Contractor) managedUnallocatedFunds() types.Currency {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	allocated := c.readlockPeriodSpending().TotalAllocated
+	if c.allowance.Funds.Cmp(allocated) <= 0 {
+		return types.ZeroCurrency
+	}
+	return c.allowance.Funds.Sub(allocated)
+}
+
+// managedDefaultFunding returns the funding that the contract maintenance
+// would use for a contract with the host that ends at endHeight.
+func (c *Contractor) managedDefaultFunding(host modules.HostDBEntry, endHeight types.BlockHeight) types.Currency {
+	c.mu.RLock()
+	allowance, blockHeight := c.allowance, c.blockHeight
+	c.mu.RUnlock()
+	if hasExpectedUsage(allowance) {
+		return contractFunding(allowance, host, blockHeight, endHeight)
+	}
+	return allowance.Funds.Div64(allowance.Hosts).Div64(3)
+}
+
+// managedCheckFunding returns the funding to use for a contract with the host.
+// If funding is zero, the contract is funded the same way the contract
+// maintenance would fund it.
+func (c *Contractor) managedCheckFunding(host modules.HostDBEntry, funding types.Currency, endHeight types.BlockHeight) (types.Currency, error) {
+	if funding.IsZero() {
+		funding = c.managedDefaultFunding(host, endHeight)
+	}
+	if funding.Cmp(c.managedUnallocatedFunds()) > 0 {
+		return types.ZeroCurrency, errFundingExceedsAllowance
+	}
+	return funding, nil
+}
+
+// FormContract forms a contract with the host, bypassing the host selection of
+// the contract maintenance. The contract ends with the current period. If
+// funding is zero, the contract is funded the same way the contract
+// maintenance would fund it.
+func (c *Contractor) FormContract(spk types.SiaPublicKey, funding types.Currency) (modules.RenterContract, error) {
+	if err := c.tg.Add(); err != nil {
+		return modules.RenterContract{}, err
+	}
+	defer c.tg.Done()
+	defer c.managedLockMaintenance()()
+
+	c.mu.RLock()
+	allowance := c.allowance
+	endHeight := c.contractEndHeight()
+	c.mu.RUnlock()
+	if isCancelAllowance(allowance) {
+		return modules.RenterContract{}, errAllowanceNotSet
+	}
+
+	// Check that the host is usable and that we don't have a contract with it
+	// yet.
+	for _, contract := range c.staticContracts.ViewAll() {
+		if contract.HostPublicKey.String() == spk.String() {
+			return modules.RenterContract{}, errHostHasContract
+		}
+	}
+	host, ok := c.hdb.Host(spk)
+	if !ok {
+		return modules.RenterContract{}, errHostNotFound
+	}
+	if c.hdb.IsFiltered(spk) {
+		return modules.RenterContract{}, errHostFiltered
+	}
+	funding, err := c.managedCheckFunding(host, funding, endHeight)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+
+	// Form the contract and add it to the contractor.
+	contract, err := c.managedNewContract(host, funding, endHeight)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	err = c.managedUpdateContractUtility(contract.ID, modules.ContractUtility{
+		GoodForUpload: true,
+		GoodForRenew:  true,
+	})
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	c.mu.Lock()
+	err = c.saveSync()
+	c.mu.Unlock()
+	return contract, err
+}
+
+// CancelContract marks the contract as canceled. A canceled contract is
+// neither used for uploads nor renewed, so the renter migrates its data to
+// other hosts and the contract expires at the end of the period.
+func (c *Contractor) CancelContract(id types.FileContractID) error {
+	if err := c.tg.Add(); err != nil {
+		return err
+	}
+	defer c.tg.Done()
+	// The ID is resolved after the maintenance has been stopped, so that the
+	// contract can't be renewed before it is marked as canceled.
+	defer c.managedLockMaintenance()()
+
+	c.mu.RLock()
+	id = c.readlockResolveID(id)
+	c.mu.RUnlock()
+	if _, ok := c.staticContracts.View(id); !ok {
+		return errContractNotFound
+	}
+
+	c.mu.Lock()
+	c.canceledContracts[id] = struct{}{}
+	err := c.saveSync()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	c.log.Println("INFO: canceled contract", id)
+	return c.managedUpdateContractUtility(id, modules.ContractUtility{
+		GoodForUpload: false,
+		GoodForRenew:  false,
+	})
+}
+
+// RenewContract renews the contract immediately instead of waiting for its
+// renew window. The renewed contract ends with the current period, or with the
+// next period if the contract already does. If funding is zero, the contract is
+// funded the same way the contract maintenance would fund it.
+func (c *Contractor) RenewContract(id types.FileContractID, funding types.Currency) (modules.RenterContract, error) {
+	if err := c.tg.Add(); err != nil {
+		return modules.RenterContract{}, err
+	}
+	defer c.tg.Done()
+	defer c.managedLockMaintenance()()
+
+	c.mu.RLock()
+	id = c.readlockResolveID(id)
+	allowance := c.allowance
+	endHeight := c.contractEndHeight()
+	_, canceled := c.canceledContracts[id]
+	c.mu.RUnlock()
+	if isCancelAllowance(allowance) {
+		return modules.RenterContract{}, errAllowanceNotSet
+	} else if canceled {
+		return modules.RenterContract{}, errContractCanceled
+	}
+	contract, ok := c.staticContracts.View(id)
+	if !ok {
+		return modules.RenterContract{}, errContractNotFound
+	} else if !contract.Utility.GoodForRenew {
+		return modules.RenterContract{}, errContractNotGoodForRenew
+	}
+	if contract.EndHeight >= endHeight {
+		endHeight += allowance.Period - allowance.RenewWindow
+	}
+	host, ok := c.hdb.Host(contract.HostPublicKey)
+	if !ok {
+		return modules.RenterContract{}, errHostNotFound
+	}
+	funding, err := c.managedCheckFunding(host, funding, endHeight)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+
+	// Mark the contract as being renewed, and defer logic to unmark it once
+	// renewing is complete.
+	c.mu.Lock()
+	if c.renewing[id] {
+		c.mu.Unlock()
+		return modules.RenterContract{}, errContractRenewing
+	}
+	c.renewing[id] = true
+	e, eok := c.editors[id]
+	d, dok := c.downloaders[id]
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.renewing, id)
+		c.mu.Unlock()
+	}()
+
+	// Wait for any active editors and downloaders to finish for this contract,
+	// and then grab the latest revision.
+	if eok {
+		e.invalidate()
+	}
+	if dok {
+		d.invalidate()
+	}
+	oldContract, ok := c.staticContracts.Acquire(id)
+	if !ok {
+		return modules.RenterContract{}, errContractNotFound
+	}
+	oldUtility := oldContract.Utility()
+	if !oldUtility.GoodForRenew {
+		c.staticContracts.Return(oldContract)
+		return modules.RenterContract{}, errContractNotGoodForRenew
+	}
+
+	// Renew the contract and replace the old contract with the new one.
+	newContract, err := c.managedRenew(oldContract, funding, endHeight)
+	if err != nil {
+		c.staticContracts.Return(oldContract)
+		return modules.RenterContract{}, err
+	}
+	c.log.Printf("Manually renewed contract %v\n", id)
+	if err := c.managedReplaceRenewedContract(oldContract, oldUtility, newContract, false); err != nil {
+		return modules.RenterContract{}, err
+	}
+	return newContract, nil
+}
@@ -21,6 +21,14 @@ type contractorPersist struct {
 	LastChange    modules.ConsensusChangeID `json:"lastchange"`
 	OldContracts  []modules.RenterContract  `json:"oldcontracts"`
 	RenewedIDs    map[string]string         `json:"renewedids"`
+	RefreshedIDs  map[string]string         `json:"refreshedids"`
+	PeriodRefunds types.Currency            `json:"periodrefunds"`
+
+	CanceledContracts []types.FileContractID `json:"canceledcontracts"`
+
+	SpendingHistory []modules.ContractorPeriodSpending `json:"spendinghistory"`
+
+	AcknowledgedAlerts []modules.AlertID `json:"acknowledgedalerts"`
 }
 
 // persistData returns the data in the Contractor that will be saved to disk.
@@ -30,7 +38,13 @@ func (c *Contractor) persistData() contractorPersist {
 		BlockHeight:   c.blockHeight,
 		CurrentPeriod: c.currentPeriod,
 		LastChange:    c.lastChange,
+		PeriodRefunds: c.periodRefunds,
 		RenewedIDs:    make(map[string]string),
+		RefreshedIDs:  make(map[string]string),
+
+		SpendingHistory: c.spendingHistory,
+
+		AcknowledgedAlerts: c.staticAlerter.Acknowledged(),
 	}
 	for _, contract := range c.oldContracts {
 		data.OldContracts = append(data.OldContracts, contract)
@@ -38,6 +52,12 @@ func (c *Contractor) persistData() contractorPersist {
 	for oldID, newID := range c.renewedIDs {
 		data.RenewedIDs[oldID.String()] = newID.String()
 	}
+	for newID, oldID := range c.refreshedIDs {
+		data.RefreshedIDs[newID.String()] = oldID.String()
+	}
+	for id := range c.canceledContracts {
+		data.CanceledContracts = append(data.CanceledContracts, id)
+	}
 	return data
 }
 
@@ -52,6 +72,8 @@ func (c *Contractor) load() error {
 	c.blockHeight = data.BlockHeight
 	c.currentPeriod = data.CurrentPeriod
 	c.lastChange = data.LastChange
+	c.periodRefunds = data.PeriodRefunds
+	c.spendingHistory = data.SpendingHistory
 	for _, contract := range data.OldContracts {
 		c.oldContracts[contract.ID] = contract
 	}
@@ -61,6 +83,16 @@ func (c *Contractor) load() error {
 		newHash.LoadString(newString)
 		c.renewedIDs[types.FileContractID(oldHash)] = types.FileContractID(newHash)
 	}
+	for newString, oldString := range data.RefreshedIDs {
+		var newHash, oldHash crypto.Hash
+		newHash.LoadString(newString)
+		oldHash.LoadString(oldString)
+		c.refreshedIDs[types.FileContractID(newHash)] = types.FileContractID(oldHash)
+	}
+	for _, id := range data.CanceledContracts {
+		c.canceledContracts[id] = struct{}{}
+	}
+	c.staticAlerter.SetAcknowledged(data.AcknowledgedAlerts)
 
 	return nil
 }
@@ -22,7 +22,8 @@ func (m memPersist) load(data *contractorPersist) error { *data = contractorPers
 func TestSaveLoad(t *testing.T) {
 	// create contractor with mocked persist dependency
 	c := &Contractor{
-		persist: new(memPersist),
+		persist:       new(memPersist),
+		staticAlerter: modules.NewAlerter("contractor"),
 	}
 
 	c.renewedIDs = map[types.FileContractID]types.FileContractID{
@@ -30,6 +31,17 @@ func TestSaveLoad(t *testing.T) {
 		{1}: {2},
 		{2}: {3},
 	}
+	c.refreshedIDs = map[types.FileContractID]types.FileContractID{
+		{3}: {2},
+	}
+	c.canceledContracts = map[types.FileContractID]struct{}{
+		{3}: {},
+	}
+	c.spendingHistory = []modules.ContractorPeriodSpending{
+		{StartHeight: 10, EndHeight: 20, Refunded: types.NewCurrency64(30)},
+	}
+	c.staticAlerter.RegisterAlert("foo", "foo", "", modules.SeverityWarning)
+	c.staticAlerter.AcknowledgeAlert("foo")
 	c.oldContracts = map[types.FileContractID]modules.RenterContract{
 		{0}: {ID: types.FileContractID{0}, HostPublicKey: types.SiaPublicKey{Key: []byte("foo")}},
 		{1}: {ID: types.FileContractID{1}, HostPublicKey: types.SiaPublicKey{Key: []byte("bar")}},
@@ -43,7 +55,11 @@ func TestSaveLoad(t *testing.T) {
 	}
 	c.hdb = stubHostDB{}
 	c.renewedIDs = make(map[types.FileContractID]types.FileContractID)
+	c.refreshedIDs = make(map[types.FileContractID]types.FileContractID)
+	c.canceledContracts = make(map[types.FileContractID]struct{})
 	c.oldContracts = make(map[types.FileContractID]modules.RenterContract)
+	c.spendingHistory = nil
+	c.staticAlerter = modules.NewAlerter("contractor")
 	err = c.load()
 	if err != nil {
 		t.Fatal(err)
@@ -55,6 +71,18 @@ func TestSaveLoad(t *testing.T) {
 	if !ok0 || !ok1 || !ok2 {
 		t.Fatal("renewed IDs were not restored properly:", c.renewedIDs)
 	}
+	if c.refreshedIDs[types.FileContractID{3}] != (types.FileContractID{2}) {
+		t.Fatal("refreshed IDs were not restored properly:", c.refreshedIDs)
+	}
+	if _, ok := c.canceledContracts[types.FileContractID{3}]; !ok {
+		t.Fatal("canceled contracts were not restored properly:", c.canceledContracts)
+	}
+	if len(c.spendingHistory) != 1 || c.spendingHistory[0].StartHeight != 10 || !c.spendingHistory[0].Refunded.Equals64(30) {
+		t.Fatal("spending history was not restored properly:", c.spendingHistory)
+	}
+	if acked := c.staticAlerter.Acknowledged(); len(acked) != 1 || acked[0] != "foo" {
+		t.Fatal("acknowledged alerts were not restored properly:", acked)
+	}
 	_, ok0 = c.oldContracts[types.FileContractID{0}]
 	_, ok1 = c.oldContracts[types.FileContractID{1}]
 	_, ok2 = c.oldContracts[types.FileContractID{2}]
@@ -72,7 +100,10 @@ func TestSaveLoad(t *testing.T) {
 		t.Fatal(err)
 	}
 	c.renewedIDs = make(map[types.FileContractID]types.FileContractID)
+	c.refreshedIDs = make(map[types.FileContractID]types.FileContractID)
+	c.canceledContracts = make(map[types.FileContractID]struct{})
 	c.oldContracts = make(map[types.FileContractID]modules.RenterContract)
+	c.spendingHistory = nil
 	err = c.load()
 	if err != nil {
 		t.Fatal(err)
@@ -84,6 +115,15 @@ func TestSaveLoad(t *testing.T) {
 	if !ok0 || !ok1 || !ok2 {
 		t.Fatal("renewed IDs were not restored properly:", c.renewedIDs)
 	}
+	if c.refreshedIDs[types.FileContractID{3}] != (types.FileContractID{2}) {
+		t.Fatal("refreshed IDs were not restored properly:", c.refreshedIDs)
+	}
+	if _, ok := c.canceledContracts[types.FileContractID{3}]; !ok {
+		t.Fatal("canceled contracts were not restored properly:", c.canceledContracts)
+	}
+	if len(c.spendingHistory) != 1 || c.spendingHistory[0].StartHeight != 10 || !c.spendingHistory[0].Refunded.Equals64(30) {
+		t.Fatal("spending history was not restored properly:", c.spendingHistory)
+	}
 	_, ok0 = c.oldContracts[types.FileContractID{0}]
 	_, ok1 = c.oldContracts[types.FileContractID{1}]
 	_, ok2 = c.oldContracts[types.FileContractID{2}]
@@ -0,0 +1,149 @@
+package contractor
+
+// planner.go simulates the contract formation of the contract maintenance for
+// a proposed allowance. It selects hosts and funds contracts the same way the
+// maintenance would, but it never negotiates with hosts or touches the
+// wallet, so it can be used to preview an allowance before setting it.
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// planContract calculates the fees and the storage of a contract with the
+// host that is formed with the specified funding and transaction fee. It
+// mirrors the payouts that the proto package creates when forming a contract.
+// false is returned if the funding cannot cover the fees of the contract.
+func planContract(host modules.HostDBEntry, funding, txnFee types.Currency, startHeight, endHeight types.BlockHeight) (modules.PlannedContract, bool) {
+	if funding.Cmp(host.ContractPrice.Add(txnFee)) <= 0 || endHeight <= startHeight {
+		return modules.PlannedContract{}, false
+	}
+	storagePrice := host.StoragePrice
+	if storagePrice.IsZero() {
+		storagePrice = types.NewCurrency64(1)
+	}
+
+	// The siafund fee is paid on the whole payout of the contract, which
+	// includes the host's collateral, and it is deducted from the renter's
+	// payout.
+	renterPayout := funding.Sub(host.ContractPrice).Sub(txnFee)
+	collateral := renterPayout.Div(storagePrice).Mul(host.Collateral)
+	if collateral.Cmp(host.MaxCollateral) > 0 {
+		collateral = host.MaxCollateral
+	}
+	siafundFee := types.Tax(startHeight, renterPayout.Add(collateral).Add(host.ContractPrice))
+	if siafundFee.Cmp(renterPayout) >= 0 {
+		return modules.PlannedContract{}, false
+	}
+
+	storage, err := renterPayout.Sub(siafundFee).Div(storagePrice).Div64(uint64(endHeight - startHeight)).Uint64()
+	if err != nil {
+		storage = math.MaxUint64
+	}
+	return modules.PlannedContract{
+		HostPublicKey: host.PublicKey,
+		NetAddress:    host.NetAddress,
+		Funding:       funding,
+		ContractPrice: host.ContractPrice,
+		TxnFee:        txnFee,
+		SiafundFee:    siafundFee,
+		StoragePrice:  host.StoragePrice,
+		Storage:       storage,
+	}, true
+}
+
+// PlanAllowance returns the contracts that the contract maintenance would form
+// if the allowance was set, using the hosts that are currently in the hostdb.
+// The plan assumes that the renter does not have any contracts yet. Since
+// hosts are selected randomly, consecutive plans may choose different hosts.
+func (c *Contractor) PlanAllowance(a modules.Allowance) (modules.AllowancePlan, error) {
+	if err := c.tg.Add(); err != nil {
+		return modules.AllowancePlan{}, err
+	}
+	defer c.tg.Done()
+	if err := validateAllowance(a); err != nil {
+		return modules.AllowancePlan{}, err
+	}
+
+	// The period of a new allowance begins at the current height, otherwise
+	// the contracts end with the current period.
+	c.mu.RLock()
+	blockHeight := c.blockHeight
+	endHeight := c.currentPeriod + a.Period
+	if reflect.DeepEqual(c.allowance, modules.Allowance{}) {
+		endHeight = blockHeight + a.Period
+	}
+	c.mu.RUnlock()
+	_, maxFee := c.tpool.FeeEstimation()
+	txnFee := maxFee.Mul64(estFormationTxnSize)
+
+	hosts, err := c.hdb.RandomHosts(int(a.Hosts)*2+randomHostsBufferForScore, nil)
+	if err != nil {
+		return modules.AllowancePlan{}, err
+	}
+
+	plan := modules.AllowancePlan{
+		EndHeight:  endHeight,
+		Redundancy: a.ExpectedRedundancy,
+	}
+	if plan.Redundancy <= 0 {
+		plan.Redundancy = defaultExpectedRedundancy
+	}
+	reject := func(host modules.HostDBEntry, reason string) {
+		plan.RejectedHosts = append(plan.RejectedHosts, modules.RejectedHost{
+			PublicKey:   host.PublicKey,
+			NetAddress:  host.NetAddress,
+			Reason:      reason,
+			BlockHeight: blockHeight,
+		})
+	}
+	fundsAvailable := a.Funds
+	var totalStorage uint64
+	for _, host := range hosts {
+		if uint64(len(plan.Contracts)) >= a.Hosts {
+			break
+		}
+		if host.StoragePrice.Cmp(maxStoragePrice) > 0 {
+			reject(host, errTooExpensive.Error())
+			continue
+		} else if reason := a.PriceCapViolation(host.HostExternalSettings); reason != "" {
+			reject(host, reason)
+			continue
+		}
+		if host.MaxCollateral.Cmp(maxCollateral) > 0 {
+			host.MaxCollateral = maxCollateral
+		}
+
+		contractFunds := a.Funds.Div64(a.Hosts).Div64(3)
+		if hasExpectedUsage(a) {
+			contractFunds = contractFunding(a, host, blockHeight, endHeight)
+		}
+		// Like the contract maintenance, stop once the allowance cannot fund
+		// another contract.
+		if fundsAvailable.Cmp(contractFunds) < 0 {
+			break
+		}
+		pc, ok := planContract(host, contractFunds, txnFee, blockHeight, endHeight)
+		if !ok {
+			reject(host, "funding does not cover the fees of the contract")
+			continue
+		}
+		fundsAvailable = fundsAvailable.Sub(contractFunds)
+
+		plan.Contracts = append(plan.Contracts, pc)
+		plan.TotalFunding = plan.TotalFunding.Add(pc.Funding)
+		plan.TotalFees = plan.TotalFees.Add(pc.ContractPrice).Add(pc.TxnFee)
+		plan.TotalSiafundFee = plan.TotalSiafundFee.Add(pc.SiafundFee)
+		if totalStorage+pc.Storage < totalStorage {
+			totalStorage = math.MaxUint64
+		} else {
+			totalStorage += pc.Storage
+		}
+	}
+	plan.StorageCapacity = uint64(float64(totalStorage) / plan.Redundancy)
+	plan.Unallocated = fundsAvailable
+	return plan, nil
+}
@@ -0,0 +1,62 @@
+package contractor
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestPlanContract checks that planned contracts account for the fees that
+// the proto package deducts when forming a contract.
+func TestPlanContract(t *testing.T) {
+	var host modules.HostDBEntry
+	host.ContractPrice = types.NewCurrency64(1e6)
+	host.StoragePrice = types.NewCurrency64(10)
+	txnFee := types.NewCurrency64(1e5)
+	funding := types.NewCurrency64(1e9)
+
+	// Without collateral the siafund fee is paid on the renter's payout and
+	// the contract price.
+	pc, ok := planContract(host, funding, txnFee, 0, 100)
+	if !ok {
+		t.Fatal("contract could not be planned")
+	}
+	renterPayout := funding.Sub(host.ContractPrice).Sub(txnFee)
+	siafundFee := types.Tax(0, renterPayout.Add(host.ContractPrice))
+	if !pc.SiafundFee.Equals(siafundFee) {
+		t.Fatalf("expected siafund fee of %v, got %v", siafundFee, pc.SiafundFee)
+	}
+	storage, _ := renterPayout.Sub(siafundFee).Div64(10 * 100).Uint64()
+	if pc.Storage != storage {
+		t.Fatalf("expected storage of %v, got %v", storage, pc.Storage)
+	}
+	if !pc.Funding.Equals(funding) || !pc.TxnFee.Equals(txnFee) || !pc.ContractPrice.Equals(host.ContractPrice) {
+		t.Fatal("planned contract has wrong fees:", pc)
+	}
+
+	// Collateral increases the siafund fee, but is capped by the host.
+	host.Collateral = types.NewCurrency64(10)
+	host.MaxCollateral = types.NewCurrency64(1e8)
+	pc, ok = planContract(host, funding, txnFee, 0, 100)
+	if !ok {
+		t.Fatal("contract could not be planned")
+	}
+	siafundFee = types.Tax(0, renterPayout.Add(host.ContractPrice).Add(host.MaxCollateral))
+	if !pc.SiafundFee.Equals(siafundFee) {
+		t.Fatalf("expected siafund fee of %v, got %v", siafundFee, pc.SiafundFee)
+	}
+
+	// A longer contract stores less data for the same funding.
+	if longer, _ := planContract(host, funding, txnFee, 0, 200); longer.Storage >= pc.Storage {
+		t.Fatal("longer contract did not store less data")
+	}
+
+	// Funding that does not cover the fees cannot form a contract.
+	if _, ok := planContract(host, host.ContractPrice.Add(txnFee), txnFee, 0, 100); ok {
+		t.Fatal("contract was planned without funds for storage")
+	}
+	if _, ok := planContract(host, funding, txnFee, 100, 100); ok {
+		t.Fatal("contract was planned with an empty duration")
+	}
+}
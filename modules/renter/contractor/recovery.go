@@ -0,0 +1,211 @@
+package contractor
+
+// recovery.go recovers the contracts of the renter from the blockchain. New
+// contracts are formed with a key derived from the wallet seed, and their
+// formation transactions carry an identifier that only the seed can decode.
+// After losing its contract set, the renter can scan the blockchain for these
+// identifiers and ask the hosts for the latest revisions of the contracts.
+// Contracts can also be restored from the contract backups that are included
+// in renter snapshots.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var errRecoveryInProgress = errors.New("a recovery scan is already in progress")
+
+// recoveryScanner is subscribed to the consensus set during a recovery scan.
+// It passes every transaction of the blockchain to a proto.ContractScanner.
+type recoveryScanner struct {
+	c       *Contractor
+	height  types.BlockHeight
+	scanner *proto.ContractScanner
+}
+
+// ProcessConsensusChange scans the transactions of the applied blocks.
+func (rs *recoveryScanner) ProcessConsensusChange(cc modules.ConsensusChange) {
+	for _, block := range cc.RevertedBlocks {
+		if block.ID() != types.GenesisID {
+			rs.height--
+		}
+	}
+	for _, block := range cc.AppliedBlocks {
+		if block.ID() != types.GenesisID {
+			rs.height++
+		}
+		for _, txn := range block.Transactions {
+			rs.scanner.ScanTransaction(txn, rs.height)
+		}
+	}
+	rs.c.mu.Lock()
+	rs.c.recoveryStatus.ScannedHeight = rs.height
+	rs.c.mu.Unlock()
+}
+
+// managedRenterSeed derives the renter seed from the wallet seed. The wallet
+// must be unlocked.
+func (c *Contractor) managedRenterSeed() (proto.RenterSeed, error) {
+	walletSeed, _, err := c.wallet.PrimarySeed()
+	if err != nil {
+		return proto.RenterSeed{}, err
+	}
+	defer crypto.SecureWipe(walletSeed[:])
+	return proto.DeriveRenterSeed(walletSeed), nil
+}
+
+// ContractRecoveryStatus returns the progress of the most recent recovery
+// scan.
+func (c *Contractor) ContractRecoveryStatus() modules.ContractRecoveryStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.recoveryStatus
+}
+
+// RecoverContracts starts a scan of the blockchain for the contracts that were
+// formed with the wallet seed. Unexpired contracts that are not known to the
+// contractor are recovered from their hosts. RecoverContracts returns once the
+// scan has started; its progress is reported by ContractRecoveryStatus.
+func (c *Contractor) RecoverContracts() error {
+	if err := c.tg.Add(); err != nil {
+		return err
+	}
+	defer c.tg.Done()
+
+	renterSeed, err := c.managedRenterSeed()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.recoveryStatus.ScanInProgress {
+		c.mu.Unlock()
+		return errRecoveryInProgress
+	}
+	c.recoveryStatus = modules.ContractRecoveryStatus{ScanInProgress: true}
+	c.mu.Unlock()
+
+	go c.threadedRecoverContracts(renterSeed)
+	return nil
+}
+
+// threadedRecoverContracts scans the blockchain for the contracts of the
+// renter and recovers the contracts that are missing from the contract set.
+func (c *Contractor) threadedRecoverContracts(renterSeed proto.RenterSeed) {
+	defer func() {
+		c.mu.Lock()
+		c.recoveryStatus.ScanInProgress = false
+		c.mu.Unlock()
+	}()
+	if err := c.tg.Add(); err != nil {
+		return
+	}
+	defer c.tg.Done()
+
+	// Scan the whole blockchain. ConsensusSetSubscribe returns once the
+	// scanner has caught up with the consensus set.
+	rs := &recoveryScanner{
+		c:       c,
+		scanner: proto.NewContractScanner(renterSeed),
+	}
+	err := c.cs.ConsensusSetSubscribe(rs, modules.ConsensusChangeBeginning, c.tg.StopChan())
+	if err != nil {
+		c.log.Println("WARN: recovery scan failed:", err)
+		return
+	}
+	c.cs.Unsubscribe(rs)
+
+	c.mu.RLock()
+	blockHeight := c.blockHeight
+	c.mu.RUnlock()
+	for _, rc := range rs.scanner.Contracts() {
+		// Expired contracts don't need to be recovered.
+		if rc.EndHeight() <= blockHeight {
+			continue
+		}
+		c.mu.Lock()
+		c.recoveryStatus.ContractsFound++
+		_, archived := c.oldContracts[rc.ID]
+		c.mu.Unlock()
+		if _, exists := c.staticContracts.View(rc.ID); exists || archived {
+			continue
+		}
+
+		host, ok := c.hdb.Host(rc.HostPublicKey)
+		if !ok {
+			c.log.Printf("WARN: unable to recover contract %v: no record of host %v\n", rc.ID, rc.HostPublicKey)
+			continue
+		}
+		contract, err := c.staticContracts.RecoverContract(rc, host, c.tg.StopChan())
+		if err != nil {
+			c.log.Printf("WARN: unable to recover contract %v with %v: %v\n", rc.ID, host.NetAddress, err)
+			continue
+		}
+		c.log.Printf("Recovered contract %v with %v\n", contract.ID, host.NetAddress)
+
+		c.mu.Lock()
+		c.recoveryStatus.ContractsRecovered++
+		c.mu.Unlock()
+		c.managedAddRecoveredContract(contract)
+	}
+}
+
+// managedAddRecoveredContract updates the contractor after a contract was
+// added to the contract set by recovering or restoring it.
+func (c *Contractor) managedAddRecoveredContract(contract modules.RenterContract) {
+	// The Merkle roots of a recovered contract are unknown, so a contract
+	// that holds data is treated like a canceled contract. Its data stays
+	// available for download and migration until the contract expires.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(contract.Transaction.FileContractRevisions) > 0 && contract.Transaction.FileContractRevisions[0].NewFileSize > 0 {
+		c.canceledContracts[contract.ID] = struct{}{}
+	}
+	if err := c.saveSync(); err != nil {
+		c.log.Println("Unable to save the contractor after recovering a contract:", err)
+	}
+}
+
+// ContractBackups returns the backups of the contractor's active contracts.
+func (c *Contractor) ContractBackups() []proto.ContractBackup {
+	return c.staticContracts.Backups()
+}
+
+// RestoreContracts restores the unexpired contracts of the backups that are
+// not known to the contractor. The latest revisions of the contracts are
+// requested from their hosts. It returns the number of restored contracts.
+func (c *Contractor) RestoreContracts(backups []proto.ContractBackup) (int, error) {
+	if err := c.tg.Add(); err != nil {
+		return 0, err
+	}
+	defer c.tg.Done()
+
+	var restored int
+	for _, cb := range backups {
+		c.mu.RLock()
+		expired := cb.EndHeight() <= c.blockHeight
+		_, archived := c.oldContracts[cb.ID()]
+		c.mu.RUnlock()
+		if _, exists := c.staticContracts.View(cb.ID()); exists || archived || expired {
+			continue
+		}
+
+		host, ok := c.hdb.Host(cb.HostPublicKey())
+		if !ok {
+			c.log.Printf("WARN: unable to restore contract %v: no record of host %v\n", cb.ID(), cb.HostPublicKey())
+			continue
+		}
+		contract, err := c.staticContracts.RestoreContract(cb, host, c.tg.StopChan())
+		if err != nil {
+			c.log.Printf("WARN: unable to restore contract %v with %v: %v\n", cb.ID(), host.NetAddress, err)
+			continue
+		}
+		c.log.Printf("Restored contract %v with %v\n", contract.ID, host.NetAddress)
+		c.managedAddRecoveredContract(contract)
+		restored++
+	}
+	return restored, nil
+}
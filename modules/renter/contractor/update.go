@@ -21,6 +21,17 @@ func (c *Contractor) managedArchiveContracts() {
 			id := contract.ID
 			c.mu.Lock()
 			c.oldContracts[id] = contract
+			c.periodRefunds = c.periodRefunds.Add(contract.RenterFunds)
+			// The contracts that were refreshed into this contract are no
+			// longer part of the current period.
+			oldID, exists := c.refreshedIDs[id]
+			delete(c.refreshedIDs, id)
+			for exists {
+				previousID, ok := c.refreshedIDs[oldID]
+				delete(c.refreshedIDs, oldID)
+				oldID, exists = previousID, ok
+			}
+			delete(c.canceledContracts, id)
 			c.mu.Unlock()
 			expired = append(expired, id)
 			c.log.Println("INFO: archived expired contract", id)
@@ -61,6 +72,16 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// TODO: How to make this more explicit.
 	cycleLen := c.allowance.Period - c.allowance.RenewWindow
 	if c.blockHeight >= c.currentPeriod+cycleLen {
+		// Record the spending of the period that just ended.
+		if cycleLen > 0 {
+			c.spendingHistory = append(c.spendingHistory, modules.ContractorPeriodSpending{
+				ContractorSpending: c.readlockPeriodSpending(),
+				StartHeight:        c.currentPeriod,
+				EndHeight:          c.currentPeriod + cycleLen,
+				Refunded:           c.periodRefunds,
+			})
+			c.periodRefunds = types.ZeroCurrency
+		}
 		c.currentPeriod += cycleLen
 		// COMPATv1.0.4-lts
 		// if we were storing a special metrics contract, it will be invalid
@@ -131,6 +131,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
 	"github.com/NebulousLabs/Sia/types"
@@ -228,6 +229,15 @@ func (d *download) staticComplete() bool {
 	}
 }
 
+// staticMemoryClass returns the class of memory that the download uses.
+// Downloads feeding the /renter/stream endpoint draw from the stream reserve.
+func (d *download) staticMemoryClass() memoryClass {
+	if d.staticDestinationType == destinationTypeSeekStream {
+		return memoryClassStream
+	}
+	return memoryClassDownload
+}
+
 // Err returns the error encountered by a download, if it exists.
 func (d *download) Err() (err error) {
 	d.mu.Lock()
@@ -339,6 +349,39 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download,
 	return d, nil
 }
 
+// pieceFetchRange returns the range within each piece that needs to be
+// fetched to download the given range of a chunk. If the range lies within a
+// single piece, only that range of each piece is fetched, which saves
+// bandwidth for streaming and other small downloads. The range is extended to
+// the segments that the host sends for it anyway, so that streaming downloads
+// can cache as much of the chunk as possible. Otherwise the whole pieces are
+// fetched.
+func pieceFetchRange(fetchOffset, fetchLength, pieceSize uint64) (pieceOffset, pieceLength uint64) {
+	if fetchLength == 0 {
+		return 0, pieceSize
+	}
+	firstPiece := fetchOffset / pieceSize
+	lastPiece := (fetchOffset + fetchLength - 1) / pieceSize
+	if firstPiece != lastPiece {
+		return 0, pieceSize
+	}
+
+	// Within the sector, the encrypted piece follows the nonce.
+	start := crypto.TwofishNonceSize + fetchOffset - firstPiece*pieceSize
+	end := start + fetchLength
+	start -= start % crypto.SegmentSize
+	if end%crypto.SegmentSize != 0 {
+		end += crypto.SegmentSize - end%crypto.SegmentSize
+	}
+	if start < crypto.TwofishNonceSize {
+		start = crypto.TwofishNonceSize
+	}
+	if end > crypto.TwofishNonceSize+pieceSize {
+		end = crypto.TwofishNonceSize + pieceSize
+	}
+	return start - crypto.TwofishNonceSize, end - start
+}
+
 // managedNewDownload creates and initializes a download based on the provided
 // parameters.
 func (r *Renter) managedNewDownload(params downloadParams) (*download, error) {
@@ -455,6 +498,8 @@ func (r *Renter) managedNewDownload(params downloadParams) (*download, error) {
 		} else {
 			udc.staticFetchLength = params.file.staticChunkSize() - udc.staticFetchOffset
 		}
+		// Set the range within each piece that needs to be fetched.
+		udc.staticPieceOffset, udc.staticPieceLength = pieceFetchRange(udc.staticFetchOffset, udc.staticFetchLength, params.file.pieceSize)
 		// Set the writeOffset within the destination for where the data should
 		// be written.
 		udc.staticWriteOffset = writeOffset
@@ -0,0 +1,31 @@
+package renter
+
+import (
+	"testing"
+)
+
+// TestPieceFetchRange tests that only ranges within a single piece are
+// fetched partially, and that partial ranges are extended to the segments
+// that contain them.
+func TestPieceFetchRange(t *testing.T) {
+	const pieceSize = 1 << 12
+	tests := []struct {
+		fetchOffset, fetchLength uint64
+		pieceOffset, pieceLength uint64
+	}{
+		{0, pieceSize, 0, pieceSize},
+		{0, 10, 0, 52},
+		{100, 200, 52, 256},
+		{pieceSize + 100, 200, 52, 256},
+		{pieceSize - 96, 96, pieceSize - 140, 140},
+		{pieceSize - 100, 200, 0, pieceSize},
+		{0, 3 * pieceSize, 0, pieceSize},
+		{100, 0, 0, pieceSize},
+	}
+	for _, test := range tests {
+		offset, length := pieceFetchRange(test.fetchOffset, test.fetchLength, pieceSize)
+		if offset != test.pieceOffset || length != test.pieceLength {
+			t.Errorf("[%v, %v): expected piece range %v+%v, got %v+%v", test.fetchOffset, test.fetchOffset+test.fetchLength, test.pieceOffset, test.pieceLength, offset, length)
+		}
+	}
+}
@@ -48,6 +48,8 @@ type unfinishedDownloadChunk struct {
 	staticChunkSize   uint64
 	staticFetchLength uint64 // Length within the logical chunk to fetch.
 	staticFetchOffset uint64 // Offset within the logical chunk that is being downloaded.
+	staticPieceLength uint64 // Length within each piece to fetch.
+	staticPieceOffset uint64 // Offset within each piece that is being downloaded.
 	staticPieceSize   uint64
 	staticWriteOffset int64 // Offet within the writer to write the completed data.
 
@@ -78,6 +80,12 @@ type unfinishedDownloadChunk struct {
 	staticStreamCache *streamCache
 }
 
+// partialPieces returns true if only a range of each piece is downloaded
+// instead of the whole pieces.
+func (udc *unfinishedDownloadChunk) partialPieces() bool {
+	return udc.staticPieceLength < udc.staticPieceSize
+}
+
 // fail will set the chunk status to failed. The physical chunk memory will be
 // wiped and any memory allocation will be returned to the renter. The download
 // as a whole will be failed as well.
@@ -165,7 +173,7 @@ func (udc *unfinishedDownloadChunk) returnMemory() {
 	}
 	// Return any memory we don't need.
 	if uint64(udc.memoryAllocated) > maxMemory {
-		udc.download.memoryManager.Return(udc.memoryAllocated - maxMemory)
+		udc.download.memoryManager.Return(udc.memoryAllocated-maxMemory, udc.download.staticMemoryClass())
 		udc.memoryAllocated = maxMemory
 	}
 }
@@ -188,7 +196,13 @@ func (udc *unfinishedDownloadChunk) threadedRecoverLogicalData() error {
 		}
 
 		key := deriveKey(udc.masterKey, udc.staticChunkIndex, uint64(i))
-		decryptedPiece, err := key.DecryptBytes(udc.physicalChunkData[i])
+		var decryptedPiece []byte
+		var err error
+		if udc.partialPieces() {
+			decryptedPiece, err = key.DecryptBytesRange(udc.physicalChunkData[i], udc.staticPieceOffset)
+		} else {
+			decryptedPiece, err = key.DecryptBytes(udc.physicalChunkData[i])
+		}
 		if err != nil {
 			udc.mu.Lock()
 			udc.fail(err)
@@ -198,12 +212,24 @@ func (udc *unfinishedDownloadChunk) threadedRecoverLogicalData() error {
 		udc.physicalChunkData[i] = decryptedPiece
 	}
 
-	// Recover the pieces into the logical chunk data.
+	// Recover the pieces into the logical chunk data. If only a range of each
+	// piece was downloaded, the recovered data consists of that range of each
+	// data piece, and the fetch range lies within the range of a single data
+	// piece.
 	//
 	// TODO: Might be some way to recover into the downloadDestination instead
 	// of creating a buffer and then writing that.
+	recoverSize := udc.staticChunkSize
+	start := udc.staticFetchOffset
+	var piece uint64
+	if udc.partialPieces() {
+		piece = udc.staticFetchOffset / udc.staticPieceSize
+		recoverSize = (piece + 1) * udc.staticPieceLength
+		start = piece*udc.staticPieceLength + udc.staticFetchOffset - piece*udc.staticPieceSize - udc.staticPieceOffset
+	}
+	end := start + udc.staticFetchLength
 	recoverWriter := new(bytes.Buffer)
-	err := udc.erasureCode.Recover(udc.physicalChunkData, udc.staticChunkSize, recoverWriter)
+	err := udc.erasureCode.Recover(udc.physicalChunkData, recoverSize, recoverWriter)
 	if err != nil {
 		udc.mu.Lock()
 		udc.fail(err)
@@ -218,17 +244,21 @@ func (udc *unfinishedDownloadChunk) threadedRecoverLogicalData() error {
 	// Get recovered data
 	recoveredData := recoverWriter.Bytes()
 
-	// Add the chunk to the cache.
+	// Add the chunk to the cache. If only a range of each piece was
+	// downloaded, the range of the chunk that was recovered is cached.
 	if udc.download.staticDestinationType == destinationTypeSeekStream {
 		// We only cache streaming chunks since browsers and media players tend
 		// to only request a few kib at once when streaming data. That way we can
 		// prevent scheduling the same chunk for download over and over.
-		udc.staticStreamCache.Add(udc.staticCacheID, recoveredData)
+		if udc.partialPieces() {
+			cacheData := append([]byte(nil), recoveredData[piece*udc.staticPieceLength:recoverSize]...)
+			udc.staticStreamCache.Add(udc.staticCacheID, piece*udc.staticPieceSize+udc.staticPieceOffset, cacheData)
+		} else {
+			udc.staticStreamCache.Add(udc.staticCacheID, 0, recoveredData)
+		}
 	}
 
 	// Write the bytes to the requested output.
-	start := udc.staticFetchOffset
-	end := udc.staticFetchOffset + udc.staticFetchLength
 	_, err = udc.destination.WriteAt(recoveredData[start:end], udc.staticWriteOffset)
 	if err != nil {
 		udc.mu.Lock()
@@ -0,0 +1,202 @@
+package renter
+
+// downloadestimate.go computes dry-run estimates for downloads. No money is
+// spent and no data is fetched; the estimate is built entirely from the file
+// metadata, the contract set, the hostdb, and the throughput that the workers
+// have observed so far.
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// estimatePieceSource is a contract that holds a specific piece of a chunk
+// along with the price of fetching that piece from the host.
+type estimatePieceSource struct {
+	id    types.FileContractID
+	piece uint64
+	cost  types.Currency
+}
+
+// EstimateDownload returns the expected cost and duration of downloading
+// every file whose siapath begins with the provided prefix. For each chunk,
+// the cheapest set of online hosts that together hold enough pieces to
+// recover the chunk is assumed to be used.
+func (r *Renter) EstimateDownload(siaPathPrefix string) (modules.RenterDownloadEstimate, error) {
+	var est modules.RenterDownloadEstimate
+
+	// Grab the matching files.
+	var files []*file
+	lockID := r.mu.RLock()
+	for name, f := range r.files {
+		if strings.HasPrefix(name, siaPathPrefix) {
+			files = append(files, f)
+		}
+	}
+	r.mu.RUnlock(lockID)
+	if len(files) == 0 {
+		return est, ErrUnknownPath
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+
+	// Resolve every file contract id to the current contract, and build a map
+	// from each current contract to the cost of downloading a single sector
+	// from its host. Files may refer to several old ids that were renewed
+	// into the same contract, so everything is keyed by the resolved id.
+	// Offline contracts and contracts with unknown hosts are left out.
+	resolvedIDs := make(map[types.FileContractID]types.FileContractID)
+	contracts := make(map[types.FileContractID]modules.RenterContract)
+	sectorCost := make(map[types.FileContractID]types.Currency)
+	for _, f := range files {
+		f.mu.RLock()
+		for id := range f.contracts {
+			if _, exists := resolvedIDs[id]; exists {
+				continue
+			}
+			resolvedID := r.hostContractor.ResolveID(id)
+			resolvedIDs[id] = resolvedID
+			if _, exists := contracts[resolvedID]; exists {
+				continue
+			}
+			if r.hostContractor.IsOffline(resolvedID) {
+				continue
+			}
+			contract, ok := r.hostContractor.ContractByID(resolvedID)
+			if !ok {
+				continue
+			}
+			host, ok := r.hostDB.Host(contract.HostPublicKey)
+			if !ok {
+				continue
+			}
+			contracts[resolvedID] = contract
+			sectorCost[resolvedID] = host.DownloadBandwidthPrice.Mul64(modules.SectorSize)
+		}
+		f.mu.RUnlock()
+	}
+
+	// Pick the cheapest sources for every chunk of every file and tally the
+	// expected spending and bandwidth of each contract.
+	spending := make(map[types.FileContractID]types.Currency)
+	bandwidth := make(map[types.FileContractID]uint64)
+	for _, f := range files {
+		f.mu.RLock()
+		est.Files = append(est.Files, f.name)
+		est.Bytes += f.size
+		sources := make([][]estimatePieceSource, f.numChunks())
+		for fcid, fc := range f.contracts {
+			id := resolvedIDs[fcid]
+			cost, ok := sectorCost[id]
+			if !ok {
+				continue
+			}
+			for _, p := range fc.Pieces {
+				if p.Chunk >= uint64(len(sources)) {
+					continue
+				}
+				sources[p.Chunk] = append(sources[p.Chunk], estimatePieceSource{
+					id:    id,
+					piece: p.Piece,
+					cost:  cost,
+				})
+			}
+		}
+		minPieces := f.erasureCode.MinPieces()
+		for _, chunkSources := range sources {
+			sort.Slice(chunkSources, func(i, j int) bool {
+				return chunkSources[i].cost.Cmp(chunkSources[j].cost) < 0
+			})
+			usedPieces := make(map[uint64]struct{})
+			usedContracts := make(map[types.FileContractID]struct{})
+			var chunkCost types.Currency
+			for _, src := range chunkSources {
+				if len(usedPieces) == minPieces {
+					break
+				}
+				_, pieceUsed := usedPieces[src.piece]
+				_, contractUsed := usedContracts[src.id]
+				if pieceUsed || contractUsed {
+					continue
+				}
+				usedPieces[src.piece] = struct{}{}
+				usedContracts[src.id] = struct{}{}
+				chunkCost = chunkCost.Add(src.cost)
+			}
+			if len(usedPieces) < minPieces {
+				est.UnavailableChunks++
+				continue
+			}
+			for id := range usedContracts {
+				spending[id] = spending[id].Add(sectorCost[id])
+				bandwidth[id] += modules.SectorSize
+			}
+			est.BandwidthCost = est.BandwidthCost.Add(chunkCost)
+		}
+		f.mu.RUnlock()
+	}
+
+	// Check that every contract has enough funds remaining to cover its share
+	// of the download.
+	est.FundsSufficient = true
+	for id, cost := range spending {
+		if contracts[id].RenterFunds.Cmp(cost) < 0 {
+			est.FundsSufficient = false
+			est.InsufficientContracts = append(est.InsufficientContracts, id)
+		}
+	}
+	sort.Slice(est.InsufficientContracts, func(i, j int) bool {
+		return est.InsufficientContracts[i].String() < est.InsufficientContracts[j].String()
+	})
+
+	est.EstimatedDuration = r.managedEstimateDownloadDuration(bandwidth)
+	return est, nil
+}
+
+// managedEstimateDownloadDuration estimates how long it takes to fetch the
+// provided number of bytes from each contract. Workers download in parallel,
+// so the estimate is the time needed by the slowest worker. Workers that have
+// not downloaded anything yet are assumed to perform like the average worker.
+func (r *Renter) managedEstimateDownloadDuration(bandwidth map[types.FileContractID]uint64) time.Duration {
+	lockID := r.mu.RLock()
+	workers := make(map[types.FileContractID]*worker, len(r.workerPool))
+	for id, w := range r.workerPool {
+		workers[id] = w
+	}
+	r.mu.RUnlock(lockID)
+
+	var totalThroughput float64
+	var observed int
+	throughput := make(map[types.FileContractID]float64)
+	for id, w := range workers {
+		bps := w.managedDownloadThroughput()
+		if bps <= 0 {
+			continue
+		}
+		throughput[id] = bps
+		totalThroughput += bps
+		observed++
+	}
+	if observed == 0 {
+		return 0
+	}
+	averageThroughput := totalThroughput / float64(observed)
+
+	var longest time.Duration
+	for id, bytes := range bandwidth {
+		bps, ok := throughput[id]
+		if !ok {
+			bps = averageThroughput
+		}
+		d := time.Duration(float64(bytes) / bps * float64(time.Second))
+		if d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
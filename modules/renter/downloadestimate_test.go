@@ -0,0 +1,288 @@
+package renter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	siasync "github.com/NebulousLabs/Sia/sync"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// estimateContractor is a hostContractor that serves a fixed set of
+// contracts. Only the methods used by EstimateDownload are implemented.
+type estimateContractor struct {
+	hostContractor
+	contracts map[types.FileContractID]modules.RenterContract
+	offline   map[types.FileContractID]bool
+	renewed   map[types.FileContractID]types.FileContractID
+}
+
+func (ec *estimateContractor) IsOffline(id types.FileContractID) bool { return ec.offline[id] }
+func (ec *estimateContractor) ResolveID(id types.FileContractID) types.FileContractID {
+	if newID, ok := ec.renewed[id]; ok {
+		return newID
+	}
+	return id
+}
+func (ec *estimateContractor) ContractByID(id types.FileContractID) (modules.RenterContract, bool) {
+	c, ok := ec.contracts[id]
+	return c, ok
+}
+
+// estimateHostDB is a hostDB that serves a fixed set of hosts.
+type estimateHostDB struct {
+	stubHostDB
+	hosts map[string]modules.HostDBEntry
+}
+
+func (hdb *estimateHostDB) Host(pk types.SiaPublicKey) (modules.HostDBEntry, bool) {
+	h, ok := hdb.hosts[pk.String()]
+	return h, ok
+}
+
+// newEstimateTester returns a bare renter with contracts on four hosts. The
+// hosts charge 1, 2, 3, and 0 per byte downloaded; the last one is offline.
+// The renter has two files:
+//
+//   - "foo/a" has two chunks. Chunk 0 is stored on hosts 0, 1, and 2. Chunk 1
+//     has piece 0 on hosts 0 and 1, piece 1 on the offline host 3, and piece
+//     2 on host 2.
+//   - "bar/b" has one chunk with a single piece on host 0, which is not
+//     enough to recover it.
+func newEstimateTester() (*Renter, []types.FileContractID) {
+	ids := make([]types.FileContractID, 4)
+	contractor := &estimateContractor{
+		contracts: make(map[types.FileContractID]modules.RenterContract),
+		offline:   make(map[types.FileContractID]bool),
+		renewed:   make(map[types.FileContractID]types.FileContractID),
+	}
+	hdb := &estimateHostDB{
+		hosts: make(map[string]modules.HostDBEntry),
+	}
+	prices := []uint64{1, 2, 3, 0}
+	for i := range ids {
+		ids[i] = types.FileContractID{byte(i + 1)}
+		pk := types.SiaPublicKey{
+			Algorithm: types.SignatureEd25519,
+			Key:       []byte{byte(i + 1)},
+		}
+		contractor.contracts[ids[i]] = modules.RenterContract{
+			ID:            ids[i],
+			HostPublicKey: pk,
+			RenterFunds:   types.SiacoinPrecision,
+		}
+		var entry modules.HostDBEntry
+		entry.DownloadBandwidthPrice = types.NewCurrency64(prices[i])
+		hdb.hosts[pk.String()] = entry
+	}
+	contractor.offline[ids[3]] = true
+
+	rsc, _ := NewRSCode(2, 1)
+	pieceSize := uint64(64)
+	foo := newFile("foo/a", rsc, pieceSize, uint64(2*rsc.MinPieces())*pieceSize)
+	foo.contracts[ids[0]] = fileContract{ID: ids[0], Pieces: []pieceData{{Chunk: 0, Piece: 0}, {Chunk: 1, Piece: 0}}}
+	foo.contracts[ids[1]] = fileContract{ID: ids[1], Pieces: []pieceData{{Chunk: 0, Piece: 1}, {Chunk: 1, Piece: 0}}}
+	foo.contracts[ids[2]] = fileContract{ID: ids[2], Pieces: []pieceData{{Chunk: 0, Piece: 2}, {Chunk: 1, Piece: 2}}}
+	foo.contracts[ids[3]] = fileContract{ID: ids[3], Pieces: []pieceData{{Chunk: 1, Piece: 1}}}
+	bar := newFile("bar/b", rsc, pieceSize, pieceSize)
+	bar.contracts[ids[0]] = fileContract{ID: ids[0], Pieces: []pieceData{{Chunk: 0, Piece: 0}}}
+
+	r := &Renter{
+		files: map[string]*file{
+			foo.name: foo,
+			bar.name: bar,
+		},
+		workerPool:     make(map[types.FileContractID]*worker),
+		hostContractor: contractor,
+		hostDB:         hdb,
+		mu:             siasync.New(modules.SafeMutexDelay, 1),
+	}
+	return r, ids
+}
+
+// TestEstimateDownload checks that EstimateDownload picks the cheapest
+// sources for each chunk and reports unrecoverable chunks.
+func TestEstimateDownload(t *testing.T) {
+	r, _ := newEstimateTester()
+
+	// Chunk 0 of foo/a should be fetched from hosts 0 and 1. Chunk 1 should
+	// be fetched from hosts 0 and 2, since host 1 only holds the same piece
+	// as host 0 and host 3 is offline.
+	est, err := r.EstimateDownload("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectorPrice := types.NewCurrency64(modules.SectorSize)
+	if expected := sectorPrice.Mul64((1 + 2) + (1 + 3)); !est.BandwidthCost.Equals(expected) {
+		t.Errorf("expected bandwidth cost %v, got %v", expected, est.BandwidthCost)
+	}
+	if !reflect.DeepEqual(est.Files, []string{"foo/a"}) {
+		t.Errorf("unexpected files: %v", est.Files)
+	}
+	if est.UnavailableChunks != 0 {
+		t.Errorf("expected 0 unavailable chunks, got %v", est.UnavailableChunks)
+	}
+	if !est.FundsSufficient || len(est.InsufficientContracts) != 0 {
+		t.Error("expected funds to be sufficient")
+	}
+
+	// Including bar/b adds an unrecoverable chunk, which does not add to the
+	// cost.
+	est, err = r.EstimateDownload("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(est.Files, []string{"bar/b", "foo/a"}) {
+		t.Errorf("unexpected files: %v", est.Files)
+	}
+	if est.UnavailableChunks != 1 {
+		t.Errorf("expected 1 unavailable chunk, got %v", est.UnavailableChunks)
+	}
+	if expected := sectorPrice.Mul64((1 + 2) + (1 + 3)); !est.BandwidthCost.Equals(expected) {
+		t.Errorf("expected bandwidth cost %v, got %v", expected, est.BandwidthCost)
+	}
+
+	// An unknown prefix should return an error.
+	if _, err := r.EstimateDownload("baz"); err != ErrUnknownPath {
+		t.Errorf("expected %v, got %v", ErrUnknownPath, err)
+	}
+}
+
+// TestEstimateDownloadInsufficientContracts checks that contracts without
+// enough funds to cover their share of the download are reported.
+func TestEstimateDownloadInsufficientContracts(t *testing.T) {
+	r, ids := newEstimateTester()
+	contractor := r.hostContractor.(*estimateContractor)
+
+	// Host 2 fetches a single sector at 3 per byte, so funds just short of that
+	// are not enough. Host 1 is given exactly enough.
+	c := contractor.contracts[ids[2]]
+	c.RenterFunds = types.NewCurrency64(3*modules.SectorSize - 1)
+	contractor.contracts[ids[2]] = c
+	c = contractor.contracts[ids[1]]
+	c.RenterFunds = types.NewCurrency64(2 * modules.SectorSize)
+	contractor.contracts[ids[1]] = c
+
+	est, err := r.EstimateDownload("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.FundsSufficient {
+		t.Error("expected funds to be insufficient")
+	}
+	if !reflect.DeepEqual(est.InsufficientContracts, []types.FileContractID{ids[2]}) {
+		t.Errorf("expected %v to be insufficient, got %v", ids[2], est.InsufficientContracts)
+	}
+
+	// Host 0 is used for both chunks, so it needs funds for two sectors.
+	c = contractor.contracts[ids[0]]
+	c.RenterFunds = types.NewCurrency64(modules.SectorSize)
+	contractor.contracts[ids[0]] = c
+	est, err = r.EstimateDownload("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []types.FileContractID{ids[0], ids[2]}
+	if !reflect.DeepEqual(est.InsufficientContracts, expected) {
+		t.Errorf("expected %v to be insufficient, got %v", expected, est.InsufficientContracts)
+	}
+}
+
+// TestEstimateDownloadRenewedContracts checks that old contract ids that were
+// renewed into the same contract are treated as a single source.
+func TestEstimateDownloadRenewedContracts(t *testing.T) {
+	r, ids := newEstimateTester()
+	contractor := r.hostContractor.(*estimateContractor)
+	oldID := types.FileContractID{0xff}
+	contractor.renewed[oldID] = ids[1]
+	rsc, _ := NewRSCode(2, 1)
+	pieceSize := uint64(64)
+
+	// Both pieces of the only chunk of baz/c are on host 1, under the old and
+	// the current id, so the chunk can't be recovered.
+	baz := newFile("baz/c", rsc, pieceSize, pieceSize)
+	baz.contracts[oldID] = fileContract{ID: oldID, Pieces: []pieceData{{Chunk: 0, Piece: 0}}}
+	baz.contracts[ids[1]] = fileContract{ID: ids[1], Pieces: []pieceData{{Chunk: 0, Piece: 1}}}
+	r.files[baz.name] = baz
+	est, err := r.EstimateDownload("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.UnavailableChunks != 1 {
+		t.Errorf("expected 1 unavailable chunk, got %v", est.UnavailableChunks)
+	}
+	if !est.BandwidthCost.IsZero() {
+		t.Errorf("expected no bandwidth cost, got %v", est.BandwidthCost)
+	}
+
+	// Each chunk of qux/d fetches a sector from host 1, once under the old id
+	// and once under the current id. Together they exceed the funds of the
+	// contract.
+	qux := newFile("qux/d", rsc, pieceSize, uint64(2*rsc.MinPieces())*pieceSize)
+	qux.contracts[oldID] = fileContract{ID: oldID, Pieces: []pieceData{{Chunk: 0, Piece: 0}}}
+	qux.contracts[ids[1]] = fileContract{ID: ids[1], Pieces: []pieceData{{Chunk: 1, Piece: 0}}}
+	qux.contracts[ids[0]] = fileContract{ID: ids[0], Pieces: []pieceData{{Chunk: 0, Piece: 1}, {Chunk: 1, Piece: 1}}}
+	r.files[qux.name] = qux
+	c := contractor.contracts[ids[1]]
+	c.RenterFunds = types.NewCurrency64(3 * modules.SectorSize)
+	contractor.contracts[ids[1]] = c
+	est, err = r.EstimateDownload("qux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.UnavailableChunks != 0 {
+		t.Errorf("expected 0 unavailable chunks, got %v", est.UnavailableChunks)
+	}
+	if expected := types.NewCurrency64(modules.SectorSize).Mul64(2 * (1 + 2)); !est.BandwidthCost.Equals(expected) {
+		t.Errorf("expected bandwidth cost %v, got %v", expected, est.BandwidthCost)
+	}
+	if est.FundsSufficient {
+		t.Error("expected funds to be insufficient")
+	}
+	if !reflect.DeepEqual(est.InsufficientContracts, []types.FileContractID{ids[1]}) {
+		t.Errorf("expected %v to be insufficient, got %v", ids[1], est.InsufficientContracts)
+	}
+}
+
+// TestEstimateDownloadDuration checks that the estimated duration is derived
+// from the throughput observed by the workers.
+func TestEstimateDownloadDuration(t *testing.T) {
+	r, ids := newEstimateTester()
+
+	// Without any observed throughput there is no estimate.
+	est, err := r.EstimateDownload("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.EstimatedDuration != 0 {
+		t.Errorf("expected no duration estimate, got %v", est.EstimatedDuration)
+	}
+
+	// Only host 1 has been observed, downloading 1 sector per second. The
+	// other hosts are assumed to perform like the average, so host 0 is the
+	// slowest since it fetches two sectors.
+	r.workerPool[ids[1]] = &worker{}
+	r.workerPool[ids[1]].managedRecordDownloadThroughput(modules.SectorSize, time.Second)
+	est, err = r.EstimateDownload("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.EstimatedDuration != 2*time.Second {
+		t.Errorf("expected a duration of %v, got %v", 2*time.Second, est.EstimatedDuration)
+	}
+
+	// Once host 0 is observed downloading 8 sectors per second, it only needs
+	// a quarter of a second. Host 2 is assumed to download at the average of
+	// 4.5 sectors per second, so host 1 is now the slowest.
+	r.workerPool[ids[0]] = &worker{}
+	r.workerPool[ids[0]].managedRecordDownloadThroughput(8*modules.SectorSize, time.Second)
+	est, err = r.EstimateDownload("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.EstimatedDuration != time.Second {
+		t.Errorf("expected a duration of %v, got %v", time.Second, est.EstimatedDuration)
+	}
+}
@@ -57,6 +57,36 @@ func TestRSEncode(t *testing.T) {
 	}
 }
 
+// TestRSRecoverRange checks that the same range of each piece can be recovered
+// into that range of each data piece, which is used for partial downloads.
+func TestRSRecoverRange(t *testing.T) {
+	rsc, err := NewRSCode(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(4 * 100)
+	pieces, err := rsc.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Take bytes [30, 50) of each piece, leaving out two of the data pieces.
+	ranges := make([][]byte, len(pieces))
+	for i := range pieces {
+		ranges[i] = append([]byte(nil), pieces[i][30:50]...)
+	}
+	ranges[0], ranges[2] = nil, nil
+
+	// Recover the range of the third data piece.
+	buf := new(bytes.Buffer)
+	if err := rsc.Recover(ranges, 3*20, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes()[2*20:], data[2*100+30:2*100+50]) {
+		t.Fatal("recovered range does not match original")
+	}
+}
+
 func BenchmarkRSEncode(b *testing.B) {
 	rsc, err := NewRSCode(80, 20)
 	if err != nil {
@@ -0,0 +1,53 @@
+package hostdb
+
+// benchmark.go contains the functions that measure and record the performance
+// of hosts. The renter decides which hosts are benchmarked, since only hosts
+// that it has contracts with can be benchmarked with a download.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// BenchmarkSettings measures the time it takes to dial the host and request
+// its settings.
+func (hdb *HostDB) BenchmarkSettings(spk types.SiaPublicKey) (time.Duration, error) {
+	if err := hdb.tg.Add(); err != nil {
+		return 0, err
+	}
+	defer hdb.tg.Done()
+
+	host, exists := hdb.hostTree.Select(spk)
+	if !exists {
+		return 0, errUnknownHost
+	}
+	hdb.mu.RLock()
+	timeout := hdb.scanPolicy.ScanTimeout
+	hdb.mu.RUnlock()
+	start := time.Now()
+	_, _, err := hdb.managedRequestSettings(host.NetAddress, host.PublicKey, timeout)
+	return time.Since(start), err
+}
+
+// RecordBenchmark adds a benchmark to the host's entry and recalculates the
+// host's weight. Only the most recent maxBenchmarks benchmarks are kept.
+func (hdb *HostDB) RecordBenchmark(spk types.SiaPublicKey, b modules.HostBenchmark) error {
+	if err := hdb.tg.Add(); err != nil {
+		return err
+	}
+	defer hdb.tg.Done()
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	host, exists := hdb.hostTree.Select(spk)
+	if !exists {
+		return errUnknownHost
+	}
+	host.Benchmarks = append(host.Benchmarks, b)
+	if len(host.Benchmarks) > maxBenchmarks {
+		host.Benchmarks = host.Benchmarks[len(host.Benchmarks)-maxBenchmarks:]
+	}
+	return hdb.hostTree.Modify(host)
+}
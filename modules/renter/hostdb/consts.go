@@ -7,6 +7,14 @@ import (
 )
 
 const (
+	// benchmarkLatencyTarget is the settings latency below which a host's
+	// score is not penalized.
+	benchmarkLatencyTarget = 500 * time.Millisecond
+
+	// benchmarkThroughputTarget is the download throughput in bytes per second
+	// above which a host's score is not penalized.
+	benchmarkThroughputTarget = 1 << 20
+
 	// historicInteractionDecay defines the decay of the HistoricSuccessfulInteractions
 	// and HistoricFailedInteractions after every block for a host entry.
 	historicInteractionDecay = 0.9995
@@ -15,7 +23,8 @@ const (
 	// interactions required before decay is applied.
 	historicInteractionDecayLimit = 500
 
-	// hostRequestTimeout indicates how long a host has to respond to a dial.
+	// hostRequestTimeout indicates how long a host has to respond to a dial,
+	// unless the scan policy sets a different timeout.
 	hostRequestTimeout = 2 * time.Minute
 
 	// hostScanDeadline indicates how long a host has to complete an entire
@@ -26,6 +35,18 @@ const (
 	// allowed to be offline while still being in the hostdb.
 	maxHostDowntime = 10 * 24 * time.Hour
 
+	// maxBenchmarks is the number of benchmarks that are kept for every host.
+	// Older benchmarks are discarded.
+	maxBenchmarks = 10
+
+	// maxScanningThreadsLimit is the largest number of scanning threads that
+	// can be set in the scan policy.
+	maxScanningThreadsLimit = 1000
+
+	// maxSettingsHistory is the maximum number of settings changes that are
+	// kept for every host.
+	maxSettingsHistory = 50
+
 	// maxSettingsLen indicates how long in bytes the host settings field is
 	// allowed to be before being ignored as a DoS attempt.
 	maxSettingsLen = 10e3
@@ -45,6 +66,14 @@ const (
 	// minScansForSpeedup successful scans.
 	scanSpeedupMedianMultiplier = 5
 
+	// priceIncreasePenalty is the factor by which a host's score is
+	// multiplied for every price increase within the priceVolatilityWindow.
+	priceIncreasePenalty = 0.8
+
+	// priceVolatilityWindow is the period of time over which price increases
+	// count against a host's score.
+	priceVolatilityWindow = 30 * 24 * time.Hour
+
 	// recentInteractionWeightLimit caps the number of recent interactions as a
 	// percentage of the historic interactions, to be certain that a large
 	// amount of activity in a short period of time does not overwhelm the
@@ -61,6 +90,16 @@ const (
 	// scanCheckInterval is the interval used when waiting for the scanList to
 	// empty itself and for waiting on the consensus set to be synced.
 	scanCheckInterval = time.Second
+
+	// settingsHistoryRetention is how long a change of a host's settings is
+	// kept in the settings history.
+	settingsHistoryRetention = 90 * 24 * time.Hour
+
+	// settingsHistoryStorageThreshold is the fraction of a host's total
+	// storage by which its remaining storage has to change to be recorded in
+	// the settings history. Smaller changes happen constantly as the host
+	// stores data.
+	settingsHistoryStorageThreshold = 0.05
 )
 
 var (
@@ -72,8 +111,8 @@ var (
 		Testing:  int(5),
 	}).(int)
 
-	// scanningThreads is the number of threads that will be probing hosts for
-	// their settings and checking for reliability.
+	// maxScanningThreads is the default number of threads that will be
+	// probing hosts for their settings and checking for reliability.
 	maxScanningThreads = build.Select(build.Var{
 		Standard: int(80),
 		Dev:      int(4),
@@ -82,19 +121,27 @@ var (
 )
 
 var (
-	// maxScanSleep is the maximum amount of time that the hostdb will sleep
-	// between performing scans of the hosts.
+	// maxScanSleep is the default minimum amount of time between the scans
+	// of hosts that were offline at their last scan.
 	maxScanSleep = build.Select(build.Var{
 		Standard: time.Hour * 8,
 		Dev:      time.Minute * 10,
 		Testing:  time.Second * 5,
 	}).(time.Duration)
 
-	// minScanSleep is the minimum amount of time that the hostdb will sleep
-	// between performing scans of the hosts.
+	// minScanSleep is the default minimum amount of time between the scans
+	// of hosts that were online at their last scan.
 	minScanSleep = build.Select(build.Var{
 		Standard: time.Hour + time.Minute*20,
 		Dev:      time.Minute * 3,
 		Testing:  time.Second * 1,
 	}).(time.Duration)
+
+	// minScanInterval is the shortest scan interval that can be set in the
+	// scan policy.
+	minScanInterval = build.Select(build.Var{
+		Standard: time.Minute * 10,
+		Dev:      time.Second * 10,
+		Testing:  time.Millisecond * 100,
+	}).(time.Duration)
 )
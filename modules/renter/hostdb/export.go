@@ -0,0 +1,154 @@
+package hostdb
+
+// export.go contains the functions that export the hostdb to a file and
+// import it into another hostdb, so that a new renter can start out with the
+// observations of an established one instead of scanning the network from
+// scratch.
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// exportMetadata defines the metadata of an exported hostdb. The version
+	// has to be incremented whenever the format of the export changes in a
+	// way that older versions can't read.
+	exportMetadata = persist.Metadata{
+		Header:  "HostDB Export",
+		Version: "1.0",
+	}
+)
+
+// hostdbExport is the content of an exported hostdb.
+type hostdbExport struct {
+	BlockHeight types.BlockHeight
+	Exported    time.Time
+	Hosts       []modules.HostDBEntry
+}
+
+// mergeHostEntries merges the history of an imported entry into a local
+// entry. The scan histories are combined, skipping imported scans that were
+// taken at the same time as a local scan. The counters of the entries are
+// not added, since they might be based on the same observations, so the
+// larger of each is kept instead. The settings of the local entry are kept.
+func mergeHostEntries(local *modules.HostDBEntry, imported modules.HostDBEntry) {
+	scanTimes := make(map[int64]struct{}, len(local.ScanHistory))
+	for _, scan := range local.ScanHistory {
+		scanTimes[scan.Timestamp.UnixNano()] = struct{}{}
+	}
+	for _, scan := range imported.ScanHistory {
+		if _, exists := scanTimes[scan.Timestamp.UnixNano()]; exists {
+			continue
+		}
+		scanTimes[scan.Timestamp.UnixNano()] = struct{}{}
+		local.ScanHistory = append(local.ScanHistory, scan)
+	}
+	sort.Sort(local.ScanHistory)
+
+	if imported.HistoricUptime > local.HistoricUptime {
+		local.HistoricUptime = imported.HistoricUptime
+	}
+	if imported.HistoricDowntime > local.HistoricDowntime {
+		local.HistoricDowntime = imported.HistoricDowntime
+	}
+	if imported.HistoricSuccessfulInteractions > local.HistoricSuccessfulInteractions {
+		local.HistoricSuccessfulInteractions = imported.HistoricSuccessfulInteractions
+	}
+	if imported.HistoricFailedInteractions > local.HistoricFailedInteractions {
+		local.HistoricFailedInteractions = imported.HistoricFailedInteractions
+	}
+	if imported.FirstSeen != 0 && (local.FirstSeen == 0 || imported.FirstSeen < local.FirstSeen) {
+		local.FirstSeen = imported.FirstSeen
+	}
+}
+
+// ExportHostDB writes all hosts of the hostdb, including their scan history
+// and interactions, to dst.
+func (hdb *HostDB) ExportHostDB(dst string) error {
+	if err := hdb.tg.Add(); err != nil {
+		return err
+	}
+	defer hdb.tg.Done()
+
+	hdb.mu.RLock()
+	data := hostdbExport{
+		BlockHeight: hdb.blockHeight,
+		Exported:    time.Now(),
+		Hosts:       hdb.hostTree.All(),
+	}
+	hdb.mu.RUnlock()
+	return persist.SaveJSON(exportMetadata, data, dst)
+}
+
+// ImportHostDB merges the hosts of the hostdb exported to src into the
+// hostdb. Hosts that are not known yet are added, the history of known hosts
+// is merged with the imported history. Imported scans are marked with the
+// provided origin, or the name of the file if no origin is provided. Scans
+// that were imported into the exporting hostdb keep their original origin.
+func (hdb *HostDB) ImportHostDB(src, origin string) (modules.HostDBImportReport, error) {
+	var report modules.HostDBImportReport
+	if err := hdb.tg.Add(); err != nil {
+		return report, err
+	}
+	defer hdb.tg.Done()
+
+	if origin == "" {
+		origin = filepath.Base(src)
+	}
+	var data hostdbExport
+	if err := persist.LoadJSON(exportMetadata, &data, src); err != nil {
+		return report, err
+	}
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	for _, host := range data.Hosts {
+		if len(host.PublicKey.Key) == 0 {
+			report.Skipped++
+			continue
+		}
+		for i := range host.ScanHistory {
+			if host.ScanHistory[i].Origin == "" {
+				host.ScanHistory[i].Origin = origin
+			}
+		}
+		sort.Sort(host.ScanHistory)
+
+		// Benchmarks measure the connection of the exporting renter, so they
+		// are not imported. The block heights are limited to the height of
+		// this hostdb, in case the exporting renter is further ahead.
+		host.Benchmarks = nil
+		if host.FirstSeen > hdb.blockHeight {
+			host.FirstSeen = hdb.blockHeight
+		}
+		if host.LastHistoricUpdate > hdb.blockHeight {
+			host.LastHistoricUpdate = hdb.blockHeight
+		}
+
+		local, exists := hdb.hostTree.Select(host.PublicKey)
+		if !exists {
+			if err := hdb.hostTree.Insert(host); err != nil {
+				hdb.log.Debugln("ERROR: could not insert imported host:", host.NetAddress, err)
+				report.Skipped++
+				continue
+			}
+			report.Added++
+			continue
+		}
+		mergeHostEntries(&local, host)
+		if err := hdb.hostTree.Modify(local); err != nil {
+			hdb.log.Debugln("ERROR: could not merge imported host:", host.NetAddress, err)
+			report.Skipped++
+			continue
+		}
+		report.Merged++
+	}
+	hdb.log.Printf("Imported hostdb from %v: %v hosts added, %v merged, %v skipped\n", origin, report.Added, report.Merged, report.Skipped)
+	return report, hdb.saveSync()
+}
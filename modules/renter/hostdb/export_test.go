@@ -0,0 +1,110 @@
+package hostdb
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestMergeHostEntries checks that imported scans are merged into the local
+// scan history in order, and that counters are not added up.
+func TestMergeHostEntries(t *testing.T) {
+	now := time.Now()
+	local := modules.HostDBEntry{
+		HistoricUptime:                 time.Hour,
+		HistoricSuccessfulInteractions: 10,
+		ScanHistory: modules.HostDBScans{
+			{Timestamp: now.Add(-2 * time.Hour), Success: true},
+			{Timestamp: now, Success: true},
+		},
+	}
+	imported := modules.HostDBEntry{
+		HistoricUptime:                 2 * time.Hour,
+		HistoricSuccessfulInteractions: 5,
+		ScanHistory: modules.HostDBScans{
+			{Timestamp: now.Add(-time.Hour), Success: false, Origin: "peer"},
+			{Timestamp: now, Success: true, Origin: "peer"},
+		},
+	}
+	mergeHostEntries(&local, imported)
+
+	if len(local.ScanHistory) != 3 {
+		t.Fatal("expected 3 scans, got", len(local.ScanHistory))
+	}
+	if local.ScanHistory[1].Origin != "peer" || local.ScanHistory[2].Origin != "" {
+		t.Fatal("scans were not merged in order:", local.ScanHistory)
+	}
+	if local.HistoricUptime != 2*time.Hour || local.HistoricSuccessfulInteractions != 10 {
+		t.Fatal("counters were not merged correctly:", local.HistoricUptime, local.HistoricSuccessfulInteractions)
+	}
+}
+
+// TestExportImportHostDB checks that an exported hostdb can be imported into
+// another hostdb, and that importing it twice doesn't duplicate any scans.
+func TestExportImportHostDB(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// The scan loops are disabled so that the inserted hosts aren't scanned
+	// before the import is checked.
+	hdbt, err := newHDBTesterDeps(t.Name(), &disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdbt2, err := newHDBTesterDeps(t.Name()+"2", &disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Export two hosts, one of which is also known to the importing hostdb.
+	known, unknown := makeHostDBEntry(), makeHostDBEntry()
+	known.ScanHistory[0].Timestamp = time.Now().Add(-time.Hour)
+	if err := hdbt.hdb.hostTree.Insert(known); err != nil {
+		t.Fatal(err)
+	}
+	if err := hdbt.hdb.hostTree.Insert(unknown); err != nil {
+		t.Fatal(err)
+	}
+	if err := hdbt2.hdb.hostTree.Insert(makeHostDBEntryWithKey(known)); err != nil {
+		t.Fatal(err)
+	}
+	exportPath := filepath.Join(hdbt.persistDir, "hostdb-export.json")
+	if err := hdbt.hdb.ExportHostDB(exportPath); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := hdbt2.hdb.ImportHostDB(exportPath, "fleet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 1 || report.Merged != 1 || report.Skipped != 0 {
+		t.Fatal("wrong import report:", report)
+	}
+	host, exists := hdbt2.hdb.Host(known.PublicKey)
+	if !exists || len(host.ScanHistory) != 2 {
+		t.Fatal("scan history was not merged:", host.ScanHistory)
+	}
+	if host.ScanHistory[0].Origin != "fleet" || host.ScanHistory[1].Origin != "" {
+		t.Fatal("imported scans were not marked with their origin:", host.ScanHistory)
+	}
+
+	// Importing the same file again doesn't add any scans.
+	if _, err := hdbt2.hdb.ImportHostDB(exportPath, "fleet"); err != nil {
+		t.Fatal(err)
+	}
+	host, _ = hdbt2.hdb.Host(known.PublicKey)
+	if len(host.ScanHistory) != 2 {
+		t.Fatal("scans were imported twice:", host.ScanHistory)
+	}
+}
+
+// makeHostDBEntryWithKey makes a new host entry with the public key of the
+// provided entry and a single scan from the current time.
+func makeHostDBEntryWithKey(entry modules.HostDBEntry) modules.HostDBEntry {
+	dbe := makeHostDBEntry()
+	dbe.PublicKey = entry.PublicKey
+	return dbe
+}
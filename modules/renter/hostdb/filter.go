@@ -0,0 +1,95 @@
+package hostdb
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errEmptyWhitelist is returned when activating a whitelist without any
+	// hosts, which would prevent the renter from using any host at all.
+	errEmptyWhitelist = errors.New("cannot activate a whitelist without any hosts")
+)
+
+// FilterMode returns the hostdb's filter mode and the hosts it applies to.
+func (hdb *HostDB) FilterMode() (modules.FilterMode, []types.SiaPublicKey) {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	hosts := make([]types.SiaPublicKey, 0, len(hdb.filteredHosts))
+	for _, spk := range hdb.filteredHosts {
+		hosts = append(hosts, spk)
+	}
+	return hdb.filterMode, hosts
+}
+
+// SetFilterMode sets the hostdb's filter mode. In blacklist mode the provided
+// hosts are never returned by RandomHosts, in whitelist mode only the provided
+// hosts are returned.
+func (hdb *HostDB) SetFilterMode(fm modules.FilterMode, hosts []types.SiaPublicKey) error {
+	switch fm {
+	case modules.HostDBFilterDisabled:
+		hosts = nil
+	case modules.HostDBFilterBlacklist:
+	case modules.HostDBFilterWhitelist:
+		if len(hosts) == 0 {
+			return errEmptyWhitelist
+		}
+	default:
+		return modules.ErrInvalidFilterMode
+	}
+
+	filteredHosts := make(map[string]types.SiaPublicKey, len(hosts))
+	for _, spk := range hosts {
+		filteredHosts[spk.String()] = spk
+	}
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.filterMode = fm
+	hdb.filteredHosts = filteredHosts
+	return hdb.saveSync()
+}
+
+// IsFiltered returns true if the filter mode prevents the host from being
+// used.
+func (hdb *HostDB) IsFiltered(spk types.SiaPublicKey) bool {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.isFiltered(spk)
+}
+
+// isFiltered returns true if the filter mode prevents the host from being
+// used.
+func (hdb *HostDB) isFiltered(spk types.SiaPublicKey) bool {
+	_, listed := hdb.filteredHosts[spk.String()]
+	switch hdb.filterMode {
+	case modules.HostDBFilterBlacklist:
+		return listed
+	case modules.HostDBFilterWhitelist:
+		return !listed
+	}
+	return false
+}
+
+// filteredKeys returns the keys of all hosts that are excluded by the filter
+// mode, to be passed to the host tree as hosts to ignore.
+func (hdb *HostDB) filteredKeys() []types.SiaPublicKey {
+	switch hdb.filterMode {
+	case modules.HostDBFilterBlacklist:
+		keys := make([]types.SiaPublicKey, 0, len(hdb.filteredHosts))
+		for _, spk := range hdb.filteredHosts {
+			keys = append(keys, spk)
+		}
+		return keys
+	case modules.HostDBFilterWhitelist:
+		var keys []types.SiaPublicKey
+		for _, host := range hdb.hostTree.All() {
+			if hdb.isFiltered(host.PublicKey) {
+				keys = append(keys, host.PublicKey)
+			}
+		}
+		return keys
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package hostdb
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestFilterMode checks that RandomHosts respects the blacklist and whitelist
+// filter modes.
+func TestFilterMode(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	hdbt, err := newHDBTesterDeps(t.Name(), &disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []types.SiaPublicKey
+	for i := 0; i < 10; i++ {
+		entry := makeHostDBEntry()
+		keys = append(keys, entry.PublicKey)
+		if err := hdbt.hdb.hostTree.Insert(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// randomHostSet returns the set of hosts returned by RandomHosts.
+	randomHostSet := func() map[string]struct{} {
+		hosts, err := hdbt.hdb.RandomHosts(len(keys), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set := make(map[string]struct{})
+		for _, host := range hosts {
+			set[host.PublicKey.String()] = struct{}{}
+		}
+		return set
+	}
+
+	// Blacklisted hosts are not returned.
+	if err := hdbt.hdb.SetFilterMode(modules.HostDBFilterBlacklist, keys[:3]); err != nil {
+		t.Fatal(err)
+	}
+	hosts := randomHostSet()
+	if len(hosts) != 7 {
+		t.Fatal("expected 7 hosts, got", len(hosts))
+	}
+	for _, spk := range keys[:3] {
+		if _, exists := hosts[spk.String()]; exists || !hdbt.hdb.IsFiltered(spk) {
+			t.Fatal("blacklisted host was not filtered")
+		}
+	}
+
+	// Only whitelisted hosts are returned.
+	if err := hdbt.hdb.SetFilterMode(modules.HostDBFilterWhitelist, keys[:3]); err != nil {
+		t.Fatal(err)
+	}
+	hosts = randomHostSet()
+	if len(hosts) != 3 {
+		t.Fatal("expected 3 hosts, got", len(hosts))
+	}
+	for _, spk := range keys[:3] {
+		if _, exists := hosts[spk.String()]; !exists || hdbt.hdb.IsFiltered(spk) {
+			t.Fatal("whitelisted host was filtered")
+		}
+	}
+	if err := hdbt.hdb.SetFilterMode(modules.HostDBFilterWhitelist, nil); err != errEmptyWhitelist {
+		t.Fatal("expected errEmptyWhitelist, got", err)
+	}
+
+	// Disabling the filter returns all hosts again.
+	if err := hdbt.hdb.SetFilterMode(modules.HostDBFilterDisabled, keys[:3]); err != nil {
+		t.Fatal(err)
+	}
+	if hosts := randomHostSet(); len(hosts) != len(keys) {
+		t.Fatal("expected all hosts, got", len(hosts))
+	}
+	if fm, filtered := hdbt.hdb.FilterMode(); fm != modules.HostDBFilterDisabled || len(filtered) != 0 {
+		t.Fatal("unexpected filter mode:", fm, filtered)
+	}
+}
@@ -25,6 +25,10 @@ var (
 	ErrInitialScanIncomplete = errors.New("initial hostdb scan is not yet completed")
 	errNilCS                 = errors.New("cannot create hostdb with nil consensus set")
 	errNilGateway            = errors.New("cannot create hostdb with nil gateway")
+
+	// errUnknownHost is returned when requesting an operation on a host that
+	// is not in the hostdb.
+	errUnknownHost = errors.New("host is not in the hostdb")
 )
 
 // The HostDB is a database of potential hosts. It assigns a weight to each
@@ -56,6 +60,39 @@ type HostDB struct {
 	scanWait             bool
 	scanningThreads      int
 
+	// scanPolicy determines the number of scanning threads, the intervals
+	// between scans and the scan timeout. The scan loop is notified of
+	// changes through scanPolicyChan.
+	scanPolicy     modules.HostDBScanPolicy
+	scanPolicyChan chan struct{}
+
+	// scanWaiters contains the channels of the ScanHost calls that wait for
+	// the next scan of a host, keyed by the host's public key.
+	scanWaiters map[string][]chan error
+
+	// rescanPending contains the hosts of the most recent full rescan that
+	// have not been scanned yet.
+	rescanPending map[string]struct{}
+	rescanStart   time.Time
+	rescanTotal   int
+
+	// allowance is the renter's allowance. Hosts whose prices exceed the caps
+	// of the allowance get the lowest possible weight. It has a separate mutex
+	// because host weights are calculated both with and without hdb.mu held.
+	allowance   modules.Allowance
+	allowanceMu sync.RWMutex
+
+	// scoringProfile determines how the factors of a host's weight are traded
+	// off against each other. Like the allowance, it has a separate mutex.
+	scoringProfile modules.HostScoringProfile
+	scoringMu      sync.RWMutex
+
+	// The filter mode determines whether the filtered hosts are a blacklist
+	// or a whitelist. filteredHosts is keyed by the string representation of
+	// the host public keys.
+	filterMode    modules.FilterMode
+	filteredHosts map[string]types.SiaPublicKey
+
 	blockHeight types.BlockHeight
 	lastChange  modules.ConsensusChangeID
 }
@@ -84,7 +121,13 @@ func NewCustomHostDB(g modules.Gateway, cs modules.ConsensusSet, persistDir stri
 		gateway:    g,
 		persistDir: persistDir,
 
-		scanMap: make(map[string]struct{}),
+		filteredHosts:  make(map[string]types.SiaPublicKey),
+		rescanPending:  make(map[string]struct{}),
+		scanMap:        make(map[string]struct{}),
+		scanPolicy:     defaultScanPolicy(),
+		scanPolicyChan: make(chan struct{}, 1),
+		scanWaiters:    make(map[string][]chan error),
+		scoringProfile: modules.DefaultHostScoringProfile,
 	}
 
 	// Create the persist directory if it does not yet exist.
@@ -202,7 +245,7 @@ func (hdb *HostDB) AllHosts() (allHosts []modules.HostDBEntry) {
 // AverageContractPrice returns the average price of a host.
 func (hdb *HostDB) AverageContractPrice() (totalPrice types.Currency) {
 	sampleSize := 32
-	hosts := hdb.hostTree.SelectRandom(sampleSize, nil)
+	hosts := hdb.hostTree.SelectRandom(sampleSize, nil, nil)
 	if len(hosts) == 0 {
 		return totalPrice
 	}
@@ -212,6 +255,20 @@ func (hdb *HostDB) AverageContractPrice() (totalPrice types.Currency) {
 	return totalPrice.Div64(uint64(len(hosts)))
 }
 
+// SetAllowance updates the allowance used to weight hosts, and recalculates
+// the weights of all hosts.
+func (hdb *HostDB) SetAllowance(a modules.Allowance) {
+	hdb.allowanceMu.Lock()
+	hdb.allowance = a
+	hdb.allowanceMu.Unlock()
+
+	// Modifying a host recalculates its weight. Modify only fails if the host
+	// was removed in the meantime, in which case there is nothing to update.
+	for _, host := range hdb.hostTree.All() {
+		hdb.hostTree.Modify(host)
+	}
+}
+
 // Close closes the hostdb, terminating its scanning threads
 func (hdb *HostDB) Close() error {
 	return hdb.tg.Stop()
@@ -232,13 +289,21 @@ func (hdb *HostDB) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 
 // RandomHosts implements the HostDB interface's RandomHosts() method. It takes
 // a number of hosts to return, and a slice of netaddresses to ignore, and
-// returns a slice of entries.
+// returns a slice of entries. Hosts excluded by the filter mode are never
+// returned. No two returned hosts share a subnet, and no returned host shares
+// a subnet with an excluded host, so that the hosts are independent of the
+// hosts the renter already uses.
 func (hdb *HostDB) RandomHosts(n int, excludeKeys []types.SiaPublicKey) ([]modules.HostDBEntry, error) {
 	hdb.mu.RLock()
 	initialScanComplete := hdb.initialScanComplete
+	filteredKeys := hdb.filteredKeys()
 	hdb.mu.RUnlock()
 	if !initialScanComplete {
 		return []modules.HostDBEntry{}, ErrInitialScanIncomplete
 	}
-	return hdb.hostTree.SelectRandom(n, excludeKeys), nil
+	blacklist := excludeKeys
+	if len(filteredKeys) > 0 {
+		blacklist = append(append([]types.SiaPublicKey(nil), excludeKeys...), filteredKeys...)
+	}
+	return hdb.hostTree.SelectRandom(n, blacklist, excludeKeys), nil
 }
@@ -39,7 +39,13 @@ type hdbTester struct {
 // dependencies or scanning threads. It is only intended for use in unit tests.
 func bareHostDB() *HostDB {
 	hdb := &HostDB{
-		log: persist.NewLogger(ioutil.Discard),
+		log:            persist.NewLogger(ioutil.Discard),
+		rescanPending:  make(map[string]struct{}),
+		scanMap:        make(map[string]struct{}),
+		scanPolicy:     defaultScanPolicy(),
+		scanPolicyChan: make(chan struct{}, 1),
+		scanWaiters:    make(map[string][]chan error),
+		scoringProfile: modules.DefaultHostScoringProfile,
 	}
 	hdb.hostTree = hosttree.New(hdb.calculateHostWeight)
 	return hdb
@@ -583,3 +589,29 @@ func TestUpdateHistoricInteractions(t *testing.T) {
 			host.HistoricFailedInteractions, host.HistoricSuccessfulInteractions)
 	}
 }
+
+// TestRecordBenchmark checks that only the most recent benchmarks of a host
+// are kept.
+func TestRecordBenchmark(t *testing.T) {
+	hdb := bareHostDB()
+	entry := makeHostDBEntry()
+	if err := hdb.RecordBenchmark(entry.PublicKey, modules.HostBenchmark{}); err != errUnknownHost {
+		t.Fatal("expected errUnknownHost, got", err)
+	}
+	if err := hdb.hostTree.Insert(entry); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxBenchmarks+2; i++ {
+		err := hdb.RecordBenchmark(entry.PublicKey, modules.HostBenchmark{SettingsLatency: time.Duration(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	host, _ := hdb.Host(entry.PublicKey)
+	if len(host.Benchmarks) != maxBenchmarks {
+		t.Fatalf("expected %v benchmarks, got %v", maxBenchmarks, len(host.Benchmarks))
+	}
+	if host.Benchmarks[0].SettingsLatency != 2 || host.Benchmarks[maxBenchmarks-1].SettingsLatency != maxBenchmarks+1 {
+		t.Fatal("the oldest benchmarks were not discarded:", host.Benchmarks)
+	}
+}
@@ -278,15 +278,29 @@ func (ht *HostTree) Select(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 // SelectRandom grabs a random n hosts from the tree. There will be no repeats, but
 // the length of the slice returned may be less than n, and may even be zero.
 // The hosts that are returned first have the higher priority. Hosts passed to
-// 'ignore' will not be considered; pass `nil` if no blacklist is desired.
-func (ht *HostTree) SelectRandom(n int, ignore []types.SiaPublicKey) []modules.HostDBEntry {
+// 'blacklist' will not be considered; pass `nil` if no blacklist is desired.
+// No two returned hosts share an IP subnet, and no returned host shares an IP
+// subnet with a host passed to 'addressBlacklist'.
+func (ht *HostTree) SelectRandom(n int, blacklist, addressBlacklist []types.SiaPublicKey) []modules.HostDBEntry {
 	ht.mu.Lock()
 	defer ht.mu.Unlock()
 
 	var hosts []modules.HostDBEntry
 	var removedEntries []*hostEntry
 
-	for _, pubkey := range ignore {
+	// Collect the subnets that are already in use.
+	usedNets := make(map[string]struct{})
+	for _, pubkey := range addressBlacklist {
+		node, exists := ht.hosts[string(pubkey.Key)]
+		if !exists {
+			continue
+		}
+		for _, ipNet := range node.entry.IPNets {
+			usedNets[ipNet] = struct{}{}
+		}
+	}
+
+	for _, pubkey := range blacklist {
 		node, exists := ht.hosts[string(pubkey.Key)]
 		if !exists {
 			continue
@@ -302,10 +316,15 @@ func (ht *HostTree) SelectRandom(n int, ignore []types.SiaPublicKey) []modules.H
 
 		if node.entry.AcceptingContracts &&
 			len(node.entry.ScanHistory) > 0 &&
-			node.entry.ScanHistory[len(node.entry.ScanHistory)-1].Success {
-			// The host must be online and accepting contracts to be returned
-			// by the random function.
+			node.entry.ScanHistory[len(node.entry.ScanHistory)-1].Success &&
+			!sharesIPNet(node.entry.IPNets, usedNets) {
+			// The host must be online and accepting contracts, and may not
+			// share a subnet with a host that was already selected, to be
+			// returned by the random function.
 			hosts = append(hosts, node.entry.HostDBEntry)
+			for _, ipNet := range node.entry.IPNets {
+				usedNets[ipNet] = struct{}{}
+			}
 		}
 
 		removedEntries = append(removedEntries, node.entry)
@@ -320,3 +339,13 @@ func (ht *HostTree) SelectRandom(n int, ignore []types.SiaPublicKey) []modules.H
 
 	return hosts
 }
+
+// sharesIPNet returns true if any of the subnets is in use.
+func sharesIPNet(ipNets []string, usedNets map[string]struct{}) bool {
+	for _, ipNet := range ipNets {
+		if _, used := usedNets[ipNet]; used {
+			return true
+		}
+	}
+	return false
+}
@@ -35,7 +35,7 @@ func verifyTree(tree *HostTree, nentries int) error {
 		selectionMap := make(map[string]int)
 		expected := 100
 		for i := 0; i < expected*nentries; i++ {
-			entries := tree.SelectRandom(1, nil)
+			entries := tree.SelectRandom(1, nil, nil)
 			if len(entries) == 0 {
 				return errors.New("no hosts")
 			}
@@ -222,7 +222,7 @@ func TestHostTreeParallel(t *testing.T) {
 
 					// FETCH
 					case 3:
-						tree.SelectRandom(3, nil)
+						tree.SelectRandom(3, nil, nil)
 					}
 				}
 			}
@@ -310,7 +310,7 @@ func TestVariedWeights(t *testing.T) {
 	// time.
 	selectionMap := make(map[string]int)
 	for i := 0; i < selections; i++ {
-		randEntry := tree.SelectRandom(1, nil)
+		randEntry := tree.SelectRandom(1, nil, nil)
 		if len(randEntry) == 0 {
 			t.Fatal("no hosts!")
 		}
@@ -389,7 +389,7 @@ func TestRandomHosts(t *testing.T) {
 	})
 
 	// Empty.
-	hosts := tree.SelectRandom(1, nil)
+	hosts := tree.SelectRandom(1, nil, nil)
 	if len(hosts) != 0 {
 		t.Errorf("empty hostdb returns %v hosts: %v", len(hosts), hosts)
 	}
@@ -418,13 +418,13 @@ func TestRandomHosts(t *testing.T) {
 	}
 
 	// Grab 1 random host.
-	randHosts := tree.SelectRandom(1, nil)
+	randHosts := tree.SelectRandom(1, nil, nil)
 	if len(randHosts) != 1 {
 		t.Error("didn't get 1 hosts")
 	}
 
 	// Grab 2 random hosts.
-	randHosts = tree.SelectRandom(2, nil)
+	randHosts = tree.SelectRandom(2, nil, nil)
 	if len(randHosts) != 2 {
 		t.Error("didn't get 2 hosts")
 	}
@@ -433,7 +433,7 @@ func TestRandomHosts(t *testing.T) {
 	}
 
 	// Grab 3 random hosts.
-	randHosts = tree.SelectRandom(3, nil)
+	randHosts = tree.SelectRandom(3, nil, nil)
 	if len(randHosts) != 3 {
 		t.Error("didn't get 3 hosts")
 	}
@@ -443,7 +443,7 @@ func TestRandomHosts(t *testing.T) {
 	}
 
 	// Grab 4 random hosts. 3 should be returned.
-	randHosts = tree.SelectRandom(4, nil)
+	randHosts = tree.SelectRandom(4, nil, nil)
 	if len(randHosts) != 3 {
 		t.Error("didn't get 3 hosts")
 	}
@@ -458,13 +458,13 @@ func TestRandomHosts(t *testing.T) {
 		randHosts[0].PublicKey,
 		randHosts[1].PublicKey,
 		randHosts[2].PublicKey,
-	})
+	}, nil)
 	if len(uniqueHosts) != 0 {
 		t.Error("didn't get 0 hosts")
 	}
 
 	// Ask for 3 hosts, blacklisting non-existent hosts. 3 should be returned.
-	randHosts = tree.SelectRandom(3, []types.SiaPublicKey{{}, {}, {}})
+	randHosts = tree.SelectRandom(3, []types.SiaPublicKey{{}, {}, {}}, nil)
 	if len(randHosts) != 3 {
 		t.Error("didn't get 3 hosts")
 	}
@@ -473,3 +473,43 @@ func TestRandomHosts(t *testing.T) {
 		t.Error("doubled up")
 	}
 }
+
+// TestSelectRandomIPNets checks that SelectRandom doesn't return multiple hosts
+// from the same subnet, or hosts that share a subnet with the address
+// blacklist.
+func TestSelectRandomIPNets(t *testing.T) {
+	tree := New(func(dbe modules.HostDBEntry) types.Currency {
+		return types.NewCurrency64(1)
+	})
+
+	// Insert 3 hosts in one subnet, 1 host in another subnet and 1 host
+	// without any known subnet.
+	var entries []modules.HostDBEntry
+	for _, ipNets := range [][]string{{"1.2.3.0/24"}, {"1.2.3.0/24"}, {"1.2.3.0/24", "2001:db8::/54"}, {"4.5.6.0/24"}, nil} {
+		entry := makeHostDBEntry()
+		entry.IPNets = ipNets
+		if err := tree.Insert(entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+
+	// Only one host of the shared subnet may be returned.
+	for i := 0; i < 10; i++ {
+		hosts := tree.SelectRandom(len(entries), nil, nil)
+		if len(hosts) != 3 {
+			t.Fatal("expected 3 hosts, got", len(hosts))
+		}
+	}
+
+	// Hosts that share a subnet with the address blacklist are not returned.
+	hosts := tree.SelectRandom(len(entries), nil, []types.SiaPublicKey{entries[0].PublicKey})
+	if len(hosts) != 2 {
+		t.Fatal("expected 2 hosts, got", len(hosts))
+	}
+	for _, host := range hosts {
+		if host.PublicKey.String() != entries[3].PublicKey.String() && host.PublicKey.String() != entries[4].PublicKey.String() {
+			t.Fatal("host in blacklisted subnet was returned")
+		}
+	}
+}
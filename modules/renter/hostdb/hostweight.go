@@ -3,6 +3,7 @@ package hostdb
 import (
 	"math"
 	"math/big"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -54,6 +55,42 @@ var (
 	tbMonth = uint64(4032) * uint64(1e12)
 )
 
+// benchmarkAdjustments penalizes hosts whose benchmarks show a slow response
+// to the settings RPC or a slow download. Failed benchmarks are ignored, the
+// uptime and interaction adjustments already account for unreachable hosts.
+// Hosts without benchmarks are not penalized.
+func benchmarkAdjustments(entry modules.HostDBEntry) float64 {
+	var latency time.Duration
+	var throughput float64
+	var latencies, downloads int
+	for _, b := range entry.Benchmarks {
+		if b.Error != "" {
+			continue
+		}
+		latency += b.SettingsLatency
+		latencies++
+		if b.DownloadThroughput > 0 {
+			throughput += b.DownloadThroughput
+			downloads++
+		}
+	}
+
+	base := float64(1)
+	if latencies > 0 {
+		avgLatency := latency / time.Duration(latencies)
+		if avgLatency > benchmarkLatencyTarget {
+			base *= math.Max(float64(benchmarkLatencyTarget)/float64(avgLatency), 0.1)
+		}
+	}
+	if downloads > 0 {
+		avgThroughput := throughput / float64(downloads)
+		if avgThroughput < benchmarkThroughputTarget {
+			base *= math.Max(avgThroughput/benchmarkThroughputTarget, 0.1)
+		}
+	}
+	return base
+}
+
 // collateralAdjustments improves the host's weight according to the amount of
 // collateral that they have provided.
 func (hdb *HostDB) collateralAdjustments(entry modules.HostDBEntry) float64 {
@@ -141,6 +178,15 @@ func (hdb *HostDB) priceAdjustments(entry modules.HostDBEntry) float64 {
 		}
 	}
 
+	// Hosts that exceed the price caps of the allowance are not considered at
+	// all.
+	hdb.allowanceMu.RLock()
+	violation := hdb.allowance.PriceCapViolation(entry.HostExternalSettings)
+	hdb.allowanceMu.RUnlock()
+	if violation != "" {
+		return math.SmallestNonzeroFloat64
+	}
+
 	// Prices tiered as follows:
 	//    - the storage price is presented as 'per block per byte'
 	//    - the contract price is presented as a flat rate
@@ -363,28 +409,68 @@ func (hdb *HostDB) uptimeAdjustments(entry modules.HostDBEntry) float64 {
 	return math.Pow(uptimeRatio, exp)
 }
 
-// calculateHostWeight returns the weight of a host according to the settings of
-// the host database entry.
-func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency {
-	collateralReward := hdb.collateralAdjustments(entry)
-	interactionPenalty := hdb.interactionAdjustments(entry)
-	lifetimePenalty := hdb.lifetimeAdjustments(entry)
-	pricePenalty := hdb.priceAdjustments(entry)
-	storageRemainingPenalty := storageRemainingAdjustments(entry)
-	uptimePenalty := hdb.uptimeAdjustments(entry)
-	versionPenalty := versionAdjustments(entry)
+// volatilityAdjustments penalizes hosts that raised their prices recently.
+// Every price increase within the priceVolatilityWindow reduces the host's
+// score, so that renters prefer hosts with predictable prices.
+func volatilityAdjustments(entry modules.HostDBEntry) float64 {
+	var increases float64
+	for i := 1; i < len(entry.SettingsHistory); i++ {
+		if time.Since(entry.SettingsHistory[i].Timestamp) > priceVolatilityWindow {
+			continue
+		}
+		if priceIncreased(entry.SettingsHistory[i-1], entry.SettingsHistory[i]) {
+			increases++
+		}
+	}
+	return math.Max(math.Pow(priceIncreasePenalty, increases), 0.1)
+}
+
+// scoreAdjustments returns the adjustments of the host's weight, weighted by
+// the scoring profile, and the resulting weight. The conversion rate is left
+// empty.
+func (hdb *HostDB) scoreAdjustments(entry modules.HostDBEntry) modules.HostScoreBreakdown {
+	p := hdb.ScoringProfile()
+	benchmarkPenalty := weightAdjustment(benchmarkAdjustments(entry), p.BenchmarkWeight)
+	collateralReward := weightAdjustment(hdb.collateralAdjustments(entry), p.CollateralWeight)
+	interactionPenalty := weightAdjustment(hdb.interactionAdjustments(entry), p.InteractionWeight)
+	lifetimePenalty := weightAdjustment(hdb.lifetimeAdjustments(entry), p.AgeWeight)
+	pricePenalty := weightAdjustment(hdb.priceAdjustments(entry), p.PriceWeight)
+	storageRemainingPenalty := weightAdjustment(storageRemainingAdjustments(entry), p.StorageRemainingWeight)
+	uptimePenalty := weightAdjustment(hdb.uptimeAdjustments(entry), p.UptimeWeight)
+	versionPenalty := weightAdjustment(versionAdjustments(entry), p.VersionWeight)
+	volatilityPenalty := weightAdjustment(volatilityAdjustments(entry), p.VolatilityWeight)
 
 	// Combine the adjustments.
-	fullPenalty := collateralReward * interactionPenalty * lifetimePenalty *
-		pricePenalty * storageRemainingPenalty * uptimePenalty * versionPenalty
+	fullPenalty := benchmarkPenalty * collateralReward * interactionPenalty *
+		lifetimePenalty * pricePenalty * storageRemainingPenalty * uptimePenalty *
+		versionPenalty * volatilityPenalty
 
-	// Return a types.Currency.
+	// Convert to a types.Currency.
 	weight := baseWeight.MulFloat(fullPenalty)
 	if weight.IsZero() {
 		// A weight of zero is problematic for for the host tree.
-		return types.NewCurrency64(1)
+		weight = types.NewCurrency64(1)
 	}
-	return weight
+	return modules.HostScoreBreakdown{
+		Score: weight,
+
+		AgeAdjustment:              lifetimePenalty,
+		BenchmarkAdjustment:        benchmarkPenalty,
+		BurnAdjustment:             1,
+		CollateralAdjustment:       collateralReward,
+		InteractionAdjustment:      interactionPenalty,
+		PriceAdjustment:            pricePenalty,
+		StorageRemainingAdjustment: storageRemainingPenalty,
+		UptimeAdjustment:           uptimePenalty,
+		VersionAdjustment:          versionPenalty,
+		VolatilityAdjustment:       volatilityPenalty,
+	}
+}
+
+// calculateHostWeight returns the weight of a host according to the settings of
+// the host database entry and the scoring profile.
+func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency {
+	return hdb.scoreAdjustments(entry).Score
 }
 
 // calculateConversionRate calculates the conversion rate of the provided
@@ -408,12 +494,13 @@ func (hdb *HostDB) calculateConversionRate(score types.Currency) float64 {
 // EstimateHostScore takes a HostExternalSettings and returns the estimated
 // score of that host in the hostdb, assuming no penalties for age or uptime.
 func (hdb *HostDB) EstimateHostScore(entry modules.HostDBEntry) modules.HostScoreBreakdown {
-	// Grab the adjustments. Age, and uptime penalties are set to '1', to
-	// assume best behavior from the host.
-	collateralReward := hdb.collateralAdjustments(entry)
-	pricePenalty := hdb.priceAdjustments(entry)
-	storageRemainingPenalty := storageRemainingAdjustments(entry)
-	versionPenalty := versionAdjustments(entry)
+	// Grab the adjustments. Age, benchmark, uptime and volatility penalties
+	// are set to '1', to assume best behavior from the host.
+	p := hdb.ScoringProfile()
+	collateralReward := weightAdjustment(hdb.collateralAdjustments(entry), p.CollateralWeight)
+	pricePenalty := weightAdjustment(hdb.priceAdjustments(entry), p.PriceWeight)
+	storageRemainingPenalty := weightAdjustment(storageRemainingAdjustments(entry), p.StorageRemainingWeight)
+	versionPenalty := weightAdjustment(versionAdjustments(entry), p.VersionWeight)
 
 	// Combine into a full penalty, then determine the resulting estimated
 	// score.
@@ -429,33 +516,25 @@ func (hdb *HostDB) EstimateHostScore(entry modules.HostDBEntry) modules.HostScor
 		ConversionRate: hdb.calculateConversionRate(estimatedScore),
 
 		AgeAdjustment:              1,
+		BenchmarkAdjustment:        1,
 		BurnAdjustment:             1,
 		CollateralAdjustment:       collateralReward,
 		PriceAdjustment:            pricePenalty,
 		StorageRemainingAdjustment: storageRemainingPenalty,
 		UptimeAdjustment:           1,
 		VersionAdjustment:          versionPenalty,
+		VolatilityAdjustment:       1,
 	}
 }
 
 // ScoreBreakdown provdes a detailed set of scalars and bools indicating
-// elements of the host's overall score.
+// elements of the host's overall score. The adjustments are weighted by the
+// scoring profile.
 func (hdb *HostDB) ScoreBreakdown(entry modules.HostDBEntry) modules.HostScoreBreakdown {
 	hdb.mu.Lock()
 	defer hdb.mu.Unlock()
 
-	score := hdb.calculateHostWeight(entry)
-	return modules.HostScoreBreakdown{
-		Score:          score,
-		ConversionRate: hdb.calculateConversionRate(score),
-
-		AgeAdjustment:              hdb.lifetimeAdjustments(entry),
-		BurnAdjustment:             1,
-		CollateralAdjustment:       hdb.collateralAdjustments(entry),
-		InteractionAdjustment:      hdb.interactionAdjustments(entry),
-		PriceAdjustment:            hdb.priceAdjustments(entry),
-		StorageRemainingAdjustment: storageRemainingAdjustments(entry),
-		UptimeAdjustment:           hdb.uptimeAdjustments(entry),
-		VersionAdjustment:          versionAdjustments(entry),
-	}
+	breakdown := hdb.scoreAdjustments(entry)
+	breakdown.ConversionRate = hdb.calculateConversionRate(breakdown.Score)
+	return breakdown
 }
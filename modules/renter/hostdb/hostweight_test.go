@@ -1,6 +1,7 @@
 package hostdb
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -286,3 +287,103 @@ func TestHostWeightUptimeDifferences4(t *testing.T) {
 		t.Error("Been around longer should have more weight")
 	}
 }
+
+// TestHostWeightScoringProfile checks that the weights of the scoring profile
+// are applied to the adjustments of a host's weight.
+func TestHostWeightScoringProfile(t *testing.T) {
+	hdb := bareHostDB()
+	var entry modules.HostDBEntry
+	entry.Version = build.Version
+	entry.RemainingStorage = 250e3
+	entry.StoragePrice = types.NewCurrency64(1000).Mul(types.SiacoinPrecision).Div64(4032).Div64(1e9)
+	entry2 := entry
+	entry2.StoragePrice = entry.StoragePrice.Mul64(2)
+
+	// Doubling the price weight squares the price adjustment.
+	defaultBreakdown := hdb.scoreAdjustments(entry2)
+	cheap, _ := modules.HostScoringPreset("cheap")
+	hdb.scoringProfile = cheap
+	cheapBreakdown := hdb.scoreAdjustments(entry2)
+	expected := math.Pow(defaultBreakdown.PriceAdjustment, cheap.PriceWeight)
+	if math.Abs(cheapBreakdown.PriceAdjustment-expected) > expected*1e-9 {
+		t.Fatal("price weight was not applied:", cheapBreakdown.PriceAdjustment, expected)
+	}
+
+	// A price weight of 0 makes prices irrelevant.
+	hdb.scoringProfile.PriceWeight = 0
+	if w1, w2 := hdb.calculateHostWeight(entry), hdb.calculateHostWeight(entry2); !w1.Equals(w2) {
+		t.Fatal("prices affect the weight despite a price weight of 0")
+	}
+
+	// Disqualified hosts stay disqualified.
+	hdb.scoringProfile.VersionWeight = 0
+	entry.Version = "1.3.0"
+	if adj := hdb.scoreAdjustments(entry).VersionAdjustment; adj != math.SmallestNonzeroFloat64 {
+		t.Fatal("weight was applied to a disqualifying adjustment:", adj)
+	}
+
+	// Invalid profiles are rejected.
+	invalid := modules.DefaultHostScoringProfile
+	invalid.UptimeWeight = -1
+	if err := hdb.SetScoringProfile(invalid); err != errInvalidScoringWeight {
+		t.Fatal("expected errInvalidScoringWeight, got", err)
+	}
+	invalid.UptimeWeight = maxScoringWeight + 1
+	if err := hdb.SetScoringProfile(invalid); err != errInvalidScoringWeight {
+		t.Fatal("expected errInvalidScoringWeight, got", err)
+	}
+	invalid = modules.DefaultHostScoringProfile
+	invalid.Name = ""
+	if err := hdb.SetScoringProfile(invalid); err != errNoScoringProfileName {
+		t.Fatal("expected errNoScoringProfileName, got", err)
+	}
+}
+
+// TestHostWeightBenchmarks checks that hosts with slow benchmarks are
+// penalized, and that failed benchmarks are ignored.
+func TestHostWeightBenchmarks(t *testing.T) {
+	hdb := bareHostDB()
+	var entry modules.HostDBEntry
+	entry.Version = build.Version
+	entry.RemainingStorage = 250e3
+	entry.StoragePrice = types.NewCurrency64(1000).Mul(types.SiacoinPrecision).Div64(4032).Div64(1e9)
+
+	// A host without benchmarks is not penalized.
+	if adj := benchmarkAdjustments(entry); adj != 1 {
+		t.Fatal("host without benchmarks was penalized:", adj)
+	}
+
+	// A fast host is not penalized.
+	fast := entry
+	fast.Benchmarks = []modules.HostBenchmark{
+		{SettingsLatency: 100 * time.Millisecond, DownloadThroughput: 4 << 20},
+		{SettingsLatency: 200 * time.Millisecond, DownloadThroughput: 2 << 20},
+	}
+	if adj := benchmarkAdjustments(fast); adj != 1 {
+		t.Fatal("fast host was penalized:", adj)
+	}
+
+	// Failed benchmarks are ignored.
+	fast.Benchmarks = append(fast.Benchmarks, modules.HostBenchmark{SettingsLatency: time.Minute, Error: "timeout"})
+	if adj := benchmarkAdjustments(fast); adj != 1 {
+		t.Fatal("failed benchmark was not ignored:", adj)
+	}
+
+	// A slow host has less weight than a fast host.
+	slow := entry
+	slow.Benchmarks = []modules.HostBenchmark{
+		{SettingsLatency: 2 * time.Second, DownloadThroughput: 256 << 10},
+	}
+	if adj := benchmarkAdjustments(slow); math.Abs(adj-0.0625) > 1e-9 {
+		t.Fatal("wrong benchmark adjustment for slow host:", adj)
+	}
+	if hdb.calculateHostWeight(slow).Cmp(hdb.calculateHostWeight(fast)) >= 0 {
+		t.Fatal("slow host should have less weight than fast host")
+	}
+
+	// A benchmark weight of 0 ignores the benchmarks.
+	hdb.scoringProfile.BenchmarkWeight = 0
+	if !hdb.calculateHostWeight(slow).Equals(hdb.calculateHostWeight(fast)) {
+		t.Fatal("benchmarks affect the weight despite a benchmark weight of 0")
+	}
+}
@@ -0,0 +1,72 @@
+package hostdb
+
+// ipnets.go resolves the addresses of hosts into the subnets they use. Hosts
+// that share a subnet are likely run by the same operator or in the same
+// datacenter, so the host tree never selects more than one of them.
+
+import (
+	"net"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	// ipv4NetMask is the number of bits of an IPv4 address that determine
+	// its subnet.
+	ipv4NetMask = 24
+
+	// ipv6NetMask is the number of bits of an IPv6 address that determine
+	// its subnet.
+	ipv6NetMask = 54
+)
+
+// ipNets returns the sorted, unique subnets of the IP addresses. Loopback
+// addresses are ignored, since they only occur on local test networks where
+// every host shares the same address.
+func ipNets(ips []net.IP) []string {
+	set := make(map[string]struct{})
+	for _, ip := range ips {
+		if ip.IsLoopback() {
+			continue
+		}
+		var mask net.IPMask
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			mask = net.CIDRMask(ipv4NetMask, 8*net.IPv4len)
+		} else {
+			mask = net.CIDRMask(ipv6NetMask, 8*net.IPv6len)
+		}
+		ipNet := net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		set[ipNet.String()] = struct{}{}
+	}
+	nets := make([]string, 0, len(set))
+	for ipNet := range set {
+		nets = append(nets, ipNet)
+	}
+	sort.Strings(nets)
+	return nets
+}
+
+// equalIPNets returns true if both sorted lists contain the same subnets.
+func equalIPNets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// managedLookupIPNets resolves the address of a host into the subnets it
+// uses.
+func (hdb *HostDB) managedLookupIPNets(addr modules.NetAddress) ([]string, error) {
+	ips, err := hdb.deps.LookupIP(addr.Host())
+	if err != nil {
+		return nil, err
+	}
+	return ipNets(ips), nil
+}
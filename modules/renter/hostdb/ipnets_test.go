@@ -0,0 +1,28 @@
+package hostdb
+
+import (
+	"net"
+	"testing"
+)
+
+// TestIPNets checks that IP addresses are grouped into the correct subnets.
+func TestIPNets(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("1.2.3.4"),
+		net.ParseIP("1.2.3.200"),
+		net.ParseIP("1.2.4.4"),
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("2001:db8:0:3ff::1"),
+		net.ParseIP("2001:db8:0:400::1"),
+		net.ParseIP("127.0.0.1"),
+		net.ParseIP("::1"),
+	}
+	nets := ipNets(ips)
+	expected := []string{"1.2.3.0/24", "1.2.4.0/24", "2001:db8:0:400::/54", "2001:db8::/54"}
+	if !equalIPNets(nets, expected) {
+		t.Fatal("wrong subnets:", nets)
+	}
+	if equalIPNets(nets, expected[:2]) {
+		t.Fatal("lists of different length are equal")
+	}
+}
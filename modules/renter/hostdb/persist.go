@@ -24,16 +24,27 @@ var (
 
 // hdbPersist defines what HostDB data persists across sessions.
 type hdbPersist struct {
-	AllHosts    []modules.HostDBEntry
-	BlockHeight types.BlockHeight
-	LastChange  modules.ConsensusChangeID
+	AllHosts      []modules.HostDBEntry
+	BlockHeight   types.BlockHeight
+	FilterMode    modules.FilterMode
+	FilteredHosts []types.SiaPublicKey
+	LastChange    modules.ConsensusChangeID
+
+	ScanPolicy     modules.HostDBScanPolicy
+	ScoringProfile modules.HostScoringProfile
 }
 
 // persistData returns the data in the hostdb that will be saved to disk.
 func (hdb *HostDB) persistData() (data hdbPersist) {
 	data.AllHosts = hdb.hostTree.All()
 	data.BlockHeight = hdb.blockHeight
+	data.FilterMode = hdb.filterMode
+	for _, spk := range hdb.filteredHosts {
+		data.FilteredHosts = append(data.FilteredHosts, spk)
+	}
 	data.LastChange = hdb.lastChange
+	data.ScanPolicy = hdb.scanPolicy
+	data.ScoringProfile = hdb.ScoringProfile()
 	return data
 }
 
@@ -53,7 +64,24 @@ func (hdb *HostDB) load() error {
 
 	// Set the hostdb internal values.
 	hdb.blockHeight = data.BlockHeight
+	hdb.filterMode = data.FilterMode
+	for _, spk := range data.FilteredHosts {
+		hdb.filteredHosts[spk.String()] = spk
+	}
 	hdb.lastChange = data.LastChange
+	// Older persist files don't contain a scan policy, in which case the
+	// default policy is kept.
+	if data.ScanPolicy.ScanningThreads != 0 {
+		hdb.scanPolicy = data.ScanPolicy
+	}
+	// The scoring profile has to be set before the hosts are inserted, so that
+	// their weights are calculated with it. Older persist files don't contain
+	// a profile, in which case the default profile is kept.
+	if data.ScoringProfile.Name != "" {
+		hdb.scoringMu.Lock()
+		hdb.scoringProfile = data.ScoringProfile
+		hdb.scoringMu.Unlock()
+	}
 
 	// Load each of the hosts into the host tree.
 	for _, host := range data.AllHosts {
@@ -57,6 +57,10 @@ func TestSaveLoad(t *testing.T) {
 	hdbt.hdb.hostTree.Insert(host1)
 	hdbt.hdb.hostTree.Insert(host2)
 	hdbt.hdb.hostTree.Insert(host3)
+	reliable, _ := modules.HostScoringPreset("reliable")
+	if err := hdbt.hdb.SetScoringProfile(reliable); err != nil {
+		t.Fatal(err)
+	}
 
 	// Save, close, and reload.
 	hdbt.hdb.mu.Lock()
@@ -83,6 +87,9 @@ func TestSaveLoad(t *testing.T) {
 	if lastChange != stashedLC {
 		t.Error("wrong consensus change ID was loaded:", hdbt.hdb.lastChange)
 	}
+	if p := hdbt.hdb.ScoringProfile(); p != reliable {
+		t.Error("wrong scoring profile was loaded:", p)
+	}
 
 	// Check that AllHosts was loaded.
 	h1, ok0 := hdbt.hdb.hostTree.Select(host1.PublicKey)
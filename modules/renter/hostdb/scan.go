@@ -13,6 +13,7 @@ import (
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
 )
 
@@ -43,8 +44,8 @@ func (hdb *HostDB) queueScan(entry modules.HostDBEntry) {
 
 	// Sanity check - the scan map and the scan list should have the same
 	// length.
-	if build.DEBUG && len(hdb.scanMap) > len(hdb.scanList)+maxScanningThreads {
-		hdb.log.Critical("The hostdb scan map has seemingly grown too large:", len(hdb.scanMap), len(hdb.scanList), maxScanningThreads)
+	if build.DEBUG && len(hdb.scanMap) > len(hdb.scanList)+hdb.scanPolicy.ScanningThreads {
+		hdb.log.Critical("The hostdb scan map has seemingly grown too large:", len(hdb.scanMap), len(hdb.scanList), hdb.scanPolicy.ScanningThreads)
 	}
 
 	hdb.scanWait = true
@@ -103,7 +104,7 @@ func (hdb *HostDB) queueScan(entry modules.HostDBEntry) {
 			}
 
 			// Create new worker thread.
-			if hdb.scanningThreads < maxScanningThreads || !starterThread {
+			if hdb.scanningThreads < hdb.scanPolicy.ScanningThreads || !starterThread {
 				starterThread = true
 				hdb.scanningThreads++
 				go func() {
@@ -144,13 +145,17 @@ func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, netErr error) {
 	newEntry, exists := hdb.hostTree.Select(entry.PublicKey)
 	if exists {
 		newEntry.HostExternalSettings = entry.HostExternalSettings
+		newEntry.IPNets = entry.IPNets
+		newEntry.LastIPNetChange = entry.LastIPNetChange
 	} else {
 		newEntry = entry
 	}
 
-	// Update the recent interactions with this host.
+	// Update the recent interactions with this host, and record the new
+	// settings if the scan was successful.
 	if netErr == nil {
 		newEntry.RecentSuccessfulInteractions++
+		updateSettingsHistory(&newEntry, time.Now())
 	} else {
 		newEntry.RecentFailedInteractions++
 	}
@@ -242,9 +247,44 @@ func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, netErr error) {
 	}
 }
 
+// managedRequestSettings dials the host and requests its settings. The
+// returned latency is the time it took to dial the host.
+func (hdb *HostDB) managedRequestSettings(netAddr modules.NetAddress, pubKey types.SiaPublicKey, timeout time.Duration) (settings modules.HostExternalSettings, latency time.Duration, err error) {
+	dialer := &net.Dialer{
+		Cancel:  hdb.tg.StopChan(),
+		Timeout: timeout,
+	}
+	start := time.Now()
+	conn, err := dialer.Dial("tcp", string(netAddr))
+	latency = time.Since(start)
+	if err != nil {
+		return settings, latency, err
+	}
+	connCloseChan := make(chan struct{})
+	go func() {
+		select {
+		case <-hdb.tg.StopChan():
+		case <-connCloseChan:
+		}
+		conn.Close()
+	}()
+	defer close(connCloseChan)
+	conn.SetDeadline(time.Now().Add(hostScanDeadline))
+
+	err = encoding.WriteObject(conn, modules.RPCSettings)
+	if err != nil {
+		return settings, latency, err
+	}
+	var pubkey crypto.PublicKey
+	copy(pubkey[:], pubKey.Key)
+	settings, err = modules.ReadSignedHostSettings(conn, maxSettingsLen, pubkey)
+	return settings, latency, err
+}
+
 // managedScanHost will connect to a host and grab the settings, verifying
-// uptime and updating to the host's preferences.
-func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
+// uptime and updating to the host's preferences. The returned error is the
+// reason the scan failed, if it did.
+func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) error {
 	// Request settings from the queued host entry.
 	netAddr := entry.NetAddress
 	pubKey := entry.PublicKey
@@ -255,56 +295,26 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 	updateHostHistoricInteractions(&entry, hdb.blockHeight)
 	hdb.mu.RUnlock()
 
-	var settings modules.HostExternalSettings
-	var latency time.Duration
-	err := func() error {
-		timeout := hostRequestTimeout
-		hdb.mu.RLock()
-		if len(hdb.initialScanLatencies) > minScansForSpeedup {
-			build.Critical("initialScanLatencies should never be greater than minScansForSpeedup")
-		}
-		if !hdb.initialScanComplete && len(hdb.initialScanLatencies) == minScansForSpeedup {
-			// During an initial scan, when we have at least minScansForSpeedup
-			// active scans in initialScanLatencies, we use
-			// 5*median(initialScanLatencies) as the new hostRequestTimeout to
-			// speedup the scanning process.
-			timeout = hdb.initialScanLatencies[len(hdb.initialScanLatencies)/2]
-			timeout *= scanSpeedupMedianMultiplier
-			if hostRequestTimeout < timeout {
-				timeout = hostRequestTimeout
-			}
-		}
-		hdb.mu.RUnlock()
-
-		dialer := &net.Dialer{
-			Cancel:  hdb.tg.StopChan(),
-			Timeout: timeout,
-		}
-		start := time.Now()
-		conn, err := dialer.Dial("tcp", string(netAddr))
-		latency = time.Since(start)
-		if err != nil {
-			return err
+	hdb.mu.RLock()
+	maxTimeout := hdb.scanPolicy.ScanTimeout
+	timeout := maxTimeout
+	if len(hdb.initialScanLatencies) > minScansForSpeedup {
+		build.Critical("initialScanLatencies should never be greater than minScansForSpeedup")
+	}
+	if !hdb.initialScanComplete && len(hdb.initialScanLatencies) == minScansForSpeedup {
+		// During an initial scan, when we have at least minScansForSpeedup
+		// active scans in initialScanLatencies, we use
+		// 5*median(initialScanLatencies) as the new timeout to speedup the
+		// scanning process.
+		timeout = hdb.initialScanLatencies[len(hdb.initialScanLatencies)/2]
+		timeout *= scanSpeedupMedianMultiplier
+		if maxTimeout < timeout {
+			timeout = maxTimeout
 		}
-		connCloseChan := make(chan struct{})
-		go func() {
-			select {
-			case <-hdb.tg.StopChan():
-			case <-connCloseChan:
-			}
-			conn.Close()
-		}()
-		defer close(connCloseChan)
-		conn.SetDeadline(time.Now().Add(hostScanDeadline))
+	}
+	hdb.mu.RUnlock()
 
-		err = encoding.WriteObject(conn, modules.RPCSettings)
-		if err != nil {
-			return err
-		}
-		var pubkey crypto.PublicKey
-		copy(pubkey[:], pubKey.Key)
-		return crypto.ReadSignedObject(conn, &settings, maxSettingsLen, pubkey)
-	}()
+	settings, latency, err := hdb.managedRequestSettings(netAddr, pubKey, timeout)
 	if err != nil {
 		hdb.log.Debugf("Scan of host at %v failed: %v", netAddr, err)
 
@@ -314,11 +324,29 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 	}
 	success := err == nil
 
+	// Resolve the subnets of the host. They are only updated if the lookup
+	// succeeds, so that a temporary DNS failure doesn't reset the time at
+	// which they last changed.
+	nets, lookupErr := hdb.managedLookupIPNets(netAddr)
+	if lookupErr != nil {
+		hdb.log.Debugf("Unable to resolve the address of host %v: %v", netAddr, lookupErr)
+	} else if !equalIPNets(nets, entry.IPNets) {
+		entry.IPNets = nets
+		entry.LastIPNetChange = time.Now()
+	}
+
 	hdb.mu.Lock()
 	defer hdb.mu.Unlock()
 	// Update the host tree to have a new entry, including the new error. Then
 	// delete the entry from the scan map as the scan has been successful.
 	hdb.updateEntry(entry, err)
+	delete(hdb.rescanPending, pubKey.String())
+
+	// Pass the result to the ScanHost calls that wait for this scan.
+	for _, c := range hdb.scanWaiters[pubKey.String()] {
+		c <- err
+	}
+	delete(hdb.scanWaiters, pubKey.String())
 
 	// Add the scan to the initialScanLatencies if it was successful.
 	if success && len(hdb.initialScanLatencies) < minScansForSpeedup {
@@ -330,6 +358,7 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 			})
 		}
 	}
+	return err
 }
 
 // waitForScans is a helper function that blocks until the hostDB's scanList is
@@ -419,34 +448,44 @@ func (hdb *HostDB) threadedScan() {
 	hdb.initialScanComplete = true
 	hdb.mu.Unlock()
 
+	// Online and offline hosts are scanned at different intervals. A scan of
+	// both is due immediately.
+	var lastOnlineScan, lastOfflineScan, nextOnlineScan, nextOfflineScan time.Time
 	for {
 		// Set up a scan for the hostCheckupQuanity most valuable hosts in the
 		// hostdb. Hosts that fail their scans will be docked significantly,
 		// pushing them further back in the hierarchy, ensuring that for the
 		// most part only online hosts are getting scanned unless there are
 		// fewer than hostCheckupQuantity of them.
+		now := time.Now()
+		scanOnline := !now.Before(nextOnlineScan)
+		scanOffline := !now.Before(nextOfflineScan)
 
 		// Grab a set of hosts to scan, grab hosts that are active, inactive,
 		// and offline to get high diversity.
 		var onlineHosts, offlineHosts []modules.HostDBEntry
 		allHosts := hdb.hostTree.All()
 		for i := len(allHosts) - 1; i >= 0; i-- {
-			if len(onlineHosts) >= hostCheckupQuantity && len(offlineHosts) >= hostCheckupQuantity {
+			onlineFull := !scanOnline || len(onlineHosts) >= hostCheckupQuantity
+			offlineFull := !scanOffline || len(offlineHosts) >= hostCheckupQuantity
+			if onlineFull && offlineFull {
 				break
 			}
 
 			// Figure out if the host is online or offline.
 			host := allHosts[i]
 			online := len(host.ScanHistory) > 0 && host.ScanHistory[len(host.ScanHistory)-1].Success
-			if online && len(onlineHosts) < hostCheckupQuantity {
+			if online && !onlineFull {
 				onlineHosts = append(onlineHosts, host)
-			} else if !online && len(offlineHosts) < hostCheckupQuantity {
+			} else if !online && !offlineFull {
 				offlineHosts = append(offlineHosts, host)
 			}
 		}
 
 		// Queue the scans for each host.
-		hdb.log.Println("Performing scan on", len(onlineHosts), "online hosts and", len(offlineHosts), "offline hosts.")
+		if len(onlineHosts) > 0 || len(offlineHosts) > 0 {
+			hdb.log.Println("Performing scan on", len(onlineHosts), "online hosts and", len(offlineHosts), "offline hosts.")
+		}
 		hdb.mu.Lock()
 		for _, host := range onlineHosts {
 			hdb.queueScan(host)
@@ -454,20 +493,36 @@ func (hdb *HostDB) threadedScan() {
 		for _, host := range offlineHosts {
 			hdb.queueScan(host)
 		}
+		policy := hdb.scanPolicy
 		hdb.mu.Unlock()
 
-		// Sleep for a random amount of time before doing another round of
-		// scanning. The minimums and maximums keep the scan time reasonable,
-		// while the randomness prevents the scanning from always happening at
-		// the same time of day or week.
-		sleepRange := uint64(maxScanSleep - minScanSleep)
-		sleepTime := minScanSleep + time.Duration(fastrand.Uint64n(sleepRange))
+		// Schedule the next scans according to the scan policy. Both
+		// intervals are stretched by the same jitter, so that online and
+		// offline hosts with the same interval are scanned together.
+		jitter := randomScanJitter()
+		if scanOnline {
+			lastOnlineScan = now
+			nextOnlineScan = now.Add(randomScanInterval(policy.OnlineScanInterval, jitter))
+		}
+		if scanOffline {
+			lastOfflineScan = now
+			nextOfflineScan = now.Add(randomScanInterval(policy.OfflineScanInterval, jitter))
+		}
+		nextScan := nextOnlineScan
+		if nextOfflineScan.Before(nextScan) {
+			nextScan = nextOfflineScan
+		}
 
-		// Sleep until it's time for the next scan cycle.
+		// Sleep until it's time for the next scan cycle. If the scan policy
+		// changes, the next scans are rescheduled with the new intervals.
 		select {
 		case <-hdb.tg.StopChan():
 			return
-		case <-time.After(sleepTime):
+		case <-time.After(time.Until(nextScan)):
+		case <-hdb.scanPolicyChan:
+			policy := hdb.ScanPolicy()
+			nextOnlineScan = lastOnlineScan.Add(randomScanInterval(policy.OnlineScanInterval, jitter))
+			nextOfflineScan = lastOfflineScan.Add(randomScanInterval(policy.OfflineScanInterval, jitter))
 		}
 	}
 }
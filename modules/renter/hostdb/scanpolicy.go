@@ -0,0 +1,172 @@
+package hostdb
+
+// scanpolicy.go contains the functions that control how the hostdb scans
+// hosts at runtime, scan a single host on demand, and rescan all hosts.
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	siasync "github.com/NebulousLabs/Sia/sync"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+var (
+	// errInvalidScanInterval is returned when a scan interval of a scan
+	// policy is too short.
+	errInvalidScanInterval = fmt.Errorf("scan intervals must be at least %v", minScanInterval)
+
+	// errInvalidScanTimeout is returned when the scan timeout of a scan
+	// policy is not positive or longer than the deadline of a scan.
+	errInvalidScanTimeout = fmt.Errorf("scan timeout must be between 1s and %v", hostScanDeadline)
+
+	// errInvalidScanningThreads is returned when the number of scanning
+	// threads of a scan policy is out of range.
+	errInvalidScanningThreads = fmt.Errorf("scanning threads must be between 1 and %v", maxScanningThreadsLimit)
+
+	// errRescanInProgress is returned when starting a rescan while hosts of
+	// the previous rescan remain to be scanned.
+	errRescanInProgress = errors.New("a rescan of the hostdb is already in progress")
+)
+
+// defaultScanPolicy returns the scan policy that is used unless the user set
+// a different one. Online and offline hosts are scanned together, with a
+// random sleep between minScanSleep and maxScanSleep between the scans.
+func defaultScanPolicy() modules.HostDBScanPolicy {
+	return modules.HostDBScanPolicy{
+		ScanningThreads:     maxScanningThreads,
+		OnlineScanInterval:  minScanSleep,
+		OfflineScanInterval: minScanSleep,
+		ScanTimeout:         hostRequestTimeout,
+	}
+}
+
+// randomScanJitter returns a random factor between 1 and
+// maxScanSleep/minScanSleep by which the scan intervals are stretched. The
+// randomness prevents the scanning from always happening at the same time of
+// day or week.
+func randomScanJitter() float64 {
+	maxJitter := float64(maxScanSleep) / float64(minScanSleep)
+	return 1 + (maxJitter-1)*float64(fastrand.Uint64n(1<<53))/(1<<53)
+}
+
+// randomScanInterval returns the interval stretched by the jitter.
+func randomScanInterval(interval time.Duration, jitter float64) time.Duration {
+	return time.Duration(float64(interval) * jitter)
+}
+
+// ScanPolicy returns the policy that is used to scan hosts.
+func (hdb *HostDB) ScanPolicy() modules.HostDBScanPolicy {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.scanPolicy
+}
+
+// SetScanPolicy sets the policy that is used to scan hosts. The new intervals
+// apply to the next scans, the number of threads applies to the scans that
+// are queued from now on.
+func (hdb *HostDB) SetScanPolicy(p modules.HostDBScanPolicy) error {
+	if p.ScanningThreads < 1 || p.ScanningThreads > maxScanningThreadsLimit {
+		return errInvalidScanningThreads
+	}
+	if p.OnlineScanInterval < minScanInterval || p.OfflineScanInterval < minScanInterval {
+		return errInvalidScanInterval
+	}
+	if p.ScanTimeout < time.Second || p.ScanTimeout > hostScanDeadline {
+		return errInvalidScanTimeout
+	}
+
+	hdb.mu.Lock()
+	hdb.scanPolicy = p
+	err := hdb.saveSync()
+	hdb.mu.Unlock()
+
+	// Wake up the scan loop so that it picks up the new intervals.
+	select {
+	case hdb.scanPolicyChan <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// ScanHost queues a scan of the host ahead of the other queued scans, waits
+// for the next scan of the host to finish and returns its updated entry. The
+// returned error is the reason the scan failed, if it did.
+func (hdb *HostDB) ScanHost(spk types.SiaPublicKey) (modules.HostDBEntry, error) {
+	if err := hdb.tg.Add(); err != nil {
+		return modules.HostDBEntry{}, err
+	}
+	defer hdb.tg.Done()
+
+	entry, exists := hdb.hostTree.Select(spk)
+	if !exists {
+		return modules.HostDBEntry{}, errUnknownHost
+	}
+
+	// If the host is already queued, queueScan doesn't queue it again, but
+	// the queued scan is moved to the front either way.
+	key := spk.String()
+	scanDone := make(chan error, 1)
+	hdb.mu.Lock()
+	hdb.scanWaiters[key] = append(hdb.scanWaiters[key], scanDone)
+	hdb.queueScan(entry)
+	for i := range hdb.scanList {
+		if hdb.scanList[i].PublicKey.String() == key {
+			hdb.scanList[0], hdb.scanList[i] = hdb.scanList[i], hdb.scanList[0]
+			break
+		}
+	}
+	hdb.mu.Unlock()
+
+	var scanErr error
+	select {
+	case scanErr = <-scanDone:
+	case <-hdb.tg.StopChan():
+		return modules.HostDBEntry{}, siasync.ErrStopped
+	}
+
+	// The host is removed if it has been offline for too long, in which case
+	// the entry from before the scan is returned.
+	if updated, exists := hdb.Host(spk); exists {
+		entry = updated
+	}
+	return entry, scanErr
+}
+
+// RescanHosts queues a scan of every host in the hostdb.
+func (hdb *HostDB) RescanHosts() error {
+	if err := hdb.tg.Add(); err != nil {
+		return err
+	}
+	defer hdb.tg.Done()
+
+	allHosts := hdb.hostTree.All()
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	if len(hdb.rescanPending) > 0 {
+		return errRescanInProgress
+	}
+	hdb.rescanStart = time.Now()
+	hdb.rescanTotal = len(allHosts)
+	for _, host := range allHosts {
+		hdb.rescanPending[host.PublicKey.String()] = struct{}{}
+		hdb.queueScan(host)
+	}
+	hdb.log.Println("Rescanning", len(allHosts), "hosts.")
+	return nil
+}
+
+// RescanProgress returns the progress of the most recent full rescan.
+func (hdb *HostDB) RescanProgress() modules.HostDBRescanProgress {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return modules.HostDBRescanProgress{
+		Active:    len(hdb.rescanPending) > 0,
+		StartTime: hdb.rescanStart,
+		Total:     hdb.rescanTotal,
+		Remaining: len(hdb.rescanPending),
+	}
+}
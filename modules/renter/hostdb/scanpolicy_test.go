@@ -0,0 +1,112 @@
+package hostdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestSetScanPolicyValidation checks that invalid scan policies are rejected
+// without changing the current policy.
+func TestSetScanPolicyValidation(t *testing.T) {
+	hdb := bareHostDB()
+	tests := []struct {
+		modify func(p *modules.HostDBScanPolicy)
+		err    error
+	}{
+		{func(p *modules.HostDBScanPolicy) { p.ScanningThreads = 0 }, errInvalidScanningThreads},
+		{func(p *modules.HostDBScanPolicy) { p.ScanningThreads = maxScanningThreadsLimit + 1 }, errInvalidScanningThreads},
+		{func(p *modules.HostDBScanPolicy) { p.OnlineScanInterval = minScanInterval - 1 }, errInvalidScanInterval},
+		{func(p *modules.HostDBScanPolicy) { p.OfflineScanInterval = 0 }, errInvalidScanInterval},
+		{func(p *modules.HostDBScanPolicy) { p.ScanTimeout = 0 }, errInvalidScanTimeout},
+		{func(p *modules.HostDBScanPolicy) { p.ScanTimeout = hostScanDeadline + time.Second }, errInvalidScanTimeout},
+	}
+	for i, test := range tests {
+		p := defaultScanPolicy()
+		test.modify(&p)
+		if err := hdb.SetScanPolicy(p); err != test.err {
+			t.Errorf("%v: expected %v, got %v", i, test.err, err)
+		}
+	}
+	if hdb.ScanPolicy() != defaultScanPolicy() {
+		t.Fatal("invalid policy changed the scan policy:", hdb.ScanPolicy())
+	}
+}
+
+// TestRandomScanInterval checks that the default scan interval is randomized
+// between minScanSleep and maxScanSleep.
+func TestRandomScanInterval(t *testing.T) {
+	interval := defaultScanPolicy().OnlineScanInterval
+	for i := 0; i < 100; i++ {
+		if d := randomScanInterval(interval, randomScanJitter()); d < minScanSleep || d > maxScanSleep {
+			t.Fatal("scan interval out of range:", d)
+		}
+	}
+}
+
+// TestRescanInProgress checks that a rescan can't be started while hosts of
+// the previous rescan remain to be scanned, and that the progress is reported.
+func TestRescanInProgress(t *testing.T) {
+	hdb := bareHostDB()
+	hdb.rescanTotal = 3
+	hdb.rescanPending["host"] = struct{}{}
+	if err := hdb.RescanHosts(); err != errRescanInProgress {
+		t.Fatal("expected errRescanInProgress, got", err)
+	}
+	progress := hdb.RescanProgress()
+	if !progress.Active || progress.Total != 3 || progress.Remaining != 1 {
+		t.Fatal("wrong rescan progress:", progress)
+	}
+
+	delete(hdb.rescanPending, "host")
+	if hdb.RescanProgress().Active {
+		t.Fatal("rescan is active without pending hosts")
+	}
+}
+
+// TestScanHost checks that ScanHost waits for the queued scan of the host and
+// returns its result, also if several calls wait for the same host.
+func TestScanHost(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	hdbt, err := newHDBTesterDeps(t.Name(), &disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing listens on the host's address, so the scan fails.
+	host := makeHostDBEntry()
+	host.NetAddress = "127.0.0.1:1"
+	if err := hdbt.hdb.hostTree.Insert(host); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := hdbt.hdb.ScanHost(host.PublicKey)
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err == nil {
+			t.Fatal("expected the scan to fail")
+		}
+	}
+
+	entry, exists := hdbt.hdb.Host(host.PublicKey)
+	if !exists {
+		t.Fatal("host was removed")
+	}
+	if len(entry.ScanHistory) < 2 || entry.ScanHistory[len(entry.ScanHistory)-1].Success {
+		t.Fatal("failed scan was not recorded:", entry.ScanHistory)
+	}
+	hdbt.hdb.mu.Lock()
+	waiters := len(hdbt.hdb.scanWaiters)
+	hdbt.hdb.mu.Unlock()
+	if waiters != 0 {
+		t.Fatal("scan waiters were not removed:", waiters)
+	}
+}
@@ -0,0 +1,69 @@
+package hostdb
+
+import (
+	"errors"
+	"math"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	// errInvalidScoringWeight is returned when a weight of a scoring profile
+	// is negative or too large.
+	errInvalidScoringWeight = errors.New("scoring weights must be between 0 and 5")
+
+	// errNoScoringProfileName is returned when setting a scoring profile
+	// without a name.
+	errNoScoringProfileName = errors.New("scoring profile must have a name")
+
+	// maxScoringWeight is the largest weight of a scoring profile. Larger
+	// weights would let a single factor overflow the score of a host.
+	maxScoringWeight = float64(5)
+)
+
+// ScoringProfile returns the profile that is used to score hosts.
+func (hdb *HostDB) ScoringProfile() modules.HostScoringProfile {
+	hdb.scoringMu.RLock()
+	defer hdb.scoringMu.RUnlock()
+	return hdb.scoringProfile
+}
+
+// SetScoringProfile sets the profile that is used to score hosts, and
+// recalculates the weights of all hosts in the host tree.
+func (hdb *HostDB) SetScoringProfile(p modules.HostScoringProfile) error {
+	if p.Name == "" {
+		return errNoScoringProfileName
+	}
+	weights := []float64{p.AgeWeight, p.BenchmarkWeight, p.CollateralWeight,
+		p.InteractionWeight, p.PriceWeight, p.StorageRemainingWeight,
+		p.UptimeWeight, p.VersionWeight, p.VolatilityWeight}
+	for _, w := range weights {
+		if math.IsNaN(w) || w < 0 || w > maxScoringWeight {
+			return errInvalidScoringWeight
+		}
+	}
+
+	hdb.scoringMu.Lock()
+	hdb.scoringProfile = p
+	hdb.scoringMu.Unlock()
+
+	// Modifying a host recalculates its weight. Modify only fails if the host
+	// was removed in the meantime, in which case there is nothing to update.
+	for _, host := range hdb.hostTree.All() {
+		hdb.hostTree.Modify(host)
+	}
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	return hdb.saveSync()
+}
+
+// weightAdjustment raises an adjustment of a host's score to the power of its
+// weight. Adjustments that disqualify a host are returned as is, so that no
+// profile can make a disqualified host usable.
+func weightAdjustment(adjustment, weight float64) float64 {
+	if adjustment == math.SmallestNonzeroFloat64 {
+		return adjustment
+	}
+	return math.Pow(adjustment, weight)
+}
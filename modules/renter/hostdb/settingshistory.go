@@ -0,0 +1,72 @@
+package hostdb
+
+// settingshistory.go records the changes of the hosts' prices and storage, so
+// that renters can see when a host raised its prices or shrank its storage.
+
+import (
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// settingsSnapshot returns the settings of the host that are recorded in the
+// settings history.
+func settingsSnapshot(settings modules.HostExternalSettings, timestamp time.Time) modules.HostSettingsChange {
+	return modules.HostSettingsChange{
+		Timestamp: timestamp,
+
+		AcceptingContracts:     settings.AcceptingContracts,
+		Collateral:             settings.Collateral,
+		ContractPrice:          settings.ContractPrice,
+		DownloadBandwidthPrice: settings.DownloadBandwidthPrice,
+		RemainingStorage:       settings.RemainingStorage,
+		StoragePrice:           settings.StoragePrice,
+		TotalStorage:           settings.TotalStorage,
+		UploadBandwidthPrice:   settings.UploadBandwidthPrice,
+	}
+}
+
+// settingsChanged returns true if the settings of the host changed enough
+// between two snapshots to be recorded. Changes of the remaining storage are
+// only recorded if they exceed settingsHistoryStorageThreshold.
+func settingsChanged(prev, cur modules.HostSettingsChange) bool {
+	if prev.AcceptingContracts != cur.AcceptingContracts ||
+		!prev.Collateral.Equals(cur.Collateral) ||
+		!prev.ContractPrice.Equals(cur.ContractPrice) ||
+		!prev.DownloadBandwidthPrice.Equals(cur.DownloadBandwidthPrice) ||
+		!prev.StoragePrice.Equals(cur.StoragePrice) ||
+		prev.TotalStorage != cur.TotalStorage ||
+		!prev.UploadBandwidthPrice.Equals(cur.UploadBandwidthPrice) {
+		return true
+	}
+	storageDiff := prev.RemainingStorage - cur.RemainingStorage
+	if cur.RemainingStorage > prev.RemainingStorage {
+		storageDiff = cur.RemainingStorage - prev.RemainingStorage
+	}
+	return float64(storageDiff) > float64(cur.TotalStorage)*settingsHistoryStorageThreshold
+}
+
+// priceIncreased returns true if any of the prices of the host increased
+// between two snapshots.
+func priceIncreased(prev, cur modules.HostSettingsChange) bool {
+	return cur.ContractPrice.Cmp(prev.ContractPrice) > 0 ||
+		cur.DownloadBandwidthPrice.Cmp(prev.DownloadBandwidthPrice) > 0 ||
+		cur.StoragePrice.Cmp(prev.StoragePrice) > 0 ||
+		cur.UploadBandwidthPrice.Cmp(prev.UploadBandwidthPrice) > 0
+}
+
+// updateSettingsHistory adds the current settings of the entry to its
+// settings history if they changed, and discards changes that are older than
+// settingsHistoryRetention. The most recent change is always kept, since it
+// describes the current settings.
+func updateSettingsHistory(entry *modules.HostDBEntry, now time.Time) {
+	snapshot := settingsSnapshot(entry.HostExternalSettings, now)
+	history := entry.SettingsHistory
+	if len(history) == 0 || settingsChanged(history[len(history)-1], snapshot) {
+		history = append(history, snapshot)
+	}
+	for len(history) > 1 && (len(history) > maxSettingsHistory || now.Sub(history[0].Timestamp) > settingsHistoryRetention) {
+		history = history[1:]
+	}
+	entry.SettingsHistory = history
+}
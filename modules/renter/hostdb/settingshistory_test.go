@@ -0,0 +1,87 @@
+package hostdb
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestUpdateSettingsHistory checks that only changes of the settings are
+// recorded, and that old changes are discarded.
+func TestUpdateSettingsHistory(t *testing.T) {
+	var entry modules.HostDBEntry
+	entry.StoragePrice = types.NewCurrency64(100)
+	entry.TotalStorage = 1000
+	entry.RemainingStorage = 1000
+
+	now := time.Now()
+	updateSettingsHistory(&entry, now)
+	if len(entry.SettingsHistory) != 1 {
+		t.Fatal("initial settings were not recorded:", len(entry.SettingsHistory))
+	}
+
+	// Small changes of the remaining storage are not recorded.
+	entry.RemainingStorage = 990
+	updateSettingsHistory(&entry, now)
+	if len(entry.SettingsHistory) != 1 {
+		t.Fatal("small storage change was recorded")
+	}
+
+	// Large changes of the remaining storage and price changes are.
+	entry.RemainingStorage = 500
+	updateSettingsHistory(&entry, now)
+	entry.StoragePrice = types.NewCurrency64(200)
+	updateSettingsHistory(&entry, now)
+	if len(entry.SettingsHistory) != 3 {
+		t.Fatal("expected 3 changes, got", len(entry.SettingsHistory))
+	}
+	if !entry.SettingsHistory[2].StoragePrice.Equals64(200) {
+		t.Fatal("wrong price recorded:", entry.SettingsHistory[2].StoragePrice)
+	}
+
+	// Changes older than the retention period are discarded, except for the
+	// most recent one.
+	updateSettingsHistory(&entry, now.Add(settingsHistoryRetention+time.Hour))
+	if len(entry.SettingsHistory) != 1 || !entry.SettingsHistory[0].StoragePrice.Equals64(200) {
+		t.Fatal("old changes were not discarded:", entry.SettingsHistory)
+	}
+
+	// The number of changes is limited.
+	for i := 0; i < maxSettingsHistory+10; i++ {
+		entry.ContractPrice = types.NewCurrency64(uint64(i + 1))
+		updateSettingsHistory(&entry, now)
+	}
+	if len(entry.SettingsHistory) != maxSettingsHistory {
+		t.Fatalf("expected %v changes, got %v", maxSettingsHistory, len(entry.SettingsHistory))
+	}
+}
+
+// TestVolatilityAdjustments checks that recent price increases reduce a host's
+// score, and that price decreases and old increases don't.
+func TestVolatilityAdjustments(t *testing.T) {
+	var entry modules.HostDBEntry
+	if adj := volatilityAdjustments(entry); adj != 1 {
+		t.Fatal("host without history was penalized:", adj)
+	}
+
+	now := time.Now()
+	entry.SettingsHistory = []modules.HostSettingsChange{
+		{Timestamp: now.Add(-2 * priceVolatilityWindow), StoragePrice: types.NewCurrency64(100)},
+		{Timestamp: now.Add(-2 * priceVolatilityWindow), StoragePrice: types.NewCurrency64(200)},
+		{Timestamp: now.Add(-time.Hour), StoragePrice: types.NewCurrency64(100)},
+	}
+	if adj := volatilityAdjustments(entry); adj != 1 {
+		t.Fatal("old increase or price decrease was penalized:", adj)
+	}
+
+	entry.SettingsHistory = append(entry.SettingsHistory,
+		modules.HostSettingsChange{Timestamp: now, StoragePrice: types.NewCurrency64(100), ContractPrice: types.NewCurrency64(1)},
+		modules.HostSettingsChange{Timestamp: now, StoragePrice: types.NewCurrency64(150), ContractPrice: types.NewCurrency64(1)},
+	)
+	if adj := volatilityAdjustments(entry); math.Abs(adj-priceIncreasePenalty*priceIncreasePenalty) > 1e-9 {
+		t.Fatal("wrong adjustment for two price increases:", adj)
+	}
+}
@@ -2,72 +2,175 @@ package renter
 
 // TODO: Move the memory manager to its own package.
 
-// TODO: Add functions that allow a caller to increase or decrease the base
-// memory for the memory manager.
-
 import (
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
 )
 
+// errMemoryReservesTooLarge is returned if the memory reserves add up to more
+// than the total memory budget.
+var errMemoryReservesTooLarge = errors.New("memory reserves exceed the total memory budget")
+
+// memoryClass identifies the kind of work that memory is requested for. Every
+// class can have a reserve, which is memory that only that class may use.
+type memoryClass int
+
+const (
+	memoryClassUpload memoryClass = iota
+	memoryClassDownload
+	memoryClassStream
+
+	numMemoryClasses
+)
+
+// memoryReserves are the amounts of memory set aside for uploads, downloads and
+// streams. They are persisted as part of the renter settings.
+type memoryReserves struct {
+	Upload   uint64
+	Download uint64
+	Stream   uint64
+}
+
+// validate checks that the reserves fit into the memory budget. Every reserve
+// is checked against the part of the budget that remains, so that the sum of
+// the reserves can't overflow.
+func (mr memoryReserves) validate(maxMemory uint64) error {
+	remaining := maxMemory
+	for _, reserve := range []uint64{mr.Upload, mr.Download, mr.Stream} {
+		if reserve > remaining {
+			return errMemoryReservesTooLarge
+		}
+		remaining -= reserve
+	}
+	return nil
+}
+
 // memoryManager can handle requests for memory and returns of memory. The
 // memory manager is initialized with a base amount of memory and it will allow
 // up to that much memory to be requested simultaneously. Beyond that, it will
-// block on calls to 'managedGetMemory' until enough memory has been returned to
-// allow the request.
+// block on calls to 'Request' until enough memory has been returned to allow
+// the request.
+//
+// Part of the base memory can be reserved for a class of requests. Memory that
+// is reserved for one class and not in use by that class cannot be granted to
+// requests of any other class, ensuring that, for example, uploads cannot
+// starve streaming of memory.
 //
 // If a request is made that exceeds the base memory, the memory manager will
 // block until all memory is available, and then grant the request, blocking all
 // future requests for memory until the memory is returned. This allows large
 // requests to go through even if there is not enough base memory.
+//
+// Downloads and streams are placed in the priority queue, uploads are placed
+// in the regular queue. While priority requests are waiting, uploads are only
+// granted memory out of the upload reserve.
 type memoryManager struct {
-	available    uint64
 	base         uint64
 	fifo         []*memoryRequest
 	priorityFifo []*memoryRequest
+	reserves     [numMemoryClasses]uint64
+	used         [numMemoryClasses]uint64
 	mu           sync.Mutex
 	stop         <-chan struct{}
-	underflow    uint64
+
+	// Statistics about requests that had to wait for memory.
+	waitedRequests uint64
+	waitedTotal    time.Duration
 }
 
 // memoryRequest is a single thread that is blocked while waiting for memory.
 type memoryRequest struct {
 	amount uint64
+	class  memoryClass
 	done   chan struct{}
+	start  time.Time
+}
+
+// inUse returns the total amount of memory that has been granted and not yet
+// returned.
+func (mm *memoryManager) inUse() uint64 {
+	var total uint64
+	for _, used := range mm.used {
+		total += used
+	}
+	return total
+}
+
+// reservedForOthers returns the amount of memory that is reserved for classes
+// other than the provided class and is not in use by those classes.
+func (mm *memoryManager) reservedForOthers(class memoryClass) uint64 {
+	var reserved uint64
+	for c := memoryClass(0); c < numMemoryClasses; c++ {
+		if c != class && mm.reserves[c] > mm.used[c] {
+			reserved += mm.reserves[c] - mm.used[c]
+		}
+	}
+	return reserved
 }
 
 // try will try to get the amount of memory requested from the manger, returning
 // true if the attempt is successful, and false if the attempt is not.  In the
 // event that the attempt is successful, the internal state of the memory
 // manager will be updated to reflect the granted request.
-func (mm *memoryManager) try(amount uint64) bool {
-	if mm.available >= amount {
-		// There is enough memory, decrement the memory and return.
-		mm.available -= amount
-		return true
-	} else if mm.available == mm.base {
-		// The amount of memory being requested is greater than the amount of
-		// memory available, but no memory is currently in use. Set the amount
-		// of memory available to zero and return.
+func (mm *memoryManager) try(amount uint64, class memoryClass) bool {
+	inUse := mm.inUse()
+	if inUse == 0 {
+		// No memory is currently in use. Grant the request even if it exceeds
+		// the amount of memory available to the class.
 		//
 		// The effect is that all of the memory is allocated to this one
 		// request, allowing the request to succeed even though there is
 		// technically not enough total memory available for the request.
-		mm.available = 0
-		mm.underflow = amount - mm.base
+		mm.used[class] += amount
+		return true
+	}
+	if inUse+amount+mm.reservedForOthers(class) <= mm.base {
+		// There is enough memory, increment the memory in use and return.
+		mm.used[class] += amount
 		return true
 	}
 	return false
 }
 
+// tryClass is a wrapper around try that gives priority requests precedence
+// over upload requests. While priority requests are waiting, uploads are only
+// granted memory out of the upload reserve.
+func (mm *memoryManager) tryClass(amount uint64, class memoryClass) bool {
+	if class == memoryClassUpload && len(mm.priorityFifo) > 0 &&
+		mm.used[class]+amount > mm.reserves[class] {
+		return false
+	}
+	return mm.try(amount, class)
+}
+
+// waiting returns true if any requests of the provided class are waiting for
+// memory.
+func (mm *memoryManager) waiting(class memoryClass) bool {
+	fifo := mm.priorityFifo
+	if class == memoryClassUpload {
+		fifo = mm.fifo
+	}
+	for _, req := range fifo {
+		if req.class == class {
+			return true
+		}
+	}
+	return false
+}
+
 // Request is a blocking request for memory. The request will return when the
 // memory has been acquired. If 'false' is returned, it means that the renter
 // shut down before the memory could be allocated.
-func (mm *memoryManager) Request(amount uint64, priority bool) bool {
-	// Try to request the memory.
+func (mm *memoryManager) Request(amount uint64, class memoryClass) bool {
+	// Try to request the memory. Requests of a class are granted in order, so
+	// the request can only be granted immediately if no other requests of the
+	// same class are waiting.
 	mm.mu.Lock()
-	if len(mm.fifo) == 0 && mm.try(amount) {
+	if !mm.waiting(class) && mm.tryClass(amount, class) {
 		mm.mu.Unlock()
 		return true
 	}
@@ -75,9 +178,11 @@ func (mm *memoryManager) Request(amount uint64, priority bool) bool {
 	// There is not enough memory available for this request, join the fifo.
 	myRequest := &memoryRequest{
 		amount: amount,
+		class:  class,
 		done:   make(chan struct{}),
+		start:  time.Now(),
 	}
-	if priority {
+	if class != memoryClassUpload {
 		mm.priorityFifo = append(mm.priorityFifo, myRequest)
 	} else {
 		mm.fifo = append(mm.fifo, myRequest)
@@ -97,62 +202,139 @@ func (mm *memoryManager) Request(amount uint64, priority bool) bool {
 
 // Return will return memory to the manager, waking any blocking threads which
 // now have enough memory to proceed.
-func (mm *memoryManager) Return(amount uint64) {
+func (mm *memoryManager) Return(amount uint64, class memoryClass) {
 	mm.mu.Lock()
 	defer mm.mu.Unlock()
 
-	// Add the remaining memory to the pool of available memory, clearing out
-	// the underflow if needed.
-	if mm.underflow > 0 && amount <= mm.underflow {
-		// Not even enough memory has been returned to clear the underflow.
-		// Reduce the underflow amount and return.
-		mm.underflow -= amount
-		return
-	} else if mm.underflow > 0 && amount > mm.underflow {
-		amount -= mm.underflow
-		mm.underflow = 0
-	}
-	mm.available += amount
-
-	// Sanity check - the amount of memory available should not exceed the base
-	// unless the memory manager is being used incorrectly.
-	if mm.available > mm.base {
+	// Sanity check - the amount of memory returned should not exceed the
+	// amount of memory in use unless the memory manager is being used
+	// incorrectly.
+	if amount > mm.used[class] {
 		build.Critical("renter memory manager being used incorrectly, too much memory returned")
-		mm.available = mm.base
+		amount = mm.used[class]
+	}
+	mm.used[class] -= amount
+
+	mm.wake()
+}
+
+// Base returns the total amount of memory that the memory manager is allowed
+// to hand out.
+func (mm *memoryManager) Base() uint64 {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.base
+}
+
+// Reserves returns the amount of memory reserved for uploads, downloads and
+// streams.
+func (mm *memoryManager) Reserves() (upload, download, stream uint64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.reserves[memoryClassUpload], mm.reserves[memoryClassDownload], mm.reserves[memoryClassStream]
+}
+
+// SetBase changes the total amount of memory that the memory manager is
+// allowed to hand out. If the base is lowered below the amount of memory
+// currently in use, the memory manager will not grant further requests until
+// enough memory has been returned.
+func (mm *memoryManager) SetBase(base uint64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.base = base
+	mm.wake()
+}
+
+// SetReserves changes the amount of memory reserved for uploads, downloads and
+// streams. The caller is responsible for ensuring that the reserves do not
+// exceed the base memory.
+func (mm *memoryManager) SetReserves(upload, download, stream uint64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.reserves[memoryClassUpload] = upload
+	mm.reserves[memoryClassDownload] = download
+	mm.reserves[memoryClassStream] = stream
+	mm.wake()
+}
+
+// Status returns the current state of the memory manager.
+func (mm *memoryManager) Status() modules.MemoryStatus {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	status := modules.MemoryStatus{
+		Base:  mm.base,
+		InUse: mm.inUse(),
+
+		Upload:   modules.MemoryClassStatus{Reserve: mm.reserves[memoryClassUpload], InUse: mm.used[memoryClassUpload]},
+		Download: modules.MemoryClassStatus{Reserve: mm.reserves[memoryClassDownload], InUse: mm.used[memoryClassDownload]},
+		Stream:   modules.MemoryClassStatus{Reserve: mm.reserves[memoryClassStream], InUse: mm.used[memoryClassStream]},
+	}
+	if mm.waitedRequests > 0 {
+		status.AverageWait = mm.waitedTotal / time.Duration(mm.waitedRequests)
 	}
 
-	// Release as many of the priority threads blocking in the fifo as possible.
-	for len(mm.priorityFifo) > 0 {
-		if !mm.try(mm.priorityFifo[0].amount) {
-			// There is not enough memory to grant the next request, meaning no
-			// future requests should be checked either.
-			return
+	// Add the requests that are currently waiting.
+	classStatus := map[memoryClass]*modules.MemoryClassStatus{
+		memoryClassUpload:   &status.Upload,
+		memoryClassDownload: &status.Download,
+		memoryClassStream:   &status.Stream,
+	}
+	now := time.Now()
+	for _, fifo := range [][]*memoryRequest{mm.priorityFifo, mm.fifo} {
+		for _, req := range fifo {
+			cs := classStatus[req.class]
+			cs.WaitingRequests++
+			if wait := now.Sub(req.start); wait > cs.LongestWait {
+				cs.LongestWait = wait
+			}
 		}
-		// There is enough memory to grant the next request. Unblock that
-		// request and continue checking the next requests.
-		close(mm.priorityFifo[0].done)
-		mm.priorityFifo = mm.priorityFifo[1:]
-	}
-
-	// Release as many of the threads blocking in the fifo as possible.
-	for len(mm.fifo) > 0 {
-		if !mm.try(mm.fifo[0].amount) {
-			// There is not enough memory to grant the next request, meaning no
-			// future requests should be checked either.
-			return
+	}
+	status.WaitingRequests = len(mm.priorityFifo) + len(mm.fifo)
+	return status
+}
+
+// grant will close out a request that has been granted memory, updating the
+// wait statistics.
+func (mm *memoryManager) grant(req *memoryRequest) {
+	mm.waitedRequests++
+	mm.waitedTotal += time.Since(req.start)
+	close(req.done)
+}
+
+// wake will release as many of the threads blocking in the fifos as the
+// available memory allows, starting with the priority threads. Requests of the
+// same class are granted in order; once a request cannot be granted, no later
+// requests of that class are considered.
+func (mm *memoryManager) wake() {
+	mm.priorityFifo = mm.wakeFifo(mm.priorityFifo)
+	mm.fifo = mm.wakeFifo(mm.fifo)
+}
+
+// wakeFifo grants as many of the requests in the fifo as possible, returning
+// the requests that are still waiting.
+func (mm *memoryManager) wakeFifo(fifo []*memoryRequest) []*memoryRequest {
+	var blocked [numMemoryClasses]bool
+	remaining := fifo[:0]
+	for _, req := range fifo {
+		if blocked[req.class] || !mm.tryClass(req.amount, req.class) {
+			// There is not enough memory to grant the request, meaning no
+			// future requests of this class should be checked either.
+			blocked[req.class] = true
+			remaining = append(remaining, req)
+			continue
 		}
-		// There is enough memory to grant the next request. Unblock that
-		// request and continue checking the next requests.
-		close(mm.fifo[0].done)
-		mm.fifo = mm.fifo[1:]
+		// There is enough memory to grant the request. Unblock that request
+		// and continue checking the next requests.
+		mm.grant(req)
 	}
+	return remaining
 }
 
 // newMemoryManager will create a memoryManager and return it.
 func newMemoryManager(baseMemory uint64, stopChan <-chan struct{}) *memoryManager {
 	return &memoryManager{
-		available: baseMemory,
-		base:      baseMemory,
-		stop:      stopChan,
+		base: baseMemory,
+		stop: stopChan,
 	}
 }
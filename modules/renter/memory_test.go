@@ -0,0 +1,108 @@
+package renter
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMemoryManagerSetBase checks that the memory manager correctly handles
+// the base memory being raised and lowered while memory is in use.
+func TestMemoryManagerSetBase(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	mm := newMemoryManager(100, stop)
+
+	// Use most of the memory, then lower the base below the amount in use.
+	if !mm.Request(80, memoryClassUpload) {
+		t.Fatal("request failed")
+	}
+	mm.SetBase(50)
+
+	// A request should now block until enough memory is returned.
+	granted := make(chan struct{})
+	go func() {
+		mm.Request(20, memoryClassUpload)
+		close(granted)
+	}()
+	mm.Return(40, memoryClassUpload)
+	select {
+	case <-granted:
+		t.Fatal("request granted while memory is still over the base")
+	default:
+	}
+
+	// Raising the base should free up enough memory for the request.
+	mm.SetBase(80)
+	<-granted
+	mm.Return(40, memoryClassUpload)
+	mm.Return(20, memoryClassUpload)
+	status := mm.Status()
+	if status.Base != 80 || status.InUse != 0 || status.WaitingRequests != 0 {
+		t.Fatal("unexpected status after returning all memory:", status)
+	}
+}
+
+// TestMemoryManagerReserves checks that memory reserved for one class cannot
+// be used by the other classes.
+func TestMemoryManagerReserves(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	mm := newMemoryManager(100, stop)
+	mm.SetReserves(0, 0, 30)
+
+	// Uploads can use everything but the stream reserve.
+	if !mm.Request(70, memoryClassUpload) {
+		t.Fatal("request failed")
+	}
+	granted := make(chan struct{})
+	go func() {
+		mm.Request(10, memoryClassDownload)
+		close(granted)
+	}()
+
+	// The stream reserve is still available to streams even though a
+	// download is waiting.
+	done := make(chan struct{})
+	go func() {
+		mm.Request(30, memoryClassStream)
+		close(done)
+	}()
+	<-done
+	select {
+	case <-granted:
+		t.Fatal("download was granted memory from the stream reserve")
+	default:
+	}
+
+	// Returning the upload memory unblocks the download.
+	mm.Return(70, memoryClassUpload)
+	<-granted
+	status := mm.Status()
+	if status.Download.InUse != 10 || status.Stream.InUse != 30 || status.Stream.Reserve != 30 {
+		t.Fatal("unexpected status:", status)
+	}
+}
+
+// TestMemoryReservesValidate checks that reserves are only valid if they fit
+// into the memory budget, including reserves whose sum overflows.
+func TestMemoryReservesValidate(t *testing.T) {
+	tests := []struct {
+		reserves memoryReserves
+		valid    bool
+	}{
+		{memoryReserves{}, true},
+		{memoryReserves{Upload: 30, Download: 30, Stream: 40}, true},
+		{memoryReserves{Upload: 30, Download: 30, Stream: 41}, false},
+		{memoryReserves{Upload: 101}, false},
+		{memoryReserves{Upload: math.MaxUint64, Download: 2}, false},
+		{memoryReserves{Upload: 50, Download: math.MaxUint64 - 49, Stream: 100}, false},
+	}
+	for _, test := range tests {
+		err := test.reserves.validate(100)
+		if test.valid && err != nil {
+			t.Errorf("expected %v to be valid, got %v", test.reserves, err)
+		} else if !test.valid && err != errMemoryReservesTooLarge {
+			t.Errorf("expected %v to be invalid, got %v", test.reserves, err)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
@@ -191,8 +192,23 @@ func (r *Renter) saveFile(f *file) error {
 // saveSync stores the current renter data to disk and then syncs to disk.
 func (r *Renter) saveSync() error {
 	data := struct {
-		Tracking map[string]trackedFile
-	}{r.tracking}
+		AcknowledgedAlerts []modules.AlertID
+		BenchmarkHosts     bool
+		MaxMemory          uint64
+		MemoryReserves     memoryReserves
+		MaxDownloadSpeed   int64
+		MaxUploadSpeed     int64
+		RateLimitSchedule  []modules.RateLimitWindow
+		Snapshots          []snapshotRecord
+		SnapshotContent    crypto.Hash
+		SyncJobs           []modules.RenterSyncParams
+		Tracking           map[string]trackedFile
+	}{r.staticAlerter.Acknowledged(), r.benchmarkHosts, r.maxMemory, r.memoryReserve, r.rateLimitDownload, r.rateLimitUpload, r.rateLimitSchedule, r.snapshots, r.snapshotContent, nil, r.tracking}
+	for _, job := range r.syncJobs {
+		if job.recurring() {
+			data.SyncJobs = append(data.SyncJobs, job.params)
+		}
+	}
 
 	return persist.SaveJSON(saveMetadata, data, filepath.Join(r.persistDir, PersistFilename))
 }
@@ -236,8 +252,18 @@ func (r *Renter) load() error {
 
 	// Load contracts, repair set, and entropy.
 	data := struct {
-		Tracking  map[string]trackedFile
-		Repairing map[string]string // COMPATv0.4.8
+		AcknowledgedAlerts []modules.AlertID
+		BenchmarkHosts     bool
+		MaxMemory          uint64
+		MemoryReserves     memoryReserves
+		MaxDownloadSpeed   int64
+		MaxUploadSpeed     int64
+		RateLimitSchedule  []modules.RateLimitWindow
+		Snapshots          []snapshotRecord
+		SnapshotContent    crypto.Hash
+		SyncJobs           []modules.RenterSyncParams
+		Tracking           map[string]trackedFile
+		Repairing          map[string]string // COMPATv0.4.8
 	}{}
 	err = persist.LoadJSON(saveMetadata, &data, filepath.Join(r.persistDir, PersistFilename))
 	if err != nil {
@@ -246,6 +272,27 @@ func (r *Renter) load() error {
 	if data.Tracking != nil {
 		r.tracking = data.Tracking
 	}
+	r.removeSyncTempFiles()
+	if data.MaxMemory > 0 {
+		r.maxMemory = data.MaxMemory
+		r.memoryManager.SetBase(data.MaxMemory)
+	}
+	if err := data.MemoryReserves.validate(r.memoryManager.Base()); err != nil {
+		r.log.Println("WARN: ignoring the persisted memory reserves:", err)
+		data.MemoryReserves = memoryReserves{}
+	}
+	r.memoryReserve = data.MemoryReserves
+	r.rateLimitDownload = data.MaxDownloadSpeed
+	r.rateLimitUpload = data.MaxUploadSpeed
+	r.rateLimitSchedule = data.RateLimitSchedule
+	r.benchmarkHosts = data.BenchmarkHosts
+	r.snapshots = data.Snapshots
+	r.snapshotContent = data.SnapshotContent
+	r.staticAlerter.SetAcknowledged(data.AcknowledgedAlerts)
+	r.memoryManager.SetReserves(data.MemoryReserves.Upload, data.MemoryReserves.Download, data.MemoryReserves.Stream)
+	for _, params := range data.SyncJobs {
+		r.syncJobs[params.SiaPath] = newSyncJob(params)
+	}
 
 	return nil
 }
@@ -337,9 +384,8 @@ func (r *Renter) ShareFilesASCII(nicknames []string) (string, error) {
 	return buf.String(), nil
 }
 
-// loadSharedFiles reads .sia data from reader and registers the contained
-// files in the renter. It returns the nicknames of the loaded files.
-func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
+// decodeSharedFiles reads the files of .sia data from reader.
+func decodeSharedFiles(reader io.Reader) ([]*file, error) {
 	// read header
 	var header [15]byte
 	var version string
@@ -372,8 +418,20 @@ func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
+	}
+	return files, nil
+}
 
-		// Make sure the file's name does not conflict with existing files.
+// loadSharedFiles reads .sia data from reader and registers the contained
+// files in the renter. It returns the nicknames of the loaded files.
+func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
+	files, err := decodeSharedFiles(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the names of the files do not conflict with existing files.
+	for i := range files {
 		dupCount := 0
 		origName := files[i].name
 		for {
@@ -387,7 +445,7 @@ func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
 	}
 
 	// Add files to renter.
-	names := make([]string, numFiles)
+	names := make([]string, len(files))
 	for i, f := range files {
 		r.files[f.name] = f
 		names[i] = f.name
@@ -19,6 +19,11 @@ const (
 	// remainingFile is a constant used to indicate that a fileSection can access
 	// the whole remaining file instead of being bound to a certain end offset.
 	remainingFile = -1
+
+	// revisionActionOverhead is an upper bound on the size of an encoded
+	// RevisionAction, not counting its data. It is used to determine how many
+	// sectors fit into a single revision.
+	revisionActionOverhead = 64
 )
 
 var (
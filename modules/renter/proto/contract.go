@@ -247,7 +247,7 @@ func (c *SafeContract) applySetRoot(root crypto.Hash, index int) error {
 	return c.merkleRoots.insert(index, root)
 }
 
-func (c *SafeContract) recordUploadIntent(rev types.FileContractRevision, root crypto.Hash, storageCost, bandwidthCost types.Currency) (*writeaheadlog.Transaction, error) {
+func (c *SafeContract) recordUploadIntent(rev types.FileContractRevision, roots []crypto.Hash, storageCost, bandwidthCost types.Currency) (*writeaheadlog.Transaction, error) {
 	// construct new header
 	// NOTE: this header will not include the host signature
 	c.headerMu.Lock()
@@ -257,10 +257,11 @@ func (c *SafeContract) recordUploadIntent(rev types.FileContractRevision, root c
 	newHeader.StorageSpending = newHeader.StorageSpending.Add(storageCost)
 	newHeader.UploadSpending = newHeader.UploadSpending.Add(bandwidthCost)
 
-	t, err := c.wal.NewTransaction([]writeaheadlog.Update{
-		c.makeUpdateSetHeader(newHeader),
-		c.makeUpdateSetRoot(root, c.merkleRoots.len()),
-	})
+	updates := []writeaheadlog.Update{c.makeUpdateSetHeader(newHeader)}
+	for i, root := range roots {
+		updates = append(updates, c.makeUpdateSetRoot(root, c.merkleRoots.len()+i))
+	}
+	t, err := c.wal.NewTransaction(updates)
 	if err != nil {
 		return nil, err
 	}
@@ -271,7 +272,7 @@ func (c *SafeContract) recordUploadIntent(rev types.FileContractRevision, root c
 	return t, nil
 }
 
-func (c *SafeContract) commitUpload(t *writeaheadlog.Transaction, signedTxn types.Transaction, root crypto.Hash, storageCost, bandwidthCost types.Currency) error {
+func (c *SafeContract) commitUpload(t *writeaheadlog.Transaction, signedTxn types.Transaction, roots []crypto.Hash, storageCost, bandwidthCost types.Currency) error {
 	// construct new header
 	c.headerMu.Lock()
 	newHeader := c.header
@@ -283,9 +284,61 @@ func (c *SafeContract) commitUpload(t *writeaheadlog.Transaction, signedTxn type
 	if err := c.applySetHeader(newHeader); err != nil {
 		return err
 	}
-	if err := c.applySetRoot(root, c.merkleRoots.len()); err != nil {
+	for _, root := range roots {
+		if err := c.applySetRoot(root, c.merkleRoots.len()); err != nil {
+			return err
+		}
+	}
+	if err := c.headerFile.Sync(); err != nil {
+		return err
+	}
+	if err := t.SignalUpdatesApplied(); err != nil {
+		return err
+	}
+	c.unappliedTxns = nil
+	return nil
+}
+
+func (c *SafeContract) recordReplaceIntent(rev types.FileContractRevision, indices []int, roots []crypto.Hash, bandwidthCost types.Currency) (*writeaheadlog.Transaction, error) {
+	// construct new header
+	// NOTE: this header will not include the host signature
+	c.headerMu.Lock()
+	newHeader := c.header
+	c.headerMu.Unlock()
+	newHeader.Transaction.FileContractRevisions = []types.FileContractRevision{rev}
+	newHeader.UploadSpending = newHeader.UploadSpending.Add(bandwidthCost)
+
+	updates := []writeaheadlog.Update{c.makeUpdateSetHeader(newHeader)}
+	for i, root := range roots {
+		updates = append(updates, c.makeUpdateSetRoot(root, indices[i]))
+	}
+	t, err := c.wal.NewTransaction(updates)
+	if err != nil {
+		return nil, err
+	}
+	if err := <-t.SignalSetupComplete(); err != nil {
+		return nil, err
+	}
+	c.unappliedTxns = append(c.unappliedTxns, t)
+	return t, nil
+}
+
+func (c *SafeContract) commitReplace(t *writeaheadlog.Transaction, signedTxn types.Transaction, indices []int, roots []crypto.Hash, bandwidthCost types.Currency) error {
+	// construct new header
+	c.headerMu.Lock()
+	newHeader := c.header
+	c.headerMu.Unlock()
+	newHeader.Transaction = signedTxn
+	newHeader.UploadSpending = newHeader.UploadSpending.Add(bandwidthCost)
+
+	if err := c.applySetHeader(newHeader); err != nil {
 		return err
 	}
+	for i, root := range roots {
+		if err := c.applySetRoot(root, indices[i]); err != nil {
+			return err
+		}
+	}
 	if err := c.headerFile.Sync(); err != nil {
 		return err
 	}
@@ -515,7 +568,7 @@ func (cs *ContractSet) ConvertV130Contract(c V130Contract, cr V130CachedRevision
 		defer cs.Return(sc)
 		if len(cr.MerkleRoots) == sc.merkleRoots.len()+1 {
 			root := cr.MerkleRoots[len(cr.MerkleRoots)-1]
-			_, err = sc.recordUploadIntent(cr.Revision, root, types.ZeroCurrency, types.ZeroCurrency)
+			_, err = sc.recordUploadIntent(cr.Revision, []crypto.Hash{root}, types.ZeroCurrency, types.ZeroCurrency)
 		} else {
 			_, err = sc.recordDownloadIntent(cr.Revision, types.ZeroCurrency)
 		}
@@ -58,12 +58,12 @@ func TestContractUncommittedTxn(t *testing.T) {
 		StorageSpending: types.NewCurrency64(7),
 		UploadSpending:  types.NewCurrency64(17),
 	}
-	revisedRoots := []crypto.Hash{{1}, {2}}
+	revisedRoots := []crypto.Hash{{1}, {2}, {3}}
 	fcr := revisedHeader.Transaction.FileContractRevisions[0]
-	newRoot := revisedRoots[1]
+	newRoots := revisedRoots[1:]
 	storageCost := revisedHeader.StorageSpending.Sub(initialHeader.StorageSpending)
 	bandwidthCost := revisedHeader.UploadSpending.Sub(initialHeader.UploadSpending)
-	walTxn, err := sc.recordUploadIntent(fcr, newRoot, storageCost, bandwidthCost)
+	walTxn, err := sc.recordUploadIntent(fcr, newRoots, storageCost, bandwidthCost)
 	if err != nil {
 		t.Fatal(err)
 	}
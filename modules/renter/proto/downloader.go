@@ -23,12 +23,20 @@ type Downloader struct {
 	hdb         hostDB
 	host        modules.HostDBEntry
 	once        sync.Once
+
+	// session is set if the host supports sessions, in which case all
+	// downloads are made through the session instead of conn.
+	session *Session
 }
 
 // Sector retrieves the sector with the specified Merkle root, and revises
 // the underlying contract to pay the host proportionally to the data
 // retrieve.
 func (hd *Downloader) Sector(root crypto.Hash) (_ modules.RenterContract, _ []byte, err error) {
+	if hd.session != nil {
+		return hd.session.Sector(root)
+	}
+
 	// Reset deadline when finished.
 	defer extendDeadline(hd.conn, time.Hour) // TODO: Constant.
 
@@ -134,6 +142,37 @@ func (hd *Downloader) Sector(root crypto.Hash) (_ modules.RenterContract, _ []by
 	return sc.Metadata(), sector, nil
 }
 
+// Download retrieves the requested sections of sectors, and revises the
+// underlying contract to pay the host proportionally to the data retrieved.
+// The offset and length of each section must be multiples of
+// crypto.SegmentSize. Hosts that support sessions only send and charge for
+// the requested sections. Older hosts can't prove sections of a sector, so
+// the whole sector of each section is downloaded instead.
+func (hd *Downloader) Download(sections []modules.DownloadAction) (_ modules.RenterContract, _ [][]byte, err error) {
+	if hd.session != nil {
+		return hd.session.Download(sections)
+	}
+
+	var contract modules.RenterContract
+	sectors := make(map[crypto.Hash][]byte)
+	data := make([][]byte, len(sections))
+	for i, section := range sections {
+		if section.Offset+section.Length > modules.SectorSize {
+			return modules.RenterContract{}, nil, errors.New("section is out of the bounds of the sector")
+		}
+		sector, ok := sectors[section.MerkleRoot]
+		if !ok {
+			contract, sector, err = hd.Sector(section.MerkleRoot)
+			if err != nil {
+				return modules.RenterContract{}, nil, err
+			}
+			sectors[section.MerkleRoot] = sector
+		}
+		data[i] = sector[section.Offset : section.Offset+section.Length]
+	}
+	return contract, data, nil
+}
+
 // shutdown terminates the revision loop and signals the goroutine spawned in
 // NewDownloader to return.
 func (hd *Downloader) shutdown() {
@@ -147,6 +186,9 @@ func (hd *Downloader) shutdown() {
 // Close cleanly terminates the download loop with the host and closes the
 // connection.
 func (hd *Downloader) Close() error {
+	if hd.session != nil {
+		return hd.session.Close()
+	}
 	// using once ensures that Close is idempotent
 	hd.once.Do(hd.shutdown)
 	return hd.conn.Close()
@@ -155,6 +197,25 @@ func (hd *Downloader) Close() error {
 // NewDownloader initiates the download request loop with a host, and returns a
 // Downloader.
 func (cs *ContractSet) NewDownloader(host modules.HostDBEntry, id types.FileContractID, hdb hostDB, cancel <-chan struct{}) (_ *Downloader, err error) {
+	// Hosts that support sessions are downloaded from through a session, so
+	// that the settings are not exchanged again for every sector.
+	if host.Sessions {
+		s, err := cs.NewSession(host, id, 0, hdb, cancel)
+		if err == nil {
+			return &Downloader{
+				contractID:  id,
+				contractSet: cs,
+				host:        host,
+				deps:        cs.deps,
+				hdb:         hdb,
+				session:     s,
+			}, nil
+		} else if !isRPCRejected(err) {
+			return nil, err
+		}
+		// The host rejected the session, so fall back to the older RPC.
+	}
+
 	sc, ok := cs.Acquire(id)
 	if !ok {
 		return nil, errors.New("invalid contract")
@@ -178,26 +239,10 @@ func (cs *ContractSet) NewDownloader(host modules.HostDBEntry, id types.FileCont
 		}
 	}()
 
-	conn, closeChan, err := initiateRevisionLoop(host, contract, modules.RPCDownload, cancel, cs.rl)
-	if IsRevisionMismatch(err) && len(sc.unappliedTxns) > 0 {
-		// we have desynced from the host. If we have unapplied updates from the
-		// WAL, try applying them.
-		conn, closeChan, err = initiateRevisionLoop(host, sc.unappliedHeader(), modules.RPCDownload, cancel, cs.rl)
-		if err != nil {
-			return nil, err
-		}
-		// applying the updates was successful; commit them to disk
-		if err := sc.commitTxns(); err != nil {
-			return nil, err
-		}
-	} else if err != nil {
+	conn, closeChan, err := cs.initiateContractRevisionLoop(sc, host, modules.RPCDownload, cancel)
+	if err != nil {
 		return nil, err
 	}
-	// if we succeeded, we can safely discard the unappliedTxns
-	for _, txn := range sc.unappliedTxns {
-		txn.SignalUpdatesApplied()
-	}
-	sc.unappliedTxns = nil
 
 	// the host is now ready to accept revisions
 	return &Downloader{
@@ -14,6 +14,24 @@ import (
 	"github.com/NebulousLabs/ratelimit"
 )
 
+var (
+	// errEmptyUploadBatch is returned by UploadBatch if no sectors are
+	// provided.
+	errEmptyUploadBatch = errors.New("upload batch doesn't contain any sectors")
+
+	// errLargeUploadBatch is returned by UploadBatch if the sectors don't fit
+	// into a single revision of the host.
+	errLargeUploadBatch = errors.New("upload batch exceeds the host's MaxReviseBatchSize")
+
+	// errReplaceMismatch is returned by ReplaceBatch if the number of sectors
+	// doesn't match the number of replaced roots.
+	errReplaceMismatch = errors.New("number of sectors doesn't match the number of replaced roots")
+
+	// errReplacedRootNotFound is returned by ReplaceBatch if a replaced root
+	// is not stored in the contract.
+	errReplacedRootNotFound = errors.New("replaced root is not stored in the contract")
+)
+
 // cachedMerkleRoot calculates the root of a set of existing Merkle roots.
 func cachedMerkleRoot(roots []crypto.Hash) crypto.Hash {
 	tree := crypto.NewCachedTree(sectorHeight) // NOTE: height is not strictly necessary here
@@ -36,6 +54,10 @@ type Editor struct {
 	once        sync.Once
 
 	height types.BlockHeight
+
+	// session is set if the host supports sessions, in which case all
+	// revisions are made through the session instead of conn.
+	session *Session
 }
 
 // shutdown terminates the revision loop and signals the goroutine spawned in
@@ -51,56 +73,71 @@ func (he *Editor) shutdown() {
 // Close cleanly terminates the revision loop with the host and closes the
 // connection.
 func (he *Editor) Close() error {
+	if he.session != nil {
+		return he.session.Close()
+	}
 	// using once ensures that Close is idempotent
 	he.once.Do(he.shutdown)
 	return he.conn.Close()
 }
 
 // Upload negotiates a revision that adds a sector to a file contract.
-func (he *Editor) Upload(data []byte) (_ modules.RenterContract, _ crypto.Hash, err error) {
+func (he *Editor) Upload(data []byte) (modules.RenterContract, crypto.Hash, error) {
+	contract, roots, err := he.UploadBatch([][]byte{data})
+	if err != nil {
+		return modules.RenterContract{}, crypto.Hash{}, err
+	}
+	return contract, roots[0], nil
+}
+
+// MaxUploadBatch returns the maximum number of sectors that can be uploaded
+// with a single call to UploadBatch.
+func (he *Editor) MaxUploadBatch() int {
+	if he.session != nil {
+		return maxUploadBatch(he.session.host)
+	}
+	return maxUploadBatch(he.host)
+}
+
+// UploadBatch negotiates a single revision that adds several sectors to a
+// file contract. It returns the Merkle roots of the sectors. At most
+// MaxUploadBatch sectors can be uploaded at once.
+func (he *Editor) UploadBatch(sectors [][]byte) (_ modules.RenterContract, _ []crypto.Hash, err error) {
+	if he.session != nil {
+		return he.session.UploadBatch(sectors)
+	}
+	if err := checkUploadBatch(he.host, sectors); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
 	// Acquire the contract.
 	sc, haveContract := he.contractSet.Acquire(he.contractID)
 	if !haveContract {
-		return modules.RenterContract{}, crypto.Hash{}, errors.New("contract not present in contract set")
+		return modules.RenterContract{}, nil, errors.New("contract not present in contract set")
 	}
 	defer he.contractSet.Return(sc)
 	contract := sc.header // for convenience
 
 	// calculate price
-	// TODO: height is never updated, so we'll wind up overpaying on long-running uploads
-	blockBytes := types.NewCurrency64(modules.SectorSize * uint64(contract.LastRevision().NewWindowEnd-he.height))
-	sectorStoragePrice := he.host.StoragePrice.Mul(blockBytes)
-	sectorBandwidthPrice := he.host.UploadBandwidthPrice.Mul64(modules.SectorSize)
-	sectorCollateral := he.host.Collateral.Mul(blockBytes)
-
-	// to mitigate small errors (e.g. differing block heights), fudge the
-	// price and collateral by 0.2%. This is only applied to hosts above
-	// v1.0.1; older hosts use stricter math.
-	if build.VersionCmp(he.host.Version, "1.0.1") > 0 {
-		sectorStoragePrice = sectorStoragePrice.MulFloat(1 + hostPriceLeeway)
-		sectorBandwidthPrice = sectorBandwidthPrice.MulFloat(1 + hostPriceLeeway)
-		sectorCollateral = sectorCollateral.MulFloat(1 - hostPriceLeeway)
-	}
-
-	sectorPrice := sectorStoragePrice.Add(sectorBandwidthPrice)
-	if contract.RenterFunds().Cmp(sectorPrice) < 0 {
-		return modules.RenterContract{}, crypto.Hash{}, errors.New("contract has insufficient funds to support upload")
+	storagePrice, bandwidthPrice, collateral := uploadBatchPrices(he.host, contract.LastRevision(), he.height, len(sectors))
+	price := storagePrice.Add(bandwidthPrice)
+	if contract.RenterFunds().Cmp(price) < 0 {
+		return modules.RenterContract{}, nil, errors.New("contract has insufficient funds to support upload")
 	}
-	if contract.LastRevision().NewMissedProofOutputs[1].Value.Cmp(sectorCollateral) < 0 {
-		return modules.RenterContract{}, crypto.Hash{}, errors.New("contract has insufficient collateral to support upload")
+	if contract.LastRevision().NewMissedProofOutputs[1].Value.Cmp(collateral) < 0 {
+		return modules.RenterContract{}, nil, errors.New("contract has insufficient collateral to support upload")
 	}
 
 	// calculate the new Merkle root
-	sectorRoot := crypto.MerkleRoot(data)
-	merkleRoot := sc.merkleRoots.checkNewRoot(sectorRoot)
+	sectorRoots := make([]crypto.Hash, len(sectors))
+	for i, data := range sectors {
+		sectorRoots[i] = crypto.MerkleRoot(data)
+	}
+	merkleRoot := sc.merkleRoots.checkNewRoots(sectorRoots)
 
-	// create the action and revision
-	actions := []modules.RevisionAction{{
-		Type:        modules.ActionInsert,
-		SectorIndex: uint64(sc.merkleRoots.len()),
-		Data:        data,
-	}}
-	rev := newUploadRevision(contract.LastRevision(), merkleRoot, sectorPrice, sectorCollateral)
+	// create the actions and revision
+	actions := uploadActions(sc.merkleRoots.len(), sectors)
+	rev := newUploadRevision(contract.LastRevision(), merkleRoot, len(sectors), price, collateral)
 
 	// run the revision iteration
 	defer func() {
@@ -118,26 +155,26 @@ func (he *Editor) Upload(data []byte) (_ modules.RenterContract, _ crypto.Hash,
 	// initiate revision
 	extendDeadline(he.conn, modules.NegotiateSettingsTime)
 	if err := startRevision(he.conn, he.host); err != nil {
-		return modules.RenterContract{}, crypto.Hash{}, err
+		return modules.RenterContract{}, nil, err
 	}
 
 	// record the change we are about to make to the contract. If we lose power
 	// mid-revision, this allows us to restore either the pre-revision or
 	// post-revision contract.
-	walTxn, err := sc.recordUploadIntent(rev, sectorRoot, sectorStoragePrice, sectorBandwidthPrice)
+	walTxn, err := sc.recordUploadIntent(rev, sectorRoots, storagePrice, bandwidthPrice)
 	if err != nil {
-		return modules.RenterContract{}, crypto.Hash{}, err
+		return modules.RenterContract{}, nil, err
 	}
 
 	// send actions
 	extendDeadline(he.conn, modules.NegotiateFileContractRevisionTime)
 	if err := encoding.WriteObject(he.conn, actions); err != nil {
-		return modules.RenterContract{}, crypto.Hash{}, err
+		return modules.RenterContract{}, nil, err
 	}
 
 	// Disrupt here before sending the signed revision to the host.
 	if he.deps.Disrupt("InterruptUploadBeforeSendingRevision") {
-		return modules.RenterContract{}, crypto.Hash{},
+		return modules.RenterContract{}, nil,
 			errors.New("InterruptUploadBeforeSendingRevision disrupt")
 	}
 
@@ -149,27 +186,239 @@ func (he *Editor) Upload(data []byte) (_ modules.RenterContract, _ crypto.Hash,
 		// cause the next operation to fail
 		he.conn.Close()
 	} else if err != nil {
-		return modules.RenterContract{}, crypto.Hash{}, err
+		return modules.RenterContract{}, nil, err
 	}
 
 	// Disrupt here before updating the contract.
 	if he.deps.Disrupt("InterruptUploadAfterSendingRevision") {
-		return modules.RenterContract{}, crypto.Hash{},
+		return modules.RenterContract{}, nil,
 			errors.New("InterruptUploadAfterSendingRevision disrupt")
 	}
 
 	// update contract
-	err = sc.commitUpload(walTxn, signedTxn, sectorRoot, sectorStoragePrice, sectorBandwidthPrice)
+	err = sc.commitUpload(walTxn, signedTxn, sectorRoots, storagePrice, bandwidthPrice)
 	if err != nil {
-		return modules.RenterContract{}, crypto.Hash{}, err
+		return modules.RenterContract{}, nil, err
+	}
+
+	return sc.Metadata(), sectorRoots, nil
+}
+
+// ReplaceBatch negotiates a single revision that overwrites the sectors with
+// the roots oldRoots with new sectors. The contract keeps its size, so only
+// the bandwidth of the new sectors is paid for. It returns the Merkle roots of
+// the new sectors. At most MaxUploadBatch sectors can be replaced at once.
+func (he *Editor) ReplaceBatch(oldRoots []crypto.Hash, sectors [][]byte) (_ modules.RenterContract, _ []crypto.Hash, err error) {
+	if he.session != nil {
+		return he.session.ReplaceBatch(oldRoots, sectors)
+	}
+	if err := checkUploadBatch(he.host, sectors); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// Acquire the contract.
+	sc, haveContract := he.contractSet.Acquire(he.contractID)
+	if !haveContract {
+		return modules.RenterContract{}, nil, errors.New("contract not present in contract set")
+	}
+	defer he.contractSet.Return(sc)
+	contract := sc.header // for convenience
+
+	// calculate price; overwriting a sector doesn't change the amount of
+	// storage that the host provides.
+	_, bandwidthPrice, _ := uploadBatchPrices(he.host, contract.LastRevision(), he.height, len(sectors))
+	if contract.RenterFunds().Cmp(bandwidthPrice) < 0 {
+		return modules.RenterContract{}, nil, errors.New("contract has insufficient funds to support upload")
+	}
+
+	// create the actions and revision
+	roots, err := sc.merkleRoots.merkleRoots()
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	actions, indices, sectorRoots, merkleRoot, err := replaceActions(roots, oldRoots, sectors)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	rev := newModifyRevision(contract.LastRevision(), merkleRoot, bandwidthPrice)
+
+	// run the revision iteration
+	defer func() {
+		// Increase Successful/Failed interactions accordingly
+		if err != nil {
+			he.hdb.IncrementFailedInteractions(he.host.PublicKey)
+		} else {
+			he.hdb.IncrementSuccessfulInteractions(he.host.PublicKey)
+		}
+
+		// reset deadline
+		extendDeadline(he.conn, time.Hour)
+	}()
+
+	// initiate revision
+	extendDeadline(he.conn, modules.NegotiateSettingsTime)
+	if err := startRevision(he.conn, he.host); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// record the change we are about to make to the contract.
+	walTxn, err := sc.recordReplaceIntent(rev, indices, sectorRoots, bandwidthPrice)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// send actions
+	extendDeadline(he.conn, modules.NegotiateFileContractRevisionTime)
+	if err := encoding.WriteObject(he.conn, actions); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// send revision to host and exchange signatures
+	extendDeadline(he.conn, connTimeout)
+	signedTxn, err := negotiateRevision(he.conn, rev, contract.SecretKey)
+	if err == modules.ErrStopResponse {
+		// if host gracefully closed, close our connection as well; this will
+		// cause the next operation to fail
+		he.conn.Close()
+	} else if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// update contract
+	err = sc.commitReplace(walTxn, signedTxn, indices, sectorRoots, bandwidthPrice)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	return sc.Metadata(), sectorRoots, nil
+}
+
+// maxUploadBatch returns the maximum number of sectors that fit into a single
+// revision, given the host's MaxReviseBatchSize. A single sector is always
+// allowed.
+func maxUploadBatch(host modules.HostDBEntry) int {
+	n := host.MaxReviseBatchSize / (modules.SectorSize + revisionActionOverhead)
+	if n == 0 {
+		return 1
+	}
+	return int(n)
+}
+
+// checkUploadBatch checks that sectors can be uploaded to the host in a single
+// revision.
+func checkUploadBatch(host modules.HostDBEntry, sectors [][]byte) error {
+	if len(sectors) == 0 {
+		return errEmptyUploadBatch
+	} else if len(sectors) > maxUploadBatch(host) {
+		return errLargeUploadBatch
+	}
+	return nil
+}
+
+// uploadActions returns the actions that append sectors to a contract that
+// currently has numSectors sectors.
+func uploadActions(numSectors int, sectors [][]byte) []modules.RevisionAction {
+	actions := make([]modules.RevisionAction, len(sectors))
+	for i, data := range sectors {
+		actions[i] = modules.RevisionAction{
+			Type:        modules.ActionInsert,
+			SectorIndex: uint64(numSectors + i),
+			Data:        data,
+		}
+	}
+	return actions
+}
+
+// replaceActions returns the actions that overwrite the sectors with the roots
+// oldRoots in a contract with the specified roots. It also returns the indices
+// of the overwritten sectors, the roots of the new sectors, and the Merkle
+// root of the contract after the sectors were overwritten. A root that is
+// stored several times is replaced once for every time it appears in
+// oldRoots.
+func replaceActions(roots, oldRoots []crypto.Hash, sectors [][]byte) ([]modules.RevisionAction, []int, []crypto.Hash, crypto.Hash, error) {
+	if len(oldRoots) != len(sectors) {
+		return nil, nil, nil, crypto.Hash{}, errReplaceMismatch
+	}
+	positions := make(map[crypto.Hash][]int)
+	for i, root := range roots {
+		positions[root] = append(positions[root], i)
+	}
+
+	newRoots := append([]crypto.Hash(nil), roots...)
+	actions := make([]modules.RevisionAction, len(sectors))
+	indices := make([]int, len(sectors))
+	sectorRoots := make([]crypto.Hash, len(sectors))
+	for i, data := range sectors {
+		pos := positions[oldRoots[i]]
+		if len(pos) == 0 {
+			return nil, nil, nil, crypto.Hash{}, errReplacedRootNotFound
+		}
+		positions[oldRoots[i]] = pos[1:]
+
+		indices[i] = pos[0]
+		sectorRoots[i] = crypto.MerkleRoot(data)
+		newRoots[pos[0]] = sectorRoots[i]
+		actions[i] = modules.RevisionAction{
+			Type:        modules.ActionModify,
+			SectorIndex: uint64(pos[0]),
+			Offset:      0,
+			Data:        data,
+		}
 	}
+	return actions, indices, sectorRoots, cachedMerkleRoot(newRoots), nil
+}
+
+// uploadBatchPrices returns the storage price, the bandwidth price, and the
+// collateral of uploading numSectors sectors to the host.
+func uploadBatchPrices(host modules.HostDBEntry, lastRev types.FileContractRevision, height types.BlockHeight, numSectors int) (storagePrice, bandwidthPrice, collateral types.Currency) {
+	storagePrice, bandwidthPrice, collateral = uploadPrices(host, lastRev, height)
+	n := uint64(numSectors)
+	return storagePrice.Mul64(n), bandwidthPrice.Mul64(n), collateral.Mul64(n)
+}
+
+// uploadPrices returns the storage price, the bandwidth price, and the
+// collateral of uploading a sector to the host.
+func uploadPrices(host modules.HostDBEntry, lastRev types.FileContractRevision, height types.BlockHeight) (storagePrice, bandwidthPrice, collateral types.Currency) {
+	// TODO: height is never updated, so we'll wind up overpaying on long-running uploads
+	blockBytes := types.NewCurrency64(modules.SectorSize * uint64(lastRev.NewWindowEnd-height))
+	storagePrice = host.StoragePrice.Mul(blockBytes)
+	bandwidthPrice = host.UploadBandwidthPrice.Mul64(modules.SectorSize)
+	collateral = host.Collateral.Mul(blockBytes)
 
-	return sc.Metadata(), sectorRoot, nil
+	// to mitigate small errors (e.g. differing block heights), fudge the
+	// price and collateral by 0.2%. This is only applied to hosts above
+	// v1.0.1; older hosts use stricter math.
+	if build.VersionCmp(host.Version, "1.0.1") > 0 {
+		storagePrice = storagePrice.MulFloat(1 + hostPriceLeeway)
+		bandwidthPrice = bandwidthPrice.MulFloat(1 + hostPriceLeeway)
+		collateral = collateral.MulFloat(1 - hostPriceLeeway)
+	}
+	return storagePrice, bandwidthPrice, collateral
 }
 
 // NewEditor initiates the contract revision process with a host, and returns
 // an Editor.
 func (cs *ContractSet) NewEditor(host modules.HostDBEntry, id types.FileContractID, currentHeight types.BlockHeight, hdb hostDB, cancel <-chan struct{}) (_ *Editor, err error) {
+	// Hosts that support sessions are revised through a session, so that the
+	// settings are not exchanged again for every upload.
+	if host.Sessions {
+		s, err := cs.NewSession(host, id, currentHeight, hdb, cancel)
+		if err == nil {
+			return &Editor{
+				host:        host,
+				hdb:         hdb,
+				height:      currentHeight,
+				contractID:  id,
+				contractSet: cs,
+				deps:        cs.deps,
+				session:     s,
+			}, nil
+		} else if !isRPCRejected(err) {
+			return nil, err
+		}
+		// The host rejected the session, so fall back to the older RPC.
+	}
+
 	sc, ok := cs.Acquire(id)
 	if !ok {
 		return nil, errors.New("invalid contract")
@@ -187,38 +436,50 @@ func (cs *ContractSet) NewEditor(host modules.HostDBEntry, id types.FileContract
 		}
 	}()
 
-	conn, closeChan, err := initiateRevisionLoop(host, contract, modules.RPCReviseContract, cancel, cs.rl)
+	conn, closeChan, err := cs.initiateContractRevisionLoop(sc, host, modules.RPCReviseContract, cancel)
+	if err != nil {
+		return nil, err
+	}
+
+	// the host is now ready to accept revisions
+	return &Editor{
+		host:        host,
+		hdb:         hdb,
+		height:      currentHeight,
+		contractID:  id,
+		contractSet: cs,
+		conn:        conn,
+		closeChan:   closeChan,
+		deps:        cs.deps,
+	}, nil
+}
+
+// initiateContractRevisionLoop initiates the revision loop of rpc with the
+// host of the acquired contract. If the host has a more recent revision than
+// the contract, the unapplied updates of the contract's WAL are applied and
+// the revision loop is initiated again.
+func (cs *ContractSet) initiateContractRevisionLoop(sc *SafeContract, host modules.HostDBEntry, rpc types.Specifier, cancel <-chan struct{}) (net.Conn, chan struct{}, error) {
+	conn, closeChan, err := initiateRevisionLoop(host, sc.header, rpc, cancel, cs.rl)
 	if IsRevisionMismatch(err) && len(sc.unappliedTxns) > 0 {
 		// we have desynced from the host. If we have unapplied updates from the
 		// WAL, try applying them.
-		conn, closeChan, err = initiateRevisionLoop(host, sc.unappliedHeader(), modules.RPCReviseContract, cancel, cs.rl)
+		conn, closeChan, err = initiateRevisionLoop(host, sc.unappliedHeader(), rpc, cancel, cs.rl)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		// applying the updates was successful; commit them to disk
 		if err := sc.commitTxns(); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	} else if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	// if we succeeded, we can safely discard the unappliedTxns
 	for _, txn := range sc.unappliedTxns {
 		txn.SignalUpdatesApplied()
 	}
 	sc.unappliedTxns = nil
-
-	// the host is now ready to accept revisions
-	return &Editor{
-		host:        host,
-		hdb:         hdb,
-		height:      currentHeight,
-		contractID:  id,
-		contractSet: cs,
-		conn:        conn,
-		closeChan:   closeChan,
-		deps:        cs.deps,
-	}, nil
+	return conn, closeChan, nil
 }
 
 // initiateRevisionLoop initiates either the editor or downloader loop with
@@ -245,15 +506,16 @@ func initiateRevisionLoop(host modules.HostDBEntry, contract contractHeader, rpc
 	// allot 2 minutes for RPC request + revision exchange
 	extendDeadline(conn, modules.NegotiateRecentRevisionTime)
 	defer extendDeadline(conn, time.Hour)
-	if err := encoding.WriteObject(conn, rpc); err != nil {
+	rpcConn, err := startRPC(conn, host, rpc)
+	if err != nil {
 		conn.Close()
 		close(closeChan)
-		return nil, closeChan, errors.New("couldn't initiate RPC: " + err.Error())
+		return nil, closeChan, err
 	}
-	if err := verifyRecentRevision(conn, contract, host.Version); err != nil {
+	if err := verifyRecentRevision(rpcConn, contract, host.Version); err != nil {
 		conn.Close() // TODO: close gracefully if host has entered revision loop
 		close(closeChan)
 		return nil, closeChan, err
 	}
-	return conn, closeChan, nil
+	return rpcConn, closeChan, nil
 }
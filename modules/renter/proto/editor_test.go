@@ -0,0 +1,83 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestMaxUploadBatch tests that maxUploadBatch only allows as many sectors as
+// fit into the host's MaxReviseBatchSize, and at least one sector.
+func TestMaxUploadBatch(t *testing.T) {
+	tests := []struct {
+		maxReviseBatchSize uint64
+		expected           int
+	}{
+		{0, 1},
+		{modules.SectorSize, 1},
+		{4 * modules.SectorSize, 3},
+		{4 * (modules.SectorSize + revisionActionOverhead), 4},
+	}
+	for _, test := range tests {
+		var host modules.HostDBEntry
+		host.MaxReviseBatchSize = test.maxReviseBatchSize
+		if n := maxUploadBatch(host); n != test.expected {
+			t.Errorf("expected %v sectors for a batch size of %v, got %v", test.expected, test.maxReviseBatchSize, n)
+		}
+	}
+
+	// The batch is checked before any data is sent.
+	var host modules.HostDBEntry
+	host.MaxReviseBatchSize = 4 * (modules.SectorSize + revisionActionOverhead)
+	if err := checkUploadBatch(host, nil); err != errEmptyUploadBatch {
+		t.Fatal("expected errEmptyUploadBatch, got", err)
+	}
+	if err := checkUploadBatch(host, make([][]byte, 5)); err != errLargeUploadBatch {
+		t.Fatal("expected errLargeUploadBatch, got", err)
+	}
+	if err := checkUploadBatch(host, make([][]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReplaceActions tests that replaceActions overwrites every replaced root
+// once, in the order of the roots in the contract.
+func TestReplaceActions(t *testing.T) {
+	roots := []crypto.Hash{{1}, {2}, {1}, {3}}
+	sectors := [][]byte{fastrand.Bytes(64), fastrand.Bytes(64), fastrand.Bytes(64)}
+	actions, indices, sectorRoots, merkleRoot, err := replaceActions(roots, []crypto.Hash{{1}, {3}, {1}}, sectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indices) != 3 || indices[0] != 0 || indices[1] != 3 || indices[2] != 2 {
+		t.Fatal("wrong indices:", indices)
+	}
+	for i, action := range actions {
+		if action.Type != modules.ActionModify || action.SectorIndex != uint64(indices[i]) || action.Offset != 0 {
+			t.Fatal("wrong action:", action.Type, action.SectorIndex, action.Offset)
+		}
+		if sectorRoots[i] != crypto.MerkleRoot(sectors[i]) {
+			t.Fatal("wrong root for sector", i)
+		}
+	}
+	expected := cachedMerkleRoot([]crypto.Hash{sectorRoots[0], {2}, sectorRoots[2], sectorRoots[1]})
+	if merkleRoot != expected {
+		t.Fatal("wrong Merkle root")
+	}
+	// The roots of the contract are not modified.
+	if roots[0] != (crypto.Hash{1}) || roots[3] != (crypto.Hash{3}) {
+		t.Fatal("roots were modified:", roots)
+	}
+
+	// A root can't be replaced more often than it is stored.
+	_, _, _, _, err = replaceActions(roots, []crypto.Hash{{2}, {2}}, sectors[:2])
+	if err != errReplacedRootNotFound {
+		t.Fatal("expected errReplacedRootNotFound, got", err)
+	}
+	_, _, _, _, err = replaceActions(roots, []crypto.Hash{{2}}, sectors)
+	if err != errReplaceMismatch {
+		t.Fatal("expected errReplaceMismatch, got", err)
+	}
+}
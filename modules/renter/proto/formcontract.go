@@ -23,16 +23,19 @@ func (cs *ContractSet) FormContract(params ContractParams, txnBuilder transactio
 	// Extract vars from params, for convenience.
 	host, funding, startHeight, endHeight, refundAddress := params.Host, params.Funding, params.StartHeight, params.EndHeight, params.RefundAddress
 
-	// Create our key.
-	ourSK, ourPK := crypto.GenerateKeyPair()
-	// Create unlock conditions.
-	uc := types.UnlockConditions{
-		PublicKeys: []types.SiaPublicKey{
-			types.Ed25519PublicKey(ourPK),
-			host.PublicKey,
-		},
-		SignaturesRequired: 2,
+	// Create our key. If a renter seed was provided, the key is derived from
+	// it and the contract identifier is added to the transaction, so that the
+	// contract can be recovered from the seed.
+	var identifier []byte
+	var ourSK crypto.SecretKey
+	var ourPK crypto.PublicKey
+	if params.RenterSeed != (RenterSeed{}) {
+		identifier, ourSK, ourPK = params.RenterSeed.newContractIdentifier(host.PublicKey)
+	} else {
+		ourSK, ourPK = crypto.GenerateKeyPair()
 	}
+	// Create unlock conditions.
+	uc := contractUnlockConditions(ourPK, host.PublicKey)
 
 	// Calculate the anticipated transaction fee.
 	_, maxFee := tpool.FeeEstimation()
@@ -93,6 +96,9 @@ func (cs *ContractSet) FormContract(params ContractParams, txnBuilder transactio
 		return modules.RenterContract{}, err
 	}
 	txnBuilder.AddFileContract(fc)
+	if identifier != nil {
+		txnBuilder.AddArbitraryData(identifier)
+	}
 	// Add miner fee.
 	txnBuilder.AddMinerFee(txnFee)
 
@@ -126,9 +132,11 @@ func (cs *ContractSet) FormContract(params ContractParams, txnBuilder transactio
 
 	// Allot time for sending RPC ID + verifySettings.
 	extendDeadline(conn, modules.NegotiateSettingsTime)
-	if err = encoding.WriteObject(conn, modules.RPCFormContract); err != nil {
+	rpcConn, err := startRPC(conn, host, modules.RPCFormContract)
+	if err != nil {
 		return modules.RenterContract{}, err
 	}
+	conn = rpcConn
 
 	// Verify the host's settings and confirm its identity.
 	host, err = verifySettings(conn, host)
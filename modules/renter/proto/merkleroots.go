@@ -303,12 +303,15 @@ func (mr *merkleRoots) root() crypto.Hash {
 	return tree.Root()
 }
 
-// checkNewRoot returns the root of the merkleTree after appending the checkNewRoot
-// without actually appending it.
-func (mr *merkleRoots) checkNewRoot(newRoot crypto.Hash) crypto.Hash {
+// checkNewRoots returns the root of the merkleTree after appending newRoots
+// without actually appending them. Only the cached subTrees and the uncached
+// roots are pushed, so the roots don't have to be read from disk.
+func (mr *merkleRoots) checkNewRoots(newRoots []crypto.Hash) crypto.Hash {
+	// The leaves of the cached tree are sector roots, so the height of the
+	// cached subTrees needs to be relative to a sector root.
 	tree := crypto.NewCachedTree(sectorHeight)
 	for _, st := range mr.cachedSubTrees {
-		if err := tree.PushSubTree(st.height, st.sum); err != nil {
+		if err := tree.PushSubTree(st.height-int(sectorHeight), st.sum); err != nil {
 			// This should never fail.
 			build.Critical(err)
 		}
@@ -316,8 +319,10 @@ func (mr *merkleRoots) checkNewRoot(newRoot crypto.Hash) crypto.Hash {
 	for _, root := range mr.uncachedRoots {
 		tree.Push(root)
 	}
-	// Push the new root.
-	tree.Push(newRoot)
+	// Push the new roots.
+	for _, root := range newRoots {
+		tree.Push(root)
+	}
 	return tree.Root()
 }
 
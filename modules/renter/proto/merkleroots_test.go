@@ -443,3 +443,59 @@ func TestMerkleRootsRandom(t *testing.T) {
 		}
 	}
 }
+
+// TestCheckNewRoots tests that checkNewRoots computes the root of the
+// contract after appending a batch of roots, without appending them.
+func TestCheckNewRoots(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	dir := build.TempDir(t.Name())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	filePath := path.Join(dir, "file.dat")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create sector roots.
+	rootSection := newFileSection(file, 0, -1)
+	merkleRoots := newMerkleRoots(rootSection)
+	var roots []crypto.Hash
+	for i := 0; i < 200; i++ {
+		hash := crypto.Hash{}
+		copy(hash[:], fastrand.Bytes(crypto.HashSize)[:])
+		merkleRoots.push(hash)
+		roots = append(roots, hash)
+	}
+
+	// Check a batch of new roots that completes another cached subTree.
+	var newRoots []crypto.Hash
+	for i := 0; i < 100; i++ {
+		hash := crypto.Hash{}
+		copy(hash[:], fastrand.Bytes(crypto.HashSize)[:])
+		newRoots = append(newRoots, hash)
+	}
+	expected := cachedMerkleRoot(append(roots, newRoots...))
+	if merkleRoots.checkNewRoots(newRoots) != expected {
+		t.Fatal("checkNewRoots returned the wrong root")
+	}
+	if merkleRoots.len() != len(roots) {
+		t.Fatal("checkNewRoots appended the roots")
+	}
+
+	// Append the roots. The root of the contract should match.
+	for _, root := range newRoots {
+		if err := merkleRoots.push(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(merkleRoots.cachedSubTrees) != 2 {
+		t.Fatal("expected 2 cached subTrees, got", len(merkleRoots.cachedSubTrees))
+	}
+	if merkleRoots.checkNewRoots(nil) != expected {
+		t.Fatal("root after appending the roots doesn't match")
+	}
+}
@@ -15,6 +15,26 @@ import (
 // extendDeadline is a helper function for extending the connection timeout.
 func extendDeadline(conn net.Conn, d time.Duration) { _ = conn.SetDeadline(time.Now().Add(d)) }
 
+// startRPC initiates an RPC with the host. If the host supports the encrypted
+// transport, the transport handshake is performed first, and the returned
+// connection encrypts the RPC and everything that follows it.
+func startRPC(conn net.Conn, host modules.HostDBEntry, rpc types.Specifier) (net.Conn, error) {
+	if host.EncryptedTransport {
+		if err := encoding.WriteObject(conn, modules.RPCEncryptedTransport); err != nil {
+			return nil, errors.New("couldn't initiate RPC: " + err.Error())
+		}
+		encConn, err := modules.RenterTransportHandshake(conn, host.PublicKey)
+		if err != nil {
+			return nil, errors.New("transport handshake failed: " + err.Error())
+		}
+		conn = encConn
+	}
+	if err := encoding.WriteObject(conn, rpc); err != nil {
+		return nil, errors.New("couldn't initiate RPC: " + err.Error())
+	}
+	return conn, nil
+}
+
 // startRevision is run at the beginning of each revision iteration. It reads
 // the host's settings confirms that the values are acceptable, and writes an acceptance.
 func startRevision(conn net.Conn, host modules.HostDBEntry) error {
@@ -51,8 +71,8 @@ func verifySettings(conn net.Conn, host modules.HostDBEntry) (modules.HostDBEntr
 	copy(pk[:], host.PublicKey.Key)
 
 	// read signed host settings
-	var recvSettings modules.HostExternalSettings
-	if err := crypto.ReadSignedObject(conn, &recvSettings, modules.NegotiateMaxHostExternalSettingsLen, pk); err != nil {
+	recvSettings, err := modules.ReadSignedHostSettings(conn, modules.NegotiateMaxHostExternalSettingsLen, pk)
+	if err != nil {
 		return modules.HostDBEntry{}, errors.New("couldn't read host's settings: " + err.Error())
 	}
 	// TODO: check recvSettings against host.HostExternalSettings. If there is
@@ -69,35 +89,9 @@ func verifySettings(conn net.Conn, host modules.HostDBEntry) (modules.HostDBEntr
 // verifyRecentRevision confirms that the host and contractor agree upon the current
 // state of the contract being revised.
 func verifyRecentRevision(conn net.Conn, contract contractHeader, hostVersion string) error {
-	// send contract ID
-	if err := encoding.WriteObject(conn, contract.ID()); err != nil {
-		return errors.New("couldn't send contract ID: " + err.Error())
-	}
-	// read challenge
-	var challenge crypto.Hash
-	if err := encoding.ReadObject(conn, &challenge, 32); err != nil {
-		return errors.New("couldn't read challenge: " + err.Error())
-	}
-	if build.VersionCmp(hostVersion, "1.3.0") >= 0 {
-		crypto.SecureWipe(challenge[:16])
-	}
-	// sign and return
-	sig := crypto.SignHash(challenge, contract.SecretKey)
-	if err := encoding.WriteObject(conn, sig); err != nil {
-		return errors.New("couldn't send challenge response: " + err.Error())
-	}
-	// read acceptance
-	if err := modules.ReadNegotiationAcceptance(conn); err != nil {
-		return errors.New("host did not accept revision request: " + err.Error())
-	}
-	// read last revision and signatures
-	var lastRevision types.FileContractRevision
-	var hostSignatures []types.TransactionSignature
-	if err := encoding.ReadObject(conn, &lastRevision, 2048); err != nil {
-		return errors.New("couldn't read last revision: " + err.Error())
-	}
-	if err := encoding.ReadObject(conn, &hostSignatures, 2048); err != nil {
-		return errors.New("couldn't read host signatures: " + err.Error())
+	lastRevision, hostSignatures, err := fetchRecentRevision(conn, contract.ID(), contract.SecretKey, hostVersion)
+	if err != nil {
+		return err
 	}
 	// Check that the unlock hashes match; if they do not, something is
 	// seriously wrong. Otherwise, check that the revision numbers match.
@@ -117,17 +111,7 @@ func verifyRecentRevision(conn net.Conn, contract contractHeader, hostVersion st
 // completing one iteration of the revision loop.
 func negotiateRevision(conn net.Conn, rev types.FileContractRevision, secretKey crypto.SecretKey) (types.Transaction, error) {
 	// create transaction containing the revision
-	signedTxn := types.Transaction{
-		FileContractRevisions: []types.FileContractRevision{rev},
-		TransactionSignatures: []types.TransactionSignature{{
-			ParentID:       crypto.Hash(rev.ParentID),
-			CoveredFields:  types.CoveredFields{FileContractRevisions: []uint64{0}},
-			PublicKeyIndex: 0, // renter key is always first -- see formContract
-		}},
-	}
-	// sign the transaction
-	encodedSig := crypto.SignHash(signedTxn.SigHash(0), secretKey)
-	signedTxn.TransactionSignatures[0].Signature = encodedSig[:]
+	signedTxn := signRevision(rev, secretKey)
 
 	// send the revision
 	if err := encoding.WriteObject(conn, rev); err != nil {
@@ -155,17 +139,42 @@ func negotiateRevision(conn net.Conn, rev types.FileContractRevision, secretKey
 	}
 
 	// add the signature to the transaction and verify it
+	signedTxn, err := addHostSignature(signedTxn, hostSig)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+
+	// if the host sent ErrStopResponse, return it
+	return signedTxn, responseErr
+}
+
+// signRevision creates a transaction containing rev, signed by the renter.
+func signRevision(rev types.FileContractRevision, secretKey crypto.SecretKey) types.Transaction {
+	signedTxn := types.Transaction{
+		FileContractRevisions: []types.FileContractRevision{rev},
+		TransactionSignatures: []types.TransactionSignature{{
+			ParentID:       crypto.Hash(rev.ParentID),
+			CoveredFields:  types.CoveredFields{FileContractRevisions: []uint64{0}},
+			PublicKeyIndex: 0, // renter key is always first -- see formContract
+		}},
+	}
+	encodedSig := crypto.SignHash(signedTxn.SigHash(0), secretKey)
+	signedTxn.TransactionSignatures[0].Signature = encodedSig[:]
+	return signedTxn
+}
+
+// addHostSignature adds the host's signature to a revision transaction that
+// was signed by the renter, and verifies the transaction.
+func addHostSignature(signedTxn types.Transaction, hostSig types.TransactionSignature) (types.Transaction, error) {
 	// NOTE: we can fake the blockheight here because it doesn't affect
 	// verification; it just needs to be above the fork height and below the
 	// contract expiration (which was checked earlier).
-	verificationHeight := rev.NewWindowStart - 1
+	verificationHeight := signedTxn.FileContractRevisions[0].NewWindowStart - 1
 	signedTxn.TransactionSignatures = append(signedTxn.TransactionSignatures, hostSig)
 	if err := signedTxn.StandaloneValid(verificationHeight); err != nil {
 		return types.Transaction{}, err
 	}
-
-	// if the host sent ErrStopResponse, return it
-	return signedTxn, responseErr
+	return signedTxn, nil
 }
 
 // newRevision creates a copy of current with its revision number incremented,
@@ -200,8 +209,8 @@ func newDownloadRevision(current types.FileContractRevision, downloadCost types.
 }
 
 // newUploadRevision revises the current revision to cover the cost of
-// uploading a sector.
-func newUploadRevision(current types.FileContractRevision, merkleRoot crypto.Hash, price, collateral types.Currency) types.FileContractRevision {
+// uploading numSectors sectors.
+func newUploadRevision(current types.FileContractRevision, merkleRoot crypto.Hash, numSectors int, price, collateral types.Currency) types.FileContractRevision {
 	rev := newRevision(current, price)
 
 	// move collateral from host to void
@@ -209,7 +218,7 @@ func newUploadRevision(current types.FileContractRevision, merkleRoot crypto.Has
 	rev.NewMissedProofOutputs[2].Value = rev.NewMissedProofOutputs[2].Value.Add(collateral)
 
 	// set new filesize and Merkle root
-	rev.NewFileSize += modules.SectorSize
+	rev.NewFileSize += modules.SectorSize * uint64(numSectors)
 	rev.NewFileMerkleRoot = merkleRoot
 	return rev
 }
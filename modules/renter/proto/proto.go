@@ -11,6 +11,7 @@ import (
 // Dependencies.
 type (
 	transactionBuilder interface {
+		AddArbitraryData([]byte) uint64
 		AddFileContract(types.FileContract) uint64
 		AddMinerFee(types.Currency) uint64
 		AddParents([]types.Transaction)
@@ -42,7 +43,10 @@ type ContractParams struct {
 	StartHeight   types.BlockHeight
 	EndHeight     types.BlockHeight
 	RefundAddress types.UnlockHash
-	// TODO: add optional keypair
+	// RenterSeed is used to derive the renter's key of a new contract, which
+	// makes the contract recoverable from the blockchain. If it is the zero
+	// seed, a random key is used instead.
+	RenterSeed RenterSeed
 }
 
 // A revisionSaver is called just before we send our revision signature to the host; this
@@ -65,3 +69,20 @@ func IsRevisionMismatch(err error) bool {
 	_, ok := err.(*recentRevisionError)
 	return ok
 }
+
+// An rpcRejectedError occurs if the host closes the connection instead of
+// answering an RPC, which is what hosts do with RPCs they don't know.
+type rpcRejectedError struct {
+	err error
+}
+
+func (e *rpcRejectedError) Error() string {
+	return "host did not answer the RPC: " + e.err.Error()
+}
+
+// isRPCRejected returns true if err was caused by the host not answering an
+// RPC.
+func isRPCRejected(err error) bool {
+	_, ok := err.(*rpcRejectedError)
+	return ok
+}
@@ -0,0 +1,379 @@
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/fastrand"
+	"github.com/NebulousLabs/ratelimit"
+)
+
+// Contracts formed with a RenterSeed can be recovered from the blockchain.
+// The renter's key of such a contract is derived from the seed and a random
+// nonce, and the formation transaction carries a contract identifier in its
+// arbitrary data. The identifier contains the nonce and the host's public key,
+// encrypted with a key derived from the seed. A renter that only has its seed
+// can therefore find its contracts on the blockchain and ask the hosts for
+// their latest revisions. Renewals keep the unlock conditions of the contract,
+// so renewed contracts are found by their unlock hash.
+
+var (
+	// contractIdentifierSpecifier marks the arbitrary data of a transaction
+	// as a contract identifier. It follows modules.PrefixNonSia, so that the
+	// transaction is considered standard.
+	contractIdentifierSpecifier = types.Specifier{'C', 'o', 'n', 't', 'r', 'a', 'c', 't', 'I', 'D'}
+
+	// renterSeedSpecifier and identifierKeySpecifier are used to derive the
+	// renter seed and the key that encrypts contract identifiers.
+	renterSeedSpecifier    = types.Specifier{'r', 'e', 'n', 't', 'e', 'r'}
+	identifierKeySpecifier = types.Specifier{'c', 'o', 'n', 't', 'r', 'a', 'c', 't', 'i', 'd'}
+
+	errContractExists     = errors.New("contract is already in the contract set")
+	errRecoveredMismatch  = errors.New("host sent a revision of a different contract")
+	errUnsupportedHostKey = errors.New("host used unsupported signature algorithm")
+)
+
+// identifierNonceSize is the size of the nonce a contract key is derived from.
+const identifierNonceSize = 16
+
+// A RenterSeed is derived from the wallet seed. The keys of the renter's
+// contracts are derived from it.
+type RenterSeed [crypto.EntropySize]byte
+
+// A RecoverableContract is a contract of the renter that was found on the
+// blockchain.
+type RecoverableContract struct {
+	ID            types.FileContractID
+	HostPublicKey types.SiaPublicKey
+	SecretKey     crypto.SecretKey
+	StartHeight   types.BlockHeight
+	FileContract  types.FileContract
+}
+
+// EndHeight returns the height at which the host must submit the storage
+// proof of the contract.
+func (rc RecoverableContract) EndHeight() types.BlockHeight {
+	return rc.FileContract.WindowStart
+}
+
+// DeriveRenterSeed derives the renter seed from the wallet seed.
+func DeriveRenterSeed(walletSeed modules.Seed) RenterSeed {
+	return RenterSeed(crypto.HashAll(walletSeed, renterSeedSpecifier))
+}
+
+// identifierKey returns the key that encrypts the contract identifiers.
+func (rs RenterSeed) identifierKey() crypto.TwofishKey {
+	return crypto.TwofishKey(crypto.HashAll(rs, identifierKeySpecifier))
+}
+
+// contractKeys derives the renter's key of a contract from the nonce.
+func (rs RenterSeed) contractKeys(nonce []byte) (crypto.SecretKey, crypto.PublicKey) {
+	return crypto.GenerateKeyPairDeterministic(crypto.HashAll(rs, nonce))
+}
+
+// newContractIdentifier creates the identifier of a new contract with the
+// host, along with the renter's key of that contract.
+func (rs RenterSeed) newContractIdentifier(hostKey types.SiaPublicKey) ([]byte, crypto.SecretKey, crypto.PublicKey) {
+	nonce := fastrand.Bytes(identifierNonceSize)
+	sk, pk := rs.contractKeys(nonce)
+	ct := rs.identifierKey().EncryptBytes(encoding.Marshal(hostKey))
+
+	identifier := make([]byte, 0, 2*types.SpecifierLen+identifierNonceSize+len(ct))
+	identifier = append(identifier, modules.PrefixNonSia[:]...)
+	identifier = append(identifier, contractIdentifierSpecifier[:]...)
+	identifier = append(identifier, nonce...)
+	identifier = append(identifier, ct...)
+	return identifier, sk, pk
+}
+
+// decodeContractIdentifier decodes the arbitrary data of a transaction. If it
+// is a contract identifier created with this seed, the host's public key and
+// the renter's key of the contract are returned.
+func (rs RenterSeed) decodeContractIdentifier(arb []byte) (types.SiaPublicKey, crypto.SecretKey, bool) {
+	prefixLen := 2 * types.SpecifierLen
+	if len(arb) <= prefixLen+identifierNonceSize ||
+		!bytes.Equal(arb[:types.SpecifierLen], modules.PrefixNonSia[:]) ||
+		!bytes.Equal(arb[types.SpecifierLen:prefixLen], contractIdentifierSpecifier[:]) {
+		return types.SiaPublicKey{}, crypto.SecretKey{}, false
+	}
+	nonce := arb[prefixLen : prefixLen+identifierNonceSize]
+	plaintext, err := rs.identifierKey().DecryptBytes(crypto.Ciphertext(arb[prefixLen+identifierNonceSize:]))
+	if err != nil {
+		// The identifier was created with a different seed.
+		return types.SiaPublicKey{}, crypto.SecretKey{}, false
+	}
+	var hostKey types.SiaPublicKey
+	if err := encoding.Unmarshal(plaintext, &hostKey); err != nil {
+		return types.SiaPublicKey{}, crypto.SecretKey{}, false
+	}
+	sk, _ := rs.contractKeys(nonce)
+	return hostKey, sk, true
+}
+
+// contractUnlockConditions returns the unlock conditions of a contract
+// between the renter and the host.
+func contractUnlockConditions(renterKey crypto.PublicKey, hostKey types.SiaPublicKey) types.UnlockConditions {
+	return types.UnlockConditions{
+		PublicKeys: []types.SiaPublicKey{
+			types.Ed25519PublicKey(renterKey),
+			hostKey,
+		},
+		SignaturesRequired: 2,
+	}
+}
+
+// A ContractScanner finds the contracts formed with a RenterSeed in the
+// transactions of the blockchain. Transactions must be scanned in the order
+// they appear on the blockchain.
+type ContractScanner struct {
+	seed      RenterSeed
+	keys      map[types.UnlockHash]RecoverableContract
+	contracts map[types.UnlockHash]RecoverableContract
+}
+
+// NewContractScanner returns a ContractScanner for the seed.
+func NewContractScanner(seed RenterSeed) *ContractScanner {
+	return &ContractScanner{
+		seed:      seed,
+		keys:      make(map[types.UnlockHash]RecoverableContract),
+		contracts: make(map[types.UnlockHash]RecoverableContract),
+	}
+}
+
+// ScanTransaction scans a transaction that was confirmed at the given height.
+func (s *ContractScanner) ScanTransaction(txn types.Transaction, height types.BlockHeight) {
+	for _, arb := range txn.ArbitraryData {
+		hostKey, sk, ok := s.seed.decodeContractIdentifier(arb)
+		if !ok {
+			continue
+		}
+		uc := contractUnlockConditions(sk.PublicKey(), hostKey)
+		s.keys[uc.UnlockHash()] = RecoverableContract{
+			HostPublicKey: hostKey,
+			SecretKey:     sk,
+		}
+	}
+	// A renewal of a contract has the same unlock hash as the contract, and
+	// replaces it.
+	for i, fc := range txn.FileContracts {
+		rc, ok := s.keys[fc.UnlockHash]
+		if !ok {
+			continue
+		}
+		rc.ID = txn.FileContractID(uint64(i))
+		rc.StartHeight = height
+		rc.FileContract = fc
+		s.contracts[fc.UnlockHash] = rc
+	}
+}
+
+// Contracts returns the most recent contract of every contract line that was
+// found.
+func (s *ContractScanner) Contracts() []RecoverableContract {
+	contracts := make([]RecoverableContract, 0, len(s.contracts))
+	for _, rc := range s.contracts {
+		contracts = append(contracts, rc)
+	}
+	return contracts
+}
+
+// fetchRecentRevision requests the most recent revision of a contract from
+// the host, along with the signatures of the revision.
+func fetchRecentRevision(conn net.Conn, id types.FileContractID, sk crypto.SecretKey, hostVersion string) (types.FileContractRevision, []types.TransactionSignature, error) {
+	// send contract ID
+	if err := encoding.WriteObject(conn, id); err != nil {
+		return types.FileContractRevision{}, nil, errors.New("couldn't send contract ID: " + err.Error())
+	}
+	// read challenge
+	var challenge crypto.Hash
+	if err := encoding.ReadObject(conn, &challenge, 32); err != nil {
+		// The challenge is the first thing the host sends, so the host
+		// didn't answer the RPC.
+		return types.FileContractRevision{}, nil, &rpcRejectedError{errors.New("couldn't read challenge: " + err.Error())}
+	}
+	if build.VersionCmp(hostVersion, "1.3.0") >= 0 {
+		crypto.SecureWipe(challenge[:16])
+	}
+	// sign and return
+	sig := crypto.SignHash(challenge, sk)
+	if err := encoding.WriteObject(conn, sig); err != nil {
+		return types.FileContractRevision{}, nil, errors.New("couldn't send challenge response: " + err.Error())
+	}
+	// read acceptance
+	if err := modules.ReadNegotiationAcceptance(conn); err != nil {
+		return types.FileContractRevision{}, nil, errors.New("host did not accept revision request: " + err.Error())
+	}
+	// read last revision and signatures
+	var lastRevision types.FileContractRevision
+	var hostSignatures []types.TransactionSignature
+	if err := encoding.ReadObject(conn, &lastRevision, 2048); err != nil {
+		return types.FileContractRevision{}, nil, errors.New("couldn't read last revision: " + err.Error())
+	}
+	if err := encoding.ReadObject(conn, &hostSignatures, 2048); err != nil {
+		return types.FileContractRevision{}, nil, errors.New("couldn't read host signatures: " + err.Error())
+	}
+	return lastRevision, hostSignatures, nil
+}
+
+// managedFetchRevision requests the most recent revision of a contract from
+// its host through the download RPC, and verifies that the revision was
+// signed by both parties.
+func (cs *ContractSet) managedFetchRevision(host modules.HostDBEntry, id types.FileContractID, sk crypto.SecretKey, cancel <-chan struct{}) (types.FileContractRevision, []types.TransactionSignature, error) {
+	if host.PublicKey.Algorithm != types.SignatureEd25519 || len(host.PublicKey.Key) != crypto.PublicKeySize {
+		return types.FileContractRevision{}, nil, errUnsupportedHostKey
+	}
+
+	c, err := (&net.Dialer{
+		Cancel:  cancel,
+		Timeout: connTimeout,
+	}).Dial("tcp", string(host.NetAddress))
+	if err != nil {
+		return types.FileContractRevision{}, nil, err
+	}
+	conn := ratelimit.NewRLConn(c, cs.rl, cancel)
+	defer conn.Close()
+
+	// Request the most recent revision through the download RPC, and leave
+	// the download loop right away.
+	extendDeadline(conn, modules.NegotiateRecentRevisionTime)
+	rpcConn, err := startRPC(conn, host, modules.RPCDownload)
+	if err != nil {
+		return types.FileContractRevision{}, nil, err
+	}
+	rev, sigs, err := fetchRecentRevision(rpcConn, id, sk, host.Version)
+	if err != nil {
+		return types.FileContractRevision{}, nil, err
+	}
+	extendDeadline(rpcConn, modules.NegotiateSettingsTime)
+	_, _ = verifySettings(rpcConn, host)
+	_ = modules.WriteNegotiationStop(rpcConn)
+
+	if rev.ParentID != id {
+		return types.FileContractRevision{}, nil, errRecoveredMismatch
+	}
+	if err := modules.VerifyFileContractRevisionTransactionSignatures(rev, sigs, rev.NewWindowStart-1); err != nil {
+		return types.FileContractRevision{}, nil, err
+	}
+	return rev, sigs, nil
+}
+
+// RecoverContract requests the most recent revision of a contract that was
+// found on the blockchain from its host, and adds the contract to the set.
+// The Merkle roots of the contract's sectors cannot be recovered, so a
+// recovered contract that holds data is marked as neither good for upload nor
+// good for renew. Its data can be downloaded until the contract expires.
+func (cs *ContractSet) RecoverContract(rc RecoverableContract, host modules.HostDBEntry, cancel <-chan struct{}) (modules.RenterContract, error) {
+	if _, ok := cs.View(rc.ID); ok {
+		return modules.RenterContract{}, errContractExists
+	}
+	rev, sigs, err := cs.managedFetchRevision(host, rc.ID, rc.SecretKey, cancel)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	if rev.UnlockConditions.UnlockHash() != rc.FileContract.UnlockHash {
+		return modules.RenterContract{}, errRecoveredMismatch
+	}
+
+	// The spending of the contract is unknown. The fees are estimated from
+	// the formation transaction.
+	siafundFee := types.Tax(rc.StartHeight, rc.FileContract.Payout)
+	header := contractHeader{
+		Transaction: types.Transaction{
+			FileContractRevisions: []types.FileContractRevision{rev},
+			TransactionSignatures: sigs,
+		},
+		SecretKey:   rc.SecretKey,
+		StartHeight: rc.StartHeight,
+		TotalCost:   rc.FileContract.ValidProofOutputs[0].Value.Add(siafundFee),
+		SiafundFee:  siafundFee,
+		Utility:     recoveredUtility(rev),
+	}
+	return cs.managedInsertContract(header, nil)
+}
+
+// A ContractBackup is the header of a contract, without the Merkle roots of
+// its sectors. Contracts can be restored from their backups, including
+// contracts that were formed without a RenterSeed.
+type ContractBackup struct {
+	header contractHeader
+}
+
+// ID returns the ID of the backed up contract.
+func (cb ContractBackup) ID() types.FileContractID { return cb.header.ID() }
+
+// HostPublicKey returns the public key of the host of the backed up contract.
+func (cb ContractBackup) HostPublicKey() types.SiaPublicKey { return cb.header.HostPublicKey() }
+
+// EndHeight returns the height at which the host must submit the storage
+// proof of the backed up contract.
+func (cb ContractBackup) EndHeight() types.BlockHeight { return cb.header.EndHeight() }
+
+// MarshalSia implements the encoding.SiaMarshaler interface.
+func (cb ContractBackup) MarshalSia(w io.Writer) error {
+	return encoding.NewEncoder(w).Encode(cb.header)
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface.
+func (cb *ContractBackup) UnmarshalSia(r io.Reader) error {
+	return encoding.NewDecoder(r).Decode(&cb.header)
+}
+
+// Backups returns the backups of all contracts in the set.
+func (cs *ContractSet) Backups() []ContractBackup {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	backups := make([]ContractBackup, 0, len(cs.contracts))
+	for _, sc := range cs.contracts {
+		sc.headerMu.Lock()
+		backups = append(backups, ContractBackup{header: sc.header})
+		sc.headerMu.Unlock()
+	}
+	return backups
+}
+
+// RestoreContract requests the most recent revision of a backed up contract
+// from its host, and adds the contract to the set. Like a recovered contract,
+// a restored contract that holds data is neither good for upload nor good for
+// renew.
+func (cs *ContractSet) RestoreContract(cb ContractBackup, host modules.HostDBEntry, cancel <-chan struct{}) (modules.RenterContract, error) {
+	if _, ok := cs.View(cb.ID()); ok {
+		return modules.RenterContract{}, errContractExists
+	}
+	header := cb.header
+	if err := header.validate(); err != nil {
+		return modules.RenterContract{}, err
+	}
+	rev, sigs, err := cs.managedFetchRevision(host, header.ID(), header.SecretKey, cancel)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	if rev.UnlockConditions.UnlockHash() != header.LastRevision().UnlockConditions.UnlockHash() {
+		return modules.RenterContract{}, errRecoveredMismatch
+	} else if rev.NewRevisionNumber < header.LastRevision().NewRevisionNumber {
+		return modules.RenterContract{}, errors.New("host sent an outdated revision")
+	}
+
+	header.Transaction = types.Transaction{
+		FileContractRevisions: []types.FileContractRevision{rev},
+		TransactionSignatures: sigs,
+	}
+	header.Utility = recoveredUtility(rev)
+	return cs.managedInsertContract(header, nil)
+}
+
+// recoveredUtility returns the utility of a contract whose Merkle roots are
+// unknown. Such a contract can only be used for uploads if it holds no data.
+func recoveredUtility(rev types.FileContractRevision) modules.ContractUtility {
+	return modules.ContractUtility{
+		GoodForUpload: rev.NewFileSize == 0,
+		GoodForRenew:  rev.NewFileSize == 0,
+	}
+}
@@ -0,0 +1,94 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestContractIdentifier tests that contract identifiers can only be decoded
+// with the seed that created them.
+func TestContractIdentifier(t *testing.T) {
+	seed := DeriveRenterSeed(modules.Seed{1})
+	hostKey := types.Ed25519PublicKey(crypto.PublicKey{2})
+
+	identifier, sk, pk := seed.newContractIdentifier(hostKey)
+	if sk.PublicKey() != pk {
+		t.Fatal("identifier returned mismatched keys")
+	}
+	decodedKey, decodedSK, ok := seed.decodeContractIdentifier(identifier)
+	if !ok {
+		t.Fatal("could not decode identifier")
+	} else if decodedKey.String() != hostKey.String() {
+		t.Fatal("wrong host key:", decodedKey)
+	} else if decodedSK != sk {
+		t.Fatal("wrong secret key")
+	}
+
+	// A different seed cannot decode the identifier.
+	otherSeed := DeriveRenterSeed(modules.Seed{3})
+	if _, _, ok := otherSeed.decodeContractIdentifier(identifier); ok {
+		t.Fatal("identifier was decoded with the wrong seed")
+	}
+	// Other arbitrary data is ignored.
+	if _, _, ok := seed.decodeContractIdentifier(identifier[:40]); ok {
+		t.Fatal("truncated identifier was decoded")
+	}
+	if _, _, ok := seed.decodeContractIdentifier(modules.PrefixNonSia[:]); ok {
+		t.Fatal("unrelated data was decoded")
+	}
+}
+
+// TestContractScanner tests that the ContractScanner finds contracts and
+// their renewals.
+func TestContractScanner(t *testing.T) {
+	seed := DeriveRenterSeed(modules.Seed{1})
+	hostKey := types.Ed25519PublicKey(crypto.PublicKey{2})
+	identifier, sk, pk := seed.newContractIdentifier(hostKey)
+	unlockHash := contractUnlockConditions(pk, hostKey).UnlockHash()
+
+	s := NewContractScanner(seed)
+	formation := types.Transaction{
+		FileContracts: []types.FileContract{
+			{UnlockHash: types.UnlockHash{4}, WindowStart: 50},
+			{UnlockHash: unlockHash, WindowStart: 100},
+		},
+		ArbitraryData: [][]byte{identifier},
+	}
+	s.ScanTransaction(formation, 10)
+	contracts := s.Contracts()
+	if len(contracts) != 1 {
+		t.Fatal("expected 1 contract, got", len(contracts))
+	}
+	rc := contracts[0]
+	if rc.ID != formation.FileContractID(1) || rc.StartHeight != 10 || rc.EndHeight() != 100 {
+		t.Fatal("wrong contract:", rc.ID, rc.StartHeight, rc.EndHeight())
+	} else if rc.SecretKey != sk || rc.HostPublicKey.String() != hostKey.String() {
+		t.Fatal("wrong keys")
+	}
+
+	// A renewal doesn't carry an identifier, but replaces the contract.
+	renewal := types.Transaction{
+		FileContracts: []types.FileContract{
+			{UnlockHash: unlockHash, WindowStart: 200},
+		},
+	}
+	s.ScanTransaction(renewal, 90)
+	contracts = s.Contracts()
+	if len(contracts) != 1 {
+		t.Fatal("expected 1 contract, got", len(contracts))
+	}
+	if rc := contracts[0]; rc.ID != renewal.FileContractID(0) || rc.StartHeight != 90 || rc.EndHeight() != 200 {
+		t.Fatal("renewal did not replace the contract:", rc.ID, rc.StartHeight, rc.EndHeight())
+	}
+
+	// Contracts of other seeds are not found.
+	other := NewContractScanner(DeriveRenterSeed(modules.Seed{3}))
+	other.ScanTransaction(formation, 10)
+	other.ScanTransaction(renewal, 90)
+	if len(other.Contracts()) != 0 {
+		t.Fatal("found contracts of a different seed")
+	}
+}
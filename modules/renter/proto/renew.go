@@ -13,13 +13,60 @@ import (
 // Renew negotiates a new contract for data already stored with a host, and
 // submits the new contract transaction to tpool. The new contract is added to
 // the ContractSet and its metadata is returned.
-func (cs *ContractSet) Renew(oldContract *SafeContract, params ContractParams, txnBuilder transactionBuilder, tpool transactionPool, hdb hostDB, cancel <-chan struct{}) (modules.RenterContract, error) {
+func (cs *ContractSet) Renew(oldContract *SafeContract, params ContractParams, txnBuilder transactionBuilder, tpool transactionPool, hdb hostDB, cancel <-chan struct{}) (_ modules.RenterContract, err error) {
 	// for convenience
 	contract := oldContract.header
+	host := params.Host
 
+	// create the renewed contract and the transaction set that funds it
+	fc, txnSet, txnFee, err := prepareRenewal(contract, params, txnBuilder, tpool)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+
+	// Increase Successful/Failed interactions accordingly
+	defer func() {
+		// A revision mismatch might not be the host's fault.
+		if err != nil && !IsRevisionMismatch(err) {
+			hdb.IncrementFailedInteractions(contract.HostPublicKey())
+		} else if err == nil {
+			hdb.IncrementSuccessfulInteractions(contract.HostPublicKey())
+		}
+	}()
+
+	// initiate connection
+	dialer := &net.Dialer{
+		Cancel:  cancel,
+		Timeout: connTimeout,
+	}
+	conn, err := dialer.Dial("tcp", string(host.NetAddress))
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	// allot time for sending RPC ID, verifyRecentRevision, and verifySettings
+	extendDeadline(conn, modules.NegotiateRecentRevisionTime+modules.NegotiateSettingsTime)
+	rpcConn, err := startRPC(conn, host, modules.RPCRenewContract)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	conn = rpcConn
+	// verify that both parties are renewing the same contract
+	if err = verifyRecentRevision(conn, contract, host.Version); err != nil {
+		// don't add context; want to preserve the original error type so that
+		// callers can check using IsRevisionMismatch
+		return modules.RenterContract{}, err
+	}
+	return cs.negotiateRenewal(conn, oldContract, params, fc, txnSet, txnFee, txnBuilder, tpool)
+}
+
+// prepareRenewal creates the file contract that renews contract and funds it
+// with txnBuilder. The contract, the transaction set containing it, and the
+// anticipated transaction fee are returned.
+func prepareRenewal(contract contractHeader, params ContractParams, txnBuilder transactionBuilder, tpool transactionPool) (types.FileContract, []types.Transaction, types.Currency, error) {
 	// Extract vars from params, for convenience.
 	host, funding, startHeight, endHeight, refundAddress := params.Host, params.Funding, params.StartHeight, params.EndHeight, params.RefundAddress
-	ourSK := contract.SecretKey
 	lastRev := contract.LastRevision()
 
 	// Calculate additional basePrice and baseCollateral. If the contract height
@@ -37,7 +84,7 @@ func (cs *ContractSet) Renew(oldContract *SafeContract, params ContractParams, t
 
 	// Underflow check.
 	if funding.Cmp(host.ContractPrice.Add(txnFee).Add(basePrice)) <= 0 {
-		return modules.RenterContract{}, errors.New("insufficient funds to cover contract fee and transaction fee during contract renewal")
+		return types.FileContract{}, nil, types.ZeroCurrency, errors.New("insufficient funds to cover contract fee and transaction fee during contract renewal")
 	}
 	// Divide by zero check.
 	if host.StoragePrice.IsZero() {
@@ -58,9 +105,9 @@ func (cs *ContractSet) Renew(oldContract *SafeContract, params ContractParams, t
 
 	// check for negative currency
 	if types.PostTax(startHeight, totalPayout).Cmp(hostPayout) < 0 {
-		return modules.RenterContract{}, errors.New("insufficient funds to pay both siafund fee and also host payout")
+		return types.FileContract{}, nil, types.ZeroCurrency, errors.New("insufficient funds to pay both siafund fee and also host payout")
 	} else if hostCollateral.Cmp(baseCollateral) < 0 {
-		return modules.RenterContract{}, errors.New("new collateral smaller than base collateral")
+		return types.FileContract{}, nil, types.ZeroCurrency, errors.New("new collateral smaller than base collateral")
 	}
 
 	// create file contract
@@ -91,7 +138,7 @@ func (cs *ContractSet) Renew(oldContract *SafeContract, params ContractParams, t
 	// build transaction containing fc
 	err := txnBuilder.FundSiacoins(funding)
 	if err != nil {
-		return modules.RenterContract{}, err
+		return types.FileContract{}, nil, types.ZeroCurrency, err
 	}
 	txnBuilder.AddFileContract(fc)
 	// add miner fee
@@ -101,44 +148,23 @@ func (cs *ContractSet) Renew(oldContract *SafeContract, params ContractParams, t
 	txn, parentTxns := txnBuilder.View()
 	unconfirmedParents, err := txnBuilder.UnconfirmedParents()
 	if err != nil {
-		return modules.RenterContract{}, err
+		return types.FileContract{}, nil, types.ZeroCurrency, err
 	}
 	txnSet := append(unconfirmedParents, append(parentTxns, txn)...)
+	return fc, txnSet, txnFee, nil
+}
 
-	// Increase Successful/Failed interactions accordingly
-	defer func() {
-		// A revision mismatch might not be the host's fault.
-		if err != nil && !IsRevisionMismatch(err) {
-			hdb.IncrementFailedInteractions(contract.HostPublicKey())
-		} else if err == nil {
-			hdb.IncrementSuccessfulInteractions(contract.HostPublicKey())
-		}
-	}()
-
-	// initiate connection
-	dialer := &net.Dialer{
-		Cancel:  cancel,
-		Timeout: connTimeout,
-	}
-	conn, err := dialer.Dial("tcp", string(host.NetAddress))
-	if err != nil {
-		return modules.RenterContract{}, err
-	}
-	defer func() { _ = conn.Close() }()
+// negotiateRenewal negotiates the renewal of oldContract over conn, after the
+// host has verified the most recent revision of the contract. The renewed
+// contract is submitted to tpool and added to the ContractSet.
+func (cs *ContractSet) negotiateRenewal(conn net.Conn, oldContract *SafeContract, params ContractParams, fc types.FileContract, txnSet []types.Transaction, txnFee types.Currency, txnBuilder transactionBuilder, tpool transactionPool) (modules.RenterContract, error) {
+	// for convenience
+	host, funding, startHeight := params.Host, params.Funding, params.StartHeight
+	ourSK := oldContract.header.SecretKey
+	lastRev := oldContract.header.LastRevision()
 
-	// allot time for sending RPC ID, verifyRecentRevision, and verifySettings
-	extendDeadline(conn, modules.NegotiateRecentRevisionTime+modules.NegotiateSettingsTime)
-	if err = encoding.WriteObject(conn, modules.RPCRenewContract); err != nil {
-		return modules.RenterContract{}, errors.New("couldn't initiate RPC: " + err.Error())
-	}
-	// verify that both parties are renewing the same contract
-	if err = verifyRecentRevision(conn, contract, host.Version); err != nil {
-		// don't add context; want to preserve the original error type so that
-		// callers can check using IsRevisionMismatch
-		return modules.RenterContract{}, err
-	}
 	// verify the host's settings and confirm its identity
-	host, err = verifySettings(conn, host)
+	host, err := verifySettings(conn, host)
 	if err != nil {
 		return modules.RenterContract{}, errors.New("settings exchange failed: " + err.Error())
 	}
@@ -259,7 +285,7 @@ func (cs *ContractSet) Renew(oldContract *SafeContract, params ContractParams, t
 	revisionTxn.TransactionSignatures = append(revisionTxn.TransactionSignatures, hostRevisionSig)
 
 	// Construct the final transaction.
-	txn, parentTxns = txnBuilder.View()
+	txn, parentTxns := txnBuilder.View()
 	txnSet = append(parentTxns, txn)
 
 	// Submit to blockchain.
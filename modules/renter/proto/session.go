@@ -0,0 +1,504 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// A Session is an open session with a host. The host locks the contract and
+// verifies its most recent revision once, when the session is opened, and
+// then serves any number of requests over the same connection. Sessions are
+// NOT thread-safe; requests must be made in serial. If a request fails, the
+// host ends the session.
+type Session struct {
+	closeChan   chan struct{}
+	conn        net.Conn
+	contractID  types.FileContractID
+	contractSet *ContractSet
+	deps        modules.Dependencies
+	hdb         hostDB
+	host        modules.HostDBEntry
+	nextID      uint64
+	once        sync.Once
+
+	height types.BlockHeight
+}
+
+// request sends a request with the provided body to the host and reads the
+// host's response to it. If the request succeeded, the body of the response
+// can be read from the connection afterwards.
+func (s *Session) request(rpc types.Specifier, body interface{}) error {
+	id := s.nextID
+	s.nextID++
+	if err := encoding.WriteObject(s.conn, modules.SessionRequest{ID: id, RPC: rpc}); err != nil {
+		return errors.New("couldn't send request: " + err.Error())
+	}
+	if body != nil {
+		if err := encoding.WriteObject(s.conn, body); err != nil {
+			return errors.New("couldn't send request: " + err.Error())
+		}
+	}
+	var resp modules.SessionResponse
+	if err := encoding.ReadObject(s.conn, &resp, modules.NegotiateMaxSessionHeaderSize); err != nil {
+		return errors.New("couldn't read response: " + err.Error())
+	}
+	if resp.ID != id {
+		return fmt.Errorf("host responded to request %v instead of request %v", resp.ID, id)
+	} else if resp.Error != "" {
+		return errors.New("host rejected request: " + resp.Error)
+	}
+	return nil
+}
+
+// Settings requests the host's current settings, and returns the host with
+// the received settings. Later requests of the session use the received
+// settings to calculate prices.
+func (s *Session) Settings() (modules.HostDBEntry, error) {
+	extendDeadline(s.conn, modules.NegotiateSettingsTime)
+	defer extendDeadline(s.conn, time.Hour) // TODO: Constant.
+	if err := s.request(modules.SessionRPCSettings, nil); err != nil {
+		return modules.HostDBEntry{}, err
+	}
+	host, err := verifySettings(s.conn, s.host)
+	if err != nil {
+		return modules.HostDBEntry{}, err
+	}
+	s.host = host
+	return host, nil
+}
+
+// Sector retrieves the sector with the specified Merkle root, and revises
+// the underlying contract to pay the host proportionally to the data
+// retrieved.
+func (s *Session) Sector(root crypto.Hash) (_ modules.RenterContract, _ []byte, err error) {
+	contract, data, err := s.Download([]modules.DownloadAction{{
+		MerkleRoot: root,
+		Offset:     0,
+		Length:     modules.SectorSize,
+	}})
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	return contract, data[0], nil
+}
+
+// Download retrieves the requested sections of sectors, and revises the
+// underlying contract to pay the host proportionally to the data retrieved.
+// The offset and length of each section must be multiples of
+// crypto.SegmentSize. The host proves each section with a Merkle range
+// proof, so only the requested sections are downloaded and paid for.
+func (s *Session) Download(sections []modules.DownloadAction) (_ modules.RenterContract, _ [][]byte, err error) {
+	// Reset deadline when finished.
+	defer extendDeadline(s.conn, time.Hour) // TODO: Constant.
+
+	// Check that the sections can be proven.
+	var totalLength uint64
+	for _, section := range sections {
+		if section.Length == 0 || section.Offset%crypto.SegmentSize != 0 || section.Length%crypto.SegmentSize != 0 {
+			return modules.RenterContract{}, nil, errors.New("section is not aligned to segments")
+		} else if section.Offset+section.Length > modules.SectorSize {
+			return modules.RenterContract{}, nil, errors.New("section is out of the bounds of the sector")
+		}
+		totalLength += section.Length
+	}
+	if len(sections) == 0 {
+		return modules.RenterContract{}, nil, errors.New("no sections requested")
+	}
+
+	// Acquire the contract.
+	sc, haveContract := s.contractSet.Acquire(s.contractID)
+	if !haveContract {
+		return modules.RenterContract{}, nil, errors.New("contract not present in contract set")
+	}
+	defer s.contractSet.Return(sc)
+	contract := sc.header // for convenience
+
+	// calculate price
+	price := s.host.DownloadBandwidthPrice.Mul64(totalLength)
+	if contract.RenterFunds().Cmp(price) < 0 {
+		return modules.RenterContract{}, nil, errors.New("contract has insufficient funds to support download")
+	}
+	// To mitigate small errors (e.g. differing block heights), fudge the
+	// price and collateral by 0.2%.
+	price = price.MulFloat(1 + hostPriceLeeway)
+
+	// create and sign the download revision
+	rev := newDownloadRevision(contract.LastRevision(), price)
+	signedTxn := signRevision(rev, contract.SecretKey)
+
+	// record the change we are about to make to the contract. If we lose power
+	// mid-revision, this allows us to restore either the pre-revision or
+	// post-revision contract.
+	walTxn, err := sc.recordDownloadIntent(rev, price)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// Increase Successful/Failed interactions accordingly
+	defer func() {
+		if err != nil {
+			s.hdb.IncrementFailedInteractions(contract.HostPublicKey())
+		} else {
+			s.hdb.IncrementSuccessfulInteractions(contract.HostPublicKey())
+		}
+	}()
+
+	// Disrupt before sending the signed revision to the host.
+	if s.deps.Disrupt("InterruptDownloadBeforeSendingRevision") {
+		return modules.RenterContract{}, nil,
+			errors.New("InterruptDownloadBeforeSendingRevision disrupt")
+	}
+
+	// send the sections and the revision, and read the host's signature, the
+	// section data and the proofs. A range proof contains at most two hashes
+	// per level of the sector's Merkle tree.
+	extendDeadline(s.conn, modules.NegotiateDownloadTime)
+	err = s.request(modules.SessionRPCRead, modules.SessionReadRequest{
+		Sections:    sections,
+		MerkleProof: true,
+		NewRevision: rev,
+		Signature:   signedTxn.TransactionSignatures[0],
+	})
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	var resp modules.SessionReadResponse
+	maxProofSize := 16 + 2*sectorHeight*crypto.HashSize
+	maxLen := totalLength + uint64(len(sections))*maxProofSize + modules.NegotiateMaxTransactionSignatureSize
+	if err := encoding.ReadObject(s.conn, &resp, maxLen); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	signedTxn, err = addHostSignature(signedTxn, resp.Signature)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// Disrupt after sending the signed revision to the host.
+	if s.deps.Disrupt("InterruptDownloadAfterSendingRevision") {
+		return modules.RenterContract{}, nil,
+			errors.New("InterruptDownloadAfterSendingRevision disrupt")
+	}
+
+	// verify the section data
+	if len(resp.Data) != len(sections) || len(resp.MerkleProofs) != len(sections) {
+		return modules.RenterContract{}, nil, errors.New("host did not send enough sections")
+	}
+	for i, section := range sections {
+		if uint64(len(resp.Data[i])) != section.Length {
+			return modules.RenterContract{}, nil, errors.New("host did not send enough section data")
+		}
+		proofStart := section.Offset / crypto.SegmentSize
+		proofEnd := (section.Offset + section.Length) / crypto.SegmentSize
+		if !crypto.VerifyRangeProof(resp.Data[i], resp.MerkleProofs[i], modules.SectorSize/crypto.SegmentSize, proofStart, proofEnd, section.MerkleRoot) {
+			return modules.RenterContract{}, nil, errors.New("host sent bad section data")
+		}
+	}
+
+	// update contract and metrics
+	if err := sc.commitDownload(walTxn, signedTxn, price); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	return sc.Metadata(), resp.Data, nil
+}
+
+// Upload negotiates a revision that adds a sector to a file contract.
+func (s *Session) Upload(data []byte) (modules.RenterContract, crypto.Hash, error) {
+	contract, roots, err := s.UploadBatch([][]byte{data})
+	if err != nil {
+		return modules.RenterContract{}, crypto.Hash{}, err
+	}
+	return contract, roots[0], nil
+}
+
+// UploadBatch negotiates a single revision that adds several sectors to a
+// file contract. It returns the Merkle roots of the sectors.
+func (s *Session) UploadBatch(sectors [][]byte) (_ modules.RenterContract, _ []crypto.Hash, err error) {
+	if err := checkUploadBatch(s.host, sectors); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// Reset deadline when finished.
+	defer extendDeadline(s.conn, time.Hour) // TODO: Constant.
+
+	// Acquire the contract.
+	sc, haveContract := s.contractSet.Acquire(s.contractID)
+	if !haveContract {
+		return modules.RenterContract{}, nil, errors.New("contract not present in contract set")
+	}
+	defer s.contractSet.Return(sc)
+	contract := sc.header // for convenience
+
+	// calculate price
+	storagePrice, bandwidthPrice, collateral := uploadBatchPrices(s.host, contract.LastRevision(), s.height, len(sectors))
+	price := storagePrice.Add(bandwidthPrice)
+	if contract.RenterFunds().Cmp(price) < 0 {
+		return modules.RenterContract{}, nil, errors.New("contract has insufficient funds to support upload")
+	}
+	if contract.LastRevision().NewMissedProofOutputs[1].Value.Cmp(collateral) < 0 {
+		return modules.RenterContract{}, nil, errors.New("contract has insufficient collateral to support upload")
+	}
+
+	// calculate the new Merkle root
+	sectorRoots := make([]crypto.Hash, len(sectors))
+	for i, data := range sectors {
+		sectorRoots[i] = crypto.MerkleRoot(data)
+	}
+	merkleRoot := sc.merkleRoots.checkNewRoots(sectorRoots)
+
+	// create the actions and the signed revision
+	actions := uploadActions(sc.merkleRoots.len(), sectors)
+	rev := newUploadRevision(contract.LastRevision(), merkleRoot, len(sectors), price, collateral)
+	signedTxn := signRevision(rev, contract.SecretKey)
+
+	// Increase Successful/Failed interactions accordingly
+	defer func() {
+		if err != nil {
+			s.hdb.IncrementFailedInteractions(s.host.PublicKey)
+		} else {
+			s.hdb.IncrementSuccessfulInteractions(s.host.PublicKey)
+		}
+	}()
+
+	// record the change we are about to make to the contract. If we lose power
+	// mid-revision, this allows us to restore either the pre-revision or
+	// post-revision contract.
+	walTxn, err := sc.recordUploadIntent(rev, sectorRoots, storagePrice, bandwidthPrice)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// Disrupt here before sending the signed revision to the host.
+	if s.deps.Disrupt("InterruptUploadBeforeSendingRevision") {
+		return modules.RenterContract{}, nil,
+			errors.New("InterruptUploadBeforeSendingRevision disrupt")
+	}
+
+	// send the actions and the revision, and read the host's signature
+	extendDeadline(s.conn, modules.NegotiateFileContractRevisionTime)
+	err = s.request(modules.SessionRPCWrite, modules.SessionWriteRequest{
+		Actions:     actions,
+		NewRevision: rev,
+		Signature:   signedTxn.TransactionSignatures[0],
+	})
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	var resp modules.SessionWriteResponse
+	if err := encoding.ReadObject(s.conn, &resp, modules.NegotiateMaxTransactionSignatureSize); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	signedTxn, err = addHostSignature(signedTxn, resp.Signature)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// Disrupt here before updating the contract.
+	if s.deps.Disrupt("InterruptUploadAfterSendingRevision") {
+		return modules.RenterContract{}, nil,
+			errors.New("InterruptUploadAfterSendingRevision disrupt")
+	}
+
+	// update contract
+	err = sc.commitUpload(walTxn, signedTxn, sectorRoots, storagePrice, bandwidthPrice)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	return sc.Metadata(), sectorRoots, nil
+}
+
+// ReplaceBatch negotiates a single revision that overwrites the sectors with
+// the roots oldRoots with new sectors. It returns the Merkle roots of the new
+// sectors.
+func (s *Session) ReplaceBatch(oldRoots []crypto.Hash, sectors [][]byte) (_ modules.RenterContract, _ []crypto.Hash, err error) {
+	if err := checkUploadBatch(s.host, sectors); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// Reset deadline when finished.
+	defer extendDeadline(s.conn, time.Hour) // TODO: Constant.
+
+	// Acquire the contract.
+	sc, haveContract := s.contractSet.Acquire(s.contractID)
+	if !haveContract {
+		return modules.RenterContract{}, nil, errors.New("contract not present in contract set")
+	}
+	defer s.contractSet.Return(sc)
+	contract := sc.header // for convenience
+
+	// calculate price; overwriting a sector doesn't change the amount of
+	// storage that the host provides.
+	_, bandwidthPrice, _ := uploadBatchPrices(s.host, contract.LastRevision(), s.height, len(sectors))
+	if contract.RenterFunds().Cmp(bandwidthPrice) < 0 {
+		return modules.RenterContract{}, nil, errors.New("contract has insufficient funds to support upload")
+	}
+
+	// create the actions and the signed revision
+	roots, err := sc.merkleRoots.merkleRoots()
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	actions, indices, sectorRoots, merkleRoot, err := replaceActions(roots, oldRoots, sectors)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	rev := newModifyRevision(contract.LastRevision(), merkleRoot, bandwidthPrice)
+	signedTxn := signRevision(rev, contract.SecretKey)
+
+	// Increase Successful/Failed interactions accordingly
+	defer func() {
+		if err != nil {
+			s.hdb.IncrementFailedInteractions(s.host.PublicKey)
+		} else {
+			s.hdb.IncrementSuccessfulInteractions(s.host.PublicKey)
+		}
+	}()
+
+	// record the change we are about to make to the contract.
+	walTxn, err := sc.recordReplaceIntent(rev, indices, sectorRoots, bandwidthPrice)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// send the actions and the revision, and read the host's signature
+	extendDeadline(s.conn, modules.NegotiateFileContractRevisionTime)
+	err = s.request(modules.SessionRPCWrite, modules.SessionWriteRequest{
+		Actions:     actions,
+		NewRevision: rev,
+		Signature:   signedTxn.TransactionSignatures[0],
+	})
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	var resp modules.SessionWriteResponse
+	if err := encoding.ReadObject(s.conn, &resp, modules.NegotiateMaxTransactionSignatureSize); err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	signedTxn, err = addHostSignature(signedTxn, resp.Signature)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	// update contract
+	err = sc.commitReplace(walTxn, signedTxn, indices, sectorRoots, bandwidthPrice)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+
+	return sc.Metadata(), sectorRoots, nil
+}
+
+// Renew negotiates a new contract for the data of the session's contract, and
+// submits the new contract transaction to tpool. The new contract is added to
+// the ContractSet and its metadata is returned. The session ends after the
+// renewal, since the old contract can't be revised anymore.
+func (s *Session) Renew(params ContractParams, txnBuilder transactionBuilder, tpool transactionPool) (_ modules.RenterContract, err error) {
+	// Acquire the contract.
+	sc, haveContract := s.contractSet.Acquire(s.contractID)
+	if !haveContract {
+		return modules.RenterContract{}, errors.New("contract not present in contract set")
+	}
+	defer s.contractSet.Return(sc)
+
+	// create the renewed contract and the transaction set that funds it
+	fc, txnSet, txnFee, err := prepareRenewal(sc.header, params, txnBuilder, tpool)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+
+	// Increase Successful/Failed interactions accordingly
+	defer func() {
+		if err != nil {
+			s.hdb.IncrementFailedInteractions(s.host.PublicKey)
+		} else {
+			s.hdb.IncrementSuccessfulInteractions(s.host.PublicKey)
+		}
+	}()
+
+	// The host ends the session after the renewal.
+	defer func() {
+		s.once.Do(func() { close(s.closeChan) })
+		_ = s.conn.Close()
+	}()
+
+	// allot time for sending the request and verifySettings
+	extendDeadline(s.conn, modules.NegotiateSettingsTime)
+	if err = s.request(modules.SessionRPCRenew, nil); err != nil {
+		return modules.RenterContract{}, err
+	}
+	return s.contractSet.negotiateRenewal(s.conn, sc, params, fc, txnSet, txnFee, txnBuilder, tpool)
+}
+
+// shutdown ends the session and signals the goroutine spawned in NewSession
+// to return.
+func (s *Session) shutdown() {
+	extendDeadline(s.conn, modules.NegotiateSettingsTime)
+	// don't care about this error
+	_ = encoding.WriteObject(s.conn, modules.SessionRequest{ID: s.nextID, RPC: modules.SessionRPCEnd})
+	close(s.closeChan)
+}
+
+// Close cleanly ends the session with the host and closes the connection.
+func (s *Session) Close() error {
+	// using once ensures that Close is idempotent
+	s.once.Do(s.shutdown)
+	return s.conn.Close()
+}
+
+// NewSession opens a session with a host, and returns a Session. The current
+// height is used to calculate the price of uploads.
+func (cs *ContractSet) NewSession(host modules.HostDBEntry, id types.FileContractID, currentHeight types.BlockHeight, hdb hostDB, cancel <-chan struct{}) (_ *Session, err error) {
+	sc, ok := cs.Acquire(id)
+	if !ok {
+		return nil, errors.New("invalid contract")
+	}
+	defer cs.Return(sc)
+	contract := sc.header
+
+	// Increase Successful/Failed interactions accordingly
+	defer func() {
+		// A revision mismatch might not be the host's fault. If the host
+		// rejected the session, the caller falls back to the older RPCs.
+		if err != nil && !IsRevisionMismatch(err) && !isRPCRejected(err) {
+			hdb.IncrementFailedInteractions(contract.HostPublicKey())
+		} else if err == nil {
+			hdb.IncrementSuccessfulInteractions(contract.HostPublicKey())
+		}
+	}()
+
+	conn, closeChan, err := cs.initiateContractRevisionLoop(sc, host, modules.RPCSession, cancel)
+	if err != nil {
+		return nil, err
+	}
+
+	// The host sends its settings once, after it has verified the revision.
+	extendDeadline(conn, modules.NegotiateSettingsTime)
+	defer extendDeadline(conn, time.Hour) // TODO: Constant.
+	host, err = verifySettings(conn, host)
+	if err != nil {
+		conn.Close()
+		close(closeChan)
+		return nil, err
+	}
+
+	// the host is now ready to serve requests
+	return &Session{
+		closeChan:   closeChan,
+		conn:        conn,
+		contractID:  id,
+		contractSet: cs,
+		deps:        cs.deps,
+		hdb:         hdb,
+		host:        host,
+		height:      currentHeight,
+	}, nil
+}
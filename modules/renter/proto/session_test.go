@@ -0,0 +1,49 @@
+package proto
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestSessionRequest tests that session requests are sent with increasing IDs,
+// and that rejections and responses to other requests are returned as errors.
+func TestSessionRequest(t *testing.T) {
+	// simulate a renter-host connection
+	rConn, hConn := net.Pipe()
+	defer rConn.Close()
+	s := &Session{conn: rConn}
+
+	// handle the host's half of the pipe
+	go func() {
+		defer hConn.Close()
+		var req modules.SessionRequest
+		// accept the first request, which has a body
+		encoding.ReadObject(hConn, &req, modules.NegotiateMaxSessionHeaderSize)
+		encoding.ReadObject(hConn, new(modules.SessionWriteRequest), 1<<22)
+		encoding.WriteObject(hConn, modules.SessionResponse{ID: req.ID})
+		// reject the second request
+		encoding.ReadObject(hConn, &req, modules.NegotiateMaxSessionHeaderSize)
+		encoding.WriteObject(hConn, modules.SessionResponse{ID: req.ID, Error: "sentinel"})
+		// respond to the third request with the wrong ID
+		encoding.ReadObject(hConn, &req, modules.NegotiateMaxSessionHeaderSize)
+		encoding.WriteObject(hConn, modules.SessionResponse{ID: req.ID + 1})
+	}()
+
+	if err := s.request(modules.SessionRPCWrite, modules.SessionWriteRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	expectedErr := "host rejected request: sentinel"
+	if err := s.request(modules.SessionRPCSettings, nil); err == nil || err.Error() != expectedErr {
+		t.Fatalf("expected %q, got \"%v\"", expectedErr, err)
+	}
+	if err := s.request(modules.SessionRPCSettings, nil); err == nil || !strings.Contains(err.Error(), "instead of request 2") {
+		t.Fatal("expected a response to the wrong request, got", err)
+	}
+	if s.nextID != 3 {
+		t.Fatal("expected the next request ID to be 3, got", s.nextID)
+	}
+}
@@ -0,0 +1,145 @@
+package renter
+
+// ratelimit.go applies the renter's bandwidth limits. The user can configure
+// default limits and a weekly schedule of windows with different limits. A
+// background thread switches between them as windows begin and end.
+//
+// All connections to hosts share a single rate limiter in the contract set, so
+// changing the limits takes effect immediately for transfers that are already
+// running.
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+const (
+	// minutesPerDay is the number of minutes in a day, and the largest value
+	// allowed for the end of a rate limit window.
+	minutesPerDay = 24 * 60
+
+	// rateLimitPacketSize is the packet size used by the rate limiter when
+	// bandwidth is limited.
+	//
+	// TODO: In the future we might want the user to be able to configure
+	// the packetSize using the API. For now the sane default is 16kib if
+	// the user wants to limit the connection.
+	rateLimitPacketSize = 4 * 4096
+)
+
+var (
+	// errNegativeRateLimit is returned if a bandwidth limit is negative.
+	errNegativeRateLimit = errors.New("download/upload rate limit can't be below 0")
+)
+
+// validateRateLimitSchedule checks that every window in the schedule is well
+// formed. A window whose end is before its start wraps around midnight.
+func validateRateLimitSchedule(schedule []modules.RateLimitWindow) error {
+	for i, w := range schedule {
+		if w.Start == w.End || w.Start >= minutesPerDay || w.End > minutesPerDay {
+			return fmt.Errorf("rate limit window %v: start and end must differ, start must be below %v, and end must be at most %v", i, minutesPerDay, minutesPerDay)
+		}
+		if w.MaxDownloadSpeed < 0 || w.MaxUploadSpeed < 0 {
+			return fmt.Errorf("rate limit window %v: %v", i, errNegativeRateLimit)
+		}
+		for _, day := range w.Days {
+			if day < time.Sunday || day > time.Saturday {
+				return fmt.Errorf("rate limit window %v: invalid day %v", i, int(day))
+			}
+		}
+	}
+	return nil
+}
+
+// activeRateLimitWindow returns the index of the first window in the schedule
+// that contains the provided time, or -1 if no window contains it. Windows
+// that wrap around midnight belong to the day on which they start, so after
+// midnight they are matched against the previous day.
+func activeRateLimitWindow(schedule []modules.RateLimitWindow, t time.Time) int {
+	minute := uint16(t.Hour()*60 + t.Minute())
+	for i, w := range schedule {
+		weekday := t.Weekday()
+		if w.Start < w.End {
+			if minute < w.Start || minute >= w.End {
+				continue
+			}
+		} else if minute < w.End {
+			weekday = (weekday + 6) % 7
+		} else if minute < w.Start {
+			continue
+		}
+		if len(w.Days) == 0 {
+			return i
+		}
+		for _, day := range w.Days {
+			if day == weekday {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// managedRateLimitStatus determines which limits should currently be in
+// effect.
+func (r *Renter) managedRateLimitStatus() modules.RenterRateLimitStatus {
+	id := r.mu.RLock()
+	defer r.mu.RUnlock(id)
+	status := modules.RenterRateLimitStatus{
+		ActiveWindow:     activeRateLimitWindow(r.rateLimitSchedule, time.Now()),
+		MaxDownloadSpeed: r.rateLimitDownload,
+		MaxUploadSpeed:   r.rateLimitUpload,
+		Schedule:         append([]modules.RateLimitWindow(nil), r.rateLimitSchedule...),
+	}
+	if status.ActiveWindow >= 0 {
+		w := r.rateLimitSchedule[status.ActiveWindow]
+		status.MaxDownloadSpeed = w.MaxDownloadSpeed
+		status.MaxUploadSpeed = w.MaxUploadSpeed
+	}
+	return status
+}
+
+// managedApplyRateLimits updates the limits of the contractor to match the
+// limits that should currently be in effect.
+func (r *Renter) managedApplyRateLimits() {
+	status := r.managedRateLimitStatus()
+	download, upload, _ := r.hostContractor.RateLimits()
+	if download == status.MaxDownloadSpeed && upload == status.MaxUploadSpeed {
+		return
+	}
+	if status.MaxDownloadSpeed == 0 && status.MaxUploadSpeed == 0 {
+		r.hostContractor.SetRateLimits(0, 0, 0)
+	} else {
+		r.hostContractor.SetRateLimits(status.MaxDownloadSpeed, status.MaxUploadSpeed, rateLimitPacketSize)
+	}
+	r.log.Printf("Bandwidth limits changed to %v B/s download and %v B/s upload (schedule window %v)",
+		status.MaxDownloadSpeed, status.MaxUploadSpeed, status.ActiveWindow)
+}
+
+// RateLimitStatus returns the bandwidth limits currently in effect and the
+// schedule window they come from.
+func (r *Renter) RateLimitStatus() modules.RenterRateLimitStatus {
+	return r.managedRateLimitStatus()
+}
+
+// threadedRateLimitLoop periodically checks the rate limit schedule and
+// applies the limits of the window that is currently active.
+func (r *Renter) threadedRateLimitLoop() {
+	err := r.tg.Add()
+	if err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	for {
+		r.managedApplyRateLimits()
+		select {
+		case <-r.tg.StopChan():
+			return
+		case <-time.After(rateLimitScheduleInterval):
+		}
+	}
+}
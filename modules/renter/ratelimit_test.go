@@ -0,0 +1,108 @@
+package renter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// TestActiveRateLimitWindow checks that the correct window of a rate limit
+// schedule is selected for a given time.
+func TestActiveRateLimitWindow(t *testing.T) {
+	schedule := []modules.RateLimitWindow{
+		// Business hours on weekdays.
+		{
+			Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+			Start: 9 * 60,
+			End:   17 * 60,
+		},
+		// Every evening.
+		{
+			Start: 17 * 60,
+			End:   minutesPerDay,
+		},
+	}
+	if err := validateRateLimitSchedule(schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2018-06-04 is a Monday.
+	tests := []struct {
+		t      time.Time
+		window int
+	}{
+		{time.Date(2018, 6, 4, 8, 59, 0, 0, time.Local), -1},
+		{time.Date(2018, 6, 4, 9, 0, 0, 0, time.Local), 0},
+		{time.Date(2018, 6, 4, 16, 59, 0, 0, time.Local), 0},
+		{time.Date(2018, 6, 4, 17, 0, 0, 0, time.Local), 1},
+		{time.Date(2018, 6, 4, 23, 59, 0, 0, time.Local), 1},
+		{time.Date(2018, 6, 9, 12, 0, 0, 0, time.Local), -1}, // Saturday
+		{time.Date(2018, 6, 9, 18, 0, 0, 0, time.Local), 1},
+	}
+	for _, test := range tests {
+		if w := activeRateLimitWindow(schedule, test.t); w != test.window {
+			t.Errorf("expected window %v at %v, got %v", test.window, test.t, w)
+		}
+	}
+}
+
+// TestActiveRateLimitWindowMidnight checks that windows wrapping around
+// midnight are selected on both sides of midnight, and that they belong to
+// the day on which they start.
+func TestActiveRateLimitWindowMidnight(t *testing.T) {
+	schedule := []modules.RateLimitWindow{
+		// Friday and Saturday nights.
+		{
+			Days:  []time.Weekday{time.Friday, time.Saturday},
+			Start: 22 * 60,
+			End:   6 * 60,
+		},
+		// Every night.
+		{
+			Start: 23 * 60,
+			End:   60,
+		},
+	}
+	if err := validateRateLimitSchedule(schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2018-06-08 is a Friday.
+	tests := []struct {
+		t      time.Time
+		window int
+	}{
+		{time.Date(2018, 6, 8, 21, 59, 0, 0, time.Local), -1},
+		{time.Date(2018, 6, 8, 22, 0, 0, 0, time.Local), 0},
+		{time.Date(2018, 6, 9, 0, 0, 0, 0, time.Local), 0},
+		{time.Date(2018, 6, 9, 5, 59, 0, 0, time.Local), 0},
+		{time.Date(2018, 6, 9, 6, 0, 0, 0, time.Local), -1},
+		{time.Date(2018, 6, 10, 5, 0, 0, 0, time.Local), 0},  // Saturday night
+		{time.Date(2018, 6, 10, 23, 0, 0, 0, time.Local), 1}, // Sunday night
+		{time.Date(2018, 6, 11, 0, 30, 0, 0, time.Local), 1},
+		{time.Date(2018, 6, 11, 1, 0, 0, 0, time.Local), -1},
+		{time.Date(2018, 6, 11, 5, 0, 0, 0, time.Local), -1},
+	}
+	for _, test := range tests {
+		if w := activeRateLimitWindow(schedule, test.t); w != test.window {
+			t.Errorf("expected window %v at %v, got %v", test.window, test.t, w)
+		}
+	}
+}
+
+// TestValidateRateLimitSchedule checks that malformed schedules are rejected.
+func TestValidateRateLimitSchedule(t *testing.T) {
+	bad := [][]modules.RateLimitWindow{
+		{{Start: 60, End: 60}},
+		{{Start: 0, End: minutesPerDay + 1}},
+		{{Start: minutesPerDay, End: 60}},
+		{{Start: 0, End: 60, MaxDownloadSpeed: -1}},
+		{{Start: 0, End: 60, Days: []time.Weekday{7}}},
+	}
+	for i, schedule := range bad {
+		if validateRateLimitSchedule(schedule) == nil {
+			t.Errorf("schedule %v should have been rejected", i)
+		}
+	}
+}
@@ -20,11 +20,14 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/renter/contractor"
 	"github.com/NebulousLabs/Sia/modules/renter/hostdb"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
 	"github.com/NebulousLabs/Sia/persist"
 	siasync "github.com/NebulousLabs/Sia/sync"
 	"github.com/NebulousLabs/Sia/types"
@@ -38,6 +41,7 @@ var (
 	errNilGateway    = errors.New("cannot create hostdb with nil gateway")
 	errNilHdb        = errors.New("cannot create renter with nil hostdb")
 	errNilTpool      = errors.New("cannot create renter with nil transaction pool")
+	errNilWallet     = errors.New("cannot create renter with nil wallet")
 )
 
 var (
@@ -66,12 +70,41 @@ type hostDB interface {
 	// AverageContractPrice returns the average contract price of a host.
 	AverageContractPrice() types.Currency
 
+	// BenchmarkSettings measures how long it takes to request the settings
+	// of a host.
+	BenchmarkSettings(types.SiaPublicKey) (time.Duration, error)
+
 	// Close closes the hostdb.
 	Close() error
 
+	// ExportHostDB writes the hosts of the hostdb to a file.
+	ExportHostDB(dst string) error
+
+	// FilterMode returns the hostdb's filter mode and the hosts it applies
+	// to.
+	FilterMode() (modules.FilterMode, []types.SiaPublicKey)
+
 	// Host returns the HostDBEntry for a given host.
 	Host(types.SiaPublicKey) (modules.HostDBEntry, bool)
 
+	// ImportHostDB merges the hosts of an exported hostdb into the hostdb.
+	ImportHostDB(src, origin string) (modules.HostDBImportReport, error)
+
+	// RecordBenchmark adds a benchmark to a host's entry.
+	RecordBenchmark(types.SiaPublicKey, modules.HostBenchmark) error
+
+	// RescanHosts queues a scan of every host.
+	RescanHosts() error
+
+	// RescanProgress returns the progress of the most recent full rescan.
+	RescanProgress() modules.HostDBRescanProgress
+
+	// ScanHost scans a host immediately and returns its updated entry.
+	ScanHost(types.SiaPublicKey) (modules.HostDBEntry, error)
+
+	// ScanPolicy returns the policy that is used to scan hosts.
+	ScanPolicy() modules.HostDBScanPolicy
+
 	// RandomHosts returns a set of random hosts, weighted by their estimated
 	// usefulness / attractiveness to the renter. RandomHosts will not return
 	// any offline or inactive hosts.
@@ -81,6 +114,21 @@ type hostDB interface {
 	// of the host.
 	ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown
 
+	// SetAllowance updates the allowance used to weight hosts.
+	SetAllowance(modules.Allowance)
+
+	// SetFilterMode sets the hostdb's filter mode.
+	SetFilterMode(modules.FilterMode, []types.SiaPublicKey) error
+
+	// SetScanPolicy sets the policy that is used to scan hosts.
+	SetScanPolicy(modules.HostDBScanPolicy) error
+
+	// ScoringProfile returns the profile that is used to score hosts.
+	ScoringProfile() modules.HostScoringProfile
+
+	// SetScoringProfile sets the profile that is used to score hosts.
+	SetScoringProfile(modules.HostScoringProfile) error
+
 	// EstimateHostScore returns the estimated score breakdown of a host with the
 	// provided settings.
 	EstimateHostScore(modules.HostDBEntry) modules.HostScoreBreakdown
@@ -95,6 +143,12 @@ type hostContractor interface {
 	// soon as SetAllowance is called; that is, it may block.
 	SetAllowance(modules.Allowance) error
 
+	// AcknowledgeAlert acknowledges an active alert of the contractor.
+	AcknowledgeAlert(modules.AlertID) error
+
+	// Alerts returns the active alerts of the contractor.
+	Alerts() []modules.Alert
+
 	// Allowance returns the current allowance
 	Allowance() modules.Allowance
 
@@ -107,18 +161,56 @@ type hostContractor interface {
 	// ContractByID returns the contract associated with the file contract id.
 	ContractByID(types.FileContractID) (modules.RenterContract, bool)
 
+	// ContractRecoveryStatus returns the progress of the most recent scan of
+	// the blockchain for the renter's contracts.
+	ContractRecoveryStatus() modules.ContractRecoveryStatus
+
 	// ContractUtility returns the utility field for a given contract, along
 	// with a bool indicating if it exists.
 	ContractUtility(types.FileContractID) (modules.ContractUtility, bool)
 
+	// CancelContract marks the contract as canceled, so that it is neither
+	// used for uploads nor renewed.
+	CancelContract(types.FileContractID) error
+
 	// CurrentPeriod returns the height at which the current allowance period
 	// began.
 	CurrentPeriod() types.BlockHeight
 
+	// FormContract forms a contract with the specified host.
+	FormContract(types.SiaPublicKey, types.Currency) (modules.RenterContract, error)
+
 	// PeriodSpending returns the amount spent on contracts during the current
 	// billing period.
 	PeriodSpending() modules.ContractorSpending
 
+	// PlanAllowance simulates the contract formation for the allowance
+	// without forming any contracts.
+	PlanAllowance(modules.Allowance) (modules.AllowancePlan, error)
+
+	// RecoverContracts starts a scan of the blockchain for the renter's
+	// contracts.
+	RecoverContracts() error
+
+	// RejectedHosts returns the hosts that were rejected because their prices
+	// exceed the caps of the allowance.
+	RejectedHosts() []modules.RejectedHost
+
+	// RenewContract renews the contract immediately.
+	RenewContract(types.FileContractID, types.Currency) (modules.RenterContract, error)
+
+	// ContractBackups returns the backups of the active contracts, which are
+	// included in snapshots of the renter's metadata.
+	ContractBackups() []proto.ContractBackup
+
+	// RestoreContracts restores the contracts of the backups that are not
+	// known to the contractor, and returns the number of restored contracts.
+	RestoreContracts([]proto.ContractBackup) (int, error)
+
+	// SpendingHistory returns the spending of every finished billing period,
+	// followed by the spending of the current period.
+	SpendingHistory() []modules.ContractorPeriodSpending
+
 	// Editor creates an Editor from the specified contract ID, allowing the
 	// insertion, deletion, and modification of sectors.
 	Editor(types.FileContractID, <-chan struct{}) (contractor.Editor, error)
@@ -149,6 +241,12 @@ type hostContractor interface {
 type trackedFile struct {
 	// location of original file on disk
 	RepairPath string
+
+	// ModTime and Hash describe the original file at the time it was
+	// uploaded. Sync jobs use them to detect files that have changed. The
+	// hash is only recorded for files uploaded by a sync job.
+	ModTime time.Time
+	Hash    crypto.Hash
 }
 
 // A Renter is responsible for tracking all of the files that a user has
@@ -182,17 +280,48 @@ type Renter struct {
 	downloadHistory   []*download
 	downloadHistoryMu sync.Mutex
 
-	// Upload management.
-	uploadHeap uploadHeap
+	// Upload management. Chunks that have been fetched are sent down the
+	// uploadEncodeQueue to be erasure coded and encrypted by a pool of
+	// encoding threads.
+	uploadHeap        uploadHeap
+	uploadEncodeQueue chan *unfinishedUploadChunk
 
-	// List of workers that can be used for uploading and/or downloading.
+	// maxMemory is the memory budget set by the user, zero means that
+	// defaultMemory is used. The memory reserves are the parts of the budget
+	// set aside for uploads, downloads and streams.
+	maxMemory     uint64
 	memoryManager *memoryManager
-	workerPool    map[types.FileContractID]*worker
+	memoryReserve memoryReserves
+
+	// Bandwidth limits. The default limits apply whenever no window of the
+	// schedule is active.
+	rateLimitDownload int64
+	rateLimitUpload   int64
+	rateLimitSchedule []modules.RateLimitWindow
+
+	// benchmarkHosts enables the benchmarking of contracted hosts.
+	benchmarkHosts bool
+
+	// Sync jobs, keyed by siapath prefix. syncMu ensures that only one sync
+	// runs at a time.
+	syncJobs map[string]*syncJob
+	syncMu   sync.Mutex
+
+	// Snapshots of the renter's metadata, oldest first. snapshotContent is
+	// the content hash of the most recent snapshot. snapshotMu ensures that
+	// only one snapshot is created or restored at a time.
+	snapshots       []snapshotRecord
+	snapshotContent crypto.Hash
+	snapshotMu      sync.Mutex
+
+	// List of workers that can be used for uploading and/or downloading.
+	workerPool map[types.FileContractID]*worker
 
 	// Cache the last price estimation result.
 	lastEstimation modules.RenterPriceEstimation
 
 	// Utilities.
+	staticAlerter     *modules.GenericAlerter
 	staticStreamCache *streamCache
 	cs                modules.ConsensusSet
 	deps              modules.Dependencies
@@ -204,6 +333,7 @@ type Renter struct {
 	mu                *siasync.RWMutex
 	tg                threadgroup.ThreadGroup
 	tpool             modules.TransactionPool
+	wallet            modules.Wallet
 }
 
 // Close closes the Renter and its dependencies
@@ -288,29 +418,62 @@ func (r *Renter) PriceEstimation() modules.RenterPriceEstimation {
 
 // SetSettings will update the settings for the renter.
 func (r *Renter) SetSettings(s modules.RenterSettings) error {
-	// Set allowance.
-	err := r.hostContractor.SetAllowance(s.Allowance)
-	if err != nil {
+	// Validate the settings before any of them are applied.
+	if s.MaxDownloadSpeed < 0 || s.MaxUploadSpeed < 0 {
+		return errNegativeRateLimit
+	}
+	if err := validateRateLimitSchedule(s.RateLimitSchedule); err != nil {
 		return err
 	}
-	// Set ratelimit
-	if s.MaxDownloadSpeed < 0 || s.MaxUploadSpeed < 0 {
-		return errors.New("download/upload rate limit can't be below 0")
+	// A MaxMemory of zero resets the memory budget to the default.
+	maxMemory := s.MaxMemory
+	if maxMemory == 0 {
+		maxMemory = defaultMemory
+	}
+	reserves := memoryReserves{
+		Upload:   s.UploadMemoryReserve,
+		Download: s.DownloadMemoryReserve,
+		Stream:   s.StreamMemoryReserve,
+	}
+	if err := reserves.validate(maxMemory); err != nil {
+		return err
 	}
-	if s.MaxDownloadSpeed == 0 && s.MaxUploadSpeed == 0 {
-		r.hostContractor.SetRateLimits(0, 0, 0)
-	} else {
-		// TODO: In the future we might want the user to be able to configure
-		// the packetSize using the API. For now the sane default is 16kib if
-		// the user wants to limit the connection.
-		r.hostContractor.SetRateLimits(s.MaxDownloadSpeed, s.MaxUploadSpeed, 4*4096)
+
+	// Set allowance. The contractor validates the allowance before setting
+	// it.
+	err := r.hostContractor.SetAllowance(s.Allowance)
+	if err != nil {
+		return err
 	}
+	r.hostDB.SetAllowance(s.Allowance)
 
 	// Set StreamingCacheSize
 	if s.StreamCacheSize > 0 {
 		r.staticStreamCache.SetStreamingCacheSize(s.StreamCacheSize)
 	}
 
+	// Set the ratelimit and the ratelimit schedule, and the memory budget and
+	// reserves.
+	id := r.mu.Lock()
+	r.rateLimitDownload = s.MaxDownloadSpeed
+	r.rateLimitUpload = s.MaxUploadSpeed
+	r.rateLimitSchedule = append([]modules.RateLimitWindow(nil), s.RateLimitSchedule...)
+	r.benchmarkHosts = s.BenchmarkHosts
+	if maxMemory != r.memoryManager.Base() {
+		r.maxMemory = s.MaxMemory
+		r.memoryManager.SetBase(maxMemory)
+	}
+	if reserves != r.memoryReserve {
+		r.memoryReserve = reserves
+		r.memoryManager.SetReserves(reserves.Upload, reserves.Download, reserves.Stream)
+	}
+	err = r.saveSync()
+	r.mu.Unlock(id)
+	if err != nil {
+		return err
+	}
+	r.managedApplyRateLimits()
+
 	r.managedUpdateWorkerPool()
 	return nil
 }
@@ -321,9 +484,64 @@ func (r *Renter) ActiveHosts() []modules.HostDBEntry { return r.hostDB.ActiveHos
 // AllHosts returns an array of all hosts
 func (r *Renter) AllHosts() []modules.HostDBEntry { return r.hostDB.AllHosts() }
 
+// ExportHostDB writes the hosts of the hostdb, including their scan history
+// and interactions, to dst.
+func (r *Renter) ExportHostDB(dst string) error { return r.hostDB.ExportHostDB(dst) }
+
+// FilterMode returns the hostdb's filter mode and the hosts it applies to.
+func (r *Renter) FilterMode() (modules.FilterMode, []types.SiaPublicKey) {
+	return r.hostDB.FilterMode()
+}
+
 // Host returns the host associated with the given public key
 func (r *Renter) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool) { return r.hostDB.Host(spk) }
 
+// ImportHostDB merges the hosts of the hostdb exported to src into the
+// hostdb. Imported scans are marked with the provided origin.
+func (r *Renter) ImportHostDB(src, origin string) (modules.HostDBImportReport, error) {
+	return r.hostDB.ImportHostDB(src, origin)
+}
+
+// SetFilterMode sets the hostdb's filter mode. Contracts with hosts that are
+// excluded by the filter are marked as not good for upload or renew the next
+// time the contractor updates the contract utilities, after which the renter
+// migrates the data to other hosts.
+func (r *Renter) SetFilterMode(fm modules.FilterMode, hosts []types.SiaPublicKey) error {
+	return r.hostDB.SetFilterMode(fm, hosts)
+}
+
+// RejectedHosts returns the hosts that were rejected because their prices
+// exceed the caps of the allowance.
+func (r *Renter) RejectedHosts() []modules.RejectedHost { return r.hostContractor.RejectedHosts() }
+
+// RescanHosts queues a scan of every host in the hostdb.
+func (r *Renter) RescanHosts() error { return r.hostDB.RescanHosts() }
+
+// RescanProgress returns the progress of the most recent full rescan of the
+// hostdb.
+func (r *Renter) RescanProgress() modules.HostDBRescanProgress { return r.hostDB.RescanProgress() }
+
+// ScanHost scans the host immediately and returns its updated entry.
+func (r *Renter) ScanHost(pk types.SiaPublicKey) (modules.HostDBEntry, error) {
+	return r.hostDB.ScanHost(pk)
+}
+
+// ScanPolicy returns the policy that the hostdb uses to scan hosts.
+func (r *Renter) ScanPolicy() modules.HostDBScanPolicy { return r.hostDB.ScanPolicy() }
+
+// SetScanPolicy sets the policy that the hostdb uses to scan hosts.
+func (r *Renter) SetScanPolicy(p modules.HostDBScanPolicy) error { return r.hostDB.SetScanPolicy(p) }
+
+// ScoringProfile returns the profile that the hostdb uses to score hosts.
+func (r *Renter) ScoringProfile() modules.HostScoringProfile { return r.hostDB.ScoringProfile() }
+
+// SetScoringProfile sets the profile that the hostdb uses to score hosts. The
+// scores of all hosts are recalculated, which affects the hosts that are
+// selected for new contracts.
+func (r *Renter) SetScoringProfile(p modules.HostScoringProfile) error {
+	return r.hostDB.SetScoringProfile(p)
+}
+
 // ScoreBreakdown returns the score breakdown
 func (r *Renter) ScoreBreakdown(e modules.HostDBEntry) modules.HostScoreBreakdown {
 	return r.hostDB.ScoreBreakdown(e)
@@ -346,20 +564,80 @@ func (r *Renter) ContractUtility(id types.FileContractID) (modules.ContractUtili
 	return r.hostContractor.ContractUtility(id)
 }
 
+// FormContract forms a contract with the host, funded with the specified
+// amount. If funding is zero, the contract is funded the same way the contract
+// maintenance would fund it.
+func (r *Renter) FormContract(spk types.SiaPublicKey, funding types.Currency) (modules.RenterContract, error) {
+	return r.hostContractor.FormContract(spk, funding)
+}
+
+// CancelContract marks the contract as canceled. The renter migrates the data
+// stored in a canceled contract to other hosts.
+func (r *Renter) CancelContract(id types.FileContractID) error {
+	return r.hostContractor.CancelContract(id)
+}
+
+// ContractRecoveryStatus returns the progress of the most recent scan of the
+// blockchain for the renter's contracts.
+func (r *Renter) ContractRecoveryStatus() modules.ContractRecoveryStatus {
+	return r.hostContractor.ContractRecoveryStatus()
+}
+
+// RecoverContracts starts a scan of the blockchain for the contracts that were
+// formed with the wallet seed, and recovers the contracts that the renter no
+// longer knows about from their hosts.
+func (r *Renter) RecoverContracts() error { return r.hostContractor.RecoverContracts() }
+
+// RenewContract renews the contract immediately instead of waiting for its
+// renew window.
+func (r *Renter) RenewContract(id types.FileContractID, funding types.Currency) (modules.RenterContract, error) {
+	return r.hostContractor.RenewContract(id, funding)
+}
+
 // PeriodSpending returns the host contractor's period spending
-func (r *Renter) PeriodSpending() modules.ContractorSpending { return r.hostContractor.PeriodSpending() }
+func (r *Renter) PeriodSpending() modules.ContractorSpending {
+	return r.hostContractor.PeriodSpending()
+}
+
+// PlanAllowance returns the contracts that the contractor would form if the
+// allowance was set, without forming any contracts or spending any money.
+func (r *Renter) PlanAllowance(a modules.Allowance) (modules.AllowancePlan, error) {
+	return r.hostContractor.PlanAllowance(a)
+}
+
+// SpendingHistory returns the host contractor's spending of every finished
+// period, followed by the spending of the current period.
+func (r *Renter) SpendingHistory() []modules.ContractorPeriodSpending {
+	return r.hostContractor.SpendingHistory()
+}
 
 // Settings returns the host contractor's allowance
 func (r *Renter) Settings() modules.RenterSettings {
-	download, upload, _ := r.hostContractor.RateLimits()
+	upReserve, downReserve, streamReserve := r.memoryManager.Reserves()
+	id := r.mu.RLock()
+	download, upload := r.rateLimitDownload, r.rateLimitUpload
+	schedule := append([]modules.RateLimitWindow(nil), r.rateLimitSchedule...)
+	benchmarkHosts := r.benchmarkHosts
+	r.mu.RUnlock(id)
 	return modules.RenterSettings{
 		Allowance:        r.hostContractor.Allowance(),
 		MaxDownloadSpeed: download,
 		MaxUploadSpeed:   upload,
 		StreamCacheSize:  r.staticStreamCache.cacheSize,
+		MaxMemory:        r.memoryManager.Base(),
+
+		UploadMemoryReserve:   upReserve,
+		DownloadMemoryReserve: downReserve,
+		StreamMemoryReserve:   streamReserve,
+
+		RateLimitSchedule: schedule,
+		BenchmarkHosts:    benchmarkHosts,
 	}
 }
 
+// MemoryStatus returns the current state of the renter's memory manager.
+func (r *Renter) MemoryStatus() modules.MemoryStatus { return r.memoryManager.Status() }
+
 // ProcessConsensusChange returns the process consensus change
 func (r *Renter) ProcessConsensusChange(cc modules.ConsensusChange) {
 	id := r.mu.Lock()
@@ -402,7 +680,7 @@ func validateSiapath(siapath string) error {
 var _ modules.Renter = (*Renter)(nil)
 
 // NewCustomRenter initializes a renter and returns it.
-func NewCustomRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.TransactionPool, hdb hostDB, hc hostContractor, persistDir string, deps modules.Dependencies) (*Renter, error) {
+func NewCustomRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.TransactionPool, wallet modules.Wallet, hdb hostDB, hc hostContractor, persistDir string, deps modules.Dependencies) (*Renter, error) {
 	if g == nil {
 		return nil, errNilGateway
 	}
@@ -412,6 +690,9 @@ func NewCustomRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.T
 	if tpool == nil {
 		return nil, errNilTpool
 	}
+	if wallet == nil {
+		return nil, errNilWallet
+	}
 	if hc == nil {
 		return nil, errNilContractor
 	}
@@ -422,6 +703,7 @@ func NewCustomRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.T
 	r := &Renter{
 		files:    make(map[string]*file),
 		tracking: make(map[string]trackedFile),
+		syncJobs: make(map[string]*syncJob),
 
 		// Making newDownloads a buffered channel means that most of the time, a
 		// new download will trigger an unnecessary extra iteration of the
@@ -435,9 +717,11 @@ func NewCustomRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.T
 			activeChunks: make(map[uploadChunkID]struct{}),
 			newUploads:   make(chan struct{}, 1),
 		},
+		uploadEncodeQueue: make(chan *unfinishedUploadChunk),
 
 		workerPool: make(map[types.FileContractID]*worker),
 
+		staticAlerter:     modules.NewAlerter("renter"),
 		staticStreamCache: newStreamCache(),
 		cs:                cs,
 		deps:              deps,
@@ -447,9 +731,15 @@ func NewCustomRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.T
 		persistDir:        persistDir,
 		mu:                siasync.New(modules.SafeMutexDelay, 1),
 		tpool:             tpool,
+		wallet:            wallet,
 	}
 	r.memoryManager = newMemoryManager(defaultMemory, r.tg.StopChan())
 
+	// Weight hosts according to the price caps of the allowance.
+	if hdb != nil {
+		hdb.SetAllowance(hc.Allowance())
+	}
+
 	// Load all saved data.
 	if err := r.initPersist(); err != nil {
 		return nil, err
@@ -465,6 +755,15 @@ func NewCustomRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.T
 	r.managedUpdateWorkerPool()
 	go r.threadedDownloadLoop()
 	go r.threadedUploadLoop()
+	go r.threadedRateLimitLoop()
+	go r.threadedSnapshotLoop()
+	go r.threadedBenchmarkLoop()
+	for _, job := range r.syncJobs {
+		go r.threadedSyncLoop(job, false)
+	}
+	for i := 0; i < uploadEncodeThreads; i++ {
+		go r.threadedUploadEncodeLoop()
+	}
 
 	// Kill workers on shutdown.
 	r.tg.OnStop(func() error {
@@ -490,5 +789,5 @@ func New(g modules.Gateway, cs modules.ConsensusSet, wallet modules.Wallet, tpoo
 		return nil, err
 	}
 
-	return NewCustomRenter(g, cs, tpool, hdb, hc, persistDir, modules.ProdDependencies)
+	return NewCustomRenter(g, cs, tpool, wallet, hdb, hc, persistDir, modules.ProdDependencies)
 }
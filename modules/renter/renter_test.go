@@ -1,9 +1,11 @@
 package renter
 
 import (
+	"math"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -12,6 +14,8 @@ import (
 	"github.com/NebulousLabs/Sia/modules/gateway"
 	"github.com/NebulousLabs/Sia/modules/miner"
 	"github.com/NebulousLabs/Sia/modules/renter/contractor"
+	"github.com/NebulousLabs/Sia/modules/renter/hostdb"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
 	"github.com/NebulousLabs/Sia/modules/transactionpool"
 	"github.com/NebulousLabs/Sia/modules/wallet"
 	"github.com/NebulousLabs/Sia/types"
@@ -40,6 +44,12 @@ func (rt *renterTester) Close() error {
 // newRenterTester creates a ready-to-use renter tester with money in the
 // wallet.
 func newRenterTester(name string) (*renterTester, error) {
+	return newRenterTesterWithDependency(name, modules.ProdDependencies)
+}
+
+// newRenterTesterWithDependency creates a ready-to-use renter tester with
+// money in the wallet, using the provided dependencies for the renter.
+func newRenterTesterWithDependency(name string, deps modules.Dependencies) (*renterTester, error) {
 	// Create the modules.
 	testdir := build.TempDir("renter", name)
 	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
@@ -67,7 +77,16 @@ func newRenterTester(name string) (*renterTester, error) {
 	if err != nil {
 		return nil, err
 	}
-	r, err := New(g, cs, w, tp, filepath.Join(testdir, modules.RenterDir))
+	renterDir := filepath.Join(testdir, modules.RenterDir)
+	hdb, err := hostdb.New(g, cs, renterDir)
+	if err != nil {
+		return nil, err
+	}
+	hc, err := contractor.New(cs, w, tp, hdb, renterDir)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewCustomRenter(g, cs, tp, w, hdb, hc, renterDir, deps)
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +126,9 @@ func (stubHostDB) AllHosts() []modules.HostDBEntry      { return nil }
 func (stubHostDB) AverageContractPrice() types.Currency { return types.Currency{} }
 func (stubHostDB) Close() error                         { return nil }
 func (stubHostDB) IsOffline(modules.NetAddress) bool    { return true }
+func (stubHostDB) FilterMode() (modules.FilterMode, []types.SiaPublicKey) {
+	return modules.HostDBFilterDisabled, nil
+}
 func (stubHostDB) RandomHosts(int, []types.SiaPublicKey) ([]modules.HostDBEntry, error) {
 	return []modules.HostDBEntry{}, nil
 }
@@ -119,20 +141,65 @@ func (stubHostDB) Host(types.SiaPublicKey) (modules.HostDBEntry, bool) {
 func (stubHostDB) ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown {
 	return modules.HostScoreBreakdown{}
 }
+func (stubHostDB) SetAllowance(modules.Allowance)                               {}
+func (stubHostDB) SetFilterMode(modules.FilterMode, []types.SiaPublicKey) error { return nil }
+func (stubHostDB) ScoringProfile() modules.HostScoringProfile {
+	return modules.DefaultHostScoringProfile
+}
+func (stubHostDB) SetScoringProfile(modules.HostScoringProfile) error { return nil }
+func (stubHostDB) BenchmarkSettings(types.SiaPublicKey) (time.Duration, error) {
+	return 0, nil
+}
+func (stubHostDB) RecordBenchmark(types.SiaPublicKey, modules.HostBenchmark) error { return nil }
+func (stubHostDB) RescanHosts() error                                              { return nil }
+func (stubHostDB) RescanProgress() modules.HostDBRescanProgress {
+	return modules.HostDBRescanProgress{}
+}
+func (stubHostDB) ScanHost(types.SiaPublicKey) (modules.HostDBEntry, error) {
+	return modules.HostDBEntry{}, nil
+}
+func (stubHostDB) ScanPolicy() modules.HostDBScanPolicy         { return modules.HostDBScanPolicy{} }
+func (stubHostDB) SetScanPolicy(modules.HostDBScanPolicy) error { return nil }
+func (stubHostDB) ExportHostDB(string) error                    { return nil }
+func (stubHostDB) ImportHostDB(string, string) (modules.HostDBImportReport, error) {
+	return modules.HostDBImportReport{}, nil
+}
 
 // stubContractor is the minimal implementation of the hostContractor
 // interface.
 type stubContractor struct{}
 
-func (stubContractor) SetAllowance(modules.Allowance) error { return nil }
-func (stubContractor) Allowance() modules.Allowance         { return modules.Allowance{} }
+func (stubContractor) SetAllowance(modules.Allowance) error   { return nil }
+func (stubContractor) AcknowledgeAlert(modules.AlertID) error { return modules.ErrUnknownAlert }
+func (stubContractor) Alerts() []modules.Alert                { return nil }
+func (stubContractor) Allowance() modules.Allowance           { return modules.Allowance{} }
 func (stubContractor) Contract(modules.NetAddress) (modules.RenterContract, bool) {
 	return modules.RenterContract{}, false
 }
+func (stubContractor) ContractRecoveryStatus() modules.ContractRecoveryStatus {
+	return modules.ContractRecoveryStatus{}
+}
+func (stubContractor) CancelContract(types.FileContractID) error              { return nil }
+func (stubContractor) ContractBackups() []proto.ContractBackup                { return nil }
 func (stubContractor) Contracts() []modules.RenterContract                    { return nil }
 func (stubContractor) CurrentPeriod() types.BlockHeight                       { return 0 }
 func (stubContractor) IsOffline(modules.NetAddress) bool                      { return false }
+func (stubContractor) RecoverContracts() error                                { return nil }
+func (stubContractor) RejectedHosts() []modules.RejectedHost                  { return nil }
+func (stubContractor) SpendingHistory() []modules.ContractorPeriodSpending    { return nil }
 func (stubContractor) Editor(types.FileContractID) (contractor.Editor, error) { return nil, nil }
+func (stubContractor) FormContract(types.SiaPublicKey, types.Currency) (modules.RenterContract, error) {
+	return modules.RenterContract{}, nil
+}
+func (stubContractor) PlanAllowance(modules.Allowance) (modules.AllowancePlan, error) {
+	return modules.AllowancePlan{}, nil
+}
+func (stubContractor) RenewContract(types.FileContractID, types.Currency) (modules.RenterContract, error) {
+	return modules.RenterContract{}, nil
+}
+func (stubContractor) RestoreContracts([]proto.ContractBackup) (int, error) {
+	return 0, nil
+}
 func (stubContractor) Downloader(types.FileContractID) (contractor.Downloader, error) {
 	return nil, nil
 }
@@ -223,3 +290,74 @@ func TestRenterSiapathValidate(t *testing.T) {
 		}
 	}
 }
+
+// TestSetSettingsValidation checks that none of the settings are applied if
+// one of them is invalid.
+func TestSetSettingsValidation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	settings := rt.renter.Settings()
+	settings.MaxDownloadSpeed = 1000
+	settings.StreamMemoryReserve = 1
+	settings.UploadMemoryReserve = math.MaxUint64
+	settings.DownloadMemoryReserve = 2
+	if err := rt.renter.SetSettings(settings); err != errMemoryReservesTooLarge {
+		t.Fatal("expected errMemoryReservesTooLarge, got", err)
+	}
+	if s := rt.renter.Settings(); s.MaxDownloadSpeed != 0 || s.UploadMemoryReserve != 0 || s.StreamMemoryReserve != 0 {
+		t.Fatal("settings were applied:", s.MaxDownloadSpeed, s.UploadMemoryReserve, s.StreamMemoryReserve)
+	}
+}
+
+// TestSetSettingsMaxMemory checks that the memory budget can be changed and
+// reset to the default.
+func TestSetSettingsMaxMemory(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	settings := rt.renter.Settings()
+	settings.MaxMemory = 2 * defaultMemory
+	if err := rt.renter.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if s := rt.renter.Settings(); s.MaxMemory != 2*defaultMemory {
+		t.Fatal("memory budget was not set:", s.MaxMemory)
+	}
+
+	// Applying the current settings leaves the budget unchanged.
+	if err := rt.renter.SetSettings(rt.renter.Settings()); err != nil {
+		t.Fatal(err)
+	}
+	if s := rt.renter.Settings(); s.MaxMemory != 2*defaultMemory {
+		t.Fatal("memory budget was changed:", s.MaxMemory)
+	}
+
+	// Zero resets the budget to the default.
+	settings = rt.renter.Settings()
+	settings.MaxMemory = 0
+	if err := rt.renter.SetSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if s := rt.renter.Settings(); s.MaxMemory != defaultMemory {
+		t.Fatal("memory budget was not reset:", s.MaxMemory)
+	}
+	id := rt.renter.mu.RLock()
+	maxMemory := rt.renter.maxMemory
+	rt.renter.mu.RUnlock(id)
+	if maxMemory != 0 {
+		t.Fatal("default memory budget was persisted as", maxMemory)
+	}
+}
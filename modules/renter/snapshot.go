@@ -0,0 +1,636 @@
+package renter
+
+// snapshot.go uploads encrypted snapshots of the renter's metadata to hosts.
+// A snapshot contains the backups of the renter's contracts followed by the
+// renter's files in the .sia format. It is encrypted with a key derived from
+// the wallet seed and uploaded as ordinary sectors to several hosts.
+//
+// A new node cannot ask a host which sectors it stores, so the renter also
+// puts a record of every snapshot, containing the sector roots and the hosts
+// of the snapshot, in the arbitrary data of a transaction. The record is
+// encrypted with the same key. To restore a snapshot into a new node, the
+// renter scans the blockchain for its records and downloads the snapshot
+// through the contracts that were recovered from the seed.
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/fastrand"
+)
+
+// snapshotTxnOverhead is the estimated size in bytes of a snapshot record
+// transaction, excluding the record itself.
+const snapshotTxnOverhead = 400
+
+var (
+	// snapshotSpecifier marks the arbitrary data of a transaction as a
+	// snapshot record. It follows modules.PrefixNonSia, so that the
+	// transaction is considered standard.
+	snapshotSpecifier = types.Specifier{'S', 'n', 'a', 'p', 's', 'h', 'o', 't'}
+
+	// snapshotKeySpecifier is used to derive the key that encrypts snapshots
+	// from the renter seed.
+	snapshotKeySpecifier = types.Specifier{'s', 'n', 'a', 'p', 's', 'h', 'o', 't'}
+
+	errNoSnapshotHosts     = errors.New("no contracts are available to store the snapshot")
+	errNoSnapshots         = errors.New("no snapshots were found")
+	errSnapshotNotFound    = errors.New("snapshot not found")
+	errSnapshotUnavailable = errors.New("the snapshot could not be downloaded from any of its hosts")
+	errSnapshotUnchanged   = errors.New("the renter's metadata hasn't changed since the last snapshot")
+)
+
+// snapshotRecord describes a snapshot that was uploaded to hosts. Every host
+// stores the whole snapshot, so the sector roots are the same for all hosts.
+type snapshotRecord struct {
+	ID           crypto.Hash
+	Timestamp    int64
+	Size         uint64
+	NumFiles     uint64
+	NumContracts uint64
+	Roots        []crypto.Hash
+	Hosts        []types.SiaPublicKey
+}
+
+// info returns the modules.RenterSnapshot of the record.
+func (sr snapshotRecord) info() modules.RenterSnapshot {
+	return modules.RenterSnapshot{
+		ID:           sr.ID,
+		CreationTime: time.Unix(sr.Timestamp, 0),
+		Size:         sr.Size,
+		NumFiles:     sr.NumFiles,
+		NumContracts: sr.NumContracts,
+		Hosts:        sr.Hosts,
+	}
+}
+
+// snapshotScanner is subscribed to the consensus set while the renter scans
+// the blockchain for the records of its snapshots.
+type snapshotScanner struct {
+	key     crypto.TwofishKey
+	records []snapshotRecord
+}
+
+// ProcessConsensusChange decodes the snapshot records of the applied blocks.
+func (ss *snapshotScanner) ProcessConsensusChange(cc modules.ConsensusChange) {
+	for _, block := range cc.AppliedBlocks {
+		for _, txn := range block.Transactions {
+			for _, arb := range txn.ArbitraryData {
+				if sr, ok := decodeSnapshotRecord(ss.key, arb); ok {
+					ss.records = append(ss.records, sr)
+				}
+			}
+		}
+	}
+}
+
+// encodeSnapshotRecord encrypts the record so that it can be added to the
+// arbitrary data of a transaction.
+func encodeSnapshotRecord(key crypto.TwofishKey, sr snapshotRecord) []byte {
+	ciphertext := key.EncryptBytes(encoding.Marshal(sr))
+	arb := make([]byte, 0, 2*types.SpecifierLen+len(ciphertext))
+	arb = append(arb, modules.PrefixNonSia[:]...)
+	arb = append(arb, snapshotSpecifier[:]...)
+	return append(arb, ciphertext...)
+}
+
+// decodeSnapshotRecord decodes the snapshot record in the arbitrary data of a
+// transaction. It returns false if the arbitrary data is not a snapshot record
+// that was encrypted with the key.
+func decodeSnapshotRecord(key crypto.TwofishKey, arb []byte) (snapshotRecord, bool) {
+	if len(arb) < 2*types.SpecifierLen {
+		return snapshotRecord{}, false
+	}
+	var prefix, specifier types.Specifier
+	copy(prefix[:], arb)
+	copy(specifier[:], arb[types.SpecifierLen:])
+	if prefix != modules.PrefixNonSia || specifier != snapshotSpecifier {
+		return snapshotRecord{}, false
+	}
+	plaintext, err := key.DecryptBytes(crypto.Ciphertext(arb[2*types.SpecifierLen:]))
+	if err != nil {
+		return snapshotRecord{}, false
+	}
+	var sr snapshotRecord
+	if err := encoding.Unmarshal(plaintext, &sr); err != nil {
+		return snapshotRecord{}, false
+	}
+	return sr, true
+}
+
+// writeSnapshot writes the contract backups and the files of a snapshot to w.
+func writeSnapshot(w io.Writer, contracts []proto.ContractBackup, files []*file) error {
+	if err := encoding.NewEncoder(w).Encode(contracts); err != nil {
+		return err
+	}
+	return shareFiles(files, w)
+}
+
+// readSnapshot reads the contract backups and the files of a snapshot from r.
+func readSnapshot(r io.Reader) ([]proto.ContractBackup, []*file, error) {
+	var contracts []proto.ContractBackup
+	if err := encoding.NewDecoder(r).Decode(&contracts); err != nil {
+		return nil, nil, err
+	}
+	files, err := decodeSharedFiles(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return contracts, files, nil
+}
+
+// snapshotContentHash returns a hash of the contracts and files of a snapshot,
+// which is used to skip snapshots when nothing changed. Only the IDs of the
+// contracts are hashed, since every upload of a snapshot revises the
+// contracts that store it.
+func snapshotContentHash(contracts []proto.ContractBackup, files []*file) (crypto.Hash, error) {
+	ids := make([]types.FileContractID, len(contracts))
+	for i, c := range contracts {
+		ids[i] = c.ID()
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i][:], ids[j][:]) < 0
+	})
+
+	h := crypto.NewHash()
+	enc := encoding.NewEncoder(h)
+	if err := enc.Encode(ids); err != nil {
+		return crypto.Hash{}, err
+	}
+	for _, f := range files {
+		f.mu.RLock()
+		err := enc.EncodeAll(f.name, f.size, f.mode, uint64(len(f.contracts)))
+		fcids := make([]types.FileContractID, 0, len(f.contracts))
+		for id := range f.contracts {
+			fcids = append(fcids, id)
+		}
+		sort.Slice(fcids, func(i, j int) bool {
+			return bytes.Compare(fcids[i][:], fcids[j][:]) < 0
+		})
+		for _, id := range fcids {
+			if err == nil {
+				err = enc.Encode(f.contracts[id])
+			}
+		}
+		f.mu.RUnlock()
+		if err != nil {
+			return crypto.Hash{}, err
+		}
+	}
+	var sum crypto.Hash
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// snapshotSectors splits an encrypted snapshot into sectors. The last sector
+// is padded with zeros.
+func snapshotSectors(ciphertext []byte) [][]byte {
+	var sectors [][]byte
+	for len(ciphertext) > 0 {
+		sector := make([]byte, modules.SectorSize)
+		n := copy(sector, ciphertext)
+		ciphertext = ciphertext[n:]
+		sectors = append(sectors, sector)
+	}
+	return sectors
+}
+
+// snapshotKey derives the key that encrypts snapshots from the wallet seed.
+// The wallet must be unlocked.
+func (r *Renter) snapshotKey() (crypto.TwofishKey, error) {
+	walletSeed, _, err := r.wallet.PrimarySeed()
+	if err != nil {
+		return crypto.TwofishKey{}, err
+	}
+	defer crypto.SecureWipe(walletSeed[:])
+	renterSeed := proto.DeriveRenterSeed(walletSeed)
+	defer crypto.SecureWipe(renterSeed[:])
+	return crypto.TwofishKey(crypto.HashAll(renterSeed, snapshotKeySpecifier)), nil
+}
+
+// managedUploadSnapshot uploads the sectors of a snapshot to up to
+// snapshotHosts hosts that the renter has contracts with. If old is not nil,
+// the sectors of the old snapshot are overwritten on its hosts first, so that
+// the contracts don't grow with every snapshot. The snapshot must have at
+// least as many sectors as the old snapshot. It returns the hosts that store
+// the snapshot.
+func (r *Renter) managedUploadSnapshot(sectors [][]byte, old *snapshotRecord) []types.SiaPublicKey {
+	var contracts []modules.RenterContract
+	for _, c := range r.hostContractor.Contracts() {
+		if c.Utility.GoodForUpload {
+			contracts = append(contracts, c)
+		}
+	}
+
+	// The hosts of the old snapshot are not used for a fresh upload, even if
+	// overwriting their sectors fails.
+	var hosts []types.SiaPublicKey
+	oldHosts := make(map[string]struct{})
+	if old != nil {
+		for _, host := range old.Hosts {
+			oldHosts[host.String()] = struct{}{}
+		}
+		for _, c := range contracts {
+			if _, ok := oldHosts[c.HostPublicKey.String()]; !ok || len(hosts) >= snapshotHosts {
+				continue
+			}
+			if err := r.managedReplaceSnapshot(c, old.Roots, sectors); err != nil {
+				r.log.Debugln("Unable to overwrite snapshot on", c.HostPublicKey, err)
+				continue
+			}
+			hosts = append(hosts, c.HostPublicKey)
+		}
+	}
+
+	for _, i := range fastrand.Perm(len(contracts)) {
+		if len(hosts) >= snapshotHosts {
+			break
+		}
+		c := contracts[i]
+		if _, ok := oldHosts[c.HostPublicKey.String()]; ok {
+			continue
+		}
+		e, err := r.hostContractor.Editor(c.ID, r.tg.StopChan())
+		if err != nil {
+			r.log.Debugln("Unable to upload snapshot to", c.HostPublicKey, err)
+			continue
+		}
+		_, err = e.UploadBatch(sectors)
+		e.Close()
+		if err != nil {
+			r.log.Debugln("Unable to upload snapshot to", c.HostPublicKey, err)
+			continue
+		}
+		hosts = append(hosts, c.HostPublicKey)
+	}
+	return hosts
+}
+
+// managedReplaceSnapshot overwrites the sectors with the roots oldRoots in
+// the contract with the first sectors of a snapshot, and uploads the
+// remaining sectors.
+func (r *Renter) managedReplaceSnapshot(c modules.RenterContract, oldRoots []crypto.Hash, sectors [][]byte) error {
+	e, err := r.hostContractor.Editor(c.ID, r.tg.StopChan())
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+	if _, err := e.ReplaceBatch(oldRoots, sectors[:len(oldRoots)]); err != nil {
+		return err
+	}
+	if len(sectors) > len(oldRoots) {
+		_, err = e.UploadBatch(sectors[len(oldRoots):])
+	}
+	return err
+}
+
+// managedPostSnapshotRecord adds the encrypted record of a snapshot to the
+// blockchain.
+func (r *Renter) managedPostSnapshotRecord(key crypto.TwofishKey, sr snapshotRecord) (err error) {
+	arb := encodeSnapshotRecord(key, sr)
+	txnBuilder, err := r.wallet.StartTransaction()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			txnBuilder.Drop()
+		}
+	}()
+	_, fee := r.tpool.FeeEstimation()
+	fee = fee.Mul64(uint64(len(arb) + snapshotTxnOverhead))
+	err = txnBuilder.FundSiacoins(fee)
+	if err != nil {
+		return err
+	}
+	_ = txnBuilder.AddMinerFee(fee)
+	_ = txnBuilder.AddArbitraryData(arb)
+	txnSet, err := txnBuilder.Sign(true)
+	if err != nil {
+		return err
+	}
+	return r.tpool.AcceptTransactionSet(txnSet)
+}
+
+// managedCreateSnapshot uploads a snapshot of the renter's metadata to hosts
+// and adds its record to the blockchain. Once snapshotsKept snapshots exist,
+// the sectors of the oldest snapshot are overwritten and its record is
+// removed. If skipUnchanged is set, errSnapshotUnchanged is returned if the
+// contracts and files are the same as in the most recent snapshot.
+func (r *Renter) managedCreateSnapshot(skipUnchanged bool) (snapshotRecord, error) {
+	key, err := r.snapshotKey()
+	if err != nil {
+		return snapshotRecord{}, err
+	}
+
+	// Encode and encrypt the snapshot. The files are sorted so that the
+	// content hash doesn't depend on the order of the renter's file map.
+	contracts := r.hostContractor.ContractBackups()
+	buf := new(bytes.Buffer)
+	id := r.mu.RLock()
+	files := make([]*file, 0, len(r.files))
+	for _, f := range r.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+	content, err := snapshotContentHash(contracts, files)
+	if err == nil && skipUnchanged && content == r.snapshotContent {
+		err = errSnapshotUnchanged
+	} else if err == nil {
+		err = writeSnapshot(buf, contracts, files)
+	}
+	var old *snapshotRecord
+	if len(r.snapshots) >= snapshotsKept {
+		oldest := r.snapshots[0]
+		old = &oldest
+	}
+	r.mu.RUnlock(id)
+	if err != nil {
+		return snapshotRecord{}, err
+	}
+	ciphertext := key.EncryptBytes(buf.Bytes())
+
+	// A snapshot that overwrites a larger snapshot is padded with random
+	// sectors, so that the roots of the padding are unique within the
+	// contracts.
+	sectors := snapshotSectors(ciphertext)
+	if old != nil {
+		for len(sectors) < len(old.Roots) {
+			sectors = append(sectors, fastrand.Bytes(int(modules.SectorSize)))
+		}
+	}
+	hosts := r.managedUploadSnapshot(sectors, old)
+
+	// The sectors of the old snapshot might have been overwritten, even if
+	// the upload failed, so its record is removed either way.
+	if old != nil {
+		id = r.mu.Lock()
+		for i, sr := range r.snapshots {
+			if sr.ID == old.ID {
+				r.snapshots = append(r.snapshots[:i], r.snapshots[i+1:]...)
+				break
+			}
+		}
+		err = r.saveSync()
+		r.mu.Unlock(id)
+		if err != nil {
+			return snapshotRecord{}, err
+		}
+	}
+	if len(hosts) == 0 {
+		return snapshotRecord{}, errNoSnapshotHosts
+	}
+	sr := snapshotRecord{
+		ID:           crypto.HashBytes(ciphertext),
+		Timestamp:    time.Now().Unix(),
+		Size:         uint64(len(ciphertext)),
+		NumFiles:     uint64(len(files)),
+		NumContracts: uint64(len(contracts)),
+		Hosts:        hosts,
+	}
+	for _, sector := range sectors {
+		sr.Roots = append(sr.Roots, crypto.MerkleRoot(sector))
+	}
+	if err := r.managedPostSnapshotRecord(key, sr); err != nil {
+		return snapshotRecord{}, err
+	}
+
+	id = r.mu.Lock()
+	r.snapshots = append(r.snapshots, sr)
+	r.snapshotContent = content
+	err = r.saveSync()
+	r.mu.Unlock(id)
+	if err != nil {
+		return snapshotRecord{}, err
+	}
+	r.log.Printf("Uploaded snapshot %v to %v hosts\n", sr.ID, len(hosts))
+	return sr, nil
+}
+
+// managedScanSnapshots scans the blockchain for the records of the renter's
+// snapshots, and adds the snapshots to the snapshot history.
+func (r *Renter) managedScanSnapshots(key crypto.TwofishKey) error {
+	ss := &snapshotScanner{key: key}
+	err := r.cs.ConsensusSetSubscribe(ss, modules.ConsensusChangeBeginning, r.tg.StopChan())
+	if err != nil {
+		return err
+	}
+	r.cs.Unsubscribe(ss)
+
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	known := make(map[crypto.Hash]struct{})
+	for _, sr := range r.snapshots {
+		known[sr.ID] = struct{}{}
+	}
+	for _, sr := range ss.records {
+		if _, ok := known[sr.ID]; !ok {
+			known[sr.ID] = struct{}{}
+			r.snapshots = append(r.snapshots, sr)
+		}
+	}
+	sort.SliceStable(r.snapshots, func(i, j int) bool {
+		return r.snapshots[i].Timestamp < r.snapshots[j].Timestamp
+	})
+	// The sectors of older snapshots have been overwritten by newer ones.
+	if len(r.snapshots) > snapshotsKept {
+		r.snapshots = r.snapshots[len(r.snapshots)-snapshotsKept:]
+	}
+	return r.saveSync()
+}
+
+// managedSnapshot returns the record of the snapshot with the specified ID,
+// or the most recent snapshot if the ID is empty.
+func (r *Renter) managedSnapshot(snapshotID crypto.Hash) (snapshotRecord, bool) {
+	id := r.mu.RLock()
+	defer r.mu.RUnlock(id)
+	if snapshotID == (crypto.Hash{}) && len(r.snapshots) > 0 {
+		return r.snapshots[len(r.snapshots)-1], true
+	}
+	for _, sr := range r.snapshots {
+		if sr.ID == snapshotID {
+			return sr, true
+		}
+	}
+	return snapshotRecord{}, false
+}
+
+// managedDownloadSnapshot downloads a snapshot from one of its hosts and
+// decrypts it.
+func (r *Renter) managedDownloadSnapshot(key crypto.TwofishKey, sr snapshotRecord) ([]byte, error) {
+	contracts := r.hostContractor.Contracts()
+	for _, host := range sr.Hosts {
+		for _, c := range contracts {
+			if c.HostPublicKey.String() != host.String() {
+				continue
+			}
+			d, err := r.hostContractor.Downloader(c.ID, r.tg.StopChan())
+			if err != nil {
+				r.log.Debugln("Unable to download snapshot from", host, err)
+				continue
+			}
+			ciphertext := make([]byte, 0, uint64(len(sr.Roots))*modules.SectorSize)
+			for _, root := range sr.Roots {
+				var sector []byte
+				if sector, err = d.Sector(root); err != nil {
+					break
+				}
+				ciphertext = append(ciphertext, sector...)
+			}
+			d.Close()
+			if err != nil {
+				r.log.Debugln("Unable to download snapshot from", host, err)
+				continue
+			}
+			if uint64(len(ciphertext)) < sr.Size || crypto.HashBytes(ciphertext[:sr.Size]) != sr.ID {
+				r.log.Debugln("Host", host, "returned a corrupted snapshot")
+				continue
+			}
+			return key.DecryptBytes(crypto.Ciphertext(ciphertext[:sr.Size]))
+		}
+	}
+	return nil, errSnapshotUnavailable
+}
+
+// threadedSnapshotLoop periodically uploads a snapshot of the renter's
+// metadata. Snapshots are only created while the wallet is unlocked and the
+// renter has files, and only if the files or contracts changed since the most
+// recent snapshot.
+func (r *Renter) threadedSnapshotLoop() {
+	err := r.tg.Add()
+	if err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	for {
+		select {
+		case <-r.tg.StopChan():
+			return
+		case <-time.After(snapshotInterval):
+		}
+
+		id := r.mu.RLock()
+		numFiles := len(r.files)
+		var lastSnapshot time.Time
+		if len(r.snapshots) > 0 {
+			lastSnapshot = time.Unix(r.snapshots[len(r.snapshots)-1].Timestamp, 0)
+		}
+		r.mu.RUnlock(id)
+		if numFiles == 0 || time.Since(lastSnapshot) < snapshotInterval {
+			continue
+		}
+		if unlocked, err := r.wallet.Unlocked(); err != nil || !unlocked {
+			continue
+		}
+
+		r.snapshotMu.Lock()
+		_, err = r.managedCreateSnapshot(true)
+		r.snapshotMu.Unlock()
+		if err != nil && err != errSnapshotUnchanged {
+			r.log.Println("WARN: unable to create snapshot:", err)
+		}
+	}
+}
+
+// CreateSnapshot uploads a snapshot of the renter's metadata to hosts and
+// adds its record to the blockchain. The wallet must be unlocked.
+func (r *Renter) CreateSnapshot() (modules.RenterSnapshot, error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.RenterSnapshot{}, err
+	}
+	defer r.tg.Done()
+	r.snapshotMu.Lock()
+	defer r.snapshotMu.Unlock()
+
+	sr, err := r.managedCreateSnapshot(false)
+	if err != nil {
+		return modules.RenterSnapshot{}, err
+	}
+	return sr.info(), nil
+}
+
+// RestoreSnapshot restores the contracts and files of a snapshot. If the ID
+// is empty, the most recent snapshot is restored. If the snapshot is not in
+// the snapshot history, the blockchain is scanned for the renter's
+// snapshots first. Files that the renter already knows are not replaced.
+func (r *Renter) RestoreSnapshot(snapshotID crypto.Hash) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	r.snapshotMu.Lock()
+	defer r.snapshotMu.Unlock()
+
+	key, err := r.snapshotKey()
+	if err != nil {
+		return err
+	}
+	sr, ok := r.managedSnapshot(snapshotID)
+	if !ok {
+		if err := r.managedScanSnapshots(key); err != nil {
+			return err
+		}
+		sr, ok = r.managedSnapshot(snapshotID)
+	}
+	if !ok && snapshotID == (crypto.Hash{}) {
+		return errNoSnapshots
+	} else if !ok {
+		return errSnapshotNotFound
+	}
+
+	plaintext, err := r.managedDownloadSnapshot(key, sr)
+	if err != nil {
+		return err
+	}
+	contracts, files, err := readSnapshot(bytes.NewReader(plaintext))
+	if err != nil {
+		return err
+	}
+
+	// Restore the contracts first, so that the files can be downloaded and
+	// repaired.
+	restored, err := r.hostContractor.RestoreContracts(contracts)
+	if err != nil {
+		return err
+	}
+	r.managedUpdateWorkerPool()
+
+	id := r.mu.Lock()
+	var added int
+	for _, f := range files {
+		if _, exists := r.files[f.name]; exists {
+			continue
+		}
+		r.files[f.name] = f
+		if err := r.saveFile(f); err != nil {
+			r.log.Println("WARN: unable to save restored file:", err)
+		}
+		added++
+	}
+	r.mu.Unlock(id)
+	r.log.Printf("Restored snapshot %v: %v contracts, %v files\n", sr.ID, restored, added)
+	return nil
+}
+
+// Snapshots returns the snapshots of the renter's metadata, oldest first.
+func (r *Renter) Snapshots() []modules.RenterSnapshot {
+	id := r.mu.RLock()
+	defer r.mu.RUnlock(id)
+	snapshots := make([]modules.RenterSnapshot, len(r.snapshots))
+	for i, sr := range r.snapshots {
+		snapshots[i] = sr.info()
+	}
+	return snapshots
+}
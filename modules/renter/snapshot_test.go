@@ -0,0 +1,136 @@
+package renter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSnapshotRecord tests that snapshot records can only be decoded with the
+// key that encrypted them.
+func TestSnapshotRecord(t *testing.T) {
+	key := crypto.GenerateTwofishKey()
+	sr := snapshotRecord{
+		ID:           crypto.Hash{1},
+		Timestamp:    1234,
+		Size:         5678,
+		NumFiles:     3,
+		NumContracts: 2,
+		Roots:        []crypto.Hash{{2}, {3}},
+		Hosts:        []types.SiaPublicKey{{Algorithm: types.SignatureEd25519, Key: []byte{4}}},
+	}
+	arb := encodeSnapshotRecord(key, sr)
+
+	decoded, ok := decodeSnapshotRecord(key, arb)
+	if !ok {
+		t.Fatal("could not decode snapshot record")
+	} else if decoded.ID != sr.ID || decoded.Timestamp != sr.Timestamp || decoded.Size != sr.Size {
+		t.Fatal("decoded record does not match:", decoded)
+	} else if len(decoded.Roots) != 2 || decoded.Roots[1] != sr.Roots[1] {
+		t.Fatal("decoded roots do not match:", decoded.Roots)
+	} else if len(decoded.Hosts) != 1 || decoded.Hosts[0].String() != sr.Hosts[0].String() {
+		t.Fatal("decoded hosts do not match:", decoded.Hosts)
+	}
+
+	// A different key cannot decode the record.
+	if _, ok := decodeSnapshotRecord(crypto.GenerateTwofishKey(), arb); ok {
+		t.Fatal("record was decoded with the wrong key")
+	}
+	// Other arbitrary data is ignored.
+	if _, ok := decodeSnapshotRecord(key, arb[:20]); ok {
+		t.Fatal("truncated record was decoded")
+	}
+	if _, ok := decodeSnapshotRecord(key, modules.PrefixHostAnnouncement[:]); ok {
+		t.Fatal("host announcement was decoded")
+	}
+}
+
+// TestSnapshotSectors tests that snapshots are split into padded sectors.
+func TestSnapshotSectors(t *testing.T) {
+	if sectors := snapshotSectors(nil); len(sectors) != 0 {
+		t.Fatal("expected no sectors, got", len(sectors))
+	}
+	data := bytes.Repeat([]byte{1}, int(modules.SectorSize)+10)
+	sectors := snapshotSectors(data)
+	if len(sectors) != 2 {
+		t.Fatal("expected 2 sectors, got", len(sectors))
+	}
+	for _, sector := range sectors {
+		if uint64(len(sector)) != modules.SectorSize {
+			t.Fatal("sector has wrong size:", len(sector))
+		}
+	}
+	if !bytes.Equal(append(sectors[0], sectors[1][:10]...), data) {
+		t.Fatal("sectors do not contain the data")
+	} else if !bytes.Equal(sectors[1][10:], make([]byte, modules.SectorSize-10)) {
+		t.Fatal("last sector was not padded with zeros")
+	}
+}
+
+// TestReadWriteSnapshot tests that the files of a snapshot survive a
+// roundtrip.
+func TestReadWriteSnapshot(t *testing.T) {
+	f1, f2 := newTestingFile(), newTestingFile()
+	buf := new(bytes.Buffer)
+	if err := writeSnapshot(buf, nil, []*file{f1, f2}); err != nil {
+		t.Fatal(err)
+	}
+	contracts, files, err := readSnapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(contracts) != 0 {
+		t.Fatal("expected no contracts, got", len(contracts))
+	} else if len(files) != 2 {
+		t.Fatal("expected 2 files, got", len(files))
+	}
+	if err := equalFiles(f1, files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := equalFiles(f2, files[1]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSnapshotContentHash tests that the content hash of a snapshot only
+// changes if its files or the IDs of its contracts change.
+func TestSnapshotContentHash(t *testing.T) {
+	f := newTestingFile()
+	f.contracts = make(map[types.FileContractID]fileContract)
+	for i := byte(0); i < 10; i++ {
+		id := types.FileContractID{i}
+		f.contracts[id] = fileContract{ID: id, Pieces: []pieceData{{Chunk: uint64(i)}}}
+	}
+	h1, err := snapshotContentHash(nil, []*file{f})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The order of the contracts in the file's map doesn't matter.
+	f2 := &file{
+		name:      f.name,
+		size:      f.size,
+		contracts: make(map[types.FileContractID]fileContract),
+	}
+	for i := byte(9); i < 10; i-- {
+		id := types.FileContractID{i}
+		f2.contracts[id] = f.contracts[id]
+	}
+	if h2, err := snapshotContentHash(nil, []*file{f2}); err != nil {
+		t.Fatal(err)
+	} else if h2 != h1 {
+		t.Fatal("content hash depends on the order of the file's contracts")
+	}
+
+	// A new piece changes the hash.
+	fc := f2.contracts[types.FileContractID{3}]
+	fc.Pieces = append(fc.Pieces, pieceData{Chunk: 11})
+	f2.contracts[types.FileContractID{3}] = fc
+	if h2, err := snapshotContentHash(nil, []*file{f2}); err != nil {
+		t.Fatal(err)
+	} else if h2 == h1 {
+		t.Fatal("content hash did not change")
+	}
+}
@@ -13,9 +13,11 @@ import (
 type streamHeap []*chunkData
 
 // chunkData contatins the data and the timestamp for the unfinished
-// download chunks
+// download chunks. The data is either the whole chunk or the range of the
+// chunk that starts at offset.
 type chunkData struct {
 	id         string
+	offset     uint64
 	data       []byte
 	lastAccess time.Time
 	index      int
@@ -70,18 +72,26 @@ func (sh *streamHeap) update(cd *chunkData, id string, data []byte, lastAccess t
 }
 
 // Add adds the chunk to the cache if the download is a streaming
-// endpoint download.
-// TODO this won't be necessary anymore once we have partial downloads.
-func (sc *streamCache) Add(cacheID string, data []byte) {
+// endpoint download. The data is the range of the chunk that starts at
+// offset. If the cache already contains a range of the chunk, it is replaced.
+func (sc *streamCache) Add(cacheID string, offset uint64, data []byte) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 
+	// Replace the cached range of the chunk, if any.
+	if cd, exists := sc.streamMap[cacheID]; exists {
+		cd.offset = offset
+		sc.streamHeap.update(cd, cacheID, data, time.Now())
+		return
+	}
+
 	// pruning cache to cacheSize - 1 to make room to add the new chunk
 	sc.pruneCache(sc.cacheSize - 1)
 
 	// Add chunk to Map and Heap
 	cd := &chunkData{
 		id:         cacheID,
+		offset:     offset,
 		data:       data,
 		lastAccess: time.Now(),
 	}
@@ -111,8 +121,8 @@ func (sc *streamCache) pruneCache(size uint64) {
 
 // Retrieve tries to retrieve the chunk from the renter's cache. If
 // successful it will write the data to the destination and stop the download
-// if it was the last missing chunk. The function returns true if the chunk was
-// in the cache.
+// if it was the last missing chunk. The function returns true if the range of
+// the chunk that is being downloaded was in the cache.
 // Using the entire unfisihedDownloadChunk as the argument as there are seven different fields
 // used from unfinishedDownloadChunk and it allows using udc.fail()
 //
@@ -128,13 +138,16 @@ func (sc *streamCache) Retrieve(udc *unfinishedDownloadChunk) bool {
 	if !cached {
 		return false
 	}
+	if udc.staticFetchOffset < cd.offset || udc.staticFetchOffset+udc.staticFetchLength > cd.offset+uint64(len(cd.data)) {
+		return false
+	}
 
 	// chunk exists, updating lastAccess and reinserting into map, updating heap
 	cd.lastAccess = time.Now()
 	sc.streamMap[udc.staticCacheID] = cd
 	sc.streamHeap.update(cd, cd.id, cd.data, cd.lastAccess)
 
-	start := udc.staticFetchOffset
+	start := udc.staticFetchOffset - cd.offset
 	end := start + udc.staticFetchLength
 	_, err := udc.destination.WriteAt(cd.data[start:end], udc.staticWriteOffset)
 	if err != nil {
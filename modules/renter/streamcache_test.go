@@ -1,11 +1,14 @@
 package renter
 
 import (
+	"bytes"
 	"container/heap"
 	"reflect"
 	"strconv"
 	"testing"
 	"time"
+
+	"github.com/NebulousLabs/fastrand"
 )
 
 // TestHeapImplementation tests that the streamCache heap functions properly
@@ -82,7 +85,7 @@ func TestPruneCache(t *testing.T) {
 	// Purposefully trying to fill to a value larger than cacheSize to confirm
 	// cacheSize won't be exceeded
 	for i := 0; i < int(sc.cacheSize)+5; i++ {
-		sc.Add(strconv.Itoa(i), []byte{})
+		sc.Add(strconv.Itoa(i), 0, []byte{})
 	}
 	// Confirm that the streamHeap didn't exceed the cacheSize
 	if len(sc.streamHeap) != int(sc.cacheSize) || len(sc.streamMap) != len(sc.streamHeap) {
@@ -125,7 +128,7 @@ func TestStreamCache(t *testing.T) {
 	// Purposefully trying to fill to a value larger than cacheSize to confirm Add()
 	// keeps pruning cache
 	for i := 0; i < int(sc.cacheSize)+5; i++ {
-		sc.Add(strconv.Itoa(i), []byte{})
+		sc.Add(strconv.Itoa(i), 0, []byte{})
 	}
 	// Confirm that the streamHeap didn't exceed the cacheSize
 	if len(sc.streamHeap) != int(sc.cacheSize) || len(sc.streamMap) != len(sc.streamHeap) {
@@ -134,13 +137,13 @@ func TestStreamCache(t *testing.T) {
 
 	// Reduce cacheSize and call Add() to confirm cache is pruned
 	sc.cacheSize = 2
-	sc.Add("", []byte{})
+	sc.Add("", 0, []byte{})
 	if len(sc.streamHeap) != int(sc.cacheSize) || len(sc.streamMap) != len(sc.streamHeap) {
 		t.Error("Cache is not equal to the cacheSize")
 	}
 
 	// Add new chunk with known staticCacheID
-	sc.Add("chunk1", []byte{}) // "chunk1" should be at the bottom of the Heap
+	sc.Add("chunk1", 0, []byte{}) // "chunk1" should be at the bottom of the Heap
 
 	// Confirm chunk is in the Map and at the bottom of the Heap
 	cd, ok := sc.streamMap["chunk1"]
@@ -160,7 +163,7 @@ func TestStreamCache(t *testing.T) {
 	}
 
 	// Add additional chunk to force deletion of a chunk
-	sc.Add("chunk2", []byte{})
+	sc.Add("chunk2", 0, []byte{})
 
 	// check if chunk1 was removed from Map and Heap
 	if _, ok := sc.streamMap["chunk1"]; ok {
@@ -170,3 +173,46 @@ func TestStreamCache(t *testing.T) {
 		t.Error("chunk1 wasn't removed from the heap")
 	}
 }
+
+// TestStreamCacheRetrieveRange tests that a cached range of a chunk is only
+// retrieved for downloads that lie within that range.
+func TestStreamCacheRetrieveRange(t *testing.T) {
+	sc := newStreamCache()
+	data := fastrand.Bytes(100)
+	sc.Add("chunk", 50, data)
+
+	tests := []struct {
+		fetchOffset, fetchLength uint64
+		cached                   bool
+	}{
+		{50, 100, true},
+		{60, 10, true},
+		{140, 10, true},
+		{40, 20, false},
+		{140, 20, false},
+		{0, 10, false},
+	}
+	for _, test := range tests {
+		buf := NewDownloadDestinationBuffer(test.fetchLength)
+		udc := &unfinishedDownloadChunk{
+			destination:       buf,
+			staticCacheID:     "chunk",
+			staticFetchOffset: test.fetchOffset,
+			staticFetchLength: test.fetchLength,
+			download:          &download{chunksRemaining: 2},
+		}
+		if sc.Retrieve(udc) != test.cached {
+			t.Fatalf("[%v, %v): expected cached to be %v", test.fetchOffset, test.fetchOffset+test.fetchLength, test.cached)
+		}
+		start := test.fetchOffset - 50
+		if test.cached && !bytes.Equal(buf[0][:test.fetchLength], data[start:start+test.fetchLength]) {
+			t.Fatal("retrieved the wrong range of the chunk")
+		}
+	}
+
+	// Adding another range of the chunk replaces the cached range.
+	sc.Add("chunk", 0, data)
+	if len(sc.streamMap) != 1 || len(sc.streamHeap) != 1 || sc.streamMap["chunk"].offset != 0 {
+		t.Fatal("cached range was not replaced")
+	}
+}
@@ -0,0 +1,601 @@
+package renter
+
+// sync.go implements sync jobs, which mirror a local directory to a siapath
+// prefix. Every file in the directory tree is uploaded to the prefix under its
+// relative path. Files that have already been uploaded are only uploaded again
+// if they have changed, which is detected by comparing the size, the
+// modification time and, if the modification time differs, the hash of the
+// file with the values recorded when it was uploaded.
+//
+// The renter cannot overwrite a file in place, so the new version of a changed
+// file is uploaded to a temporary siapath under syncTempDir. Once the new
+// version is available, it replaces the old version in a single step, so that
+// the siapath always refers to one of the two versions. If the upload fails,
+// the old version is kept. Temporary uploads that were interrupted by a
+// shutdown are removed when the renter starts.
+//
+// Syncs run in the background. The progress and the report of the most recent
+// run of every job are reported by SyncJobs.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	// errSyncCancelled is returned if a sync job is cancelled or replaced
+	// while it is running.
+	errSyncCancelled = errors.New("sync job was cancelled")
+
+	// errSyncInterrupted is returned if the renter shuts down during a sync.
+	errSyncInterrupted = errors.New("sync was interrupted by shutdown")
+
+	// errSyncSourceEmpty is returned if a sync that deletes remote files is
+	// run against an empty directory. This protects the remote files from
+	// being wiped when, for example, the directory is an unmounted drive.
+	errSyncSourceEmpty = errors.New("source directory is empty, refusing to delete remote files")
+
+	// errSyncSourceNotAbs is returned if the source of a sync is a relative
+	// path.
+	errSyncSourceNotAbs = errors.New("sync source must be an absolute path")
+
+	// errSyncSiaPathReserved is returned if the siapath of a sync is the
+	// siapath that holds the temporary uploads of sync jobs.
+	errSyncSiaPathReserved = errors.New("siapath is reserved for the temporary uploads of sync jobs")
+
+	// errSyncUpdateTimeout is returned if the new version of a changed file
+	// did not become available in time.
+	errSyncUpdateTimeout = errors.New("new version of the file did not become available in time")
+
+	// errUnknownSyncJob is returned when cancelling a sync job that does not
+	// exist.
+	errUnknownSyncJob = errors.New("no sync job exists for that siapath")
+)
+
+// syncJob is a sync of a local directory, which is either run once or
+// repeated on an interval.
+type syncJob struct {
+	params     modules.RenterSyncParams
+	active     bool
+	lastReport modules.RenterSyncReport
+	cancel     chan struct{}
+}
+
+// syncLocalFile is a file found while walking the source directory.
+type syncLocalFile struct {
+	path string
+	info os.FileInfo
+}
+
+// syncRemoteFile is the state of a remote file when the sync started.
+type syncRemoteFile struct {
+	size    uint64
+	tracked trackedFile
+}
+
+// newSyncJob creates a sync job for the provided parameters.
+func newSyncJob(params modules.RenterSyncParams) *syncJob {
+	return &syncJob{
+		params: params,
+		cancel: make(chan struct{}),
+	}
+}
+
+// recurring returns true if the job is repeated on its interval. Recurring
+// jobs are persisted.
+func (job *syncJob) recurring() bool {
+	return job.params.Interval > 0 && !job.params.DryRun
+}
+
+// syncTempSiaPath returns the siapath that the new version of a changed file
+// is uploaded to.
+func syncTempSiaPath(siaPath string) string {
+	return syncTempDir + "/" + siaPath
+}
+
+// validateSyncParams checks that the parameters of a sync job are sane.
+func validateSyncParams(params modules.RenterSyncParams) error {
+	if !filepath.IsAbs(params.Source) {
+		return errSyncSourceNotAbs
+	}
+	info, err := os.Stat(params.Source)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("sync source %v is not a directory", params.Source)
+	}
+	if err := validateSiapath(params.SiaPath); err != nil {
+		return err
+	}
+	if params.SiaPath == syncTempDir || strings.HasPrefix(params.SiaPath, syncTempDir+"/") {
+		return errSyncSiaPathReserved
+	}
+	if params.Interval < 0 || (params.Interval > 0 && params.Interval < minSyncInterval) {
+		return fmt.Errorf("sync interval must be at least %v", minSyncInterval)
+	}
+	return nil
+}
+
+// hashLocalFile returns the hash of the contents of a file on disk.
+func hashLocalFile(path string) (crypto.Hash, error) {
+	var hash crypto.Hash
+	f, err := os.Open(path)
+	if err != nil {
+		return hash, err
+	}
+	defer f.Close()
+	h := crypto.NewHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return hash, err
+	}
+	copy(hash[:], h.Sum(nil))
+	return hash, nil
+}
+
+// syncChangeReason compares a local file with the remote file that was
+// uploaded from it, returning the reason that the remote file needs to be
+// updated, or the empty string if the file has not changed. The local file is
+// only hashed if its size matches but its modification time does not.
+func syncChangeReason(remote syncRemoteFile, local syncLocalFile) (string, error) {
+	if remote.tracked.RepairPath != local.path {
+		return "source", nil
+	}
+	if uint64(local.info.Size()) != remote.size {
+		return "size", nil
+	}
+	if remote.tracked.ModTime.Equal(local.info.ModTime()) {
+		return "", nil
+	}
+	if remote.tracked.Hash == (crypto.Hash{}) {
+		return "mtime", nil
+	}
+	hash, err := hashLocalFile(local.path)
+	if err != nil {
+		return "", err
+	}
+	if hash != remote.tracked.Hash {
+		return "hash", nil
+	}
+	return "", nil
+}
+
+// managedRecordSyncFile stores the modification time and hash of the local
+// file that a remote file was uploaded from.
+func (r *Renter) managedRecordSyncFile(siaPath string, modTime time.Time, hash crypto.Hash) error {
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	tf, exists := r.tracking[siaPath]
+	if !exists {
+		return ErrUnknownPath
+	}
+	tf.ModTime = modTime
+	tf.Hash = hash
+	r.tracking[siaPath] = tf
+	return r.saveSync()
+}
+
+// managedSyncUpload uploads a local file to the provided siapath and records
+// the state of the local file, so that later syncs can detect changes.
+func (r *Renter) managedSyncUpload(siaPath string, local syncLocalFile) error {
+	hash, err := hashLocalFile(local.path)
+	if err != nil {
+		return err
+	}
+	err = r.Upload(modules.FileUploadParams{
+		Source:  local.path,
+		SiaPath: siaPath,
+	})
+	if err != nil {
+		return err
+	}
+	return r.managedRecordSyncFile(siaPath, local.info.ModTime(), hash)
+}
+
+// managedWaitForSyncUpload blocks until the file uploaded to the provided
+// siapath is available.
+func (r *Renter) managedWaitForSyncUpload(siaPath string, cancel <-chan struct{}) error {
+	timeout := time.After(syncUpdateTimeout)
+	for {
+		if r.deps.Disrupt("SyncUploadAvailable") {
+			return nil
+		}
+		fi, err := r.File(siaPath)
+		if err != nil {
+			return err
+		}
+		if fi.Available {
+			return nil
+		}
+		select {
+		case <-r.tg.StopChan():
+			return errSyncInterrupted
+		case <-cancel:
+			return errSyncCancelled
+		case <-timeout:
+			return errSyncUpdateTimeout
+		case <-time.After(syncUpdateCheckInterval):
+		}
+	}
+}
+
+// managedSyncUpdate replaces the remote file at the provided siapath with a
+// new upload of the local file. The old version is only deleted once the new
+// version is available.
+func (r *Renter) managedSyncUpdate(siaPath string, local syncLocalFile, cancel <-chan struct{}) error {
+	// Remove the leftovers of an update that was interrupted.
+	tempPath := syncTempSiaPath(siaPath)
+	if err := r.DeleteFile(tempPath); err != nil && err != ErrUnknownPath {
+		return err
+	}
+
+	if err := r.managedSyncUpload(tempPath, local); err != nil {
+		return err
+	}
+	if err := r.managedWaitForSyncUpload(tempPath, cancel); err != nil {
+		if err != errSyncInterrupted {
+			// Keep the old version.
+			if err := r.DeleteFile(tempPath); err != nil {
+				r.log.Println("WARN: sync could not delete temporary upload:", err)
+			}
+		}
+		return err
+	}
+	return r.managedReplaceSyncFile(tempPath, siaPath)
+}
+
+// managedReplaceSyncFile renames the new version of a file at tempPath to
+// siaPath, replacing the old version if there is one. The .sia file of the
+// new version is written over the .sia file of the old version, so the
+// replacement is atomic on disk as well.
+func (r *Renter) managedReplaceSyncFile(tempPath, siaPath string) error {
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	f, exists := r.files[tempPath]
+	if !exists {
+		return ErrUnknownPath
+	}
+	old := r.files[siaPath]
+
+	f.mu.Lock()
+	f.name = siaPath
+	err := r.saveFile(f)
+	if err != nil {
+		f.name = tempPath
+	}
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	delete(r.files, tempPath)
+	r.files[siaPath] = f
+	delete(r.tracking, siaPath)
+	if tf, ok := r.tracking[tempPath]; ok {
+		delete(r.tracking, tempPath)
+		r.tracking[siaPath] = tf
+	}
+	if old != nil {
+		old.mu.Lock()
+		old.deleted = true
+		old.mu.Unlock()
+	}
+	if err := r.saveSync(); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(r.persistDir, tempPath+ShareExtension))
+}
+
+// removeSyncTempFiles removes the temporary uploads of sync updates that were
+// interrupted by a shutdown. Their .sia files are deleted as well.
+func (r *Renter) removeSyncTempFiles() {
+	for name := range r.files {
+		if !strings.HasPrefix(name, syncTempDir+"/") {
+			continue
+		}
+		delete(r.files, name)
+		delete(r.tracking, name)
+		if err := os.RemoveAll(filepath.Join(r.persistDir, name+ShareExtension)); err != nil {
+			r.log.Println("WARN: could not remove temporary sync upload:", err)
+		}
+	}
+}
+
+// managedUpdateSyncReport stores a copy of the report of the job's current
+// run.
+func (r *Renter) managedUpdateSyncReport(job *syncJob, report modules.RenterSyncReport) {
+	report.Actions = append([]modules.RenterSyncAction(nil), report.Actions...)
+	id := r.mu.Lock()
+	job.lastReport = report
+	r.mu.Unlock(id)
+}
+
+// managedSync runs a single sync of a local directory, returning a report of
+// the actions taken. In a dry run the actions are only reported. The report of
+// the job is updated as the actions are taken.
+func (r *Renter) managedSync(job *syncJob) (modules.RenterSyncReport, error) {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+	params := job.params
+
+	report := modules.RenterSyncReport{
+		Source:  params.Source,
+		SiaPath: params.SiaPath,
+		DryRun:  params.DryRun,
+		Start:   time.Now(),
+		Actions: []modules.RenterSyncAction{},
+	}
+
+	// Walk the source directory. Files that cannot be read are skipped.
+	local := make(map[string]syncLocalFile)
+	err := filepath.Walk(params.Source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			r.log.Println("WARN: sync skipping file:", err)
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(params.Source, path)
+		if err != nil {
+			return err
+		}
+		siaPath := params.SiaPath + "/" + filepath.ToSlash(rel)
+		if err := validateSiapath(siaPath); err != nil {
+			r.log.Printf("WARN: sync skipping file %v: %v", path, err)
+			return nil
+		}
+		local[siaPath] = syncLocalFile{path: path, info: info}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	if len(local) == 0 && params.Delete {
+		return report, errSyncSourceEmpty
+	}
+
+	// Grab the remote files under the prefix.
+	remote := make(map[string]syncRemoteFile)
+	prefix := params.SiaPath + "/"
+	id := r.mu.RLock()
+	for name, f := range r.files {
+		if strings.HasPrefix(name, prefix) {
+			remote[name] = syncRemoteFile{
+				size:    f.size,
+				tracked: r.tracking[name],
+			}
+		}
+	}
+	r.mu.RUnlock(id)
+
+	// Determine which actions need to be taken.
+	localPaths := make([]string, 0, len(local))
+	for siaPath := range local {
+		localPaths = append(localPaths, siaPath)
+	}
+	sort.Strings(localPaths)
+	for _, siaPath := range localPaths {
+		lf := local[siaPath]
+		rf, exists := remote[siaPath]
+		if !exists {
+			report.Actions = append(report.Actions, modules.RenterSyncAction{
+				Action:  modules.SyncActionUpload,
+				SiaPath: siaPath,
+				Source:  lf.path,
+				Reason:  "new",
+			})
+			continue
+		}
+		reason, err := syncChangeReason(rf, lf)
+		if err != nil {
+			report.Actions = append(report.Actions, modules.RenterSyncAction{
+				Action:  modules.SyncActionUpdate,
+				SiaPath: siaPath,
+				Source:  lf.path,
+				Error:   err.Error(),
+			})
+			report.Failed++
+			continue
+		}
+		if reason == "" {
+			// The contents are unchanged. If only the modification time
+			// differs, record the new one so that the file does not need to
+			// be hashed again next time.
+			report.Unchanged++
+			if !params.DryRun && !rf.tracked.ModTime.Equal(lf.info.ModTime()) {
+				err := r.managedRecordSyncFile(siaPath, lf.info.ModTime(), rf.tracked.Hash)
+				if err != nil {
+					r.log.Printf("WARN: sync could not record modification time of %v: %v", siaPath, err)
+				}
+			}
+			continue
+		}
+		report.Actions = append(report.Actions, modules.RenterSyncAction{
+			Action:  modules.SyncActionUpdate,
+			SiaPath: siaPath,
+			Source:  lf.path,
+			Reason:  reason,
+		})
+	}
+	if params.Delete {
+		var remotePaths []string
+		for siaPath := range remote {
+			if _, exists := local[siaPath]; !exists {
+				remotePaths = append(remotePaths, siaPath)
+			}
+		}
+		sort.Strings(remotePaths)
+		for _, siaPath := range remotePaths {
+			report.Actions = append(report.Actions, modules.RenterSyncAction{
+				Action:  modules.SyncActionDelete,
+				SiaPath: siaPath,
+				Source:  remote[siaPath].tracked.RepairPath,
+				Reason:  "missing",
+			})
+		}
+	}
+	if params.DryRun {
+		report.End = time.Now()
+		return report, nil
+	}
+	r.managedUpdateSyncReport(job, report)
+
+	// Take the actions.
+	for i := range report.Actions {
+		select {
+		case <-r.tg.StopChan():
+			report.End = time.Now()
+			return report, errSyncInterrupted
+		case <-job.cancel:
+			report.End = time.Now()
+			return report, errSyncCancelled
+		default:
+		}
+
+		action := &report.Actions[i]
+		if action.Error != "" {
+			continue
+		}
+		var err error
+		switch action.Action {
+		case modules.SyncActionUpload:
+			err = r.managedSyncUpload(action.SiaPath, local[action.SiaPath])
+		case modules.SyncActionUpdate:
+			err = r.managedSyncUpdate(action.SiaPath, local[action.SiaPath], job.cancel)
+		case modules.SyncActionDelete:
+			err = r.DeleteFile(action.SiaPath)
+		}
+		if err != nil {
+			action.Error = err.Error()
+			report.Failed++
+		}
+		report.Completed++
+		r.managedUpdateSyncReport(job, report)
+		if err == errSyncInterrupted || err == errSyncCancelled {
+			report.End = time.Now()
+			return report, err
+		}
+	}
+	report.End = time.Now()
+	r.log.Printf("Synced %v to %v: %v actions, %v unchanged, %v failed",
+		params.Source, params.SiaPath, len(report.Actions), report.Unchanged, report.Failed)
+	return report, nil
+}
+
+// managedRunSyncJob runs a sync job once and stores its report.
+func (r *Renter) managedRunSyncJob(job *syncJob) {
+	id := r.mu.Lock()
+	job.active = true
+	r.mu.Unlock(id)
+
+	report, err := r.managedSync(job)
+	if err != nil {
+		r.log.Printf("WARN: sync of %v to %v failed: %v", job.params.Source, job.params.SiaPath, err)
+		report.Error = err.Error()
+	}
+
+	report.Actions = append([]modules.RenterSyncAction(nil), report.Actions...)
+	id = r.mu.Lock()
+	job.active = false
+	job.lastReport = report
+	r.mu.Unlock(id)
+}
+
+// threadedSyncLoop runs a sync job, and re-runs recurring jobs on their
+// interval until the job is cancelled or the renter shuts down. If runNow is
+// false, the job waits for its interval before the first run.
+func (r *Renter) threadedSyncLoop(job *syncJob, runNow bool) {
+	err := r.tg.Add()
+	if err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	if runNow {
+		r.managedRunSyncJob(job)
+	}
+	if !job.recurring() {
+		return
+	}
+	for {
+		select {
+		case <-r.tg.StopChan():
+			return
+		case <-job.cancel:
+			return
+		case <-time.After(job.params.Interval):
+		}
+		r.managedRunSyncJob(job)
+	}
+}
+
+// Sync starts a sync of a local directory to a siapath prefix in the
+// background. If an interval is provided and the sync is not a dry run, the
+// directory is synced again on that interval until the job is cancelled. Any
+// existing job for the same prefix is replaced. The progress of the sync is
+// reported by SyncJobs.
+func (r *Renter) Sync(params modules.RenterSyncParams) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	if err := validateSyncParams(params); err != nil {
+		return err
+	}
+
+	// Register the job. Only recurring jobs are persisted, but the report of
+	// other jobs is kept until they are replaced or cancelled.
+	job := newSyncJob(params)
+	job.active = true
+	id := r.mu.Lock()
+	if old, exists := r.syncJobs[params.SiaPath]; exists {
+		close(old.cancel)
+	}
+	r.syncJobs[params.SiaPath] = job
+	err := r.saveSync()
+	r.mu.Unlock(id)
+	go r.threadedSyncLoop(job, true)
+	return err
+}
+
+// SyncJobs returns the sync jobs along with their progress, sorted by siapath.
+func (r *Renter) SyncJobs() []modules.RenterSyncJob {
+	id := r.mu.RLock()
+	defer r.mu.RUnlock(id)
+	jobs := make([]modules.RenterSyncJob, 0, len(r.syncJobs))
+	for _, job := range r.syncJobs {
+		jobs = append(jobs, modules.RenterSyncJob{
+			RenterSyncParams: job.params,
+			Active:           job.active,
+			LastReport:       job.lastReport,
+		})
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].SiaPath < jobs[j].SiaPath
+	})
+	return jobs
+}
+
+// CancelSync stops the sync job for a siapath prefix. A running sync is
+// stopped before its next action.
+func (r *Renter) CancelSync(siaPath string) error {
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	job, exists := r.syncJobs[siaPath]
+	if !exists {
+		return errUnknownSyncJob
+	}
+	close(job.cancel)
+	delete(r.syncJobs, siaPath)
+	return r.saveSync()
+}
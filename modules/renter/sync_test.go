@@ -0,0 +1,439 @@
+package renter
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// dependencySyncUploadAvailable treats the uploads of sync jobs as available
+// as soon as they have been started.
+type dependencySyncUploadAvailable struct {
+	modules.ProductionDependencies
+}
+
+// Disrupt returns true for the SyncUploadAvailable disruption.
+func (*dependencySyncUploadAvailable) Disrupt(s string) bool {
+	return s == "SyncUploadAvailable"
+}
+
+// newSyncSource creates a directory containing the provided files.
+func newSyncSource(name string, files map[string]string) (string, error) {
+	dir := build.TempDir("renter", name, "source")
+	for path, contents := range files {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// checkSyncActions checks that a sync report contains the expected actions,
+// in order, and that none of them failed.
+func checkSyncActions(report modules.RenterSyncReport, actions ...string) error {
+	if len(report.Actions) != len(actions)/2 {
+		return fmt.Errorf("expected %v actions, got %v", len(actions)/2, report.Actions)
+	}
+	for i, a := range report.Actions {
+		if a.Action != actions[2*i] || a.SiaPath != actions[2*i+1] {
+			return fmt.Errorf("expected %v of %v, got %v of %v", actions[2*i], actions[2*i+1], a.Action, a.SiaPath)
+		}
+		if a.Error != "" {
+			return fmt.Errorf("%v of %v failed: %v", a.Action, a.SiaPath, a.Error)
+		}
+	}
+	if report.Failed != 0 {
+		return fmt.Errorf("expected no failed actions, got %v", report.Failed)
+	}
+	return nil
+}
+
+// TestSyncChangeReason checks that changes to a local file are detected by
+// size, modification time and hash.
+func TestSyncChangeReason(t *testing.T) {
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, []byte("foo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashLocalFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := syncLocalFile{path: path, info: info}
+	remote := syncRemoteFile{
+		size: 3,
+		tracked: trackedFile{
+			RepairPath: path,
+			ModTime:    info.ModTime(),
+			Hash:       hash,
+		},
+	}
+
+	// A file that has not been touched is unchanged.
+	if reason, err := syncChangeReason(remote, local); err != nil || reason != "" {
+		t.Fatal("untouched file reported as changed:", reason, err)
+	}
+
+	// A file that was uploaded from somewhere else needs to be replaced.
+	other := remote
+	other.tracked.RepairPath = filepath.Join(dir, "other")
+	if reason, _ := syncChangeReason(other, local); reason != "source" {
+		t.Fatal("expected source change, got", reason)
+	}
+
+	// Touching the file without changing it is detected by the hash.
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if local.info, err = os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if reason, err := syncChangeReason(remote, local); err != nil || reason != "" {
+		t.Fatal("touched file reported as changed:", reason, err)
+	}
+
+	// A different hash or no recorded hash at all counts as a change.
+	changed := remote
+	changed.tracked.Hash[0]++
+	if reason, _ := syncChangeReason(changed, local); reason != "hash" {
+		t.Fatal("expected hash change, got", reason)
+	}
+	changed.tracked.Hash = crypto.Hash{}
+	if reason, _ := syncChangeReason(changed, local); reason != "mtime" {
+		t.Fatal("expected mtime change, got", reason)
+	}
+
+	// Changing the contents is detected by the size.
+	if err := ioutil.WriteFile(path, []byte("foobar"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if local.info, err = os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if reason, _ := syncChangeReason(remote, local); reason != "size" {
+		t.Fatal("expected size change, got", reason)
+	}
+}
+
+// TestSyncDryRun checks that a dry run reports the actions of a sync without
+// taking them.
+func TestSyncDryRun(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	dir, err := newSyncSource(t.Name(), map[string]string{
+		"a":     "foo",
+		"sub/b": "bar",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := modules.RenterSyncParams{Source: dir, SiaPath: "sync", DryRun: true}
+	report, err := rt.renter.managedSync(newSyncJob(params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSyncActions(report, modules.SyncActionUpload, "sync/a", modules.SyncActionUpload, "sync/sub/b"); err != nil {
+		t.Fatal(err)
+	}
+	if report.Completed != 0 || len(rt.renter.FileList()) != 0 {
+		t.Fatal("dry run uploaded files")
+	}
+
+	// After a real sync, a dry run reports the deletion of files that no
+	// longer exist locally, but doesn't delete them.
+	params.DryRun = false
+	report, err = rt.renter.managedSync(newSyncJob(params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Completed != 2 || len(rt.renter.FileList()) != 2 {
+		t.Fatal("sync did not upload the files:", report.Completed)
+	}
+	if err := os.Remove(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	params.Delete = true
+	params.DryRun = true
+	report, err = rt.renter.managedSync(newSyncJob(params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSyncActions(report, modules.SyncActionDelete, "sync/a"); err != nil {
+		t.Fatal(err)
+	}
+	if report.Unchanged != 1 || len(rt.renter.FileList()) != 2 {
+		t.Fatal("dry run deleted files")
+	}
+}
+
+// TestSyncDelete checks that a sync with Delete set deletes the remote files
+// that no longer exist locally, and refuses to run against an empty
+// directory.
+func TestSyncDelete(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	dir, err := newSyncSource(t.Name(), map[string]string{
+		"a":     "foo",
+		"sub/b": "bar",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := modules.RenterSyncParams{Source: dir, SiaPath: "sync", Delete: true}
+	if _, err := rt.renter.managedSync(newSyncJob(params)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files outside of the prefix are never deleted.
+	other := filepath.Join(dir, "..", "other")
+	if err := ioutil.WriteFile(other, []byte("baz"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.Upload(modules.FileUploadParams{Source: other, SiaPath: "other"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	report, err := rt.renter.managedSync(newSyncJob(params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSyncActions(report, modules.SyncActionDelete, "sync/sub/b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.renter.File("sync/sub/b"); err != ErrUnknownPath {
+		t.Fatal("expected file to be deleted, got", err)
+	}
+	if _, err := rt.renter.File("sync/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.renter.File("other"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty source directory doesn't delete anything.
+	if err := os.Remove(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.renter.managedSync(newSyncJob(params)); err != errSyncSourceEmpty {
+		t.Fatal("expected errSyncSourceEmpty, got", err)
+	}
+	if _, err := rt.renter.File("sync/a"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSyncUpdate checks that a changed file only replaces the remote file
+// once the new version is available, and that the remote file is kept if the
+// update is cancelled.
+func TestSyncUpdate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	dir, err := newSyncSource(t.Name(), map[string]string{"a": "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := modules.RenterSyncParams{Source: dir, SiaPath: "sync"}
+	if _, err := rt.renter.managedSync(newSyncJob(params)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the file. Without any hosts the new version never becomes
+	// available, so the sync keeps waiting until it is cancelled.
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), []byte("foobar"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	job := newSyncJob(params)
+	done := make(chan error)
+	go func() {
+		report, err := rt.renter.managedSync(job)
+		if err == nil {
+			err = checkSyncActions(report, modules.SyncActionUpdate, "sync/a")
+		}
+		done <- err
+	}()
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		_, err := rt.renter.File(syncTempSiaPath("sync/a"))
+		return err
+	})
+	if err != nil {
+		t.Fatal("new version was not uploaded:", err)
+	}
+	if fi, err := rt.renter.File("sync/a"); err != nil || fi.Filesize != 3 {
+		t.Fatal("old version was not kept during the update:", fi.Filesize, err)
+	}
+	close(job.cancel)
+	if err := <-done; err != errSyncCancelled {
+		t.Fatal("expected errSyncCancelled, got", err)
+	}
+	if fi, err := rt.renter.File("sync/a"); err != nil || fi.Filesize != 3 {
+		t.Fatal("old version was not kept after the update was cancelled:", fi.Filesize, err)
+	}
+	if _, err := rt.renter.File(syncTempSiaPath("sync/a")); err != ErrUnknownPath {
+		t.Fatal("new version was not deleted:", err)
+	}
+}
+
+// TestSyncUpdateAvailable checks that the new version of a changed file
+// replaces the remote file once it is available.
+func TestSyncUpdateAvailable(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencySyncUploadAvailable{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	dir, err := newSyncSource(t.Name(), map[string]string{"a": "foo", "b": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the sync in the background and wait for it to finish.
+	params := modules.RenterSyncParams{Source: dir, SiaPath: "sync"}
+	waitForSync := func() (modules.RenterSyncReport, error) {
+		if err := rt.renter.Sync(params); err != nil {
+			return modules.RenterSyncReport{}, err
+		}
+		var report modules.RenterSyncReport
+		err := build.Retry(100, 100*time.Millisecond, func() error {
+			jobs := rt.renter.SyncJobs()
+			if len(jobs) != 1 {
+				return fmt.Errorf("expected 1 job, got %v", len(jobs))
+			} else if jobs[0].Active {
+				return errors.New("sync is still running")
+			}
+			report = jobs[0].LastReport
+			return nil
+		})
+		return report, err
+	}
+	if _, err := waitForSync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "a"), []byte("foobar"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	report, err := waitForSync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSyncActions(report, modules.SyncActionUpdate, "sync/a"); err != nil {
+		t.Fatal(err)
+	}
+	if report.Completed != 1 || report.Unchanged != 1 || report.End.IsZero() {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	fi, err := rt.renter.File("sync/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Filesize != 6 {
+		t.Fatal("file was not replaced by the new version:", fi.Filesize)
+	}
+	if _, err := rt.renter.File(syncTempSiaPath("sync/a")); err != ErrUnknownPath {
+		t.Fatal("new version was not renamed:", err)
+	}
+	if _, err := os.Stat(filepath.Join(rt.renter.persistDir, syncTempSiaPath("sync/a")+ShareExtension)); !os.IsNotExist(err) {
+		t.Fatal(".sia file of the new version was not renamed:", err)
+	}
+
+	// The state of the new version is recorded, so the next sync leaves the
+	// file alone.
+	report, err = waitForSync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSyncActions(report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Unchanged != 2 {
+		t.Fatal("expected 2 unchanged files, got", report.Unchanged)
+	}
+}
+
+// TestSyncTempFilesRemoved checks that the temporary uploads of interrupted
+// sync updates are removed when the renter is loaded.
+func TestSyncTempFilesRemoved(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	f1 := newTestingFile()
+	f1.name = "sync/a"
+	f2 := newTestingFile()
+	f2.name = syncTempSiaPath("sync/a")
+	for _, f := range []*file{f1, f2} {
+		if err := rt.renter.saveFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rt.renter.saveSync(); err != nil {
+		t.Fatal(err)
+	}
+
+	id := rt.renter.mu.Lock()
+	err = rt.renter.load()
+	rt.renter.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := rt.renter.files[f1.name]; !exists {
+		t.Fatal("file was not loaded")
+	}
+	if _, exists := rt.renter.files[f2.name]; exists {
+		t.Fatal("temporary upload was not removed")
+	}
+	if _, err := os.Stat(filepath.Join(rt.renter.persistDir, f2.name+ShareExtension)); !os.IsNotExist(err) {
+		t.Fatal(".sia file of the temporary upload was not removed:", err)
+	}
+}
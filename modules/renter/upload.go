@@ -89,6 +89,7 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	r.files[up.SiaPath] = f
 	r.tracking[up.SiaPath] = trackedFile{
 		RepairPath: up.Source,
+		ModTime:    fileInfo.ModTime(),
 	}
 	r.saveSync()
 	err = r.saveFile(f)
@@ -3,9 +3,13 @@ package renter
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/NebulousLabs/Sia/modules"
+
+	"github.com/NebulousLabs/fastrand"
 )
 
 // TestRenterUploadDirectory verifies that the renter returns an error if a
@@ -43,3 +47,130 @@ func TestRenterUploadInode(t *testing.T) {
 		t.Fatal("expected errUploadDirectory, got", err)
 	}
 }
+
+// newBenchmarkUploadChunk returns a file and the logical data of a single
+// chunk of that file, using a 10-of-30 erasure code.
+func newBenchmarkUploadChunk(b *testing.B) (*file, [][]byte) {
+	rsc, err := NewRSCode(10, 20)
+	if err != nil {
+		b.Fatal(err)
+	}
+	f := newFile("bench", rsc, pieceSize, 10*pieceSize)
+	data := make([][]byte, rsc.MinPieces())
+	for i := range data {
+		data[i] = fastrand.Bytes(int(f.pieceSize))
+	}
+	return f, data
+}
+
+// BenchmarkUploadChunkEncode measures the throughput of erasure coding and
+// encrypting upload chunks on a single thread.
+func BenchmarkUploadChunkEncode(b *testing.B) {
+	f, data := newBenchmarkUploadChunk(b)
+	b.SetBytes(int64(f.staticChunkSize()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		uc := &unfinishedUploadChunk{
+			renterFile:       f,
+			index:            uint64(i),
+			logicalChunkData: append([][]byte(nil), data...),
+			pieceUsage:       make([]bool, f.erasureCode.NumPieces()),
+		}
+		if err := encodeUploadChunk(uc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUploadChunkEncodeParallel measures the throughput of erasure
+// coding and encrypting upload chunks with one encoding thread per core. The
+// chunks are already in memory, see BenchmarkUploadPipeline for the
+// throughput including the reads from disk.
+func BenchmarkUploadChunkEncodeParallel(b *testing.B) {
+	f, data := newBenchmarkUploadChunk(b)
+	b.SetBytes(int64(f.staticChunkSize()))
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			uc := &unfinishedUploadChunk{
+				renterFile:       f,
+				logicalChunkData: append([][]byte(nil), data...),
+				pieceUsage:       make([]bool, f.erasureCode.NumPieces()),
+			}
+			if err := encodeUploadChunk(uc); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkUploadPipeline measures the throughput of the preparation stage of
+// the upload pipeline. Chunks are read from a file on disk with
+// managedFetchLogicalChunkData and handed to uploadEncodeThreads threads that
+// erasure code and encrypt them, the same way managedFetchAndRepairChunk and
+// threadedUploadEncodeLoop do. Distributing the pieces to the workers is not
+// included.
+func BenchmarkUploadPipeline(b *testing.B) {
+	rt, err := newRenterTester(b.Name())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Create a local file that is a few chunks long.
+	const numChunks = 4
+	f, _ := newBenchmarkUploadChunk(b)
+	chunkSize := f.staticChunkSize()
+	localPath := filepath.Join(rt.renter.persistDir, "bench")
+	if err := ioutil.WriteFile(localPath, fastrand.Bytes(int(numChunks*chunkSize)), 0600); err != nil {
+		b.Fatal(err)
+	}
+
+	queue := make(chan *unfinishedUploadChunk)
+	errs := make(chan error, uploadEncodeThreads)
+	var wg sync.WaitGroup
+	for i := 0; i < uploadEncodeThreads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for uc := range queue {
+				if err := encodeUploadChunk(uc); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	b.SetBytes(int64(chunkSize))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index := uint64(i % numChunks)
+		uc := &unfinishedUploadChunk{
+			localPath:     localPath,
+			renterFile:    f,
+			index:         index,
+			length:        chunkSize,
+			offset:        int64(index * chunkSize),
+			minimumPieces: f.erasureCode.MinPieces(),
+			piecesNeeded:  f.erasureCode.NumPieces(),
+			pieceUsage:    make([]bool, f.erasureCode.NumPieces()),
+		}
+		if err := rt.renter.managedFetchLogicalChunkData(uc); err != nil {
+			b.Fatal(err)
+		}
+		select {
+		case queue <- uc:
+		case err := <-errs:
+			b.Fatal(err)
+		}
+	}
+	close(queue)
+	wg.Wait()
+	b.StopTimer()
+	select {
+	case err := <-errs:
+		b.Fatal(err)
+	default:
+	}
+}
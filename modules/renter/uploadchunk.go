@@ -5,6 +5,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 
 	"github.com/NebulousLabs/errors"
@@ -171,48 +172,24 @@ func (r *Renter) managedDownloadLogicalChunkData(chunk *unfinishedUploadChunk) e
 	return nil
 }
 
-// managedFetchAndRepairChunk will fetch the logical data for a chunk, create
-// the physical pieces for the chunk, and then distribute them.
-func (r *Renter) managedFetchAndRepairChunk(chunk *unfinishedUploadChunk) {
-	// Calculate the amount of memory needed for erasure coding. This will need
-	// to be released if there's an error before erasure coding is complete.
-	erasureCodingMemory := chunk.renterFile.pieceSize * uint64(chunk.renterFile.erasureCode.MinPieces())
-
-	// Calculate the amount of memory to release due to already completed
-	// pieces. This memory gets released during encryption, but needs to be
-	// released if there's a failure before encryption happens.
-	var pieceCompletedMemory uint64
+// uploadChunkMemory returns the amount of memory held by a chunk for erasure
+// coding, and the amount of memory held for pieces that have already been
+// uploaded. The former is released once the chunk has been erasure coded, the
+// latter once the chunk has been encrypted.
+func uploadChunkMemory(chunk *unfinishedUploadChunk) (erasureCodingMemory, pieceCompletedMemory uint64) {
+	erasureCodingMemory = chunk.renterFile.pieceSize * uint64(chunk.renterFile.erasureCode.MinPieces())
 	for i := 0; i < len(chunk.pieceUsage); i++ {
 		if chunk.pieceUsage[i] {
 			pieceCompletedMemory += chunk.renterFile.pieceSize + crypto.TwofishOverhead
 		}
 	}
+	return erasureCodingMemory, pieceCompletedMemory
+}
 
-	// Ensure that memory is released and that the chunk is cleaned up properly
-	// after the chunk is distributed.
-	//
-	// Need to ensure the erasure coding memory is released as well as the
-	// physical chunk memory. Physical chunk memory is released by setting
-	// 'workersRemaining' to zero if the repair fails before being distributed
-	// to workers. Erasure coding memory is released manually if the repair
-	// fails before the erasure coding occurs.
-	defer r.managedCleanUpUploadChunk(chunk)
-
-	// Fetch the logical data for the chunk.
-	err := r.managedFetchLogicalChunkData(chunk)
-	if err != nil {
-		// Logical data is not available, cannot upload. Chunk will not be
-		// distributed to workers, therefore set workersRemaining equal to zero.
-		// The erasure coding memory has not been released yet, be sure to
-		// release that as well.
-		chunk.logicalChunkData = nil
-		chunk.workersRemaining = 0
-		r.memoryManager.Return(erasureCodingMemory + pieceCompletedMemory)
-		chunk.memoryReleased += erasureCodingMemory + pieceCompletedMemory
-		r.log.Debugln("Fetching logical data of a chunk failed:", err)
-		return
-	}
-
+// encodeUploadChunk creates the physical pieces of a chunk from its logical
+// data and encrypts every piece that still needs to be uploaded. Pieces that
+// have already been uploaded are dropped. The logical data is released.
+func encodeUploadChunk(chunk *unfinishedUploadChunk) error {
 	// Create the physical pieces for the data. Immediately release the logical
 	// data.
 	//
@@ -221,28 +198,21 @@ func (r *Renter) managedFetchAndRepairChunk(chunk *unfinishedUploadChunk) {
 	// fact to reduce the total memory required to create the physical data.
 	// That will also change the amount of memory we need to allocate, and the
 	// number of times we need to return memory.
+	var err error
 	chunk.physicalChunkData, err = chunk.renterFile.erasureCode.EncodeShards(chunk.logicalChunkData)
 	chunk.logicalChunkData = nil
-	r.memoryManager.Return(erasureCodingMemory)
-	chunk.memoryReleased += erasureCodingMemory
 	if err != nil {
-		// Physical data is not available, cannot upload. Chunk will not be
-		// distributed to workers, therefore set workersRemaining equal to zero.
-		chunk.workersRemaining = 0
-		r.memoryManager.Return(pieceCompletedMemory)
-		chunk.memoryReleased += pieceCompletedMemory
 		for i := 0; i < len(chunk.physicalChunkData); i++ {
 			chunk.physicalChunkData[i] = nil
 		}
-		r.log.Debugln("Fetching physical data of a chunk failed:", err)
-		return
+		return err
 	}
 
 	// Sanity check - we should have at least as many physical data pieces as we
 	// do elements in our piece usage.
 	if len(chunk.physicalChunkData) < len(chunk.pieceUsage) {
-		r.log.Critical("not enough physical pieces to match the upload settings of the file")
-		return
+		build.Critical("not enough physical pieces to match the upload settings of the file")
+		return errors.New("not enough physical pieces to match the upload settings of the file")
 	}
 	// Loop through the pieces and encrypt any that are needed, while dropping
 	// any pieces that are not needed.
@@ -255,16 +225,94 @@ func (r *Renter) managedFetchAndRepairChunk(chunk *unfinishedUploadChunk) {
 			chunk.physicalChunkData[i] = key.EncryptBytes(chunk.physicalChunkData[i])
 		}
 	}
-	// Return the released memory.
-	if pieceCompletedMemory > 0 {
-		r.memoryManager.Return(pieceCompletedMemory)
-		chunk.memoryReleased += pieceCompletedMemory
+	return nil
+}
+
+// managedFetchAndRepairChunk will fetch the logical data for a chunk and then
+// hand the chunk to the encoding stage of the upload pipeline, which creates
+// the physical pieces and distributes them. Fetching happens as soon as memory
+// for the chunk has been acquired, which means that chunks are read ahead
+// while earlier chunks are still being encoded.
+func (r *Renter) managedFetchAndRepairChunk(chunk *unfinishedUploadChunk) {
+	// Fetch the logical data for the chunk.
+	err := r.managedFetchLogicalChunkData(chunk)
+	if err != nil {
+		// Logical data is not available, cannot upload. Chunk will not be
+		// distributed to workers.
+		r.log.Debugln("Fetching logical data of a chunk failed:", err)
+		r.managedAbortUploadChunk(chunk)
+		return
+	}
+
+	// Wait for an encoding thread to pick up the chunk.
+	select {
+	case r.uploadEncodeQueue <- chunk:
+	case <-r.tg.StopChan():
+		r.managedAbortUploadChunk(chunk)
+	}
+}
+
+// managedAbortUploadChunk releases all memory held by a chunk that failed
+// before it was encoded and cleans the chunk up. The chunk is not distributed
+// to the workers.
+func (r *Renter) managedAbortUploadChunk(chunk *unfinishedUploadChunk) {
+	erasureCodingMemory, pieceCompletedMemory := uploadChunkMemory(chunk)
+	chunk.logicalChunkData = nil
+	chunk.workersRemaining = 0
+	r.memoryManager.Return(erasureCodingMemory+pieceCompletedMemory, memoryClassUpload)
+	chunk.memoryReleased += erasureCodingMemory + pieceCompletedMemory
+	r.managedCleanUpUploadChunk(chunk)
+}
+
+// managedEncodeAndDistributeChunk will create the physical pieces for a chunk
+// whose logical data has been fetched, and then distribute them to the
+// workers.
+func (r *Renter) managedEncodeAndDistributeChunk(chunk *unfinishedUploadChunk) {
+	// Ensure that memory is released and that the chunk is cleaned up properly
+	// after the chunk is distributed.
+	//
+	// Need to ensure the erasure coding memory is released as well as the
+	// physical chunk memory. Physical chunk memory is released by setting
+	// 'workersRemaining' to zero if the repair fails before being distributed
+	// to workers.
+	defer r.managedCleanUpUploadChunk(chunk)
+
+	erasureCodingMemory, pieceCompletedMemory := uploadChunkMemory(chunk)
+	err := encodeUploadChunk(chunk)
+	r.memoryManager.Return(erasureCodingMemory+pieceCompletedMemory, memoryClassUpload)
+	chunk.memoryReleased += erasureCodingMemory + pieceCompletedMemory
+	if err != nil {
+		// Physical data is not available, cannot upload. Chunk will not be
+		// distributed to workers, therefore set workersRemaining equal to zero.
+		chunk.workersRemaining = 0
+		r.log.Debugln("Fetching physical data of a chunk failed:", err)
+		return
 	}
 
 	// Distribute the chunk to the workers.
 	r.managedDistributeChunkToWorkers(chunk)
 }
 
+// threadedUploadEncodeLoop is one of the threads of the encoding stage of the
+// upload pipeline. Several of these threads run in parallel so that the erasure
+// coding and encryption of chunks can make use of every core.
+func (r *Renter) threadedUploadEncodeLoop() {
+	err := r.tg.Add()
+	if err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	for {
+		select {
+		case chunk := <-r.uploadEncodeQueue:
+			r.managedEncodeAndDistributeChunk(chunk)
+		case <-r.tg.StopChan():
+			return
+		}
+	}
+}
+
 // managedFetchLogicalChunkData will get the raw data for a chunk, pulling it from disk if
 // possible but otherwise queueing a download.
 //
@@ -367,7 +415,7 @@ func (r *Renter) managedCleanUpUploadChunk(uc *unfinishedUploadChunk) {
 	}
 	// If required, return the memory to the renter.
 	if memoryReleased > 0 {
-		r.memoryManager.Return(memoryReleased)
+		r.memoryManager.Return(memoryReleased, memoryClassUpload)
 	}
 	// If required, remove the chunk from the set of active chunks.
 	if chunkComplete && !released {
@@ -253,11 +253,13 @@ func (r *Renter) managedPrepareNextChunk(uuc *unfinishedUploadChunk, hosts map[s
 	// Grab the next chunk, loop until we have enough memory, update the amount
 	// of memory available, and then spin up a thread to asynchronously handle
 	// the rest of the chunk tasks.
-	if !r.memoryManager.Request(uuc.memoryNeeded, memoryPriorityLow) {
+	if !r.memoryManager.Request(uuc.memoryNeeded, memoryClassUpload) {
 		return
 	}
 	// Fetch the chunk in a separate goroutine, as it can take a long time and
-	// does not need to bottleneck the repair loop.
+	// does not need to bottleneck the repair loop. Once fetched, the chunk is
+	// encoded by the encoding threads, so chunks are read ahead for as long as
+	// there is memory available.
 	go r.managedFetchAndRepairChunk(uuc)
 }
 
@@ -306,6 +308,7 @@ func (r *Renter) threadedUploadLoop() {
 		// able to go through the filesystem piecewise instead of doing
 		// everything all at once.
 		r.managedBuildChunkHeap(hosts)
+		r.managedUpdateRedundancyAlerts()
 		r.uploadHeap.mu.Lock()
 		heapLen := r.uploadHeap.heap.Len()
 		r.uploadHeap.mu.Unlock()
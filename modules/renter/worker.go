@@ -40,6 +40,10 @@ type worker struct {
 	downloadMu         sync.Mutex
 	downloadTerminated bool // Has downloading been terminated for this worker?
 
+	// Download throughput observed from the host, protected by the downloadMu.
+	downloadBytes uint64        // Total bytes fetched from the host.
+	downloadTime  time.Duration // Total time spent fetching those bytes.
+
 	// Upload variables.
 	unprocessedChunks         []*unfinishedUploadChunk // Yet unprocessed work items.
 	uploadChan                chan struct{}            // Notifications of new work.
@@ -123,9 +127,9 @@ func (w *worker) threadedWorkLoop() {
 		}
 
 		// Perform one step of processing upload work.
-		chunk, pieceIndex := w.managedNextUploadChunk()
-		if chunk != nil {
-			w.managedUpload(chunk, pieceIndex)
+		chunks, pieceIndices := w.managedNextUploadChunks()
+		if len(chunks) > 0 {
+			w.managedUpload(chunks, pieceIndices)
 			continue
 		}
 
@@ -7,6 +7,10 @@ package renter
 import (
 	"sync/atomic"
 	"time"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/contractor"
 )
 
 // managedDownload will perform some download work.
@@ -34,18 +38,26 @@ func (w *worker) managedDownload(udc *unfinishedDownloadChunk) {
 		return
 	}
 	defer d.Close()
-	data, err := d.Sector(udc.staticChunkMap[w.contract.ID].root)
+	start := time.Now()
+	root := udc.staticChunkMap[w.contract.ID].root
+	var data []byte
+	if udc.partialPieces() {
+		data, err = downloadPieceRange(d, root, udc.staticPieceOffset, udc.staticPieceLength)
+	} else {
+		data, err = d.Sector(root)
+	}
 	if err != nil {
 		w.renter.log.Debugln("worker failed to download sector:", err)
 		udc.managedUnregisterWorker(w)
 		return
 	}
+	w.managedRecordDownloadThroughput(uint64(len(data)), time.Since(start))
 	// TODO: Instead of adding the whole sector after the download completes,
 	// have the 'd.Sector' call add to this value ongoing as the sector comes
 	// in. Perhaps even include the data from creating the downloader and other
 	// data sent to and received from the host (like signatures) that aren't
 	// actually payload data.
-	atomic.AddUint64(&udc.download.atomicTotalDataTransferred, udc.staticPieceSize)
+	atomic.AddUint64(&udc.download.atomicTotalDataTransferred, udc.staticPieceLength)
 
 	// Mark the piece as completed. Perform chunk recovery if we newly have
 	// enough pieces to do so. Chunk recovery is an expensive operation that
@@ -62,6 +74,69 @@ func (w *worker) managedDownload(udc *unfinishedDownloadChunk) {
 	udc.mu.Unlock()
 }
 
+// downloadPieceRange downloads the range of an encrypted piece that starts at
+// 'offset' within the plaintext of the piece, along with the nonce of the
+// piece. The returned data can be decrypted with DecryptBytesRange. Since the
+// host can only prove segments of a sector, the range is extended to whole
+// segments for the download and trimmed afterwards.
+func downloadPieceRange(d contractor.Downloader, root crypto.Hash, offset, length uint64) ([]byte, error) {
+	// The sector starts with the nonce, followed by the encrypted piece.
+	start := crypto.TwofishNonceSize + offset
+	end := start + length
+	segmentStart := start - start%crypto.SegmentSize
+	segmentEnd := end
+	if segmentEnd%crypto.SegmentSize != 0 {
+		segmentEnd += crypto.SegmentSize - segmentEnd%crypto.SegmentSize
+	}
+
+	// Download the range, and the first segment for the nonce if the range
+	// doesn't contain it.
+	var sections []modules.DownloadAction
+	if segmentStart > 0 {
+		sections = append(sections, modules.DownloadAction{
+			MerkleRoot: root,
+			Offset:     0,
+			Length:     crypto.SegmentSize,
+		})
+	}
+	sections = append(sections, modules.DownloadAction{
+		MerkleRoot: root,
+		Offset:     segmentStart,
+		Length:     segmentEnd - segmentStart,
+	})
+	data, err := d.Download(sections)
+	if err != nil {
+		return nil, err
+	}
+
+	// Combine the nonce and the requested range.
+	piece := make([]byte, 0, crypto.TwofishNonceSize+length)
+	piece = append(piece, data[0][:crypto.TwofishNonceSize]...)
+	piece = append(piece, data[len(data)-1][start-segmentStart:end-segmentStart]...)
+	return piece, nil
+}
+
+// managedDownloadThroughput returns the average download throughput observed
+// from the worker's host in bytes per second. Zero is returned if nothing has
+// been downloaded from the host yet.
+func (w *worker) managedDownloadThroughput() float64 {
+	w.downloadMu.Lock()
+	defer w.downloadMu.Unlock()
+	if w.downloadTime <= 0 {
+		return 0
+	}
+	return float64(w.downloadBytes) / w.downloadTime.Seconds()
+}
+
+// managedRecordDownloadThroughput adds a completed sector download to the
+// worker's throughput statistics.
+func (w *worker) managedRecordDownloadThroughput(bytes uint64, elapsed time.Duration) {
+	w.downloadMu.Lock()
+	w.downloadBytes += bytes
+	w.downloadTime += elapsed
+	w.downloadMu.Unlock()
+}
+
 // managedKillDownloading will drop all of the download work given to the
 // worker, and set a signal to prevent the worker from accepting more download
 // work.
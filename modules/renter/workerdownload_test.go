@@ -0,0 +1,69 @@
+package renter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// sectorDownloader is a contractor.Downloader that serves a single sector.
+type sectorDownloader struct {
+	sector   []byte
+	sections []modules.DownloadAction
+}
+
+func (sd *sectorDownloader) Sector(crypto.Hash) ([]byte, error) { return sd.sector, nil }
+func (sd *sectorDownloader) Close() error                       { return nil }
+
+func (sd *sectorDownloader) Download(sections []modules.DownloadAction) ([][]byte, error) {
+	sd.sections = append(sd.sections, sections...)
+	var data [][]byte
+	for _, s := range sections {
+		data = append(data, sd.sector[s.Offset:s.Offset+s.Length])
+	}
+	return data, nil
+}
+
+// TestDownloadPieceRange checks that ranges of an encrypted piece can be
+// downloaded and decrypted.
+func TestDownloadPieceRange(t *testing.T) {
+	key := crypto.GenerateTwofishKey()
+	piece := fastrand.Bytes(int(pieceSize))
+	sd := &sectorDownloader{sector: key.EncryptBytes(piece)}
+
+	ranges := []struct {
+		offset, length uint64
+		sections       int
+	}{
+		{0, 10, 1},
+		{40, 100, 1},
+		{52, 64, 2},
+		{1000, 1, 2},
+		{pieceSize - 100, 100, 2},
+	}
+	for _, r := range ranges {
+		sd.sections = nil
+		data, err := downloadPieceRange(sd, crypto.Hash{}, r.offset, r.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sd.sections) != r.sections {
+			t.Fatalf("expected %v sections to be downloaded, got %v", r.sections, len(sd.sections))
+		}
+		for _, s := range sd.sections {
+			if s.Offset%crypto.SegmentSize != 0 || s.Length%crypto.SegmentSize != 0 {
+				t.Fatal("section is not aligned to segments:", s.Offset, s.Length)
+			}
+		}
+		decrypted, err := key.DecryptBytesRange(data, r.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decrypted, piece[r.offset:r.offset+r.length]) {
+			t.Fatal("downloaded range does not match the piece:", r.offset, r.length)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 // managedDropChunk will remove a worker from the responsibility of tracking a chunk.
@@ -95,32 +98,57 @@ func (w *worker) managedQueueUploadChunk(uc *unfinishedUploadChunk) {
 	}
 }
 
-// managedUpload will perform some upload work.
-func (w *worker) managedUpload(uc *unfinishedUploadChunk, pieceIndex uint64) {
+// managedNextUploadChunks will pull up to maxUploadBatchPieces potential
+// chunks out of the worker's work queue, so that their pieces can be uploaded
+// to the host at once.
+func (w *worker) managedNextUploadChunks() (chunks []*unfinishedUploadChunk, pieceIndices []uint64) {
+	for len(chunks) < maxUploadBatchPieces {
+		chunk, pieceIndex := w.managedNextUploadChunk()
+		if chunk == nil {
+			break
+		}
+		chunks = append(chunks, chunk)
+		pieceIndices = append(pieceIndices, pieceIndex)
+	}
+	return chunks, pieceIndices
+}
+
+// managedUpload will perform some upload work. The pieces are uploaded to the
+// host in as few contract revisions as the host allows.
+func (w *worker) managedUpload(ucs []*unfinishedUploadChunk, pieceIndices []uint64) {
 	// Open an editing connection to the host.
 	e, err := w.renter.hostContractor.Editor(w.contract.ID, w.renter.tg.StopChan())
 	if err != nil {
 		w.renter.log.Debugln("Worker failed to acquire an editor:", err)
-		w.managedUploadFailed(uc, pieceIndex)
+		w.managedUploadFailed(ucs, pieceIndices)
 		return
 	}
 	defer e.Close()
 
-	// Perform the upload, and update the failure stats based on the success of
-	// the upload attempt.
-	root, err := e.Upload(uc.physicalChunkData[pieceIndex])
+	// Perform the upload. The pieces that were uploaded before a failure are
+	// completed, the others are handed back to their chunks.
+	pieces := make([][]byte, len(ucs))
+	for i, uc := range ucs {
+		pieces[i] = uc.physicalChunkData[pieceIndices[i]]
+	}
+	roots, err := e.UploadBatch(pieces)
+	for i, root := range roots {
+		w.managedUploadCompleted(ucs[i], pieceIndices[i], root, e.Address(), e.EndHeight())
+	}
 	if err != nil {
 		w.renter.log.Debugln("Worker failed to upload via the editor:", err)
-		w.managedUploadFailed(uc, pieceIndex)
+		w.managedUploadFailed(ucs[len(roots):], pieceIndices[len(roots):])
 		return
 	}
 	w.mu.Lock()
 	w.uploadConsecutiveFailures = 0
 	w.mu.Unlock()
+}
 
+// managedUploadCompleted updates the renter metadata and the state of the
+// chunk after a piece was uploaded to the host.
+func (w *worker) managedUploadCompleted(uc *unfinishedUploadChunk, pieceIndex uint64, root crypto.Hash, addr modules.NetAddress, endHeight types.BlockHeight) {
 	// Update the renter metadata.
-	addr := e.Address()
-	endHeight := e.EndHeight()
 	id := w.renter.mu.Lock()
 	uc.renterFile.mu.Lock()
 	contract, exists := uc.renterFile.contracts[w.contract.ID]
@@ -150,7 +178,7 @@ func (w *worker) managedUpload(uc *unfinishedUploadChunk, pieceIndex uint64) {
 	uc.physicalChunkData[pieceIndex] = nil
 	uc.memoryReleased += uint64(releaseSize)
 	uc.mu.Unlock()
-	w.renter.memoryManager.Return(uint64(releaseSize))
+	w.renter.memoryManager.Return(uint64(releaseSize), memoryClassUpload)
 	w.renter.managedCleanUpUploadChunk(uc)
 }
 
@@ -220,9 +248,9 @@ func (w *worker) managedProcessUploadChunk(uc *unfinishedUploadChunk) (nextChunk
 	return uc, uint64(index)
 }
 
-// managedUploadFailed is called if a worker failed to upload part of an unfinished
-// chunk.
-func (w *worker) managedUploadFailed(uc *unfinishedUploadChunk, pieceIndex uint64) {
+// managedUploadFailed is called if a worker failed to upload pieces of
+// unfinished chunks.
+func (w *worker) managedUploadFailed(ucs []*unfinishedUploadChunk, pieceIndices []uint64) {
 	// Mark the failure in the worker if the gateway says we are online. It's
 	// not the worker's fault if we are offline.
 	if w.renter.g.Online() {
@@ -232,15 +260,17 @@ func (w *worker) managedUploadFailed(uc *unfinishedUploadChunk, pieceIndex uint6
 		w.mu.Unlock()
 	}
 
-	// Unregister the piece from the chunk and hunt for a replacement.
-	uc.mu.Lock()
-	uc.piecesRegistered--
-	uc.pieceUsage[pieceIndex] = false
-	uc.mu.Unlock()
+	// Unregister the pieces from the chunks and hunt for replacements.
+	for i, uc := range ucs {
+		uc.mu.Lock()
+		uc.piecesRegistered--
+		uc.pieceUsage[pieceIndices[i]] = false
+		uc.mu.Unlock()
 
-	// Notify the standby workers of the chunk
-	uc.managedNotifyStandbyWorkers()
-	w.renter.managedCleanUpUploadChunk(uc)
+		// Notify the standby workers of the chunk
+		uc.managedNotifyStandbyWorkers()
+		w.renter.managedCleanUpUploadChunk(uc)
+	}
 
 	// Because the worker is now on cooldown, drop all remaining chunks.
 	w.managedDropUploadChunks()
@@ -9,9 +9,56 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
 )
 
+// TestAllowancePriceCapViolation checks that host settings are checked against
+// each of the price caps of an allowance.
+func TestAllowancePriceCapViolation(t *testing.T) {
+	settings := HostExternalSettings{
+		ContractPrice:          types.NewCurrency64(100),
+		StoragePrice:           types.NewCurrency64(100),
+		UploadBandwidthPrice:   types.NewCurrency64(100),
+		DownloadBandwidthPrice: types.NewCurrency64(100),
+		Collateral:             types.NewCurrency64(200),
+	}
+
+	// An allowance without caps accepts any host.
+	var a Allowance
+	if reason := a.PriceCapViolation(settings); reason != "" {
+		t.Fatal("host rejected without caps:", reason)
+	}
+
+	// Caps that equal the host's prices accept the host.
+	a = Allowance{
+		MaxContractPrice:          types.NewCurrency64(100),
+		MaxStoragePrice:           types.NewCurrency64(100),
+		MaxUploadBandwidthPrice:   types.NewCurrency64(100),
+		MaxDownloadBandwidthPrice: types.NewCurrency64(100),
+		MaxCollateralRatio:        2,
+	}
+	if reason := a.PriceCapViolation(settings); reason != "" {
+		t.Fatal("host rejected at the caps:", reason)
+	}
+
+	// Each cap rejects the host on its own.
+	caps := []func(*Allowance){
+		func(a *Allowance) { a.MaxContractPrice = types.NewCurrency64(99) },
+		func(a *Allowance) { a.MaxStoragePrice = types.NewCurrency64(99) },
+		func(a *Allowance) { a.MaxUploadBandwidthPrice = types.NewCurrency64(99) },
+		func(a *Allowance) { a.MaxDownloadBandwidthPrice = types.NewCurrency64(99) },
+		func(a *Allowance) { a.MaxCollateralRatio = 1.5 },
+	}
+	for i, lower := range caps {
+		capped := a
+		lower(&capped)
+		if reason := capped.PriceCapViolation(settings); reason == "" {
+			t.Error("host not rejected by cap", i)
+		}
+	}
+}
+
 // TestMerkleRootSetCompatibility checks that the persist encoding for the
 // MerkleRootSet type is compatible with the previous encoding for the data,
 // which was a slice of type crypto.Hash.
@@ -0,0 +1,227 @@
+package modules
+
+// transport.go contains the encrypted transport that renters and hosts can use
+// for their RPCs. The transport starts with a handshake, in which the renter
+// and the host exchange ephemeral X25519 keys, and the host signs both keys
+// with the key that it announced. Afterwards, all data is sent in frames that
+// are encrypted and authenticated with keys derived from the shared secret of
+// the handshake, so that nobody on the path between the renter and the host
+// can see or tamper with the RPCs.
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	// transportFrameSize is the maximum amount of data that is sent in a
+	// single frame of the encrypted transport.
+	transportFrameSize = 1 << 16
+)
+
+var (
+	// errBadTransportSignature is returned if the host's signature of the
+	// transport handshake is not valid.
+	errBadTransportSignature = errors.New("host's transport handshake signature is invalid")
+
+	// errBadTransportKey is returned if the peer sent an ephemeral key that
+	// results in an all-zero shared secret.
+	errBadTransportKey = errors.New("peer sent an invalid transport key")
+
+	// errLargeTransportFrame is returned if the peer sent a frame that is
+	// larger than transportFrameSize.
+	errLargeTransportFrame = errors.New("peer sent a transport frame that is too large")
+
+	// errUnsupportedTransportKey is returned if the host's public key can't be
+	// used to verify the transport handshake.
+	errUnsupportedTransportKey = errors.New("host public key does not support the encrypted transport")
+)
+
+// An encryptedConn is a net.Conn that encrypts and authenticates all data
+// that is sent over it. Each direction uses its own key, and the nonce of
+// each frame is the number of frames that were sent before it in the same
+// direction, so frames can't be dropped, reordered or replayed.
+type encryptedConn struct {
+	net.Conn
+
+	readAEAD  cipher.AEAD
+	readBuf   []byte
+	readNonce uint64
+	readMu    sync.Mutex
+
+	writeAEAD  cipher.AEAD
+	writeNonce uint64
+	writeMu    sync.Mutex
+}
+
+// transportNonce returns the nonce of the frame with the provided index.
+func transportNonce(index uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint64(nonce, index)
+	return nonce
+}
+
+// Read implements io.Reader, reading and decrypting the next frame when all
+// data of the previous frame has been read.
+func (c *encryptedConn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	for len(c.readBuf) == 0 {
+		var header [4]byte
+		if _, err := io.ReadFull(c.Conn, header[:]); err != nil {
+			return 0, err
+		}
+		frameLen := binary.LittleEndian.Uint32(header[:])
+		if frameLen > transportFrameSize+uint32(c.readAEAD.Overhead()) {
+			return 0, errLargeTransportFrame
+		}
+		frame := make([]byte, frameLen)
+		if _, err := io.ReadFull(c.Conn, frame); err != nil {
+			return 0, err
+		}
+		plaintext, err := c.readAEAD.Open(frame[:0], transportNonce(c.readNonce), frame, nil)
+		if err != nil {
+			return 0, err
+		}
+		c.readNonce++
+		c.readBuf = plaintext
+	}
+	n := copy(p, c.readBuf)
+	c.readBuf = c.readBuf[n:]
+	return n, nil
+}
+
+// Write implements io.Writer, encrypting p and sending it in one or more
+// frames.
+func (c *encryptedConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	var n int
+	for len(p) > 0 {
+		data := p
+		if len(data) > transportFrameSize {
+			data = data[:transportFrameSize]
+		}
+		frame := make([]byte, 4, 4+len(data)+c.writeAEAD.Overhead())
+		binary.LittleEndian.PutUint32(frame, uint32(len(data)+c.writeAEAD.Overhead()))
+		frame = c.writeAEAD.Seal(frame, transportNonce(c.writeNonce), data, nil)
+		c.writeNonce++
+		if _, err := c.Conn.Write(frame); err != nil {
+			return n, err
+		}
+		n += len(data)
+		p = p[len(data):]
+	}
+	return n, nil
+}
+
+// newEncryptedConn returns an encryptedConn that decrypts the data read from
+// conn with readKey, and encrypts the data written to conn with writeKey.
+func newEncryptedConn(conn net.Conn, readKey, writeKey crypto.Hash) net.Conn {
+	// NOTE: chacha20poly1305.New only returns an error if the key is not 32
+	// bytes long.
+	readAEAD, _ := chacha20poly1305.New(readKey[:])
+	writeAEAD, _ := chacha20poly1305.New(writeKey[:])
+	return &encryptedConn{
+		Conn:      conn,
+		readAEAD:  readAEAD,
+		writeAEAD: writeAEAD,
+	}
+}
+
+// generateTransportKeyPair generates an ephemeral X25519 key pair for the
+// transport handshake.
+func generateTransportKeyPair() (sk, pk [32]byte) {
+	fastrand.Read(sk[:])
+	curve25519.ScalarBaseMult(&pk, &sk)
+	return
+}
+
+// transportHandshakeHash returns the hash of the handshake that the host
+// signs.
+func transportHandshakeHash(req TransportHandshakeRequest, resp TransportHandshakeResponse) crypto.Hash {
+	return crypto.HashAll(RPCEncryptedTransport, req.PublicKey, resp.PublicKey)
+}
+
+// transportKeys derives the keys that the renter and the host use to encrypt
+// the data they send from the secret key of one party and the handshake.
+func transportKeys(sk, peerKey [32]byte, req TransportHandshakeRequest, resp TransportHandshakeResponse) (renterKey, hostKey crypto.Hash, err error) {
+	var secret [32]byte
+	curve25519.ScalarMult(&secret, &sk, &peerKey)
+	if secret == [32]byte{} {
+		return crypto.Hash{}, crypto.Hash{}, errBadTransportKey
+	}
+	renterKey = crypto.HashAll("renter", secret, req.PublicKey, resp.PublicKey)
+	hostKey = crypto.HashAll("host", secret, req.PublicKey, resp.PublicKey)
+	return renterKey, hostKey, nil
+}
+
+// RenterTransportHandshake performs the renter's side of the transport
+// handshake on conn, after RPCEncryptedTransport has been sent to the host.
+// The returned connection encrypts all data that is sent over conn. An error
+// is returned if the handshake was not signed by hostKey.
+func RenterTransportHandshake(conn net.Conn, hostKey types.SiaPublicKey) (net.Conn, error) {
+	if hostKey.Algorithm != types.SignatureEd25519 || len(hostKey.Key) != crypto.PublicKeySize {
+		return nil, errUnsupportedTransportKey
+	}
+	var pk crypto.PublicKey
+	copy(pk[:], hostKey.Key)
+
+	// Send the renter's ephemeral key and read the host's ephemeral key.
+	sk, ephemeralKey := generateTransportKeyPair()
+	req := TransportHandshakeRequest{PublicKey: ephemeralKey}
+	if err := encoding.WriteObject(conn, req); err != nil {
+		return nil, errors.New("couldn't send transport handshake: " + err.Error())
+	}
+	var resp TransportHandshakeResponse
+	if err := encoding.ReadObject(conn, &resp, NegotiateMaxTransportHandshakeSize); err != nil {
+		return nil, errors.New("couldn't read transport handshake: " + err.Error())
+	}
+
+	// Verify that the host's key belongs to the host, and not to someone on
+	// the path to the host.
+	if err := crypto.VerifyHash(transportHandshakeHash(req, resp), pk, resp.Signature); err != nil {
+		return nil, errBadTransportSignature
+	}
+	renterKey, hostTransportKey, err := transportKeys(sk, resp.PublicKey, req, resp)
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptedConn(conn, hostTransportKey, renterKey), nil
+}
+
+// HostTransportHandshake performs the host's side of the transport handshake
+// on conn, after the renter has sent RPCEncryptedTransport. The handshake is
+// signed with sk. The returned connection encrypts all data that is sent over
+// conn.
+func HostTransportHandshake(conn net.Conn, sk crypto.SecretKey) (net.Conn, error) {
+	var req TransportHandshakeRequest
+	if err := encoding.ReadObject(conn, &req, NegotiateMaxTransportHandshakeSize); err != nil {
+		return nil, errors.New("couldn't read transport handshake: " + err.Error())
+	}
+
+	// Send the host's ephemeral key, signed.
+	transportSK, ephemeralKey := generateTransportKeyPair()
+	resp := TransportHandshakeResponse{PublicKey: ephemeralKey}
+	resp.Signature = crypto.SignHash(transportHandshakeHash(req, resp), sk)
+	renterKey, hostKey, err := transportKeys(transportSK, req.PublicKey, req, resp)
+	if err != nil {
+		return nil, err
+	}
+	if err := encoding.WriteObject(conn, resp); err != nil {
+		return nil, errors.New("couldn't send transport handshake: " + err.Error())
+	}
+	return newEncryptedConn(conn, renterKey, hostKey), nil
+}
@@ -0,0 +1,98 @@
+package modules
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestTransportHandshake checks that a renter and a host can send data in both
+// directions after the transport handshake, and that the data is encrypted.
+func TestTransportHandshake(t *testing.T) {
+	sk, pk := crypto.GenerateKeyPair()
+	hostKey := types.SiaPublicKey{
+		Algorithm: types.SignatureEd25519,
+		Key:       pk[:],
+	}
+
+	// Record everything that the renter receives from the host.
+	rConn, hConn := net.Pipe()
+	defer rConn.Close()
+	received := new(bytes.Buffer)
+	data := fastrand.Bytes(3*transportFrameSize + 10)
+	errChan := make(chan error, 1)
+	go func() {
+		defer hConn.Close()
+		conn, err := HostTransportHandshake(hConn, sk)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		buf := make([]byte, len(data))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			errChan <- err
+			return
+		}
+		_, err = conn.Write(buf)
+		errChan <- err
+	}()
+
+	conn, err := RenterTransportHandshake(recordingConn{rConn, received}, hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	echo := make([]byte, len(data))
+	if _, err := io.ReadFull(conn, echo); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(echo, data) {
+		t.Fatal("host did not echo the data")
+	}
+	if bytes.Contains(received.Bytes(), data[:64]) {
+		t.Fatal("data was not encrypted")
+	}
+}
+
+// TestTransportHandshakeWrongKey checks that the renter rejects a handshake
+// that wasn't signed by the host it wants to talk to.
+func TestTransportHandshakeWrongKey(t *testing.T) {
+	sk, _ := crypto.GenerateKeyPair()
+	_, pk := crypto.GenerateKeyPair()
+	hostKey := types.SiaPublicKey{
+		Algorithm: types.SignatureEd25519,
+		Key:       pk[:],
+	}
+
+	rConn, hConn := net.Pipe()
+	defer rConn.Close()
+	go func() {
+		defer hConn.Close()
+		HostTransportHandshake(hConn, sk)
+	}()
+	if _, err := RenterTransportHandshake(rConn, hostKey); err != errBadTransportSignature {
+		t.Fatal("expected errBadTransportSignature, got", err)
+	}
+}
+
+// recordingConn is a net.Conn that records all data that is read from it.
+type recordingConn struct {
+	net.Conn
+	record *bytes.Buffer
+}
+
+func (rc recordingConn) Read(p []byte) (int, error) {
+	n, err := rc.Conn.Read(p)
+	rc.record.Write(p[:n])
+	return n, err
+}
@@ -1,6 +1,27 @@
 package client
 
-import "github.com/NebulousLabs/Sia/node/api"
+import (
+	"net/url"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/node/api"
+)
+
+// DaemonAlertsGet requests the /daemon/alerts resource, which lists the active
+// alerts of the daemon's modules.
+func (c *Client) DaemonAlertsGet() (dag api.DaemonAlertsGet, err error) {
+	err = c.get("/daemon/alerts", &dag)
+	return
+}
+
+// DaemonAlertsAcknowledgePost uses the /daemon/alerts/acknowledge endpoint to
+// acknowledge an alert.
+func (c *Client) DaemonAlertsAcknowledgePost(id modules.AlertID) (err error) {
+	values := url.Values{}
+	values.Set("id", string(id))
+	err = c.post("/daemon/alerts/acknowledge", values.Encode(), nil)
+	return
+}
 
 // DaemonVersionGet requests the /daemon/version resource
 func (c *Client) DaemonVersionGet() (dvg api.DaemonVersionGet, err error) {
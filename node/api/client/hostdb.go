@@ -1,6 +1,11 @@
 package client
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
 	"github.com/NebulousLabs/Sia/types"
 )
@@ -17,8 +22,124 @@ func (c *Client) HostDbAllGet() (hdag api.HostdbAllGET, err error) {
 	return
 }
 
+// HostDbExportPost uses the /hostdb/export endpoint to export the hostdb to
+// the destination on the node's filesystem.
+func (c *Client) HostDbExportPost(destination string) (err error) {
+	values := url.Values{}
+	values.Set("destination", destination)
+	err = c.post("/hostdb/export", values.Encode(), nil)
+	return
+}
+
 // HostDbHostsGet request the /hostdb/hosts/:pubkey endpoint's resources.
 func (c *Client) HostDbHostsGet(pk types.SiaPublicKey) (hhg api.HostdbHostsGET, err error) {
 	err = c.get("/hostdb/hosts/"+pk.String(), &hhg)
 	return
 }
+
+// HostDbHostsHistoryGet requests the /hostdb/hosts/:pubkey/history endpoint's
+// resources.
+func (c *Client) HostDbHostsHistoryGet(pk types.SiaPublicKey) (hhhg api.HostdbHostsHistoryGET, err error) {
+	err = c.get("/hostdb/hosts/"+pk.String()+"/history", &hhhg)
+	return
+}
+
+// HostDbImportPost uses the /hostdb/import endpoint to import an exported
+// hostdb from the source on the node's filesystem. The imported scans are
+// marked with the origin.
+func (c *Client) HostDbImportPost(source, origin string) (hip api.HostdbImportPOST, err error) {
+	values := url.Values{}
+	values.Set("source", source)
+	values.Set("origin", origin)
+	err = c.post("/hostdb/import", values.Encode(), &hip)
+	return
+}
+
+// HostDbFilterModeGet requests the /hostdb/filtermode endpoint's resources.
+func (c *Client) HostDbFilterModeGet() (hfmg api.HostdbFilterModeGET, err error) {
+	err = c.get("/hostdb/filtermode", &hfmg)
+	return
+}
+
+// HostDbFilterModePost uses the /hostdb/filtermode endpoint to set the
+// hostdb's filter mode and the hosts it applies to.
+func (c *Client) HostDbFilterModePost(fm modules.FilterMode, hosts []types.SiaPublicKey) (err error) {
+	keys := make([]string, 0, len(hosts))
+	for _, spk := range hosts {
+		keys = append(keys, spk.String())
+	}
+	values := url.Values{}
+	values.Set("filtermode", fm.String())
+	values.Set("hosts", strings.Join(keys, ","))
+	err = c.post("/hostdb/filtermode", values.Encode(), nil)
+	return
+}
+
+// HostDbRescanGet requests the /hostdb/rescan endpoint's resources.
+func (c *Client) HostDbRescanGet() (hrg api.HostdbRescanGET, err error) {
+	err = c.get("/hostdb/rescan", &hrg)
+	return
+}
+
+// HostDbRescanPost uses the /hostdb/rescan endpoint to rescan all hosts.
+func (c *Client) HostDbRescanPost() (err error) {
+	err = c.post("/hostdb/rescan", "", nil)
+	return
+}
+
+// HostDbScanPost uses the /hostdb/scan/:pubkey endpoint to scan a host
+// immediately.
+func (c *Client) HostDbScanPost(pk types.SiaPublicKey) (hsp api.HostdbScanPOST, err error) {
+	err = c.post("/hostdb/scan/"+pk.String(), "", &hsp)
+	return
+}
+
+// HostDbScanPolicyGet requests the /hostdb/scanpolicy endpoint's resources.
+func (c *Client) HostDbScanPolicyGet() (hspg api.HostdbScanPolicyGET, err error) {
+	err = c.get("/hostdb/scanpolicy", &hspg)
+	return
+}
+
+// HostDbScanPolicyPost uses the /hostdb/scanpolicy endpoint to set the policy
+// that is used to scan hosts.
+func (c *Client) HostDbScanPolicyPost(p modules.HostDBScanPolicy) (err error) {
+	values := url.Values{}
+	values.Set("scanningthreads", fmt.Sprint(p.ScanningThreads))
+	values.Set("onlinescaninterval", fmt.Sprint(uint64(p.OnlineScanInterval.Seconds())))
+	values.Set("offlinescaninterval", fmt.Sprint(uint64(p.OfflineScanInterval.Seconds())))
+	values.Set("scantimeout", fmt.Sprint(uint64(p.ScanTimeout.Seconds())))
+	err = c.post("/hostdb/scanpolicy", values.Encode(), nil)
+	return
+}
+
+// HostDbScoringGet requests the /hostdb/scoring endpoint's resources.
+func (c *Client) HostDbScoringGet() (hsg api.HostdbScoringGET, err error) {
+	err = c.get("/hostdb/scoring", &hsg)
+	return
+}
+
+// HostDbScoringPresetPost uses the /hostdb/scoring endpoint to select a preset
+// scoring profile.
+func (c *Client) HostDbScoringPresetPost(preset string) (err error) {
+	values := url.Values{}
+	values.Set("preset", preset)
+	err = c.post("/hostdb/scoring", values.Encode(), nil)
+	return
+}
+
+// HostDbScoringPost uses the /hostdb/scoring endpoint to set the weights of
+// the scoring profile.
+func (c *Client) HostDbScoringPost(p modules.HostScoringProfile) (err error) {
+	values := url.Values{}
+	values.Set("age", fmt.Sprint(p.AgeWeight))
+	values.Set("benchmark", fmt.Sprint(p.BenchmarkWeight))
+	values.Set("collateral", fmt.Sprint(p.CollateralWeight))
+	values.Set("interaction", fmt.Sprint(p.InteractionWeight))
+	values.Set("price", fmt.Sprint(p.PriceWeight))
+	values.Set("storageremaining", fmt.Sprint(p.StorageRemainingWeight))
+	values.Set("uptime", fmt.Sprint(p.UptimeWeight))
+	values.Set("version", fmt.Sprint(p.VersionWeight))
+	values.Set("volatility", fmt.Sprint(p.VolatilityWeight))
+	err = c.post("/hostdb/scoring", values.Encode(), nil)
+	return
+}
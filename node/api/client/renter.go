@@ -1,21 +1,80 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/node/api"
+	"github.com/NebulousLabs/Sia/types"
 )
 
+// RenterBackupsGet requests the /renter/backups resource, which lists the
+// snapshots of the renter's metadata.
+func (c *Client) RenterBackupsGet() (rb api.RenterBackupsGET, err error) {
+	err = c.get("/renter/backups", &rb)
+	return
+}
+
+// RenterBackupsPost uses the /renter/backups endpoint to upload a snapshot of
+// the renter's metadata to hosts.
+func (c *Client) RenterBackupsPost() (rs modules.RenterSnapshot, err error) {
+	err = c.post("/renter/backups", "", &rs)
+	return
+}
+
+// RenterBackupsRestorePost uses the /renter/backups/restore endpoint to
+// restore a snapshot. If the id is empty, the most recent snapshot is
+// restored.
+func (c *Client) RenterBackupsRestorePost(id crypto.Hash) (err error) {
+	values := url.Values{}
+	if id != (crypto.Hash{}) {
+		values.Set("id", id.String())
+	}
+	err = c.post("/renter/backups/restore", values.Encode(), nil)
+	return
+}
+
 // RenterContractsGet requests the /renter/contracts resource
 func (c *Client) RenterContractsGet() (rc api.RenterContracts, err error) {
 	err = c.get("/renter/contracts", &rc)
 	return
 }
 
+// RenterContractsCancelPost uses the /renter/contracts/cancel endpoint to
+// cancel a contract.
+func (c *Client) RenterContractsCancelPost(id types.FileContractID) (err error) {
+	values := url.Values{}
+	values.Set("id", id.String())
+	err = c.post("/renter/contracts/cancel", values.Encode(), nil)
+	return
+}
+
+// RenterContractsFormPost uses the /renter/contracts/form endpoint to form a
+// contract with a host. Zero funds select the default funding.
+func (c *Client) RenterContractsFormPost(host types.SiaPublicKey, funds types.Currency) (rc api.RenterContract, err error) {
+	values := url.Values{}
+	values.Set("host", host.String())
+	values.Set("funds", funds.String())
+	err = c.post("/renter/contracts/form", values.Encode(), &rc)
+	return
+}
+
+// RenterContractsRenewPost uses the /renter/contracts/renew endpoint to renew
+// a contract immediately. Zero funds select the default funding.
+func (c *Client) RenterContractsRenewPost(id types.FileContractID, funds types.Currency) (rc api.RenterContract, err error) {
+	values := url.Values{}
+	values.Set("id", id.String())
+	values.Set("funds", funds.String())
+	err = c.post("/renter/contracts/renew", values.Encode(), &rc)
+	return
+}
+
 // RenterDeletePost uses the /renter/delete endpoint to delete a file.
 func (c *Client) RenterDeletePost(siaPath string) (err error) {
 	siaPath = strings.TrimPrefix(siaPath, "/")
@@ -49,6 +108,14 @@ func (c *Client) RenterDownloadsGet() (rdq api.RenterDownloadQueue, err error) {
 	return
 }
 
+// RenterDownloadEstimateGet requests the /renter/downloadestimate resource
+// for every file under the provided siapath prefix.
+func (c *Client) RenterDownloadEstimateGet(siaPathPrefix string) (rde api.RenterDownloadEstimateGET, err error) {
+	siaPathPrefix = strings.TrimPrefix(siaPathPrefix, "/")
+	err = c.get("/renter/downloadestimate/"+siaPathPrefix, &rde)
+	return
+}
+
 // RenterDownloadHTTPResponseGet uses the /renter/download endpoint to download
 // a file and return its data.
 func (c *Client) RenterDownloadHTTPResponseGet(siaPath string, offset, length uint64) (resp []byte, err error) {
@@ -77,14 +144,35 @@ func (c *Client) RenterGet() (rg api.RenterGET, err error) {
 	return
 }
 
-// RenterPostAllowance uses the /renter endpoint to change the renter's allowance
-func (c *Client) RenterPostAllowance(allowance modules.Allowance) (err error) {
+// allowanceValues returns the query values that describe the allowance.
+func allowanceValues(allowance modules.Allowance) url.Values {
 	values := url.Values{}
 	values.Set("funds", allowance.Funds.String())
 	values.Set("hosts", strconv.FormatUint(allowance.Hosts, 10))
 	values.Set("period", strconv.FormatUint(uint64(allowance.Period), 10))
 	values.Set("renewwindow", strconv.FormatUint(uint64(allowance.RenewWindow), 10))
-	err = c.post("/renter", values.Encode(), nil)
+	values.Set("expectedstorage", strconv.FormatUint(allowance.ExpectedStorage, 10))
+	values.Set("expectedupload", strconv.FormatUint(allowance.ExpectedUpload, 10))
+	values.Set("expecteddownload", strconv.FormatUint(allowance.ExpectedDownload, 10))
+	values.Set("expectedredundancy", strconv.FormatFloat(allowance.ExpectedRedundancy, 'f', -1, 64))
+	values.Set("maxcontractprice", allowance.MaxContractPrice.String())
+	values.Set("maxstorageprice", allowance.MaxStoragePrice.String())
+	values.Set("maxuploadbandwidthprice", allowance.MaxUploadBandwidthPrice.String())
+	values.Set("maxdownloadbandwidthprice", allowance.MaxDownloadBandwidthPrice.String())
+	values.Set("maxcollateralratio", strconv.FormatFloat(allowance.MaxCollateralRatio, 'f', -1, 64))
+	return values
+}
+
+// RenterAllowancePlanGet requests the /renter/allowanceplan resource, which
+// simulates the contract formation for the allowance.
+func (c *Client) RenterAllowancePlanGet(allowance modules.Allowance) (rap api.RenterAllowancePlanGET, err error) {
+	err = c.get("/renter/allowanceplan?"+allowanceValues(allowance).Encode(), &rap)
+	return
+}
+
+// RenterPostAllowance uses the /renter endpoint to change the renter's allowance
+func (c *Client) RenterPostAllowance(allowance modules.Allowance) (err error) {
+	err = c.post("/renter", allowanceValues(allowance).Encode(), nil)
 	return
 }
 
@@ -110,6 +198,62 @@ func (c *Client) RenterPostRateLimit(readBPS, writeBPS int64) (err error) {
 	return
 }
 
+// RenterRateLimitGet requests the /renter/ratelimit resource
+func (c *Client) RenterRateLimitGet() (rrl api.RenterRateLimitGET, err error) {
+	err = c.get("/renter/ratelimit", &rrl)
+	return
+}
+
+// RenterRecoveryScanGet requests the /renter/recoveryscan resource, which
+// reports the progress of the most recent recovery scan.
+func (c *Client) RenterRecoveryScanGet() (rrs api.RenterRecoveryScanGET, err error) {
+	err = c.get("/renter/recoveryscan", &rrs)
+	return
+}
+
+// RenterRecoveryScanPost uses the /renter/recoveryscan endpoint to start a
+// scan of the blockchain for the renter's contracts.
+func (c *Client) RenterRecoveryScanPost() (err error) {
+	err = c.post("/renter/recoveryscan", "", nil)
+	return
+}
+
+// RenterRejectedHostsGet requests the /renter/rejectedhosts resource.
+func (c *Client) RenterRejectedHostsGet() (rrh api.RenterRejectedHostsGET, err error) {
+	err = c.get("/renter/rejectedhosts", &rrh)
+	return
+}
+
+// RenterSpendingGet requests the /renter/spending resource, which contains the
+// spending of every billing period.
+func (c *Client) RenterSpendingGet() (rsg api.RenterSpendingGET, err error) {
+	err = c.get("/renter/spending", &rsg)
+	return
+}
+
+// RenterSpendingPeriodGet requests the /renter/spending resource for the
+// period that started at the given height.
+func (c *Client) RenterSpendingPeriodGet(period types.BlockHeight) (rsg api.RenterSpendingGET, err error) {
+	err = c.get(fmt.Sprintf("/renter/spending?period=%v", period), &rsg)
+	return
+}
+
+// RenterPostRateLimitSchedule uses the /renter endpoint to replace the
+// renter's rate limit schedule.
+func (c *Client) RenterPostRateLimitSchedule(schedule []modules.RateLimitWindow) (err error) {
+	if schedule == nil {
+		schedule = []modules.RateLimitWindow{}
+	}
+	js, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	values := url.Values{}
+	values.Set("ratelimitschedule", string(js))
+	err = c.post("/renter", values.Encode(), nil)
+	return
+}
+
 // RenterRenamePost uses the /renter/rename/:siapath endpoint to rename a file.
 func (c *Client) RenterRenamePost(siaPathOld, siaPathNew string) (err error) {
 	siaPathOld = strings.TrimPrefix(siaPathOld, "/")
@@ -118,6 +262,35 @@ func (c *Client) RenterRenamePost(siaPathOld, siaPathNew string) (err error) {
 	return
 }
 
+// RenterSetBenchmarkHostsPost uses the /renter endpoint to enable or disable
+// the benchmarking of contracted hosts.
+func (c *Client) RenterSetBenchmarkHostsPost(enabled bool) (err error) {
+	values := url.Values{}
+	values.Set("benchmarkhosts", strconv.FormatBool(enabled))
+	err = c.post("/renter", values.Encode(), nil)
+	return
+}
+
+// RenterSetMaxMemoryPost uses the /renter endpoint to change the amount of
+// memory the renter may use for uploads and downloads.
+func (c *Client) RenterSetMaxMemoryPost(maxMemory uint64) (err error) {
+	values := url.Values{}
+	values.Set("maxmemory", strconv.FormatUint(maxMemory, 10))
+	err = c.post("/renter", values.Encode(), nil)
+	return
+}
+
+// RenterSetMemoryReservesPost uses the /renter endpoint to change the amount
+// of memory reserved for uploads, downloads and streams.
+func (c *Client) RenterSetMemoryReservesPost(upload, download, stream uint64) (err error) {
+	values := url.Values{}
+	values.Set("uploadmemoryreserve", strconv.FormatUint(upload, 10))
+	values.Set("downloadmemoryreserve", strconv.FormatUint(download, 10))
+	values.Set("streammemoryreserve", strconv.FormatUint(stream, 10))
+	err = c.post("/renter", values.Encode(), nil)
+	return
+}
+
 // RenterSetStreamCacheSizePost uses the /renter endpoint to change the renter's
 // streamCacheSize for streaming
 func (c *Client) RenterSetStreamCacheSizePost(cacheSize uint64) (err error) {
@@ -143,6 +316,33 @@ func (c *Client) RenterStreamPartialGet(siaPath string, start, end uint64) (resp
 	return
 }
 
+// RenterSyncGet requests the /renter/sync resource
+func (c *Client) RenterSyncGet() (rsg api.RenterSyncGET, err error) {
+	err = c.get("/renter/sync", &rsg)
+	return
+}
+
+// RenterSyncPost uses the /renter/sync endpoint to start syncing a local
+// directory to a siapath prefix.
+func (c *Client) RenterSyncPost(params modules.RenterSyncParams) (err error) {
+	siaPath := strings.TrimPrefix(params.SiaPath, "/")
+	values := url.Values{}
+	values.Set("source", params.Source)
+	values.Set("delete", strconv.FormatBool(params.Delete))
+	values.Set("dryrun", strconv.FormatBool(params.DryRun))
+	values.Set("interval", strconv.FormatInt(int64(params.Interval/time.Second), 10))
+	err = c.post("/renter/sync/"+siaPath, values.Encode(), nil)
+	return
+}
+
+// RenterSyncCancelPost uses the /renter/synccancel endpoint to cancel a
+// recurring sync job.
+func (c *Client) RenterSyncCancelPost(siaPath string) (err error) {
+	siaPath = strings.TrimPrefix(siaPath, "/")
+	err = c.post("/renter/synccancel/"+siaPath, "", nil)
+	return
+}
+
 // RenterUploadPost uses the /renter/upload endpoint to upload a file
 func (c *Client) RenterUploadPost(path, siaPath string, dataPieces, parityPieces uint64) (err error) {
 	siaPath = strings.TrimPrefix(siaPath, "/")
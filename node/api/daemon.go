@@ -1,5 +1,7 @@
 package api
 
+import "github.com/NebulousLabs/Sia/modules"
+
 // DaemonVersionGet contains information about the running daemon's version.
 type DaemonVersionGet struct {
 	Version     string
@@ -13,3 +15,8 @@ type DaemonUpdateGet struct {
 	Available bool   `json:"available"`
 	Version   string `json:"version"`
 }
+
+// DaemonAlertsGet contains the active alerts of the daemon's modules.
+type DaemonAlertsGet struct {
+	Alerts []modules.Alert `json:"alerts"`
+}
@@ -3,6 +3,11 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -29,12 +34,55 @@ type (
 		Hosts []ExtendedHostDBEntry `json:"hosts"`
 	}
 
+	// HostdbFilterModeGET contains the hostdb's filter mode and the hosts it
+	// applies to.
+	HostdbFilterModeGET struct {
+		FilterMode string   `json:"filtermode"`
+		Hosts      []string `json:"hosts"`
+	}
+
+	// HostdbScoringGET contains the profile that is used to score hosts and
+	// the preset profiles that can be selected.
+	HostdbScoringGET struct {
+		Profile modules.HostScoringProfile   `json:"profile"`
+		Presets []modules.HostScoringProfile `json:"presets"`
+	}
+
 	// HostdbHostsGET lists detailed statistics for a particular host, selected
 	// by pubkey.
 	HostdbHostsGET struct {
 		Entry          ExtendedHostDBEntry        `json:"entry"`
 		ScoreBreakdown modules.HostScoreBreakdown `json:"scorebreakdown"`
 	}
+
+	// HostdbImportPOST summarizes the import of an exported hostdb.
+	HostdbImportPOST struct {
+		modules.HostDBImportReport
+	}
+
+	// HostdbRescanGET contains the progress of the most recent full rescan of
+	// the hostdb.
+	HostdbRescanGET struct {
+		modules.HostDBRescanProgress
+	}
+
+	// HostdbScanPOST contains the result of an immediate scan of a host.
+	HostdbScanPOST struct {
+		Success bool                `json:"success"`
+		Error   string              `json:"error,omitempty"`
+		Entry   ExtendedHostDBEntry `json:"entry"`
+	}
+
+	// HostdbScanPolicyGET contains the policy that is used to scan hosts.
+	HostdbScanPolicyGET struct {
+		modules.HostDBScanPolicy
+	}
+
+	// HostdbHostsHistoryGET contains the recorded changes of a host's
+	// settings, oldest first.
+	HostdbHostsHistoryGET struct {
+		History []modules.HostSettingsChange `json:"history"`
+	}
 )
 
 // hostdbActiveHandler handles the API call asking for the list of active
@@ -114,3 +162,237 @@ func (api *API) hostdbHostsHandler(w http.ResponseWriter, req *http.Request, ps
 		ScoreBreakdown: breakdown,
 	})
 }
+
+// hostdbHostsHistoryHandler handles the API call asking for the history of a
+// host's settings.
+func (api *API) hostdbHostsHistoryHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	var pk types.SiaPublicKey
+	pk.LoadString(ps.ByName("pubkey"))
+
+	entry, exists := api.renter.Host(pk)
+	if !exists {
+		WriteError(w, Error{"requested host does not exist"}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, HostdbHostsHistoryGET{
+		History: entry.SettingsHistory,
+	})
+}
+
+// hostdbFilterModeHandlerGET handles the API call to get the hostdb's filter
+// mode.
+func (api *API) hostdbFilterModeHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	fm, hosts := api.renter.FilterMode()
+	keys := make([]string, 0, len(hosts))
+	for _, spk := range hosts {
+		keys = append(keys, spk.String())
+	}
+	sort.Strings(keys)
+	WriteJSON(w, HostdbFilterModeGET{
+		FilterMode: fm.String(),
+		Hosts:      keys,
+	})
+}
+
+// hostdbFilterModeHandlerPOST handles the API call to set the hostdb's filter
+// mode.
+func (api *API) hostdbFilterModeHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var fm modules.FilterMode
+	if err := fm.FromString(req.FormValue("filtermode")); err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var hosts []types.SiaPublicKey
+	if h := req.FormValue("hosts"); h != "" {
+		for _, pk := range strings.Split(h, ",") {
+			var spk types.SiaPublicKey
+			spk.LoadString(strings.TrimSpace(pk))
+			if len(spk.Key) == 0 {
+				WriteError(w, Error{"unable to parse host public key " + pk}, http.StatusBadRequest)
+				return
+			}
+			hosts = append(hosts, spk)
+		}
+	}
+	if err := api.renter.SetFilterMode(fm, hosts); err != nil {
+		WriteError(w, Error{"unable to set filter mode: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// hostdbScoringHandlerGET handles the API call to get the profile that is used
+// to score hosts.
+func (api *API) hostdbScoringHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, HostdbScoringGET{
+		Profile: api.renter.ScoringProfile(),
+		Presets: modules.HostScoringPresets,
+	})
+}
+
+// hostdbScoringHandlerPOST handles the API call to set the profile that is
+// used to score hosts. The profile starts out as the selected preset, or as the
+// current profile if no preset is selected, and is then modified by the
+// individual weights that are provided.
+func (api *API) hostdbScoringHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	p := api.renter.ScoringProfile()
+	if preset := req.FormValue("preset"); preset != "" {
+		var exists bool
+		p, exists = modules.HostScoringPreset(preset)
+		if !exists {
+			WriteError(w, Error{"unknown scoring preset " + preset}, http.StatusBadRequest)
+			return
+		}
+	}
+	weights := []struct {
+		param  string
+		weight *float64
+	}{
+		{"age", &p.AgeWeight},
+		{"benchmark", &p.BenchmarkWeight},
+		{"collateral", &p.CollateralWeight},
+		{"interaction", &p.InteractionWeight},
+		{"price", &p.PriceWeight},
+		{"storageremaining", &p.StorageRemainingWeight},
+		{"uptime", &p.UptimeWeight},
+		{"version", &p.VersionWeight},
+		{"volatility", &p.VolatilityWeight},
+	}
+	for _, pw := range weights {
+		v := req.FormValue(pw.param)
+		if v == "" {
+			continue
+		}
+		weight, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse " + pw.param + ": " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		*pw.weight = weight
+		p.Name = modules.HostScoringProfileCustom
+	}
+	if err := api.renter.SetScoringProfile(p); err != nil {
+		WriteError(w, Error{"unable to set scoring profile: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// hostdbExportHandler handles the API call to export the hostdb to a file.
+func (api *API) hostdbExportHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	destination := req.FormValue("destination")
+	if !filepath.IsAbs(destination) {
+		WriteError(w, Error{"destination must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	if err := api.renter.ExportHostDB(destination); err != nil {
+		WriteError(w, Error{"unable to export hostdb: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// hostdbImportHandler handles the API call to import an exported hostdb.
+func (api *API) hostdbImportHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	source := req.FormValue("source")
+	if !filepath.IsAbs(source) {
+		WriteError(w, Error{"source must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	report, err := api.renter.ImportHostDB(source, req.FormValue("origin"))
+	if err != nil {
+		WriteError(w, Error{"unable to import hostdb: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, HostdbImportPOST{
+		HostDBImportReport: report,
+	})
+}
+
+// hostdbScanHandler handles the API call to scan a host immediately.
+func (api *API) hostdbScanHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	var pk types.SiaPublicKey
+	pk.LoadString(ps.ByName("pubkey"))
+
+	if _, exists := api.renter.Host(pk); !exists {
+		WriteError(w, Error{"requested host does not exist"}, http.StatusBadRequest)
+		return
+	}
+	entry, err := api.renter.ScanHost(pk)
+	resp := HostdbScanPOST{
+		Success: err == nil,
+		Entry: ExtendedHostDBEntry{
+			HostDBEntry:     entry,
+			PublicKeyString: entry.PublicKey.String(),
+		},
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	WriteJSON(w, resp)
+}
+
+// hostdbRescanHandlerGET handles the API call asking for the progress of the
+// most recent full rescan.
+func (api *API) hostdbRescanHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, HostdbRescanGET{
+		HostDBRescanProgress: api.renter.RescanProgress(),
+	})
+}
+
+// hostdbRescanHandlerPOST handles the API call to rescan all hosts.
+func (api *API) hostdbRescanHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	if err := api.renter.RescanHosts(); err != nil {
+		WriteError(w, Error{"unable to start rescan: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// hostdbScanPolicyHandlerGET handles the API call to get the policy that is
+// used to scan hosts.
+func (api *API) hostdbScanPolicyHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, HostdbScanPolicyGET{
+		HostDBScanPolicy: api.renter.ScanPolicy(),
+	})
+}
+
+// hostdbScanPolicyHandlerPOST handles the API call to set the policy that is
+// used to scan hosts. Fields that are not provided keep their current value.
+// Durations are provided in seconds.
+func (api *API) hostdbScanPolicyHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	p := api.renter.ScanPolicy()
+	if t := req.FormValue("scanningthreads"); t != "" {
+		threads, err := strconv.Atoi(t)
+		if err != nil {
+			WriteError(w, Error{"unable to parse scanningthreads: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		p.ScanningThreads = threads
+	}
+	durations := []struct {
+		param    string
+		duration *time.Duration
+	}{
+		{"offlinescaninterval", &p.OfflineScanInterval},
+		{"onlinescaninterval", &p.OnlineScanInterval},
+		{"scantimeout", &p.ScanTimeout},
+	}
+	for _, pd := range durations {
+		v := req.FormValue(pd.param)
+		if v == "" {
+			continue
+		}
+		seconds, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse " + pd.param + ": " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		*pd.duration = time.Duration(seconds) * time.Second
+	}
+	if err := api.renter.SetScanPolicy(p); err != nil {
+		WriteError(w, Error{"unable to set scan policy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
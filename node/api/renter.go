@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -9,6 +11,7 @@ import (
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/modules/renter"
 	"github.com/NebulousLabs/Sia/types"
@@ -69,6 +72,18 @@ type (
 		Settings         modules.RenterSettings     `json:"settings"`
 		FinancialMetrics modules.ContractorSpending `json:"financialmetrics"`
 		CurrentPeriod    types.BlockHeight          `json:"currentperiod"`
+		Memory           modules.MemoryStatus       `json:"memory"`
+	}
+
+	// RenterAllowancePlanGET contains the contracts that the contractor would
+	// form for a proposed allowance.
+	RenterAllowancePlanGET struct {
+		modules.AllowancePlan
+	}
+
+	// RenterBackupsGET lists the snapshots of the renter's metadata.
+	RenterBackupsGET struct {
+		Snapshots []modules.RenterSnapshot `json:"snapshots"`
 	}
 
 	// RenterContract represents a contract formed by the renter.
@@ -123,6 +138,12 @@ type (
 		Downloads []DownloadInfo `json:"downloads"`
 	}
 
+	// RenterDownloadEstimateGET lists the data that is returned when a GET
+	// call is made to /renter/downloadestimate.
+	RenterDownloadEstimateGET struct {
+		modules.RenterDownloadEstimate
+	}
+
 	// RenterFile lists the file queried.
 	RenterFile struct {
 		File modules.FileInfo `json:"file"`
@@ -144,6 +165,34 @@ type (
 		modules.RenterPriceEstimation
 	}
 
+	// RenterRateLimitGET contains the bandwidth limits currently in effect.
+	RenterRateLimitGET struct {
+		modules.RenterRateLimitStatus
+	}
+
+	// RenterRecoveryScanGET reports the progress of a scan of the blockchain
+	// for the renter's contracts.
+	RenterRecoveryScanGET struct {
+		modules.ContractRecoveryStatus
+	}
+
+	// RenterRejectedHostsGET lists the hosts that were rejected because their
+	// prices exceed the caps of the allowance.
+	RenterRejectedHostsGET struct {
+		Hosts []modules.RejectedHost `json:"hosts"`
+	}
+
+	// RenterSpendingGET contains the spending of billing periods.
+	RenterSpendingGET struct {
+		Periods []modules.ContractorPeriodSpending `json:"periods"`
+	}
+
+	// RenterSyncGET lists the sync jobs of the renter along with their
+	// progress.
+	RenterSyncGET struct {
+		Jobs []modules.RenterSyncJob `json:"jobs"`
+	}
+
 	// RenterShareASCII contains an ASCII-encoded .sia file.
 	RenterShareASCII struct {
 		ASCIIsia string `json:"asciisia"`
@@ -175,66 +224,128 @@ func (api *API) renterHandlerGET(w http.ResponseWriter, req *http.Request, _ htt
 		Settings:         settings,
 		FinancialMetrics: api.renter.PeriodSpending(),
 		CurrentPeriod:    periodStart,
+		Memory:           api.renter.MemoryStatus(),
 	})
 }
 
-// renterHandlerPOST handles the API call to set the Renter's settings.
-func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	// Get the existing settings
-	settings := api.renter.Settings()
-
+// scanAllowance updates the allowance with the allowance parameters of the
+// request. Parameters that are not set keep their value in the allowance.
+func scanAllowance(req *http.Request, a modules.Allowance) (modules.Allowance, error) {
 	// Scan the allowance amount. (optional parameter)
 	if f := req.FormValue("funds"); f != "" {
 		funds, ok := scanAmount(f)
 		if !ok {
-			WriteError(w, Error{"unable to parse funds"}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, errors.New("unable to parse funds")
 		}
-		settings.Allowance.Funds = funds
+		a.Funds = funds
 	}
 	// Scan the number of hosts to use. (optional parameter)
 	if h := req.FormValue("hosts"); h != "" {
 		var hosts uint64
 		if _, err := fmt.Sscan(h, &hosts); err != nil {
-			WriteError(w, Error{"unable to parse hosts: " + err.Error()}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, errors.New("unable to parse hosts: " + err.Error())
 		} else if hosts != 0 && hosts < requiredHosts {
-			WriteError(w, Error{fmt.Sprintf("insufficient number of hosts, need at least %v but have %v", recommendedHosts, hosts)}, http.StatusBadRequest)
+			return modules.Allowance{}, fmt.Errorf("insufficient number of hosts, need at least %v but have %v", recommendedHosts, hosts)
 		} else {
-			settings.Allowance.Hosts = hosts
+			a.Hosts = hosts
 		}
-	} else if settings.Allowance.Hosts == 0 {
+	} else if a.Hosts == 0 {
 		// Sane defaults if host haven't been set before.
-		settings.Allowance.Hosts = recommendedHosts
+		a.Hosts = recommendedHosts
 	}
 	// Scan the period. (optional parameter)
 	if p := req.FormValue("period"); p != "" {
 		var period types.BlockHeight
 		if _, err := fmt.Sscan(p, &period); err != nil {
-			WriteError(w, Error{"unable to parse period: " + err.Error()}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, errors.New("unable to parse period: " + err.Error())
 		}
-		settings.Allowance.Period = types.BlockHeight(period)
-	} else if settings.Allowance.Period == 0 {
-		WriteError(w, Error{"period needs to be set if it hasn't been set before"}, http.StatusBadRequest)
-		return
+		a.Period = types.BlockHeight(period)
+	} else if a.Period == 0 {
+		return modules.Allowance{}, errors.New("period needs to be set if it hasn't been set before")
 	}
 	// Scan the renew window. (optional parameter)
 	if rw := req.FormValue("renewwindow"); rw != "" {
 		var renewWindow types.BlockHeight
 		if _, err := fmt.Sscan(rw, &renewWindow); err != nil {
-			WriteError(w, Error{"unable to parse renewwindow: " + err.Error()}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, errors.New("unable to parse renewwindow: " + err.Error())
 		} else if renewWindow != 0 && types.BlockHeight(renewWindow) < requiredRenewWindow {
-			WriteError(w, Error{fmt.Sprintf("renew window is too small, must be at least %v blocks but have %v blocks", requiredRenewWindow, renewWindow)}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, fmt.Errorf("renew window is too small, must be at least %v blocks but have %v blocks", requiredRenewWindow, renewWindow)
 		} else {
-			settings.Allowance.RenewWindow = types.BlockHeight(renewWindow)
+			a.RenewWindow = types.BlockHeight(renewWindow)
 		}
-	} else if settings.Allowance.RenewWindow == 0 {
+	} else if a.RenewWindow == 0 {
 		// Sane defaults if renew window hasn't been set before.
-		settings.Allowance.RenewWindow = settings.Allowance.Period / 2
+		a.RenewWindow = a.Period / 2
+	}
+	// Scan the expected usage. (optional parameters)
+	expectedUsage := []struct {
+		name  string
+		bytes *uint64
+	}{
+		{"expectedstorage", &a.ExpectedStorage},
+		{"expectedupload", &a.ExpectedUpload},
+		{"expecteddownload", &a.ExpectedDownload},
+	}
+	for _, eu := range expectedUsage {
+		if v := req.FormValue(eu.name); v != "" {
+			if _, err := fmt.Sscan(v, eu.bytes); err != nil {
+				return modules.Allowance{}, errors.New("unable to parse " + eu.name + ": " + err.Error())
+			}
+		}
+	}
+	if er := req.FormValue("expectedredundancy"); er != "" {
+		var redundancy float64
+		if _, err := fmt.Sscan(er, &redundancy); err != nil {
+			return modules.Allowance{}, errors.New("unable to parse expectedredundancy: " + err.Error())
+		} else if redundancy != 0 && redundancy < 1 {
+			return modules.Allowance{}, errors.New("expectedredundancy must be at least 1")
+		}
+		a.ExpectedRedundancy = redundancy
+	}
+	// Scan the price caps. (optional parameters)
+	priceCaps := []struct {
+		name  string
+		price *types.Currency
+	}{
+		{"maxcontractprice", &a.MaxContractPrice},
+		{"maxstorageprice", &a.MaxStoragePrice},
+		{"maxuploadbandwidthprice", &a.MaxUploadBandwidthPrice},
+		{"maxdownloadbandwidthprice", &a.MaxDownloadBandwidthPrice},
+	}
+	for _, pc := range priceCaps {
+		if v := req.FormValue(pc.name); v != "" {
+			price, ok := scanAmount(v)
+			if !ok {
+				return modules.Allowance{}, errors.New("unable to parse " + pc.name)
+			}
+			*pc.price = price
+		}
+	}
+	// Scan the collateral ratio cap. (optional parameter)
+	if cr := req.FormValue("maxcollateralratio"); cr != "" {
+		var ratio float64
+		if _, err := fmt.Sscan(cr, &ratio); err != nil {
+			return modules.Allowance{}, errors.New("unable to parse maxcollateralratio: " + err.Error())
+		} else if ratio < 0 {
+			return modules.Allowance{}, errors.New("maxcollateralratio cannot be negative")
+		}
+		a.MaxCollateralRatio = ratio
+	}
+	return a, nil
+}
+
+// renterHandlerPOST handles the API call to set the Renter's settings.
+func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Get the existing settings
+	settings := api.renter.Settings()
+
+	// Scan the allowance. (optional parameters)
+	allowance, err := scanAllowance(req, settings.Allowance)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
 	}
+	settings.Allowance = allowance
 	// Scan the download speed limit. (optional parameter)
 	if d := req.FormValue("maxdownloadspeed"); d != "" {
 		var downloadSpeed int64
@@ -262,8 +373,51 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 		}
 		settings.StreamCacheSize = streamCacheSize
 	}
+	// Scan the memory budget. (optional parameter)
+	if mm := req.FormValue("maxmemory"); mm != "" {
+		var maxMemory uint64
+		if _, err := fmt.Sscan(mm, &maxMemory); err != nil {
+			WriteError(w, Error{"unable to parse maxmemory: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.MaxMemory = maxMemory
+	}
+	// Scan the rate limit schedule. (optional parameter)
+	if rls := req.FormValue("ratelimitschedule"); rls != "" {
+		var schedule []modules.RateLimitWindow
+		if err := json.Unmarshal([]byte(rls), &schedule); err != nil {
+			WriteError(w, Error{"unable to parse ratelimitschedule: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.RateLimitSchedule = schedule
+	}
+	// Scan whether hosts are benchmarked. (optional parameter)
+	if bh := req.FormValue("benchmarkhosts"); bh != "" {
+		benchmarkHosts, err := scanBool(bh)
+		if err != nil {
+			WriteError(w, Error{"unable to parse benchmarkhosts: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.BenchmarkHosts = benchmarkHosts
+	}
+	// Scan the memory reserves. (optional parameters)
+	for _, reserve := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"uploadmemoryreserve", &settings.UploadMemoryReserve},
+		{"downloadmemoryreserve", &settings.DownloadMemoryReserve},
+		{"streammemoryreserve", &settings.StreamMemoryReserve},
+	} {
+		if v := req.FormValue(reserve.name); v != "" {
+			if _, err := fmt.Sscan(v, reserve.value); err != nil {
+				WriteError(w, Error{"unable to parse " + reserve.name + ": " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+	}
 	// Set the settings in the renter.
-	err := api.renter.SetSettings(settings)
+	err = api.renter.SetSettings(settings)
 	if err != nil {
 		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
@@ -271,54 +425,197 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 	WriteSuccess(w)
 }
 
-// renterContractsHandler handles the API call to request the Renter's contracts.
-func (api *API) renterContractsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
-	contracts := []RenterContract{}
-	for _, c := range api.renter.Contracts() {
-		var size uint64
-		if len(c.Transaction.FileContractRevisions) != 0 {
-			size = c.Transaction.FileContractRevisions[0].NewFileSize
-		}
+// renterAllowancePlanHandlerGET handles the API call to simulate the contract
+// formation for a proposed allowance. Allowance parameters that are not
+// provided are taken from the current allowance.
+func (api *API) renterAllowancePlanHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	allowance, err := scanAllowance(req, api.renter.Settings().Allowance)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	plan, err := api.renter.PlanAllowance(allowance)
+	if err != nil {
+		WriteError(w, Error{"unable to plan allowance: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterAllowancePlanGET{plan})
+}
 
-		// Fetch host address
-		var netAddress modules.NetAddress
-		hdbe, exists := api.renter.Host(c.HostPublicKey)
-		if exists {
-			netAddress = hdbe.NetAddress
-		}
+// renterBackupsHandlerGET handles the API call to list the snapshots of the
+// renter's metadata.
+func (api *API) renterBackupsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterBackupsGET{
+		Snapshots: api.renter.Snapshots(),
+	})
+}
+
+// renterBackupsHandlerPOST handles the API call to upload a snapshot of the
+// renter's metadata to hosts.
+func (api *API) renterBackupsHandlerPOST(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	snapshot, err := api.renter.CreateSnapshot()
+	if err != nil {
+		WriteError(w, Error{"unable to create snapshot: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, snapshot)
+}
 
-		// Fetch utilities for contract
-		var goodForUpload bool
-		var goodForRenew bool
-		if utility, ok := api.renter.ContractUtility(c.ID); ok {
-			goodForUpload = utility.GoodForUpload
-			goodForRenew = utility.GoodForRenew
+// renterBackupsRestoreHandler handles the API call to restore a snapshot of
+// the renter's metadata.
+func (api *API) renterBackupsRestoreHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var id crypto.Hash
+	if idStr := req.FormValue("id"); idStr != "" {
+		var err error
+		if id, err = scanHash(idStr); err != nil {
+			WriteError(w, Error{"unable to parse id: " + err.Error()}, http.StatusBadRequest)
+			return
 		}
+	}
+	if err := api.renter.RestoreSnapshot(id); err != nil {
+		WriteError(w, Error{"unable to restore snapshot: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
 
-		contracts = append(contracts, RenterContract{
-			DownloadSpending:          c.DownloadSpending,
-			EndHeight:                 c.EndHeight,
-			Fees:                      c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
-			GoodForUpload:             goodForUpload,
-			GoodForRenew:              goodForRenew,
-			HostPublicKey:             c.HostPublicKey,
-			ID:                        c.ID,
-			LastTransaction:           c.Transaction,
-			NetAddress:                netAddress,
-			RenterFunds:               c.RenterFunds,
-			Size:                      size,
-			StartHeight:               c.StartHeight,
-			StorageSpending:           c.StorageSpending,
-			StorageSpendingDeprecated: c.StorageSpending,
-			TotalCost:                 c.TotalCost,
-			UploadSpending:            c.UploadSpending,
-		})
+// renterContract converts a contract of the renter into the API
+// representation of a contract.
+func (api *API) renterContract(c modules.RenterContract) RenterContract {
+	var size uint64
+	if len(c.Transaction.FileContractRevisions) != 0 {
+		size = c.Transaction.FileContractRevisions[0].NewFileSize
+	}
+
+	// Fetch host address
+	var netAddress modules.NetAddress
+	hdbe, exists := api.renter.Host(c.HostPublicKey)
+	if exists {
+		netAddress = hdbe.NetAddress
+	}
+
+	// Fetch utilities for contract
+	var goodForUpload bool
+	var goodForRenew bool
+	if utility, ok := api.renter.ContractUtility(c.ID); ok {
+		goodForUpload = utility.GoodForUpload
+		goodForRenew = utility.GoodForRenew
+	}
+
+	return RenterContract{
+		DownloadSpending:          c.DownloadSpending,
+		EndHeight:                 c.EndHeight,
+		Fees:                      c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
+		GoodForUpload:             goodForUpload,
+		GoodForRenew:              goodForRenew,
+		HostPublicKey:             c.HostPublicKey,
+		ID:                        c.ID,
+		LastTransaction:           c.Transaction,
+		NetAddress:                netAddress,
+		RenterFunds:               c.RenterFunds,
+		Size:                      size,
+		StartHeight:               c.StartHeight,
+		StorageSpending:           c.StorageSpending,
+		StorageSpendingDeprecated: c.StorageSpending,
+		TotalCost:                 c.TotalCost,
+		UploadSpending:            c.UploadSpending,
+	}
+}
+
+// renterContractsHandler handles the API call to request the Renter's contracts.
+func (api *API) renterContractsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	contracts := []RenterContract{}
+	for _, c := range api.renter.Contracts() {
+		contracts = append(contracts, api.renterContract(c))
 	}
 	WriteJSON(w, RenterContracts{
 		Contracts: contracts,
 	})
 }
 
+// scanContractFunds scans the optional funds of a manually formed or renewed
+// contract. Zero funds select the default funding.
+func scanContractFunds(req *http.Request) (types.Currency, error) {
+	f := req.FormValue("funds")
+	if f == "" {
+		return types.ZeroCurrency, nil
+	}
+	funds, ok := scanAmount(f)
+	if !ok {
+		return types.ZeroCurrency, errors.New("unable to parse funds")
+	}
+	return funds, nil
+}
+
+// scanContractID scans the id of a contract from the request.
+func scanContractID(req *http.Request) (types.FileContractID, error) {
+	if req.FormValue("id") == "" {
+		return types.FileContractID{}, errors.New("contract id must be specified")
+	}
+	id, err := scanHash(req.FormValue("id"))
+	if err != nil {
+		return types.FileContractID{}, errors.New("unable to parse contract id: " + err.Error())
+	}
+	return types.FileContractID(id), nil
+}
+
+// renterContractsFormHandler handles the API call to form a contract with a
+// specific host.
+func (api *API) renterContractsFormHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	if req.FormValue("host") == "" {
+		WriteError(w, Error{"host public key must be specified"}, http.StatusBadRequest)
+		return
+	}
+	var spk types.SiaPublicKey
+	spk.LoadString(req.FormValue("host"))
+	funds, err := scanContractFunds(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	contract, err := api.renter.FormContract(spk, funds)
+	if err != nil {
+		WriteError(w, Error{"unable to form contract: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, api.renterContract(contract))
+}
+
+// renterContractsCancelHandler handles the API call to cancel a contract.
+func (api *API) renterContractsCancelHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	id, err := scanContractID(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err := api.renter.CancelContract(id); err != nil {
+		WriteError(w, Error{"unable to cancel contract: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterContractsRenewHandler handles the API call to renew a contract
+// immediately.
+func (api *API) renterContractsRenewHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	id, err := scanContractID(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	funds, err := scanContractFunds(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	contract, err := api.renter.RenewContract(id, funds)
+	if err != nil {
+		WriteError(w, Error{"unable to renew contract: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, api.renterContract(contract))
+}
+
 // renterDownloadsHandler handles the API call to request the download queue.
 func (api *API) renterDownloadsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	var downloads []DownloadInfo
@@ -387,6 +684,19 @@ func (api *API) renterRenameHandler(w http.ResponseWriter, req *http.Request, ps
 	WriteSuccess(w)
 }
 
+// renterDownloadEstimateHandler handles the API call to estimate the cost and
+// duration of downloading every file under a siapath prefix.
+func (api *API) renterDownloadEstimateHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	est, err := api.renter.EstimateDownload(strings.TrimPrefix(ps.ByName("siapath"), "/"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterDownloadEstimateGET{
+		RenterDownloadEstimate: est,
+	})
+}
+
 // renterFileHandler handles the API call to return specific file.
 func (api *API) renterFileHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	file, err := api.renter.File(strings.TrimPrefix(ps.ByName("siapath"), "/"))
@@ -414,6 +724,125 @@ func (api *API) renterPricesHandler(w http.ResponseWriter, req *http.Request, _
 	})
 }
 
+// renterRateLimitHandler handles the API call to report the bandwidth limits
+// currently in effect.
+func (api *API) renterRateLimitHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterRateLimitGET{
+		RenterRateLimitStatus: api.renter.RateLimitStatus(),
+	})
+}
+
+// renterRecoveryScanHandlerGET handles the API call to report the progress of
+// the most recent scan of the blockchain for the renter's contracts.
+func (api *API) renterRecoveryScanHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterRecoveryScanGET{api.renter.ContractRecoveryStatus()})
+}
+
+// renterRecoveryScanHandlerPOST handles the API call to start a scan of the
+// blockchain for the renter's contracts.
+func (api *API) renterRecoveryScanHandlerPOST(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	if err := api.renter.RecoverContracts(); err != nil {
+		WriteError(w, Error{"unable to start recovery scan: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterRejectedHostsHandler handles the API call to list the hosts that were
+// rejected because their prices exceed the caps of the allowance.
+func (api *API) renterRejectedHostsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterRejectedHostsGET{
+		Hosts: api.renter.RejectedHosts(),
+	})
+}
+
+// renterSpendingHandler handles the API call to report the spending of every
+// billing period, or of the period that started at the given height.
+func (api *API) renterSpendingHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	periods := api.renter.SpendingHistory()
+	if p := req.FormValue("period"); p != "" {
+		var start types.BlockHeight
+		if _, err := fmt.Sscan(p, &start); err != nil {
+			WriteError(w, Error{"unable to parse period: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		var match []modules.ContractorPeriodSpending
+		for _, period := range periods {
+			if period.StartHeight == start {
+				match = append(match, period)
+			}
+		}
+		if len(match) == 0 {
+			WriteError(w, Error{fmt.Sprintf("no period started at height %v", start)}, http.StatusBadRequest)
+			return
+		}
+		periods = match
+	}
+	WriteJSON(w, RenterSpendingGET{
+		Periods: periods,
+	})
+}
+
+// renterSyncHandlerGET handles the API call to list the recurring sync jobs.
+func (api *API) renterSyncHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterSyncGET{
+		Jobs: api.renter.SyncJobs(),
+	})
+}
+
+// renterSyncHandlerPOST handles the API call to sync a local directory to a
+// siapath prefix.
+func (api *API) renterSyncHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	params := modules.RenterSyncParams{
+		Source:  req.FormValue("source"),
+		SiaPath: strings.TrimPrefix(ps.ByName("siapath"), "/"),
+	}
+	if !filepath.IsAbs(params.Source) {
+		WriteError(w, Error{"source must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	if d := req.FormValue("delete"); d != "" {
+		_, err := fmt.Sscan(d, &params.Delete)
+		if err != nil {
+			WriteError(w, Error{"unable to parse delete: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if dr := req.FormValue("dryrun"); dr != "" {
+		_, err := fmt.Sscan(dr, &params.DryRun)
+		if err != nil {
+			WriteError(w, Error{"unable to parse dryrun: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if i := req.FormValue("interval"); i != "" {
+		var seconds uint64
+		_, err := fmt.Sscan(i, &seconds)
+		if err != nil {
+			WriteError(w, Error{"unable to parse interval: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		params.Interval = time.Duration(seconds) * time.Second
+	}
+
+	err := api.renter.Sync(params)
+	if err != nil {
+		WriteError(w, Error{"unable to start sync: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterSyncCancelHandler handles the API call to cancel a recurring sync job.
+func (api *API) renterSyncCancelHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	err := api.renter.CancelSync(strings.TrimPrefix(ps.ByName("siapath"), "/"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterDeleteHandler handles the API call to delete a file entry from the
 // renter.
 func (api *API) renterDeleteHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
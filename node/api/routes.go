@@ -69,11 +69,21 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 	if api.renter != nil {
 		router.GET("/renter", api.renterHandlerGET)
 		router.POST("/renter", RequirePassword(api.renterHandlerPOST, requiredPassword))
+		router.GET("/renter/allowanceplan", api.renterAllowancePlanHandlerGET)
+		router.GET("/renter/backups", api.renterBackupsHandlerGET)
+		router.POST("/renter/backups", RequirePassword(api.renterBackupsHandlerPOST, requiredPassword))
 		router.GET("/renter/contracts", api.renterContractsHandler)
 		router.GET("/renter/downloads", api.renterDownloadsHandler)
+		router.GET("/renter/downloadestimate/*siapath", api.renterDownloadEstimateHandler)
 		router.GET("/renter/files", api.renterFilesHandler)
 		router.GET("/renter/file/*siapath", api.renterFileHandler)
 		router.GET("/renter/prices", api.renterPricesHandler)
+		router.GET("/renter/ratelimit", api.renterRateLimitHandler)
+		router.GET("/renter/recoveryscan", api.renterRecoveryScanHandlerGET)
+		router.POST("/renter/recoveryscan", RequirePassword(api.renterRecoveryScanHandlerPOST, requiredPassword))
+		router.GET("/renter/rejectedhosts", api.renterRejectedHostsHandler)
+		router.GET("/renter/spending", api.renterSpendingHandler)
+		router.GET("/renter/sync", api.renterSyncHandlerGET)
 
 		// TODO: re-enable these routes once the new .sia format has been
 		// standardized and implemented.
@@ -82,17 +92,35 @@ func (api *API) buildHTTPRoutes(requiredUserAgent string, requiredPassword strin
 		// router.GET("/renter/share", RequirePassword(api.renterShareHandler, requiredPassword))
 		// router.GET("/renter/shareascii", RequirePassword(api.renterShareAsciiHandler, requiredPassword))
 
+		router.POST("/renter/backups/restore", RequirePassword(api.renterBackupsRestoreHandler, requiredPassword))
+		router.POST("/renter/contracts/cancel", RequirePassword(api.renterContractsCancelHandler, requiredPassword))
+		router.POST("/renter/contracts/form", RequirePassword(api.renterContractsFormHandler, requiredPassword))
+		router.POST("/renter/contracts/renew", RequirePassword(api.renterContractsRenewHandler, requiredPassword))
 		router.POST("/renter/delete/*siapath", RequirePassword(api.renterDeleteHandler, requiredPassword))
 		router.GET("/renter/download/*siapath", RequirePassword(api.renterDownloadHandler, requiredPassword))
 		router.GET("/renter/downloadasync/*siapath", RequirePassword(api.renterDownloadAsyncHandler, requiredPassword))
 		router.POST("/renter/rename/*siapath", RequirePassword(api.renterRenameHandler, requiredPassword))
 		router.GET("/renter/stream/*siapath", api.renterStreamHandler)
+		router.POST("/renter/sync/*siapath", RequirePassword(api.renterSyncHandlerPOST, requiredPassword))
+		router.POST("/renter/synccancel/*siapath", RequirePassword(api.renterSyncCancelHandler, requiredPassword))
 		router.POST("/renter/upload/*siapath", RequirePassword(api.renterUploadHandler, requiredPassword))
 
 		// HostDB endpoints.
 		router.GET("/hostdb/active", api.hostdbActiveHandler)
 		router.GET("/hostdb/all", api.hostdbAllHandler)
+		router.POST("/hostdb/export", RequirePassword(api.hostdbExportHandler, requiredPassword))
+		router.GET("/hostdb/filtermode", api.hostdbFilterModeHandlerGET)
+		router.POST("/hostdb/filtermode", RequirePassword(api.hostdbFilterModeHandlerPOST, requiredPassword))
 		router.GET("/hostdb/hosts/:pubkey", api.hostdbHostsHandler)
+		router.GET("/hostdb/hosts/:pubkey/history", api.hostdbHostsHistoryHandler)
+		router.POST("/hostdb/import", RequirePassword(api.hostdbImportHandler, requiredPassword))
+		router.GET("/hostdb/rescan", api.hostdbRescanHandlerGET)
+		router.POST("/hostdb/rescan", RequirePassword(api.hostdbRescanHandlerPOST, requiredPassword))
+		router.POST("/hostdb/scan/:pubkey", RequirePassword(api.hostdbScanHandler, requiredPassword))
+		router.GET("/hostdb/scanpolicy", api.hostdbScanPolicyHandlerGET)
+		router.POST("/hostdb/scanpolicy", RequirePassword(api.hostdbScanPolicyHandlerPOST, requiredPassword))
+		router.GET("/hostdb/scoring", api.hostdbScoringHandlerGET)
+		router.POST("/hostdb/scoring", RequirePassword(api.hostdbScoringHandlerPOST, requiredPassword))
 	}
 
 	// Transaction pool API Calls
@@ -294,7 +294,7 @@ func New(params NodeParams) (*Node, error) {
 		if err != nil {
 			return nil, err
 		}
-		return renter.NewCustomRenter(g, cs, tp, hdb, hc, persistDir, renterDeps)
+		return renter.NewCustomRenter(g, cs, tp, w, hdb, hc, persistDir, renterDeps)
 	}()
 	if err != nil {
 		return nil, errors.Extend(err, errors.New("unable to create renter"))